@@ -0,0 +1,431 @@
+// Package api serves students, classes and their enrollments over a JSON REST
+// API, so that other tools can work with the same data as the application.
+//
+// Collections are listed with GET and added to with POST, single entries are
+// read with GET, replaced with PUT and removed with DELETE:
+//
+//	/students[/{id}]
+//	/classes[/{id}]
+//	/enrollments[/{student_id}]
+//
+// Lists are paginated with the limit and offset query parameters. The JSON
+// schemas of the entries are served under /schemas/.
+//
+// Requests are made on behalf of a user and may change what the user may
+// change. POST /login trades the username and password for a token, which
+// later requests send as "Authorization: Bearer <token>" until POST /logout
+// or until it expires. A database without users refuses everything but
+// POST /setup, which creates the first admin and logs them in.
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"eklase/state"
+	"eklase/storage"
+)
+
+// Limits of the page size of lists.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// maxBodySize is the largest accepted request body in bytes.
+const maxBodySize = 1 << 20
+
+// SessionTTL is how long a token of POST /login is valid.
+const SessionTTL = 12 * time.Hour
+
+// Handler serves the API. Requests are handled one at a time, as the state is
+// not safe for concurrent use. Passwords are checked on login only, as bcrypt
+// would hold up every other request.
+type Handler struct {
+	mu       sync.Mutex
+	state    *state.State
+	sessions map[string]session // Sessions by their token.
+	loggedIn bool               // Whether the current request logged in.
+	now      func() time.Time
+}
+
+// session is a user logged in through POST /login.
+type session struct {
+	userID  int
+	expires time.Time
+}
+
+// NewHandler returns a handler of the API backed by st.
+func NewHandler(st *state.State) *Handler {
+	return &Handler{state: st, sessions: make(map[string]session), now: time.Now}
+}
+
+// route is a collection of the API.
+type route struct {
+	list   func(h *Handler, r *http.Request) (interface{}, int, error)
+	add    func(h *Handler, r *http.Request) (interface{}, int, error) // Returns the entry and its ID.
+	get    func(h *Handler, id int) (interface{}, error)
+	update func(h *Handler, id int, r *http.Request) error
+	remove func(h *Handler, id int) error
+}
+
+// routes are the collections by the first segment of their path.
+var routes = map[string]route{
+	"students":    {listStudents, addStudent, getStudent, updateStudent, deleteStudent},
+	"classes":     {listClasses, addClass, getClass, updateClass, deleteClass},
+	"enrollments": {listEnrollments, addEnrollment, getEnrollment, updateEnrollment, deleteEnrollment},
+}
+
+// ServeHTTP dispatches a request by its path and method.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if segments[0] == "schemas" && len(segments) == 2 {
+		serveSchema(w, r, segments[1])
+		return
+	}
+	if serve, ok := sessionRoutes[segments[0]]; ok && len(segments) == 1 {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, "POST")
+			return
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		serve(h, w, r)
+		return
+	}
+	rt, ok := routes[segments[0]]
+	if !ok || len(segments) > 2 {
+		writeError(w, http.StatusNotFound, "not_found", "There is no such resource.", "")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.authenticate(r); err != nil {
+		switch {
+		case errors.Is(err, errNoUsers):
+			writeError(w, http.StatusForbidden, "setup_required", "Create the first user with POST /setup.", "")
+		case errors.Is(err, state.ErrLogin):
+			w.Header().Set("WWW-Authenticate", `Bearer realm="eklase"`)
+			writeStateError(w, err)
+		default:
+			writeStateError(w, err)
+		}
+		return
+	}
+	defer h.logout()
+
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			items, total, err := rt.list(h, r)
+			if err != nil {
+				writeStateError(w, err)
+				return
+			}
+			limit, offset, _ := page(r)
+			writeJSON(w, http.StatusOK, List{Items: items, Total: total, Limit: limit, Offset: offset})
+		case http.MethodPost:
+			entry, id, err := rt.add(h, r)
+			if err != nil {
+				writeStateError(w, err)
+				return
+			}
+			w.Header().Set("Location", fmt.Sprintf("/%s/%d", segments[0], id))
+			writeJSON(w, http.StatusCreated, entry)
+		default:
+			methodNotAllowed(w, "GET, POST")
+		}
+		return
+	}
+
+	id, err := strconv.Atoi(segments[1])
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusNotFound, "not_found", "The ID must be a positive number.", "")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		err = rt.update(h, id, r)
+	case http.MethodDelete:
+		if err := rt.remove(h, id); err != nil {
+			writeStateError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		methodNotAllowed(w, "GET, PUT, DELETE")
+		return
+	}
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+	entry, err := rt.get(h, id)
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// errNoUsers refuses requests until the first user is set up.
+var errNoUsers = errors.New("no users")
+
+// authenticate resumes the session of the token r carries.
+func (h *Handler) authenticate(r *http.Request) error {
+	token := bearerToken(r)
+	s, ok := h.sessions[token]
+	if ok && !h.now().Before(s.expires) {
+		delete(h.sessions, token)
+		ok = false
+	}
+	if !ok {
+		if users, err := h.state.HasUsers(); err != nil || !users {
+			if err != nil {
+				return err
+			}
+			return errNoUsers
+		}
+		return &storage.Error{Op: "authenticate", Kind: state.ErrLogin}
+	}
+	if err := h.state.Resume(s.userID); err != nil {
+		if errors.Is(err, state.ErrLogin) {
+			delete(h.sessions, token)
+		}
+		return err
+	}
+	h.loggedIn = true
+	return nil
+}
+
+// bearerToken returns the token of the Authorization header of r, or an empty
+// string if there is none.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(prefix):])
+}
+
+// Credentials are the body of POST /setup and POST /login.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Token is the response of POST /setup and POST /login.
+type Token struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// sessionRoutes are the paths starting and ending sessions, which take POST
+// requests only.
+var sessionRoutes = map[string]func(h *Handler, w http.ResponseWriter, r *http.Request){
+	"setup":  serveSetup,
+	"login":  serveLogin,
+	"logout": serveLogout,
+}
+
+// serveSetup creates the first admin of a database without users and logs
+// them in.
+func serveSetup(h *Handler, w http.ResponseWriter, r *http.Request) {
+	var c Credentials
+	if err := decode(r, &c); err != nil {
+		writeStateError(w, err)
+		return
+	}
+	if err := h.state.Setup(c.Username, c.Password); err != nil {
+		writeStateError(w, err)
+		return
+	}
+	h.startSession(w)
+}
+
+// serveLogin checks the credentials of a user and hands out a token of their
+// session.
+func serveLogin(h *Handler, w http.ResponseWriter, r *http.Request) {
+	var c Credentials
+	if err := decode(r, &c); err != nil {
+		writeStateError(w, err)
+		return
+	}
+	if err := h.state.Login(c.Username, c.Password); err != nil {
+		writeStateError(w, err)
+		return
+	}
+	h.startSession(w)
+}
+
+// serveLogout ends the session of the token the request carries.
+func serveLogout(h *Handler, w http.ResponseWriter, r *http.Request) {
+	delete(h.sessions, bearerToken(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startSession writes a new token of the session the state was logged in to,
+// and logs the state out again. Expired sessions are dropped meanwhile.
+func (h *Handler) startSession(w http.ResponseWriter) {
+	s, _ := h.state.Session()
+	h.state.Logout()
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		writeStateError(w, err)
+		return
+	}
+	now := h.now()
+	for token, s := range h.sessions {
+		if !now.Before(s.expires) {
+			delete(h.sessions, token)
+		}
+	}
+	token := hex.EncodeToString(b)
+	h.sessions[token] = session{userID: s.UserID, expires: now.Add(SessionTTL)}
+	writeJSON(w, http.StatusOK, Token{Token: token, Expires: h.sessions[token].expires})
+}
+
+// logout ends the session started by authenticate, if any.
+func (h *Handler) logout() {
+	if h.loggedIn {
+		h.state.Logout()
+		h.loggedIn = false
+	}
+}
+
+// List is a page of a collection.
+type List struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"` // Number of entries matching the query, across all pages.
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// errPage rejects bad pagination parameters.
+var errPage = &badRequest{fmt.Sprintf("The limit must be from 1 to %d and the offset 0 or more.", MaxLimit)}
+
+// page returns the limit and offset of the requested page.
+func page(r *http.Request) (limit, offset int, err error) {
+	limit, offset = DefaultLimit, 0
+	q := r.URL.Query()
+	if s := q.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > MaxLimit {
+			return 0, 0, errPage
+		}
+	}
+	if s := q.Get("offset"); s != "" {
+		if offset, err = strconv.Atoi(s); err != nil || offset < 0 {
+			return 0, 0, errPage
+		}
+	}
+	return limit, offset, nil
+}
+
+// paginate returns the bounds of the requested page within n entries.
+func paginate(r *http.Request, n int) (from, to int, err error) {
+	limit, offset, err := page(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	from, to = offset, offset+limit
+	if from > n {
+		from = n
+	}
+	if to > n {
+		to = n
+	}
+	return from, to, nil
+}
+
+// badRequest rejects requests that cannot be understood, e.g. a body that is
+// not the expected JSON object.
+type badRequest struct {
+	message string // A sentence that can be shown to the user.
+}
+
+func (e *badRequest) Error() string {
+	return e.message
+}
+
+// decode reads the JSON body of r into v, rejecting unknown fields.
+func decode(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return &badRequest{"The request body is not valid: " + err.Error() + "."}
+	}
+	if dec.More() {
+		return &badRequest{"The request body must be a single JSON object."}
+	}
+	return nil
+}
+
+// Error is the body of failed requests.
+type Error struct {
+	Code    string `json:"code"`            // E.g. "not_found".
+	Message string `json:"message"`         // A sentence that can be shown to the user.
+	Field   string `json:"field,omitempty"` // Rejected field of a validation error.
+}
+
+// writeStateError writes an error returned by the state with the status code
+// of its kind.
+func writeStateError(w http.ResponseWriter, err error) {
+	var (
+		berr *badRequest
+		verr *storage.ValidationError
+	)
+	switch {
+	case errors.As(err, &berr):
+		writeError(w, http.StatusBadRequest, "bad_request", berr.message, "")
+	case errors.As(err, &verr):
+		writeError(w, http.StatusUnprocessableEntity, "invalid", state.Message(err), verr.Field)
+	case errors.Is(err, state.ErrLogin):
+		writeError(w, http.StatusUnauthorized, "unauthorized", state.Message(err), "")
+	case errors.Is(err, state.ErrForbidden):
+		writeError(w, http.StatusForbidden, "forbidden", state.Message(err), "")
+	case errors.Is(err, storage.ErrNotFound):
+		writeError(w, http.StatusNotFound, "not_found", state.Message(err), "")
+	case errors.Is(err, storage.ErrDuplicate):
+		writeError(w, http.StatusConflict, "duplicate", state.Message(err), "")
+	case errors.Is(err, storage.ErrConstraint):
+		writeError(w, http.StatusConflict, "constraint", state.Message(err), "")
+	case errors.Is(err, storage.ErrDatabaseLocked):
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusServiceUnavailable, "locked", state.Message(err), "")
+	default:
+		writeError(w, http.StatusInternalServerError, "internal", state.Message(err), "")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message, field string) {
+	writeJSON(w, status, struct {
+		Error Error `json:"error"`
+	}{Error{Code: code, Message: message, Field: field}})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "The method is not allowed here.", "")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
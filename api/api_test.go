@@ -0,0 +1,262 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"eklase/state"
+	"eklase/storage"
+)
+
+// server is a test server of the API with the token of the user requests are
+// made by, if any.
+type server struct {
+	*httptest.Server
+	token string
+}
+
+// do sends a request with an optional JSON body to srv and decodes the
+// response into out, if given. It returns the response.
+func do(t *testing.T, srv *server, method, path, body string, out interface{}) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.token != "" {
+		req.Header.Set("Authorization", "Bearer "+srv.token)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: unable to decode the response: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+// newServer returns a server of an empty database whose first admin made the
+// requests.
+func newServer(t *testing.T) *server {
+	t.Helper()
+	srv := &server{Server: httptest.NewServer(NewHandler(state.New(storage.NewMemory())))}
+	t.Cleanup(srv.Close)
+	var token Token
+	if resp := do(t, srv, "POST", "/setup", `{"username": "admin", "password": "correct horse"}`, &token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /setup = %d, want 200", resp.StatusCode)
+	}
+	srv.token = token.Token
+	return srv
+}
+
+func TestStudents(t *testing.T) {
+	srv := newServer(t)
+
+	var s Student
+	resp := do(t, srv, "POST", "/students", `{"name": "Anna", "surname": "Zariņa"}`, &s)
+	if resp.StatusCode != http.StatusCreated || s.ID == 0 || s.Surname != "Zariņa" {
+		t.Fatalf("POST /students = %d %+v, want 201 and the student", resp.StatusCode, s)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/students/1" {
+		t.Errorf("Location = %q, want /students/1", loc)
+	}
+	resp = do(t, srv, "PUT", "/students/1", `{"name": "Anna", "surname": "Bērziņa"}`, &s)
+	if resp.StatusCode != http.StatusOK || s.Surname != "Bērziņa" {
+		t.Errorf("PUT /students/1 = %d %+v, want 200 and the new surname", resp.StatusCode, s)
+	}
+	if resp = do(t, srv, "GET", "/students/1", "", &s); resp.StatusCode != http.StatusOK || s.Surname != "Bērziņa" {
+		t.Errorf("GET /students/1 = %d %+v, want the updated student", resp.StatusCode, s)
+	}
+	if resp = do(t, srv, "DELETE", "/students/1", "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE /students/1 = %d, want 204", resp.StatusCode)
+	}
+	if resp = do(t, srv, "GET", "/students/1", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /students/1 after DELETE = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	srv := newServer(t)
+	for _, name := range []string{"Anna", "Jānis", "Līga", "Pēteris", "Marta"} {
+		do(t, srv, "POST", "/students", `{"name": "`+name+`", "surname": "Ozols"}`, nil)
+	}
+	var page struct {
+		Items  []Student
+		Total  int
+		Limit  int
+		Offset int
+	}
+	if resp := do(t, srv, "GET", "/students?limit=2&offset=3", "", &page); resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /students?limit=2&offset=3 = %d, want 200", resp.StatusCode)
+	}
+	if page.Total != 5 || page.Limit != 2 || page.Offset != 3 || len(page.Items) != 2 || page.Items[0].Name != "Pēteris" {
+		t.Errorf("page = %+v, want Pēteris and Marta of 5", page)
+	}
+	do(t, srv, "GET", "/students?offset=10", "", &page)
+	if len(page.Items) != 0 || page.Items == nil || page.Limit != DefaultLimit {
+		t.Errorf("page past the end = %+v, want no items", page)
+	}
+}
+
+func TestEnrollments(t *testing.T) {
+	srv := newServer(t)
+	do(t, srv, "POST", "/classes", `{"year": "5", "modifier": "a"}`, nil)
+	do(t, srv, "POST", "/classes", `{"year": "6", "modifier": "b"}`, nil)
+	do(t, srv, "POST", "/students", `{"name": "Anna", "surname": "Zariņa"}`, nil)
+
+	var e Enrollment
+	resp := do(t, srv, "POST", "/enrollments", `{"student_id": 1, "class_id": 1}`, &e)
+	if resp.StatusCode != http.StatusCreated || e.Year != "5" || e.Name != "Anna" {
+		t.Fatalf("POST /enrollments = %d %+v, want Anna in 5.a", resp.StatusCode, e)
+	}
+	if resp = do(t, srv, "POST", "/enrollments", `{"student_id": 1, "class_id": 2}`, nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("POST /enrollments of an enrolled student = %d, want 409", resp.StatusCode)
+	}
+	if resp = do(t, srv, "PUT", "/enrollments/1", `{"class_id": 2}`, &e); resp.StatusCode != http.StatusOK || e.Year != "6" {
+		t.Errorf("PUT /enrollments/1 = %d %+v, want Anna in 6.b", resp.StatusCode, e)
+	}
+	var page struct{ Items []Enrollment }
+	do(t, srv, "GET", "/enrollments?class_id=1", "", &page)
+	if len(page.Items) != 0 {
+		t.Errorf("GET /enrollments?class_id=1 = %+v, want nobody", page.Items)
+	}
+	if resp = do(t, srv, "DELETE", "/classes/2", "", nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("DELETE /classes/2 with a student = %d, want 409", resp.StatusCode)
+	}
+	do(t, srv, "DELETE", "/enrollments/1", "", nil)
+	if resp = do(t, srv, "GET", "/enrollments/1", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /enrollments/1 after DELETE = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := newServer(t)
+	do(t, srv, "POST", "/classes", `{"year": "5", "modifier": "a"}`, nil)
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+		code, field        string
+	}{
+		{"POST", "/students", `{"name": "", "surname": "Ozols"}`, http.StatusUnprocessableEntity, "invalid", "name"},
+		{"POST", "/students", `{"name": "Anna", "surname": "Ozola", "age": 12}`, http.StatusBadRequest, "bad_request", ""},
+		{"POST", "/students", `not json`, http.StatusBadRequest, "bad_request", ""},
+		{"POST", "/classes", `{"year": "5", "modifier": "a"}`, http.StatusConflict, "duplicate", ""},
+		{"PUT", "/classes/9", `{"year": "5", "modifier": "b"}`, http.StatusNotFound, "not_found", ""},
+		{"POST", "/enrollments", `{"student_id": 7, "class_id": 1}`, http.StatusNotFound, "not_found", ""},
+		{"GET", "/students?limit=0", "", http.StatusBadRequest, "bad_request", ""},
+		{"GET", "/students/abc", "", http.StatusNotFound, "not_found", ""},
+		{"GET", "/teachers", "", http.StatusNotFound, "not_found", ""},
+		{"PATCH", "/students", "", http.StatusMethodNotAllowed, "method_not_allowed", ""},
+	} {
+		var body struct{ Error Error }
+		resp := do(t, srv, tc.method, tc.path, tc.body, &body)
+		if resp.StatusCode != tc.status || body.Error.Code != tc.code || body.Error.Field != tc.field || body.Error.Message == "" {
+			t.Errorf("%s %s = %d %+v, want %d %s %q", tc.method, tc.path, resp.StatusCode, body.Error, tc.status, tc.code, tc.field)
+		}
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	srv := newServer(t)
+	for name := range Schemas {
+		var schema map[string]interface{}
+		if resp := do(t, srv, "GET", "/schemas/"+name, "", &schema); resp.StatusCode != http.StatusOK || schema["$id"] != "/schemas/"+name {
+			t.Errorf("GET /schemas/%s = %d %v, want the schema", name, resp.StatusCode, schema["$id"])
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	srv := &server{Server: httptest.NewServer(NewHandler(state.New(storage.NewMemory())))}
+	defer srv.Close()
+
+	var body struct{ Error Error }
+	if resp := do(t, srv, "GET", "/students", "", &body); resp.StatusCode != http.StatusForbidden || body.Error.Code != "setup_required" {
+		t.Errorf("GET /students without users = %d %+v, want 403 setup_required", resp.StatusCode, body.Error)
+	}
+	if resp := do(t, srv, "POST", "/login", `{"username": "admin", "password": "correct horse"}`, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("POST /login without users = %d, want 401", resp.StatusCode)
+	}
+	if resp := do(t, srv, "POST", "/setup", `{"username": "admin", "password": "short"}`, nil); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("POST /setup with a short password = %d, want 422", resp.StatusCode)
+	}
+	var token Token
+	if resp := do(t, srv, "POST", "/setup", `{"username": "admin", "password": "correct horse"}`, &token); resp.StatusCode != http.StatusOK || token.Token == "" {
+		t.Fatalf("POST /setup = %d %+v, want 200 and a token", resp.StatusCode, token)
+	}
+	if resp := do(t, srv, "POST", "/setup", `{"username": "root", "password": "correct horse"}`, nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("POST /setup with a user = %d, want 403", resp.StatusCode)
+	}
+	srv.token = token.Token
+	if resp := do(t, srv, "POST", "/students", `{"name": "Anna", "surname": "Zariņa"}`, nil); resp.StatusCode != http.StatusCreated {
+		t.Errorf("POST /students as the first admin = %d, want 201", resp.StatusCode)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	st := state.New(storage.NewMemory())
+	if _, err := st.AddUser("admin", "correct horse", storage.RoleAdmin, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.AddUser("sekretare", "correct horse", storage.RoleSecretary, 0); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(st)
+	srv := &server{Server: httptest.NewServer(h)}
+	defer srv.Close()
+
+	login := func(username, password string) (*server, int) {
+		t.Helper()
+		var token Token
+		resp := do(t, srv, "POST", "/login", `{"username": "`+username+`", "password": "`+password+`"}`, &token)
+		return &server{Server: srv.Server, token: token.Token}, resp.StatusCode
+	}
+
+	resp := do(t, srv, "GET", "/students", "", nil)
+	if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Errorf("GET /students without a token = %d, want 401 with a challenge", resp.StatusCode)
+	}
+	if _, status := login("admin", "wrong horse"); status != http.StatusUnauthorized {
+		t.Errorf("POST /login with a wrong password = %d, want 401", status)
+	}
+	bogus := &server{Server: srv.Server, token: "bogus"}
+	if resp := do(t, bogus, "GET", "/students", "", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /students with a bogus token = %d, want 401", resp.StatusCode)
+	}
+	secretary, _ := login("sekretare", "correct horse")
+	admin, _ := login("admin", "correct horse")
+	if resp := do(t, secretary, "GET", "/students", "", nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /students as a secretary = %d, want 200", resp.StatusCode)
+	}
+	body := `{"name": "Anna", "surname": "Zariņa"}`
+	if resp := do(t, secretary, "POST", "/students", body, nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("POST /students as a secretary = %d, want 403", resp.StatusCode)
+	}
+	if resp := do(t, admin, "POST", "/students", body, nil); resp.StatusCode != http.StatusCreated {
+		t.Errorf("POST /students as an admin = %d, want 201", resp.StatusCode)
+	}
+	if _, ok := st.Session(); ok {
+		t.Error("the state keeps a session after the request")
+	}
+
+	if resp := do(t, secretary, "POST", "/logout", "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("POST /logout = %d, want 204", resp.StatusCode)
+	}
+	if resp := do(t, secretary, "GET", "/students", "", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /students after logout = %d, want 401", resp.StatusCode)
+	}
+	h.mu.Lock()
+	h.now = func() time.Time { return time.Now().Add(SessionTTL) }
+	h.mu.Unlock()
+	if resp := do(t, admin, "GET", "/students", "", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /students with an expired token = %d, want 401", resp.StatusCode)
+	}
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"eklase/storage"
+)
+
+// Student is a student in requests and responses. The ID is ignored in
+// requests.
+type Student struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
+}
+
+// Class is a class in requests and responses. Only the year and modifier are
+// read from requests.
+type Class struct {
+	ID           int    `json:"id"`
+	Year         string `json:"year"`
+	Modifier     string `json:"modifier"`
+	TeacherID    *int64 `json:"teacher_id"`     // Homeroom teacher, null if none.
+	SchoolYearID *int64 `json:"school_year_id"` // Null for classes older than school years.
+}
+
+// Enrollment is the class a student is enrolled in. Only the IDs are read from
+// requests, and the student ID from POST requests only.
+type Enrollment struct {
+	StudentID int    `json:"student_id"`
+	ClassID   int    `json:"class_id"`
+	Name      string `json:"name"`
+	Surname   string `json:"surname"`
+	Year      string `json:"year"`
+	Modifier  string `json:"modifier"`
+}
+
+func listStudents(h *Handler, r *http.Request) (interface{}, int, error) {
+	entries, err := h.state.Students()
+	if err != nil {
+		return nil, 0, err
+	}
+	from, to, err := paginate(r, len(entries))
+	if err != nil {
+		return nil, 0, err
+	}
+	students := make([]Student, 0, to-from)
+	for _, e := range entries[from:to] {
+		students = append(students, Student(e))
+	}
+	return students, len(entries), nil
+}
+
+func addStudent(h *Handler, r *http.Request) (interface{}, int, error) {
+	var in Student
+	if err := decode(r, &in); err != nil {
+		return nil, 0, err
+	}
+	id, err := h.state.AddStudent(in.Name, in.Surname)
+	if err != nil {
+		return nil, 0, err
+	}
+	s, err := getStudent(h, id)
+	return s, id, err
+}
+
+func getStudent(h *Handler, id int) (interface{}, error) {
+	e, err := h.state.Student(id)
+	return Student(e), err
+}
+
+func updateStudent(h *Handler, id int, r *http.Request) error {
+	var in Student
+	if err := decode(r, &in); err != nil {
+		return err
+	}
+	return h.state.UpdateStudent(id, in.Name, in.Surname)
+}
+
+func deleteStudent(h *Handler, id int) error {
+	return h.state.DeleteStudent(id)
+}
+
+// class converts a class entry.
+func class(e storage.ClassEntry) Class {
+	c := Class{ID: e.ID, Year: e.Year, Modifier: e.Modifier}
+	if e.TeacherID.Valid {
+		c.TeacherID = &e.TeacherID.Int64
+	}
+	if e.SchoolYearID.Valid {
+		c.SchoolYearID = &e.SchoolYearID.Int64
+	}
+	return c
+}
+
+func listClasses(h *Handler, r *http.Request) (interface{}, int, error) {
+	entries, err := h.state.Classes()
+	if err != nil {
+		return nil, 0, err
+	}
+	from, to, err := paginate(r, len(entries))
+	if err != nil {
+		return nil, 0, err
+	}
+	classes := make([]Class, 0, to-from)
+	for _, e := range entries[from:to] {
+		classes = append(classes, class(e))
+	}
+	return classes, len(entries), nil
+}
+
+func addClass(h *Handler, r *http.Request) (interface{}, int, error) {
+	var in Class
+	if err := decode(r, &in); err != nil {
+		return nil, 0, err
+	}
+	id, err := h.state.AddClass(in.Year, in.Modifier)
+	if err != nil {
+		return nil, 0, err
+	}
+	c, err := getClass(h, id)
+	return c, id, err
+}
+
+func getClass(h *Handler, id int) (interface{}, error) {
+	e, err := h.state.Class(id)
+	return class(e), err
+}
+
+func updateClass(h *Handler, id int, r *http.Request) error {
+	var in Class
+	if err := decode(r, &in); err != nil {
+		return err
+	}
+	return h.state.UpdateClass(id, in.Year, in.Modifier)
+}
+
+func deleteClass(h *Handler, id int) error {
+	return h.state.DeleteClass(id)
+}
+
+// enrollment converts the group of an enrolled student.
+func enrollment(g storage.GroupEntry) Enrollment {
+	return Enrollment{
+		StudentID: g.StudentID,
+		ClassID:   int(g.ClassID.Int64),
+		Name:      g.Name,
+		Surname:   g.Surname,
+		Year:      g.Year.String,
+		Modifier:  g.Modifier.String,
+	}
+}
+
+// listEnrollments lists the enrolled students, only those of one class if
+// the class_id query parameter is given.
+func listEnrollments(h *Handler, r *http.Request) (interface{}, int, error) {
+	classID := 0
+	if s := r.URL.Query().Get("class_id"); s != "" {
+		var err error
+		if classID, err = strconv.Atoi(s); err != nil {
+			return nil, 0, &badRequest{"The class_id parameter must be a number."}
+		}
+	}
+	groups, err := h.state.Groups()
+	if err != nil {
+		return nil, 0, err
+	}
+	var all []Enrollment
+	for _, g := range groups {
+		if g.ClassID.Valid && (classID == 0 || int(g.ClassID.Int64) == classID) {
+			all = append(all, enrollment(g))
+		}
+	}
+	from, to, err := paginate(r, len(all))
+	if err != nil {
+		return nil, 0, err
+	}
+	return append([]Enrollment{}, all[from:to]...), len(all), nil
+}
+
+// addEnrollment enrolls a student who is not enrolled yet. Moving a student
+// to another class is done with PUT.
+func addEnrollment(h *Handler, r *http.Request) (interface{}, int, error) {
+	var in Enrollment
+	if err := decode(r, &in); err != nil {
+		return nil, 0, err
+	}
+	g, err := h.state.Group(in.StudentID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if g.ClassID.Valid {
+		return nil, 0, &storage.Error{Op: fmt.Sprintf("enroll student %d", in.StudentID), Kind: storage.ErrDuplicate}
+	}
+	if err := h.state.AssignClassToStudent(in.StudentID, in.ClassID); err != nil {
+		return nil, 0, err
+	}
+	e, err := getEnrollment(h, in.StudentID)
+	return e, in.StudentID, err
+}
+
+// getEnrollment returns the enrollment of a student, ErrNotFound if they are
+// not enrolled.
+func getEnrollment(h *Handler, studentID int) (interface{}, error) {
+	g, err := h.state.Group(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if !g.ClassID.Valid {
+		return nil, &storage.Error{Op: fmt.Sprintf("get enrollment of student %d", studentID), Kind: storage.ErrNotFound}
+	}
+	return enrollment(g), nil
+}
+
+func updateEnrollment(h *Handler, studentID int, r *http.Request) error {
+	var in Enrollment
+	if err := decode(r, &in); err != nil {
+		return err
+	}
+	return h.state.AssignClassToStudent(studentID, in.ClassID)
+}
+
+func deleteEnrollment(h *Handler, studentID int) error {
+	return h.state.UnassignClassFromStudent(studentID)
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"io"
+	"net/http"
+)
+
+// Schemas are the JSON schemas of the entries by file name, served under
+// /schemas/. Read-only properties are ignored in requests.
+var Schemas = map[string]string{
+	"student.json": `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "/schemas/student.json",
+  "title": "Student",
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer", "readOnly": true},
+    "name": {"type": "string", "minLength": 1, "maxLength": 64},
+    "surname": {"type": "string", "minLength": 1, "maxLength": 64}
+  },
+  "required": ["name", "surname"],
+  "additionalProperties": false
+}
+`,
+	"class.json": `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "/schemas/class.json",
+  "title": "Class",
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer", "readOnly": true},
+    "year": {"type": "string", "pattern": "^([1-9]|1[0-2])$"},
+    "modifier": {"type": "string", "minLength": 1},
+    "teacher_id": {"type": ["integer", "null"], "readOnly": true},
+    "school_year_id": {"type": ["integer", "null"], "readOnly": true}
+  },
+  "required": ["year", "modifier"],
+  "additionalProperties": false
+}
+`,
+	"enrollment.json": `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "/schemas/enrollment.json",
+  "title": "Enrollment",
+  "description": "The class a student is enrolled in. The student ID is read from POST requests only.",
+  "type": "object",
+  "properties": {
+    "student_id": {"type": "integer"},
+    "class_id": {"type": "integer"},
+    "name": {"type": "string", "readOnly": true},
+    "surname": {"type": "string", "readOnly": true},
+    "year": {"type": "string", "readOnly": true},
+    "modifier": {"type": "string", "readOnly": true}
+  },
+  "required": ["class_id"],
+  "additionalProperties": false
+}
+`,
+	"list.json": `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "/schemas/list.json",
+  "title": "List",
+  "description": "A page of a collection, requested with the limit and offset query parameters.",
+  "type": "object",
+  "properties": {
+    "items": {"type": "array"},
+    "total": {"type": "integer", "description": "Number of entries matching the query, across all pages."},
+    "limit": {"type": "integer", "minimum": 1, "maximum": 500},
+    "offset": {"type": "integer", "minimum": 0}
+  },
+  "required": ["items", "total", "limit", "offset"]
+}
+`,
+	"credentials.json": `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "/schemas/credentials.json",
+  "title": "Credentials",
+  "description": "The body of POST /setup and POST /login.",
+  "type": "object",
+  "properties": {
+    "username": {"type": "string", "minLength": 1},
+    "password": {"type": "string", "minLength": 8}
+  },
+  "required": ["username", "password"],
+  "additionalProperties": false
+}
+`,
+	"token.json": `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "/schemas/token.json",
+  "title": "Token",
+  "description": "A session sent as \"Authorization: Bearer <token>\" until it expires.",
+  "type": "object",
+  "properties": {
+    "token": {"type": "string"},
+    "expires": {"type": "string", "format": "date-time"}
+  },
+  "required": ["token", "expires"]
+}
+`,
+	"error.json": `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "/schemas/error.json",
+  "title": "Error",
+  "type": "object",
+  "properties": {
+    "error": {
+      "type": "object",
+      "properties": {
+        "code": {"enum": ["bad_request", "unauthorized", "forbidden", "setup_required", "invalid", "not_found", "duplicate", "constraint", "locked", "method_not_allowed", "internal"]},
+        "message": {"type": "string"},
+        "field": {"type": "string"}
+      },
+      "required": ["code", "message"]
+    }
+  },
+  "required": ["error"]
+}
+`,
+}
+
+// serveSchema writes the schema of the given file name.
+func serveSchema(w http.ResponseWriter, r *http.Request, name string) {
+	schema, ok := Schemas[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "There is no such schema.", "")
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	io.WriteString(w, schema)
+}
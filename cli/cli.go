@@ -0,0 +1,767 @@
+// Package cli runs the administration subcommands of eklase, e.g.
+// "eklase student list", without opening a window, so that bulk operations
+// can be scripted.
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"eklase/api"
+	"eklase/export"
+	"eklase/state"
+	"eklase/storage"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK    = 0 // The command succeeded.
+	ExitError = 1 // The command failed, e.g. the entry does not exist.
+	ExitUsage = 2 // The command line is wrong.
+)
+
+// command is a subcommand, selected by its name.
+type command struct {
+	name     string // E.g. "student add".
+	synopsis string // Arguments following the name.
+	help     string
+	run      func(c *env, args []string) error
+}
+
+// commands lists the subcommands in the order of the help.
+var commands []command
+
+func init() {
+	// Assigned here, as the help command refers to the list itself.
+	commands = []command{
+		{"student add", "-name NAME -surname SURNAME [-class ID] [-format F]", "add a student, optionally into a class, and print its ID", studentAdd},
+		{"student list", "[-class ID] [-format F]", "list students with their classes", studentList},
+		{"student import", "[-delimiter C] [-name-column H] [-surname-column H] [-class-column H] [-dry-run] [-format F] FILE", "import students from a CSV file, adding the new rows only", studentImport},
+		{"student delete", "ID...", "delete students with their grades and attendance", studentDelete},
+		{"class add", "-year YEAR -modifier MODIFIER [-format F]", "add a class to the latest school year and print its ID", classAdd},
+		{"class list", "[-format F]", "list classes", classList},
+		{"enroll", "STUDENT_ID CLASS_ID | -remove STUDENT_ID", "enroll a student into a class or remove them from it", enroll},
+		{"export", "[-format csv|json|xlsx] [-columns C,...] [-o FILE] [students|classes|groups]", "write a list, or students and classes as JSON", exportData},
+		{"user add", "-username NAME -role ROLE [-teacher ID] -password-file FILE [-format F]", "add a user who can log in and print its ID", userAdd},
+		{"user list", "[-format F]", "list users with their roles", userList},
+		{"user password", "-password-file FILE ID", "set the password of a user", userPassword},
+		{"user delete", "ID", "delete a user other than the last admin", userDelete},
+		{"audit", "[-entity E] [-user NAME] [-id ID] [-limit N] [-format F]", "list the recorded changes, newest first", audit},
+		{"serve", "[-addr HOST:PORT]", "serve students, classes and enrollments over a JSON REST API until interrupted, to users logged in with POST /login, or POST /setup on a database without users", serve},
+		{"help", "", "show this help", help},
+	}
+}
+
+// env is what commands run with.
+type env struct {
+	state  *state.State
+	name   string // Name of the command running.
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// usageError reports a wrong command line.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// errReported is returned by commands that reported their failure on stderr
+// already.
+var errReported = errors.New("reported")
+
+// Run runs the subcommand given by args, e.g. "student", "list", writing its
+// output to stdout and problems to stderr, and returns the exit code of the
+// program.
+func Run(st *state.State, args []string, stdout, stderr io.Writer) int {
+	cmd, rest, ok := find(args)
+	if !ok {
+		fmt.Fprintf(stderr, "eklase: unknown command %q, see \"eklase help\"\n", strings.Join(args, " "))
+		return ExitUsage
+	}
+	c := &env{state: st, name: cmd.name, stdout: stdout, stderr: stderr}
+	err := cmd.run(c, rest)
+	var uerr *usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.Is(err, errReported):
+		return ExitError
+	case errors.As(err, &uerr):
+		fmt.Fprintf(stderr, "eklase %s: %v\nusage: eklase %s %s\n", cmd.name, err, cmd.name, cmd.synopsis)
+		return ExitUsage
+	default:
+		fmt.Fprintf(stderr, "eklase %s: %s\n", cmd.name, state.Message(err))
+		return ExitError
+	}
+}
+
+// find returns the command named by the first one or two args and the
+// remaining args.
+func find(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) < len(words) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+// flags returns a flag set of the running command whose errors are reported
+// on stderr.
+func (c *env) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("eklase "+c.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parse parses args with fs and checks the number of positional arguments
+// left, max -1 meaning any.
+func parse(fs *flag.FlagSet, args []string, min, max int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{err.Error()}
+	}
+	switch n := fs.NArg(); {
+	case n < min:
+		return &usageError{"missing arguments"}
+	case max >= 0 && n > max:
+		return &usageError{fmt.Sprintf("unexpected argument %q", fs.Arg(max))}
+	}
+	return nil
+}
+
+// formatFlag adds the -format flag choosing between a table and JSON.
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", "table", "output format: table or json")
+}
+
+// checkFormat rejects unknown output formats before anything is changed.
+func checkFormat(format string) error {
+	if format != "table" && format != "json" {
+		return &usageError{fmt.Sprintf("format %q is not table or json", format)}
+	}
+	return nil
+}
+
+// id parses an ID given on the command line.
+func id(what, s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, &usageError{fmt.Sprintf("%s %q is not an ID", what, s)}
+	}
+	return n, nil
+}
+
+// print writes v as indented JSON or as a table whose rows are produced by
+// rows, depending on format.
+func (c *env) print(format string, v interface{}, header []string, rows [][]string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "table":
+		tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, r := range rows {
+			fmt.Fprintln(tw, strings.Join(r, "\t"))
+		}
+		return tw.Flush()
+	default:
+		return checkFormat(format)
+	}
+}
+
+// printID prints the ID of an added entry.
+func (c *env) printID(format string, id int) error {
+	return c.print(format, struct {
+		ID int `json:"id"`
+	}{id}, []string{"ID"}, [][]string{{strconv.Itoa(id)}})
+}
+
+// Student is a student as printed by the commands.
+type Student struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
+	ClassID int    `json:"class_id,omitempty"` // 0 if not enrolled.
+	Class   string `json:"class,omitempty"`    // E.g. "5.a".
+}
+
+// Class is a class as printed by the commands.
+type Class struct {
+	ID         int    `json:"id"`
+	Year       string `json:"year"`
+	Modifier   string `json:"modifier"`
+	SchoolYear string `json:"school_year,omitempty"`
+}
+
+// student converts an enrolled student.
+func student(g storage.GroupEntry) Student {
+	s := Student{ID: g.StudentID, Name: g.Name, Surname: g.Surname}
+	if g.ClassID.Valid {
+		s.ClassID = int(g.ClassID.Int64)
+		s.Class = g.Year.String + "." + g.Modifier.String
+	}
+	return s
+}
+
+func studentAdd(c *env, args []string) error {
+	fs := c.flags()
+	name := fs.String("name", "", "name of the student")
+	surname := fs.String("surname", "", "surname of the student")
+	classID := fs.Int("class", 0, "ID of the class to enroll the student into")
+	format := formatFlag(fs)
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	if *classID != 0 {
+		// Checked first, so that no student is left behind on a typo.
+		if _, err := c.state.Class(*classID); err != nil {
+			return err
+		}
+	}
+	id, err := c.state.AddStudent(*name, *surname)
+	if err != nil {
+		return err
+	}
+	if *classID != 0 {
+		if err := c.state.AssignClassToStudent(id, *classID); err != nil {
+			c.state.DeleteStudent(id)
+			return err
+		}
+	}
+	return c.printID(*format, id)
+}
+
+func studentList(c *env, args []string) error {
+	fs := c.flags()
+	classID := fs.Int("class", 0, "list only the students of the class with this ID, ordered by surname")
+	format := formatFlag(fs)
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	var (
+		groups []storage.GroupEntry
+		err    error
+	)
+	if *classID != 0 {
+		if _, err := c.state.Class(*classID); err != nil {
+			return err
+		}
+		groups, err = c.state.ClassStudents(*classID)
+	} else {
+		groups, err = c.state.Groups()
+	}
+	if err != nil {
+		return err
+	}
+	students := make([]Student, 0, len(groups))
+	rows := make([][]string, 0, len(groups))
+	for _, g := range groups {
+		s := student(g)
+		students = append(students, s)
+		rows = append(rows, []string{strconv.Itoa(s.ID), s.Name, s.Surname, s.Class})
+	}
+	return c.print(*format, students, []string{"ID", "NAME", "SURNAME", "CLASS"}, rows)
+}
+
+func studentDelete(c *env, args []string) error {
+	fs := c.flags()
+	if err := parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+	ids := make([]int, fs.NArg())
+	for i, arg := range fs.Args() {
+		var err error
+		if ids[i], err = id("student", arg); err != nil {
+			return err
+		}
+	}
+	for _, id := range ids {
+		if err := c.state.DeleteStudent(id); err != nil {
+			// The students before it are gone, so tell which one failed.
+			fmt.Fprintf(c.stderr, "eklase %s: student %d: %s\n", c.name, id, state.Message(err))
+			return errReported
+		}
+	}
+	return nil
+}
+
+// ImportRow is a row of an imported file as printed by the commands.
+type ImportRow struct {
+	Line    int    `json:"line"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
+	Class   string `json:"class,omitempty"`
+	Status  string `json:"status"` // "new", "duplicate" or "invalid".
+	Reason  string `json:"reason,omitempty"`
+}
+
+func studentImport(c *env, args []string) error {
+	fs := c.flags()
+	delimiter := fs.String("delimiter", ",", `field delimiter, e.g. ";" or "tab"`)
+	nameColumn := fs.String("name-column", "", "header of the name column, if not a usual one")
+	surnameColumn := fs.String("surname-column", "", "header of the surname column, if not a usual one")
+	classColumn := fs.String("class-column", "", "header of the class column, if not a usual one")
+	dryRun := fs.Bool("dry-run", false, "only report what would be imported")
+	format := formatFlag(fs)
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	opts := state.CSVOptions{Columns: make(map[string]state.ImportField)}
+	if strings.EqualFold(*delimiter, "tab") {
+		*delimiter = "\t"
+	}
+	if d := []rune(*delimiter); len(d) == 1 {
+		opts.Delimiter = d[0]
+	} else {
+		return &usageError{fmt.Sprintf("delimiter %q is not a single character", *delimiter)}
+	}
+	for h, f := range map[*string]state.ImportField{
+		nameColumn:    state.FieldName,
+		surnameColumn: state.FieldSurname,
+		classColumn:   state.FieldClass,
+	} {
+		if *h != "" {
+			opts.Columns[*h] = f
+		}
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	report, err := c.state.PreviewImport(f, opts)
+	if err != nil {
+		return err
+	}
+	rows := make([]ImportRow, 0, len(report.Rows))
+	table := make([][]string, 0, len(report.Rows))
+	for _, r := range report.Rows {
+		row := ImportRow{r.Line, r.Entry.Name, r.Entry.Surname, r.Class, r.Status.String(), r.Reason}
+		rows = append(rows, row)
+		table = append(table, []string{strconv.Itoa(row.Line), row.Name, row.Surname, row.Class, row.Status, row.Reason})
+	}
+	if err := c.print(*format, rows, []string{"LINE", "NAME", "SURNAME", "CLASS", "STATUS", "REASON"}, table); err != nil {
+		return err
+	}
+	if len(report.Classes) > 0 {
+		fmt.Fprintf(c.stderr, "new classes: %s\n", strings.Join(report.Classes, ", "))
+	}
+	if *dryRun {
+		fmt.Fprintf(c.stderr, "%d new, %d duplicate and %d invalid rows, nothing imported\n", report.New, report.Duplicates, report.Invalid)
+		return nil
+	}
+	n, err := c.state.Import(report)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "%d students imported, %d duplicate and %d invalid rows skipped\n", n, report.Duplicates, report.Invalid)
+	return nil
+}
+
+func classAdd(c *env, args []string) error {
+	fs := c.flags()
+	year := fs.String("year", "", "year of the class, e.g. 5")
+	modifier := fs.String("modifier", "", "modifier of the class, e.g. a")
+	format := formatFlag(fs)
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	id, err := c.state.AddClass(*year, *modifier)
+	if err != nil {
+		return err
+	}
+	return c.printID(*format, id)
+}
+
+func classList(c *env, args []string) error {
+	fs := c.flags()
+	format := formatFlag(fs)
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	classes, err := c.classes()
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(classes))
+	for _, cl := range classes {
+		rows = append(rows, []string{strconv.Itoa(cl.ID), cl.Year, cl.Modifier, cl.SchoolYear})
+	}
+	return c.print(*format, classes, []string{"ID", "YEAR", "MODIFIER", "SCHOOL YEAR"}, rows)
+}
+
+// classes returns the classes together with the names of their school years.
+func (c *env) classes() ([]Class, error) {
+	entries, err := c.state.Classes()
+	if err != nil {
+		return nil, err
+	}
+	years, err := c.state.SchoolYears()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(years))
+	for _, y := range years {
+		names[int64(y.ID)] = y.Name
+	}
+	classes := make([]Class, 0, len(entries))
+	for _, e := range entries {
+		cl := Class{ID: e.ID, Year: e.Year, Modifier: e.Modifier}
+		if e.SchoolYearID.Valid {
+			cl.SchoolYear = names[e.SchoolYearID.Int64]
+		}
+		classes = append(classes, cl)
+	}
+	return classes, nil
+}
+
+func enroll(c *env, args []string) error {
+	fs := c.flags()
+	remove := fs.Bool("remove", false, "remove the student from their class instead")
+	if err := parse(fs, args, 1, 2); err != nil {
+		return err
+	}
+	studentID, err := id("student", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *remove {
+		if fs.NArg() > 1 {
+			return &usageError{fmt.Sprintf("unexpected argument %q", fs.Arg(1))}
+		}
+		return c.state.UnassignClassFromStudent(studentID)
+	}
+	if fs.NArg() < 2 {
+		return &usageError{"missing the class ID"}
+	}
+	classID, err := id("class", fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	return c.state.AssignClassToStudent(studentID, classID)
+}
+
+// Export is the document written by the export command.
+type Export struct {
+	Students []Student `json:"students"`
+	Classes  []Class   `json:"classes"`
+}
+
+func exportData(c *env, args []string) error {
+	fs := c.flags()
+	path := fs.String("o", "", "file to write instead of the standard output")
+	format := fs.String("format", "json", "file format of a list: csv, json or xlsx")
+	columns := fs.String("columns", "", "comma separated columns of a list to export, all by default")
+	if err := parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		return &usageError{fmt.Sprintf("format %q is not csv, json or xlsx", *format)}
+	}
+	if fs.NArg() == 0 {
+		if f != export.JSON || *columns != "" {
+			return &usageError{"-format and -columns need a list to export"}
+		}
+		return c.create(*path, c.exportAll)
+	}
+
+	table, err := c.state.ExportTable(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *columns != "" {
+		if table, err = table.Select(strings.Split(*columns, ",")); err != nil {
+			return err
+		}
+	}
+	return c.create(*path, func(w io.Writer) error {
+		return export.Write(w, table, f)
+	})
+}
+
+// create calls write with the file at path, or with the standard output if
+// path is empty.
+func (c *env) create(path string, write func(w io.Writer) error) error {
+	if path == "" {
+		return write(c.stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exportAll writes the students and classes as a single JSON document.
+func (c *env) exportAll(out io.Writer) error {
+	groups, err := c.state.Groups()
+	if err != nil {
+		return err
+	}
+	doc := Export{Students: make([]Student, 0, len(groups))}
+	for _, g := range groups {
+		doc.Students = append(doc.Students, student(g))
+	}
+	if doc.Classes, err = c.classes(); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// User is a user as printed by the commands.
+type User struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	TeacherID int    `json:"teacher_id,omitempty"` // 0 unless a teacher.
+}
+
+// passwordFlag defines the flag of the file the password is read from.
+func passwordFlag(fs *flag.FlagSet) *string {
+	return fs.String("password-file", "", `file whose first line is the password, "-" for the standard input`)
+}
+
+// readPassword returns the first line of the file at path, or of the standard
+// input if path is "-". Passwords are not taken as arguments, which other
+// users of the computer can see.
+func readPassword(path string) (string, error) {
+	if path == "" {
+		return "", &usageError{"the -password-file flag is required"}
+	}
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return "", err
+		}
+		defer f.Close()
+	}
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func userAdd(c *env, args []string) error {
+	fs := c.flags()
+	username := fs.String("username", "", "name to log in with")
+	role := fs.String("role", "", "admin, class_teacher, teacher or secretary")
+	teacherID := fs.Int("teacher", 0, "ID of the teacher entry of teachers")
+	passwordFile := passwordFlag(fs)
+	format := formatFlag(fs)
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	password, err := readPassword(*passwordFile)
+	if err != nil {
+		return err
+	}
+	id, err := c.state.AddUser(*username, password, storage.Role(*role), *teacherID)
+	if err != nil {
+		return err
+	}
+	return c.printID(*format, id)
+}
+
+func userList(c *env, args []string) error {
+	fs := c.flags()
+	format := formatFlag(fs)
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	entries, err := c.state.Users()
+	if err != nil {
+		return err
+	}
+	users := make([]User, 0, len(entries))
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		u := User{ID: e.ID, Username: e.Username, Role: string(e.Role), TeacherID: int(e.TeacherID.Int64)}
+		users = append(users, u)
+		teacher := ""
+		if u.TeacherID != 0 {
+			teacher = strconv.Itoa(u.TeacherID)
+		}
+		rows = append(rows, []string{strconv.Itoa(u.ID), u.Username, u.Role, teacher})
+	}
+	return c.print(*format, users, []string{"ID", "USERNAME", "ROLE", "TEACHER"}, rows)
+}
+
+func userPassword(c *env, args []string) error {
+	fs := c.flags()
+	passwordFile := passwordFlag(fs)
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	userID, err := id("user", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	password, err := readPassword(*passwordFile)
+	if err != nil {
+		return err
+	}
+	return c.state.SetPassword(userID, password)
+}
+
+func userDelete(c *env, args []string) error {
+	fs := c.flags()
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	userID, err := id("user", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return c.state.DeleteUser(userID)
+}
+
+// AuditEntry is a recorded change as printed by the commands.
+type AuditEntry struct {
+	ID       int             `json:"id"`
+	At       string          `json:"at"`
+	User     string          `json:"user"`
+	Entity   string          `json:"entity"`
+	EntityID int             `json:"entity_id"`
+	Action   string          `json:"action"`
+	Old      json.RawMessage `json:"old,omitempty"`
+	New      json.RawMessage `json:"new,omitempty"`
+}
+
+func audit(c *env, args []string) error {
+	fs := c.flags()
+	entity := fs.String("entity", "", "entity to list the changes of, e.g. students")
+	user := fs.String("user", "", "username to list the changes of")
+	entityID := fs.Int("id", 0, "ID of the entity, the student of enrollments and attendance")
+	limit := fs.Int("limit", 100, "most changes listed, 0 for all")
+	format := formatFlag(fs)
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	if *entity != "" && !auditedEntity(*entity) {
+		return &usageError{fmt.Sprintf("entity %q is not one of %s", *entity, strings.Join(storage.AuditedEntities, ", "))}
+	}
+	entries, err := c.state.AuditLog(storage.AuditFilter{Entity: *entity, EntityID: *entityID, Actor: *user, Limit: *limit})
+	if err != nil {
+		return err
+	}
+	changes := make([]AuditEntry, 0, len(entries))
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		a := AuditEntry{ID: e.ID, At: e.At, User: e.Actor, Entity: e.Entity, EntityID: e.EntityID, Action: string(e.Action)}
+		if e.Old.Valid {
+			a.Old = json.RawMessage(e.Old.String)
+		}
+		if e.New.Valid {
+			a.New = json.RawMessage(e.New.String)
+		}
+		changes = append(changes, a)
+		rows = append(rows, []string{strconv.Itoa(a.ID), a.At, a.User, a.Entity, strconv.Itoa(a.EntityID), a.Action, e.Old.String, e.New.String})
+	}
+	return c.print(*format, changes, []string{"ID", "AT", "USER", "ENTITY", "ENTITY ID", "ACTION", "OLD", "NEW"}, rows)
+}
+
+// auditedEntity reports whether the audit log records changes of entity.
+func auditedEntity(entity string) bool {
+	for _, e := range storage.AuditedEntities {
+		if e == entity {
+			return true
+		}
+	}
+	return false
+}
+
+func serve(c *env, args []string) error {
+	fs := c.flags()
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: *addr, Handler: api.NewHandler(c.state)}
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- srv.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(c.stderr, "serving on http://%s\n", *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-done
+}
+
+func help(c *env, args []string) error {
+	fmt.Fprintln(c.stdout, "Usage: eklase [flags] command [arguments]")
+	fmt.Fprintln(c.stdout, "Commands:")
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", cmd.name, cmd.synopsis, cmd.help)
+	}
+	tw.Flush()
+	fmt.Fprintln(c.stdout, "Run \"eklase -help\" for the flags and \"eklase COMMAND -help\" for those of a command.")
+	return nil
+}
@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"eklase/state"
+	"eklase/storage"
+)
+
+// run runs a command line against st and returns its exit code and output.
+func run(st *state.State, line string) (code int, stdout, stderr string) {
+	var out, errOut strings.Builder
+	code = Run(st, strings.Fields(line), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+// mustRun runs a command line that must succeed and returns its output.
+func mustRun(t *testing.T, st *state.State, line string) string {
+	t.Helper()
+	code, stdout, stderr := run(st, line)
+	if code != ExitOK {
+		t.Fatalf("%q exited with %d: %s", line, code, stderr)
+	}
+	return stdout
+}
+
+// decodeID returns the ID printed by an add command with -format json.
+func decodeID(t *testing.T, out string) int {
+	t.Helper()
+	var v struct{ ID int }
+	if err := json.Unmarshal([]byte(out), &v); err != nil || v.ID == 0 {
+		t.Fatalf("unable to decode ID from %q: %v", out, err)
+	}
+	return v.ID
+}
+
+func TestStudentCommands(t *testing.T) {
+	st := state.New(storage.NewMemory())
+	classID := decodeID(t, mustRun(t, st, "class add -year 5 -modifier a -format json"))
+	mustRun(t, st, "student add -name Anna -surname Zariņa -class "+strconv.Itoa(classID))
+	bob := decodeID(t, mustRun(t, st, "student add -name Bob -surname Bērziņš -format json"))
+
+	var students []Student
+	if err := json.Unmarshal([]byte(mustRun(t, st, "student list -format json")), &students); err != nil {
+		t.Fatal(err)
+	}
+	if len(students) != 2 || students[0].Class != "5.a" || students[1].ClassID != 0 {
+		t.Fatalf("student list = %+v, want Anna in 5.a and Bob without a class", students)
+	}
+
+	mustRun(t, st, "enroll "+strconv.Itoa(bob)+" "+strconv.Itoa(classID))
+	table := mustRun(t, st, "student list -class "+strconv.Itoa(classID))
+	lines := strings.Split(strings.TrimSpace(table), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[1], "Bērziņš") {
+		t.Errorf("student list -class = %q, want a header and Bērziņš before Zariņa", table)
+	}
+
+	mustRun(t, st, "enroll -remove "+strconv.Itoa(bob))
+	mustRun(t, st, "student delete "+strconv.Itoa(bob))
+	if out := mustRun(t, st, "student list"); strings.Contains(out, "Bob") {
+		t.Errorf("student list = %q after deleting Bob", out)
+	}
+}
+
+func TestClassList(t *testing.T) {
+	st := state.New(storage.NewMemory())
+	mustRun(t, st, "class add -year 5 -modifier a")
+	mustRun(t, st, "class add -year 6 -modifier b")
+	out := mustRun(t, st, "class list")
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 || !strings.Contains(lines[2], "6") {
+		t.Errorf("class list = %q, want a header and two classes", out)
+	}
+}
+
+func TestExport(t *testing.T) {
+	st := state.New(storage.NewMemory())
+	mustRun(t, st, "class add -year 5 -modifier a")
+	mustRun(t, st, "student add -name Anna -surname Zariņa -class 1")
+	path := filepath.Join(t.TempDir(), "export.json")
+	mustRun(t, st, "export -o "+path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc Export
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Students) != 1 || len(doc.Classes) != 1 || doc.Students[0].Class != "5.a" {
+		t.Errorf("export = %+v, want Anna in 5.a", doc)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	st := state.New(storage.NewMemory())
+	for _, tc := range []struct {
+		line   string
+		code   int
+		stderr string // Part of the error output.
+	}{
+		{"help", ExitOK, ""},
+		{"student list -help", ExitOK, "-class"},
+		{"", ExitUsage, "unknown command"},
+		{"teacher list", ExitUsage, "unknown command"},
+		{"student list extra", ExitUsage, `unexpected argument "extra"`},
+		{"student list -format xml", ExitUsage, "is not table or json"},
+		{"student delete", ExitUsage, "missing arguments"},
+		{"student delete abc", ExitUsage, `student "abc" is not an ID`},
+		{"student delete 42", ExitError, "student 42: The entry does not exist anymore."},
+		{"student add -name Anna", ExitError, "The surname"},
+		{"student add -name Anna -surname Zariņa -class 7", ExitError, "does not exist"},
+		{"enroll 1", ExitUsage, "missing the class ID"},
+		{"enroll -remove 1 2", ExitUsage, `unexpected argument "2"`},
+		{"class add -year 5", ExitError, "The modifier"},
+	} {
+		code, _, stderr := run(st, tc.line)
+		if code != tc.code || !strings.Contains(stderr, tc.stderr) {
+			t.Errorf("%q exited with %d and %q, want %d and %q", tc.line, code, stderr, tc.code, tc.stderr)
+		}
+	}
+	if students, _ := st.Students(); len(students) != 0 {
+		t.Errorf("failed commands left students %+v", students)
+	}
+}
+
+func TestStudentImport(t *testing.T) {
+	st := state.New(storage.NewMemory())
+	path := filepath.Join(t.TempDir(), "students.csv")
+	file := "Vārds;Uzvārds;Grupa\nAnna;Zariņa;5.a\nJānis;;5.a\n"
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := mustRun(t, st, "student import -delimiter ; -class-column Grupa -dry-run -format json "+path)
+	var rows []ImportRow
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Status != "new" || rows[1].Status != "invalid" {
+		t.Errorf("student import -dry-run = %+v, want a new and an invalid row", rows)
+	}
+	if students, _ := st.Students(); len(students) != 0 {
+		t.Fatalf("student import -dry-run added %+v", students)
+	}
+
+	mustRun(t, st, "student import -delimiter ; -class-column Grupa "+path)
+	if students, _ := st.ClassStudents(1); len(students) != 1 || students[0].Name != "Anna" {
+		t.Errorf("class 5.a after the import = %+v, want Anna", students)
+	}
+	if code, _, stderr := run(st, "student import -delimiter ;; "+path); code != ExitUsage {
+		t.Errorf("student import -delimiter ;; exited with %d and %q, want %d", code, stderr, ExitUsage)
+	}
+}
+
+func TestExportList(t *testing.T) {
+	st := state.New(storage.NewMemory())
+	mustRun(t, st, "class add -year 5 -modifier a")
+	mustRun(t, st, "student add -name Anna -surname Zariņa -class 1")
+	mustRun(t, st, "student add -name Pēteris -surname Ozols")
+
+	out := mustRun(t, st, "export -format csv -columns surname,year groups")
+	if want := "\uFEFFsurname,year\nZariņa,5\nOzols,\n"; out != want {
+		t.Errorf("export groups = %q, want %q", out, want)
+	}
+	path := filepath.Join(t.TempDir(), "classes.xlsx")
+	mustRun(t, st, "export -format xlsx -o "+path+" classes")
+	if data, err := os.ReadFile(path); err != nil || !strings.HasPrefix(string(data), "PK") {
+		t.Errorf("export -format xlsx wrote %d bytes, %v; want a zip file", len(data), err)
+	}
+	for line, code := range map[string]int{
+		"export -format pdf students":  ExitUsage,
+		"export -format csv":           ExitUsage,
+		"export -columns age students": ExitError,
+		"export teachers":              ExitError,
+		"export students classes":      ExitUsage,
+	} {
+		if got, _, stderr := run(st, line); got != code {
+			t.Errorf("%q exited with %d and %q, want %d", line, got, stderr, code)
+		}
+	}
+}
+
+func TestUserCommands(t *testing.T) {
+	st := state.New(storage.NewMemory())
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("correct horse\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	admin := decodeID(t, mustRun(t, st, "user add -username admin -role admin -format json -password-file "+path))
+	teacherID, err := st.AddTeacher("Ilze", "Kalniņa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, st, "user add -username ilze -role teacher -teacher "+strconv.Itoa(teacherID)+" -password-file "+path)
+
+	var users []User
+	if err := json.Unmarshal([]byte(mustRun(t, st, "user list -format json")), &users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Username != "admin" || users[1].TeacherID != teacherID {
+		t.Errorf("user list = %+v, want the admin and Ilze as a teacher", users)
+	}
+	if err := st.Login("ilze", "correct horse"); err != nil {
+		t.Errorf("Login() with the password of the file failed: %v", err)
+	}
+	st.Logout()
+
+	if code, _, _ := run(st, "user add -username x -role admin"); code != ExitUsage {
+		t.Errorf("user add without a password file exited with %d, want %d", code, ExitUsage)
+	}
+	if code, _, _ := run(st, "user delete "+strconv.Itoa(admin)); code != ExitError {
+		t.Errorf("user delete while logged out exited with %d, want %d", code, ExitError)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	st := state.New(storage.NewMemory())
+	id := decodeID(t, mustRun(t, st, "student add -name Anna -surname Ozola -format json"))
+	mustRun(t, st, "student delete "+strconv.Itoa(id))
+
+	var changes []AuditEntry
+	if err := json.Unmarshal([]byte(mustRun(t, st, "audit -entity students -format json")), &changes); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Action != "delete" || changes[1].Action != "insert" || changes[1].User != "system" {
+		t.Errorf("audit = %+v, want the deletion and the insertion by system", changes)
+	}
+	if code, _, _ := run(st, "audit -entity pupils"); code != ExitUsage {
+		t.Errorf("audit of an unknown entity exited with %d, want %d", code, ExitUsage)
+	}
+}
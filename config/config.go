@@ -0,0 +1,289 @@
+// Package config resolves the settings of the application from command-line
+// flags, environment variables and a JSON config file, in that order of
+// precedence, falling back to defaults.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"eklase/grading"
+	"eklase/storage"
+)
+
+// Config holds the resolved settings.
+type Config struct {
+	DB       string        // Path of the SQLite database.
+	Locale   string        // Language used for ordering names, see Locales.
+	Theme    string        // Colors of the window, "light" or "dark".
+	Width    int           // Initial width of the window in dp.
+	Height   int           // Initial height of the window in dp.
+	LogLevel string        // Least severe notifications logged, see LogLevels.
+	Grading  grading.Rules // Rules final grades are proposed by, set in the file only.
+	// Command is the subcommand and its arguments following the flags, e.g.
+	// "student", "list". It is empty when the window should open.
+	Command []string
+}
+
+// Defaults returns the settings used when nothing else is given. The database
+// stays in the working directory, where earlier releases kept it.
+func Defaults() Config {
+	return Config{
+		DB:       "school.db",
+		Locale:   "lv",
+		Theme:    "light",
+		Width:    800,
+		Height:   600,
+		LogLevel: "error",
+		Grading:  grading.DefaultRules(),
+	}
+}
+
+var (
+	// Locales lists the supported values of Config.Locale.
+	Locales = []string{"en", "lv"}
+	// Themes lists the supported values of Config.Theme.
+	Themes = []string{"light", "dark"}
+	// LogLevels lists the supported values of Config.LogLevel from the most
+	// verbose one.
+	LogLevels = []string{"debug", "info", "warn", "error"}
+)
+
+// Limits of the initial window size in dp.
+const (
+	minWindowSize = 320
+	maxWindowSize = 8192
+)
+
+// Names of the environment variables.
+const (
+	envConfig     = "EKLASE_CONFIG"
+	envDB         = "EKLASE_DB"
+	envLocale     = "EKLASE_LOCALE"
+	envTheme      = "EKLASE_THEME"
+	envWindowSize = "EKLASE_WINDOW_SIZE"
+	envLogLevel   = "EKLASE_LOG_LEVEL"
+)
+
+// Error reports an invalid setting together with where it came from, so that
+// the user knows what to fix.
+type Error struct {
+	Source  string // E.g. "flag -theme", "$EKLASE_THEME" or the path of the file.
+	Setting string
+	Reason  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s %s", e.Source, e.Setting, e.Reason)
+}
+
+// file is the layout of the config file. Missing settings are nil.
+type file struct {
+	DB         *string `json:"db"`
+	Locale     *string `json:"locale"`
+	Theme      *string `json:"theme"`
+	WindowSize *string `json:"window_size"` // E.g. "1024x768".
+	LogLevel   *string `json:"log_level"`
+	Grading    *struct {
+		Weights       map[storage.Assessment]float64 `json:"weights"`
+		DropNotGraded *bool                          `json:"drop_not_graded"`
+	} `json:"grading"`
+}
+
+// DefaultPath returns the path of the config file under the XDG config
+// directory, e.g. ~/.config/eklase/config.json. It is empty if neither
+// $XDG_CONFIG_HOME nor $HOME is set.
+func DefaultPath(getenv func(string) string) string {
+	dir := getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home := getenv("HOME")
+		if home == "" {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "eklase", "config.json")
+}
+
+// Load resolves the settings from the command-line arguments without the
+// program name, the environment read through getenv and the config file. The
+// flags must come before any subcommand, which is returned in Config.Command.
+// The file is given by -config or $EKLASE_CONFIG and must exist then,
+// otherwise DefaultPath is read if it exists. The usage is written to output
+// for -help, in which case flag.ErrHelp is returned.
+func Load(args []string, getenv func(string) string, output io.Writer) (Config, error) {
+	var (
+		cfg     = Defaults()
+		sources = make(map[string]string) // Where each setting came from, by name.
+		size    = fmt.Sprintf("%dx%d", cfg.Width, cfg.Height)
+	)
+	fs := flag.NewFlagSet("eklase", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: eklase [flags] [command [arguments]]")
+		fmt.Fprintln(fs.Output(), "Without a command the window opens. Run \"eklase help\" for the commands.")
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", "", "path of the JSON config file (default "+DefaultPath(getenv)+")")
+	flagDB := fs.String("db", cfg.DB, "path of the SQLite database")
+	flagLocale := fs.String("locale", cfg.Locale, "language used for ordering names: "+strings.Join(Locales, ", "))
+	flagTheme := fs.String("theme", cfg.Theme, "colors of the window: "+strings.Join(Themes, ", "))
+	flagSize := fs.String("size", size, "initial size of the window in dp, WIDTHxHEIGHT")
+	flagLogLevel := fs.String("log-level", cfg.LogLevel, "least severe messages logged: "+strings.Join(LogLevels, ", "))
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	cfg.Command = fs.Args()
+
+	// The file first, as everything else overrides it.
+	path, required := *configPath, true
+	if path == "" {
+		path = getenv(envConfig)
+	}
+	if path == "" {
+		path, required = DefaultPath(getenv), false
+	}
+	if path != "" {
+		f, err := readFile(path, required)
+		if err != nil {
+			return Config{}, err
+		}
+		set := func(name string, dst *string, v *string) {
+			if v != nil {
+				*dst, sources[name] = *v, path
+			}
+		}
+		set("db", &cfg.DB, f.DB)
+		set("locale", &cfg.Locale, f.Locale)
+		set("theme", &cfg.Theme, f.Theme)
+		set("window size", &size, f.WindowSize)
+		set("log level", &cfg.LogLevel, f.LogLevel)
+		if g := f.Grading; g != nil {
+			if g.Weights != nil {
+				cfg.Grading.Weights = g.Weights
+			}
+			if g.DropNotGraded != nil {
+				cfg.Grading.DropNotGraded = *g.DropNotGraded
+			}
+			if err := cfg.Grading.Validate(); err != nil {
+				var verr *storage.ValidationError
+				if errors.As(err, &verr) {
+					return Config{}, &Error{Source: path, Setting: "grading " + verr.Field, Reason: verr.Reason}
+				}
+				return Config{}, err
+			}
+		}
+	}
+
+	for _, env := range []struct {
+		name, variable string
+		dst            *string
+	}{
+		{"db", envDB, &cfg.DB},
+		{"locale", envLocale, &cfg.Locale},
+		{"theme", envTheme, &cfg.Theme},
+		{"window size", envWindowSize, &size},
+		{"log level", envLogLevel, &cfg.LogLevel},
+	} {
+		if v := getenv(env.variable); v != "" {
+			*env.dst, sources[env.name] = v, "$"+env.variable
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "db":
+			cfg.DB, sources["db"] = *flagDB, "flag -db"
+		case "locale":
+			cfg.Locale, sources["locale"] = *flagLocale, "flag -locale"
+		case "theme":
+			cfg.Theme, sources["theme"] = *flagTheme, "flag -theme"
+		case "size":
+			size, sources["window size"] = *flagSize, "flag -size"
+		case "log-level":
+			cfg.LogLevel, sources["log level"] = *flagLogLevel, "flag -log-level"
+		}
+	})
+
+	invalid := func(name, reason string) error {
+		source, ok := sources[name]
+		if !ok {
+			source = "default"
+		}
+		return &Error{Source: source, Setting: name, Reason: reason}
+	}
+	if strings.TrimSpace(cfg.DB) == "" {
+		return Config{}, invalid("db", "must not be empty")
+	}
+	if !oneOf(cfg.Locale, Locales) {
+		return Config{}, invalid("locale", "must be one of "+strings.Join(Locales, ", "))
+	}
+	if !oneOf(cfg.Theme, Themes) {
+		return Config{}, invalid("theme", "must be one of "+strings.Join(Themes, ", "))
+	}
+	if !oneOf(cfg.LogLevel, LogLevels) {
+		return Config{}, invalid("log level", "must be one of "+strings.Join(LogLevels, ", "))
+	}
+	var ok bool
+	if cfg.Width, cfg.Height, ok = parseSize(size); !ok {
+		return Config{}, invalid("window size", fmt.Sprintf("must look like 1024x768, with sides from %d to %d", minWindowSize, maxWindowSize))
+	}
+	return cfg, nil
+}
+
+// readFile decodes the config file at path. A missing file is an error only
+// if it is required.
+func readFile(path string, required bool) (file, error) {
+	var f file
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return f, nil
+	}
+	if err != nil {
+		return f, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields() // Typos should not go unnoticed.
+	if err := dec.Decode(&f); err != nil {
+		return f, &Error{Source: path, Setting: "content", Reason: "is not valid: " + err.Error()}
+	}
+	return f, nil
+}
+
+// parseSize parses a window size like "1024x768".
+func parseSize(s string) (width, height int, ok bool) {
+	sides := strings.Split(strings.ToLower(strings.TrimSpace(s)), "x")
+	if len(sides) != 2 {
+		return 0, 0, false
+	}
+	width, errW := strconv.Atoi(sides[0])
+	height, errH := strconv.Atoi(sides[1])
+	if errW != nil || errH != nil {
+		return 0, 0, false
+	}
+	for _, side := range []int{width, height} {
+		if side < minWindowSize || side > maxWindowSize {
+			return 0, 0, false
+		}
+	}
+	return width, height, true
+}
+
+// oneOf reports whether s is one of the allowed values.
+func oneOf(s string, allowed []string) bool {
+	for _, a := range allowed {
+		if s == a {
+			return true
+		}
+	}
+	return false
+}
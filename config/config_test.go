@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"eklase/storage"
+)
+
+// env returns a getenv reading from vars.
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+// writeConfig writes the default config file under a new XDG config
+// directory and returns the directory.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "eklase", "config.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil, env(map[string]string{"HOME": t.TempDir()}), io.Discard)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	want := Defaults()
+	if cfg.DB != want.DB || cfg.Theme != want.Theme || cfg.Width != want.Width || cfg.Height != want.Height {
+		t.Errorf("Load() = %+v, want the defaults %+v", cfg, want)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := writeConfig(t, `{
+		"db": "file.db",
+		"locale": "en",
+		"theme": "dark",
+		"window_size": "1024x768",
+		"log_level": "info",
+		"grading": {"weights": {"test": 5}, "drop_not_graded": false}
+	}`)
+	vars := map[string]string{
+		"XDG_CONFIG_HOME":  dir,
+		"EKLASE_DB":        "env.db",
+		"EKLASE_LOG_LEVEL": "warn",
+	}
+	cfg, err := Load([]string{"-db", "flag.db"}, env(vars), io.Discard)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"db from the flag", cfg.DB, "flag.db"},
+		{"log level from the environment", cfg.LogLevel, "warn"},
+		{"locale from the file", cfg.Locale, "en"},
+		{"theme from the file", cfg.Theme, "dark"},
+		{"width from the file", cfg.Width, 1024},
+		{"height from the file", cfg.Height, 768},
+		{"test weight from the file", cfg.Grading.Weights[storage.Test], 5.0},
+		{"nv from the file", cfg.Grading.DropNotGraded, false},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := writeConfig(t, `{"theme": "blue"}`)
+	typo := writeConfig(t, `{"them": "dark"}`)
+	badWeights := writeConfig(t, `{"grading": {"weights": {"test": -1}}}`)
+	for _, tc := range []struct {
+		desc string
+		args []string
+		vars map[string]string
+		want string // Part of the error message.
+	}{
+		{"bad theme in the file", nil, map[string]string{"XDG_CONFIG_HOME": dir}, "config.json: theme must be one of light, dark"},
+		{"unknown setting in the file", nil, map[string]string{"XDG_CONFIG_HOME": typo}, `unknown field "them"`},
+		{"negative weight", nil, map[string]string{"XDG_CONFIG_HOME": badWeights}, "grading weight must be zero or more"},
+		{"missing explicit file", []string{"-config", filepath.Join(dir, "missing.json")}, nil, "missing.json"},
+		{"bad size from the environment", nil, map[string]string{"EKLASE_WINDOW_SIZE": "big"}, "$EKLASE_WINDOW_SIZE: window size must look like 1024x768"},
+		{"too small flag", []string{"-size", "100x100"}, nil, "flag -size: window size"},
+		{"bad log level flag", []string{"-log-level", "loud"}, nil, "flag -log-level: log level must be one of"},
+		{"empty db flag", []string{"-db", " "}, nil, "flag -db: db must not be empty"},
+		{"unknown flag", []string{"-colour", "red"}, nil, "flag provided but not defined"},
+	} {
+		_, err := Load(tc.args, env(tc.vars), io.Discard)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: Load() error = %v, want one containing %q", tc.desc, err, tc.want)
+		}
+	}
+}
+
+func TestLoadHelp(t *testing.T) {
+	var usage strings.Builder
+	if _, err := Load([]string{"-help"}, env(nil), &usage); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Load(-help) error = %v, want %v", err, flag.ErrHelp)
+	}
+	if !strings.Contains(usage.String(), "-log-level") {
+		t.Errorf("usage %q does not list -log-level", usage.String())
+	}
+}
+
+func TestLoadCommand(t *testing.T) {
+	cfg, err := Load([]string{"-db", "flag.db", "student", "list", "-format", "json"}, env(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := strings.Join(cfg.Command, " "); got != "student list -format json" || cfg.DB != "flag.db" {
+		t.Errorf("Load() = %q with db %q, want the command after the flags", got, cfg.DB)
+	}
+}
@@ -0,0 +1,278 @@
+// Package export writes lists of entries, e.g. students, as CSV, JSON or XLSX
+// files to be handed to people outside of the school.
+package export
+
+import (
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"eklase/storage"
+)
+
+// Format is a file format entries are exported to.
+type Format string
+
+// Supported formats.
+const (
+	CSV  Format = "csv"
+	JSON Format = "json"
+	XLSX Format = "xlsx"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{CSV, JSON, XLSX}
+
+// ParseFormat returns the format named by s, e.g. "csv".
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(s) {
+			return f, nil
+		}
+	}
+	return "", &storage.ValidationError{Field: "format", Reason: "must be csv, json or xlsx"}
+}
+
+// Table is exported data. Values are int64, float64, bool, string or nil for
+// a missing value, e.g. the class of a student who is not enrolled.
+type Table struct {
+	Name    string // E.g. "students", used as the name of the XLSX sheet.
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// FromEntries returns a table of entries, a slice of structs like
+// []storage.StudentEntry. Every field with a db tag becomes a column named by
+// the tag. Rows are sorted by their values from the first column on, so the
+// output does not depend on the order entries were loaded in.
+func FromEntries(name string, entries interface{}) (Table, error) {
+	v := reflect.ValueOf(entries)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Struct {
+		return Table{}, fmt.Errorf("export %s: %T is not a slice of structs", name, entries)
+	}
+	t := Table{Name: name}
+	var fields []int
+	typ := v.Type().Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		if tag := typ.Field(i).Tag.Get("db"); tag != "" && tag != "-" {
+			t.Columns = append(t.Columns, tag)
+			fields = append(fields, i)
+		}
+	}
+	t.Rows = make([][]interface{}, v.Len())
+	for i := range t.Rows {
+		row := make([]interface{}, len(fields))
+		for j, f := range fields {
+			value, err := cell(v.Index(i).Field(f))
+			if err != nil {
+				return Table{}, fmt.Errorf("export %s: column %s: %w", name, t.Columns[j], err)
+			}
+			row[j] = value
+		}
+		t.Rows[i] = row
+	}
+	sort.SliceStable(t.Rows, func(i, j int) bool {
+		a, b := t.Rows[i], t.Rows[j]
+		for k := range a {
+			if c := compare(a[k], b[k]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return t, nil
+}
+
+// cell converts a field to a value of a table.
+func cell(v reflect.Value) (interface{}, error) {
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil || value == nil {
+			return nil, err
+		}
+		v = reflect.ValueOf(value)
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	default:
+		return nil, fmt.Errorf("%s values are not supported", v.Type())
+	}
+}
+
+// compare orders values of a column: missing values first, numbers by value
+// and anything else by its text.
+func compare(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	x, xok := number(a)
+	y, yok := number(b)
+	if xok && yok {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(text(a), text(b))
+}
+
+// number returns a numeric value as a float64.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// text formats a value for CSV, empty if it is missing.
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Select returns the table with the given columns only, in the given order.
+// No columns select all of them.
+func (t Table) Select(columns []string) (Table, error) {
+	if len(columns) == 0 {
+		return t, nil
+	}
+	index := make(map[string]int, len(t.Columns))
+	for i, c := range t.Columns {
+		index[c] = i
+	}
+	picked := make([]int, len(columns))
+	for i, c := range columns {
+		j, ok := index[c]
+		if !ok {
+			return Table{}, &storage.ValidationError{Field: "column " + c, Reason: "must be one of " + strings.Join(t.Columns, ", ")}
+		}
+		picked[i] = j
+	}
+	s := Table{Name: t.Name, Columns: columns, Rows: make([][]interface{}, len(t.Rows))}
+	for i, row := range t.Rows {
+		s.Rows[i] = make([]interface{}, len(picked))
+		for k, j := range picked {
+			s.Rows[i][k] = row[j]
+		}
+	}
+	return s, nil
+}
+
+// Write writes the table to w in format f.
+func Write(w io.Writer, t Table, f Format) error {
+	switch f {
+	case CSV:
+		return writeCSV(w, t)
+	case JSON:
+		return writeJSON(w, t)
+	case XLSX:
+		return writeXLSX(w, t)
+	default:
+		_, err := ParseFormat(string(f))
+		return err
+	}
+}
+
+// writeCSV writes the columns as the header row followed by the rows. The
+// file starts with a UTF-8 byte order mark, without which Excel garbles
+// names like "Bērziņš".
+func writeCSV(w io.Writer, t Table) error {
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(t.Columns))
+	for _, row := range t.Rows {
+		for i, v := range row {
+			record[i] = text(v)
+			if _, ok := v.(string); ok {
+				record[i] = defuse(record[i])
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// defuse prefixes text that spreadsheets would run as a formula, e.g.
+// "=HYPERLINK(...)" as a name, with an apostrophe, which makes them show it
+// as text.
+func defuse(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// writeJSON writes an array with an object per row whose keys follow the
+// order of the columns.
+func writeJSON(w io.Writer, t Table) error {
+	var b strings.Builder
+	b.WriteString("[")
+	for i, row := range t.Rows {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  {")
+		for j, v := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			key, _ := json.Marshal(t.Columns[j])
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			b.Write(key)
+			b.WriteString(": ")
+			b.Write(value)
+		}
+		b.WriteString("}")
+	}
+	if len(t.Rows) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,158 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"eklase/storage"
+)
+
+// groups returns enrollments listed out of order, one of them without a class.
+func groups() []storage.GroupEntry {
+	class := func(id int64, year, modifier string) (sql.NullInt64, sql.NullString, sql.NullString) {
+		return sql.NullInt64{Int64: id, Valid: true}, sql.NullString{String: year, Valid: true}, sql.NullString{String: modifier, Valid: true}
+	}
+	g1 := storage.GroupEntry{StudentID: 10, Name: "Anna", Surname: "Zariņa"}
+	g1.ClassID, g1.Year, g1.Modifier = class(1, "5", "a")
+	g2 := storage.GroupEntry{StudentID: 9, Name: `Jānis "Jancis"`, Surname: "Bērziņš, jr."}
+	g2.ClassID, g2.Year, g2.Modifier = class(1, "5", "a")
+	g3 := storage.GroupEntry{StudentID: 2, Name: "Pēteris", Surname: "Ozols"}
+	return []storage.GroupEntry{g1, g2, g3}
+}
+
+func TestFromEntries(t *testing.T) {
+	table, err := FromEntries("groups", groups())
+	if err != nil {
+		t.Fatalf("FromEntries() failed: %v", err)
+	}
+	if got := strings.Join(table.Columns, ","); got != "student_id,name,surname,class_id,year,modifier" {
+		t.Errorf("columns = %s, want the db tags", got)
+	}
+	var ids []int64
+	for _, row := range table.Rows {
+		ids = append(ids, row[0].(int64))
+	}
+	if len(ids) != 3 || ids[0] != 2 || ids[1] != 9 || ids[2] != 10 {
+		t.Errorf("rows ordered by %v, want 2, 9, 10", ids)
+	}
+	if table.Rows[0][3] != nil {
+		t.Errorf("class of a student without one = %v, want nil", table.Rows[0][3])
+	}
+	if _, err := FromEntries("x", []int{1}); err == nil {
+		t.Error("FromEntries([]int) succeeded, want an error")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	table, err := FromEntries("groups", groups())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table, err = table.Select([]string{"surname", "student_id", "year"}); err != nil {
+		t.Fatalf("Select() failed: %v", err)
+	}
+	for _, tc := range []struct {
+		format Format
+		want   string
+	}{
+		{CSV, "\uFEFFsurname,student_id,year\nOzols,2,\n\"Bērziņš, jr.\",9,5\nZariņa,10,5\n"},
+		{JSON, `[
+  {"surname": "Ozols", "student_id": 2, "year": null},
+  {"surname": "Bērziņš, jr.", "student_id": 9, "year": "5"},
+  {"surname": "Zariņa", "student_id": 10, "year": "5"}
+]
+`},
+	} {
+		var b strings.Builder
+		if err := Write(&b, table, tc.format); err != nil {
+			t.Fatalf("Write(%s) failed: %v", tc.format, err)
+		}
+		if b.String() != tc.want {
+			t.Errorf("Write(%s) = %q, want %q", tc.format, b.String(), tc.want)
+		}
+	}
+	if err := Write(io.Discard, table, "pdf"); !errors.Is(err, storage.ErrValidation) {
+		t.Errorf("Write(pdf) = %v, want %v", err, storage.ErrValidation)
+	}
+	if _, err := table.Select([]string{"age"}); !errors.Is(err, storage.ErrValidation) {
+		t.Errorf("Select(age) = %v, want %v", err, storage.ErrValidation)
+	}
+}
+
+func TestWriteCSVFormulas(t *testing.T) {
+	table := Table{Columns: []string{"name", "mark"}, Rows: [][]interface{}{
+		{"=HYPERLINK(\"http://x\")", int64(-1)},
+		{"+1", nil},
+		{"@SUM(A1)", nil},
+		{"Anna-Marija", nil},
+	}}
+	var b strings.Builder
+	if err := Write(&b, table, CSV); err != nil {
+		t.Fatalf("Write(csv) failed: %v", err)
+	}
+	want := "\uFEFFname,mark\n\"'=HYPERLINK(\"\"http://x\"\")\",-1\n'+1,\n'@SUM(A1),\nAnna-Marija,\n"
+	if b.String() != want {
+		t.Errorf("Write(csv) = %q, want %q", b.String(), want)
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	table, err := FromEntries("students: 5.a", groups())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first, second bytes.Buffer
+	if err := Write(&first, table, XLSX); err != nil {
+		t.Fatalf("Write(xlsx) failed: %v", err)
+	}
+	Write(&second, table, XLSX)
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("Write(xlsx) gives different files for the same table")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(first.Bytes()), int64(first.Len()))
+	if err != nil {
+		t.Fatalf("xlsx is not a zip file: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		parts[f.Name] = string(data)
+	}
+	for _, want := range []string{
+		`<sheet name="students_ 5.a"`,
+	} {
+		if !strings.Contains(parts["xl/workbook.xml"], want) {
+			t.Errorf("workbook %q does not contain %q", parts["xl/workbook.xml"], want)
+		}
+	}
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">student_id</t></is></c>`,
+		`<c r="A2"><v>2</v></c>`,
+		`<t xml:space="preserve">Jānis &#34;Jancis&#34;</t>`,
+	} {
+		if !strings.Contains(parts["xl/worksheets/sheet1.xml"], want) {
+			t.Errorf("sheet does not contain %q", want)
+		}
+	}
+	if strings.Contains(parts["xl/worksheets/sheet1.xml"], `r="D2"`) {
+		t.Error("sheet has a cell for a missing class")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Package grading derives the final grades of terms and school years from the
+// grades given during them.
+package grading
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"eklase/storage"
+)
+
+// Rules configure how final grades are proposed.
+type Rules struct {
+	// Weights of the kinds of assessment in averages. Kinds that are not
+	// listed weigh 1, kinds that weigh 0 are left out.
+	Weights map[storage.Assessment]float64
+	// DropNotGraded leaves "nv" marks out of averages. Otherwise they count
+	// as 0.
+	DropNotGraded bool
+}
+
+// DefaultRules returns the rules used unless configured otherwise: tests
+// weigh twice and exams three times as much as daily work, and "nv" marks
+// are left out.
+func DefaultRules() Rules {
+	return Rules{
+		Weights: map[storage.Assessment]float64{
+			storage.DailyWork: 1,
+			storage.Test:      2,
+			storage.Exam:      3,
+		},
+		DropNotGraded: true,
+	}
+}
+
+// Validate checks that no weight is negative or refers to an unknown kind of
+// assessment.
+func (r Rules) Validate() error {
+	for kind, w := range r.Weights {
+		if !kind.Valid() {
+			return &storage.ValidationError{Field: "weight", Reason: "must be given for daily, test or exam"}
+		}
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return &storage.ValidationError{Field: "weight", Reason: "must be zero or more"}
+		}
+	}
+	return nil
+}
+
+// weight returns the weight of a kind of assessment.
+func (r Rules) weight(kind storage.Assessment) float64 {
+	if w, ok := r.Weights[kind]; ok {
+		return w
+	}
+	return 1
+}
+
+// Proposal is the final grade proposed for a student in a subject.
+type Proposal struct {
+	StudentID int
+	SubjectID int
+	Average   float64      // Weighted average of the counted marks, 0 if there are none.
+	Count     int          // Number of grades counted in Average.
+	Mark      storage.Mark // Proposed final mark.
+}
+
+// Average returns the weighted average of the numeric marks of grades and how
+// many grades it counts. Pass/fail marks are never counted.
+func Average(grades []storage.GradeEntry, r Rules) (float64, int) {
+	var sum, weights float64
+	count := 0
+	for _, g := range grades {
+		n, ok := g.Mark.Numeric()
+		if !ok {
+			if g.Mark != storage.NotGraded || r.DropNotGraded {
+				continue
+			}
+			n = 0
+		}
+		w := r.weight(g.Kind)
+		if w == 0 {
+			continue
+		}
+		sum += w * float64(n)
+		weights += w
+		count++
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return sum / weights, count
+}
+
+// Round rounds an average half-up to a mark from 1 to 10, so 6.5 becomes 7.
+func Round(average float64) storage.Mark {
+	// Averages like 6.5 may come out as 6.4999999 after the division, which
+	// the epsilon absorbs without rounding 6.495 up.
+	n := int(math.Floor(average + 0.5 + 1e-9))
+	if n < 1 {
+		n = 1
+	}
+	if n > 10 {
+		n = 10
+	}
+	return storage.Mark(strconv.Itoa(n))
+}
+
+// Propose returns the proposed final grade of one student in one subject
+// from their grades, which must be ordered by date as storage returns them.
+// Without counted marks the latest pass/fail mark is proposed, e.g. for
+// subjects graded pass/fail only, or "nv" if there is none.
+func Propose(grades []storage.GradeEntry, r Rules) Proposal {
+	var p Proposal
+	if len(grades) > 0 {
+		p.StudentID, p.SubjectID = grades[0].StudentID, grades[0].SubjectID
+	}
+	p.Average, p.Count = Average(grades, r)
+	if p.Count > 0 {
+		p.Mark = Round(p.Average)
+		return p
+	}
+	p.Mark = storage.NotGraded
+	for _, g := range grades {
+		if g.Mark == storage.Passed || g.Mark == storage.Failed {
+			p.Mark = g.Mark
+		}
+	}
+	return p
+}
+
+// ProposeAll groups grades by student and subject and returns a proposal for
+// each group ordered by student and subject ID. The grades must be ordered by
+// date.
+func ProposeAll(grades []storage.GradeEntry, r Rules) []Proposal {
+	type key struct{ studentID, subjectID int }
+	groups := make(map[key][]storage.GradeEntry)
+	for _, g := range grades {
+		k := key{g.StudentID, g.SubjectID}
+		groups[k] = append(groups[k], g)
+	}
+	proposals := make([]Proposal, 0, len(groups))
+	for _, gs := range groups {
+		proposals = append(proposals, Propose(gs, r))
+	}
+	sort.Slice(proposals, func(i, j int) bool {
+		if proposals[i].StudentID != proposals[j].StudentID {
+			return proposals[i].StudentID < proposals[j].StudentID
+		}
+		return proposals[i].SubjectID < proposals[j].SubjectID
+	})
+	return proposals
+}
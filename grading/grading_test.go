@@ -0,0 +1,109 @@
+package grading
+
+import (
+	"errors"
+	"testing"
+
+	"eklase/storage"
+)
+
+// grades returns grades of one student in one subject with the marks and
+// kinds in pairs, e.g. "7", "test", "nv", "daily".
+func grades(marksAndKinds ...string) []storage.GradeEntry {
+	var entries []storage.GradeEntry
+	for i := 0; i < len(marksAndKinds); i += 2 {
+		entries = append(entries, storage.GradeEntry{
+			ID:        i/2 + 1,
+			StudentID: 1,
+			SubjectID: 1,
+			Mark:      storage.Mark(marksAndKinds[i]),
+			Kind:      storage.Assessment(marksAndKinds[i+1]),
+		})
+	}
+	return entries
+}
+
+func TestRound(t *testing.T) {
+	for in, want := range map[float64]storage.Mark{
+		0:           "1",
+		4.49:        "4",
+		4.5:         "5",
+		6.49:        "6",
+		6.495:       "6",
+		6.5 - 1e-12: "7", // 6.5 after a lossy division.
+		9.6:         "10",
+		10:          "10",
+	} {
+		if got := Round(in); got != want {
+			t.Errorf("Round(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPropose(t *testing.T) {
+	notGradedAsZero := DefaultRules()
+	notGradedAsZero.DropNotGraded = false
+	testsOnly := Rules{Weights: map[storage.Assessment]float64{storage.DailyWork: 0}}
+
+	for _, tc := range []struct {
+		name    string
+		grades  []storage.GradeEntry
+		rules   Rules
+		average float64
+		count   int
+		mark    storage.Mark
+	}{
+		{"plain average", grades("6", "daily", "7", "daily"), DefaultRules(), 6.5, 2, "7"},
+		{"weighted", grades("4", "daily", "7", "test", "9", "exam"), DefaultRules(), (4 + 14 + 27) / 6.0, 3, "8"},
+		{"nv dropped", grades("8", "daily", "nv", "test"), DefaultRules(), 8, 1, "8"},
+		{"nv counts as 0", grades("8", "daily", "nv", "daily"), notGradedAsZero, 4, 2, "4"},
+		{"weight 0 left out", grades("3", "daily", "9", "test"), testsOnly, 9, 1, "9"},
+		{"pass/fail ignored", grades("i", "daily", "5", "daily"), DefaultRules(), 5, 1, "5"},
+		{"pass/fail only", grades("ni", "daily", "i", "test"), DefaultRules(), 0, 0, storage.Passed},
+		{"nothing to count", grades("nv", "daily"), DefaultRules(), 0, 0, storage.NotGraded},
+		{"no grades", nil, DefaultRules(), 0, 0, storage.NotGraded},
+	} {
+		got := Propose(tc.grades, tc.rules)
+		if got.Average != tc.average || got.Count != tc.count || got.Mark != tc.mark {
+			t.Errorf("%s: Propose() = %.3f, %d, %q; want %.3f, %d, %q",
+				tc.name, got.Average, got.Count, got.Mark, tc.average, tc.count, tc.mark)
+		}
+	}
+}
+
+func TestProposeAll(t *testing.T) {
+	all := []storage.GradeEntry{
+		{StudentID: 2, SubjectID: 1, Mark: "4", Kind: storage.DailyWork},
+		{StudentID: 1, SubjectID: 2, Mark: "9", Kind: storage.DailyWork},
+		{StudentID: 1, SubjectID: 1, Mark: "6", Kind: storage.DailyWork},
+		{StudentID: 2, SubjectID: 1, Mark: "6", Kind: storage.DailyWork},
+	}
+	got := ProposeAll(all, DefaultRules())
+	want := []Proposal{
+		{StudentID: 1, SubjectID: 1, Average: 6, Count: 1, Mark: "6"},
+		{StudentID: 1, SubjectID: 2, Average: 9, Count: 1, Mark: "9"},
+		{StudentID: 2, SubjectID: 1, Average: 5, Count: 2, Mark: "5"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ProposeAll() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ProposeAll()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRulesValidate(t *testing.T) {
+	if err := DefaultRules().Validate(); err != nil {
+		t.Errorf("DefaultRules().Validate() = %v, want nil", err)
+	}
+	for _, r := range []Rules{
+		{Weights: map[storage.Assessment]float64{storage.Test: -1}},
+		{Weights: map[storage.Assessment]float64{"quiz": 1}},
+	} {
+		if err := r.Validate(); !errors.Is(err, storage.ErrValidation) {
+			t.Errorf("Validate(%v) = %v, want %v", r.Weights, err, storage.ErrValidation)
+		}
+	}
+}
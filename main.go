@@ -1,30 +1,74 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"image/color"
 	"log"
 	"os"
 
+	"eklase/cli"
+	"eklase/config"
 	"eklase/screen"
 	"eklase/state"
 	"eklase/storage"
 
 	"gioui.org/app"
 	"gioui.org/font/gofont"
+	"gioui.org/io/key"
 	"gioui.org/io/system"
 	"gioui.org/layout"
 	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
 	"gioui.org/unit"
 	"gioui.org/widget/material"
-
-	_ "modernc.org/sqlite"
 )
 
 func main() {
+	// Settings and the database are checked before the window opens, so that
+	// mistakes are reported on the terminal.
+	cfg, err := config.Load(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eklase: %v\n", err)
+		os.Exit(2)
+	}
+	if cfg.LogLevel == "debug" {
+		log.Printf("configuration: %+v", cfg)
+	}
+	db, err := storage.New(cfg.DB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eklase: unable to open %s: %v\n", cfg.DB, err)
+		os.Exit(1)
+	}
+	appState, err := newState(db, cfg)
+	if err != nil {
+		db.Close()
+		fmt.Fprintf(os.Stderr, "eklase: %v\n", err)
+		os.Exit(2)
+	}
 
-	// Run the main event loop.
+	// Subcommands run without a window.
+	if len(cfg.Command) > 0 {
+		code := cli.Run(appState, cfg.Command, os.Stdout, os.Stderr)
+		db.Close()
+		os.Exit(code)
+	}
+
+	// Run the main event loop. os.Exit skips deferred calls, so the
+	// database is closed before leaving either way.
 	go func() {
-		w := app.NewWindow(app.Title("e-Klasse"))
-		if err := mainLoop(w); err != nil {
+		w := app.NewWindow(
+			app.Title("e-Klasse"),
+			app.Size(unit.Dp(float32(cfg.Width)), unit.Dp(float32(cfg.Height))),
+		)
+		err := mainLoop(w, appState, cfg)
+		db.Close()
+		if err != nil {
 			log.Fatalf("failed to handle events: %v", err)
 		}
 		// Gracefully exit the application at the end.
@@ -33,14 +77,26 @@ func main() {
 	app.Main()
 }
 
-func mainLoop(w *app.Window) error {
-	storage := storage.Must(storage.New("school.db"))
-	defer storage.Close()
-
-	appState := state.New(storage)
+// newState returns the state of the application over db configured by cfg.
+func newState(db storage.Storage, cfg config.Config) (*state.State, error) {
+	appState := state.New(db)
+	appState.SetLogLevel(logLevels[cfg.LogLevel])
+	if err := appState.SetLocale(cfg.Locale); err != nil {
+		return nil, err
+	}
+	if err := appState.SetGradingRules(cfg.Grading); err != nil {
+		return nil, err
+	}
+	return appState, nil
+}
 
-	th := material.NewTheme(gofont.Collection())
-	currentLayout := screen.MainMenu(th, appState)
+func mainLoop(w *app.Window, appState *state.State, cfg config.Config) error {
+	th := newTheme(cfg.Theme)
+	// The command line runs as the system, the window as whoever logs in.
+	appState.Logout()
+	router := screen.NewRouter(screen.Login(th, appState))
+	toasts := screen.NewToasts(th, appState)
+	history := screen.HistoryIndicator(th, appState)
 
 	for {
 		select {
@@ -48,13 +104,28 @@ func mainLoop(w *app.Window) error {
 			switch e := e.(type) {
 			case system.FrameEvent:
 				gtx := layout.NewContext(&op.Ops{}, e)
+				// Undo before the screens are laid out, so that they show
+				// the reverted data right away.
+				handleUndo(gtx, appState)
+				paint.Fill(gtx.Ops, th.Bg)
 				layout.UniformInset(unit.Dp(5)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-					nextLayout, d := currentLayout(gtx)
-					if nextLayout != nil {
-						currentLayout = nextLayout
-					}
-					return d
+					// Fill the window so notifications stick to its bottom.
+					gtx.Constraints.Min = gtx.Constraints.Max
+					return layout.Stack{Alignment: layout.S}.Layout(gtx,
+						layout.Expanded(router.Layout),
+						// The history and notifications are drawn on top of
+						// the current screen.
+						layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+							return layout.NE.Layout(gtx, history)
+						}),
+						layout.Stacked(toasts.Layout),
+					)
 				})
+				// The shortcuts reach the window wherever the focus is, as
+				// editors do not handle them.
+				area := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+				key.InputOp{Tag: appState, Keys: undoKeys}.Add(gtx.Ops)
+				area.Pop()
 				if appState.ShouldQuit() {
 					w.Perform(system.ActionClose)
 				}
@@ -65,3 +136,48 @@ func mainLoop(w *app.Window) error {
 		}
 	}
 }
+
+// undoKeys are the shortcuts of undo and redo: Ctrl+Z and Ctrl+Shift+Z, or
+// Cmd instead of Ctrl on macOS.
+var undoKeys = key.Set("Short-Z|Short-Shift-Z")
+
+// handleUndo undoes or redoes a change of the data on the shortcuts of
+// undoKeys and notifies the user of the outcome.
+func handleUndo(gtx layout.Context, appState *state.State) {
+	for _, e := range gtx.Events(appState) {
+		e, ok := e.(key.Event)
+		if !ok || e.State != key.Press {
+			continue
+		}
+		verb, done, run := "undo", "Undone", appState.Undo
+		if e.Modifiers.Contain(key.ModShift) {
+			verb, done, run = "redo", "Redone", appState.Redo
+		}
+		name, err := run()
+		switch {
+		case err != nil:
+			appState.NotifyError(fmt.Sprintf("Unable to %s %s", verb, name), err)
+		case name != "":
+			appState.Notify(state.Info, fmt.Sprintf("%s: %s.", done, name))
+		}
+	}
+}
+
+// logLevels maps the log levels of the config to the least severe
+// notifications that are logged.
+var logLevels = map[string]state.Severity{
+	"debug": state.Info,
+	"info":  state.Info,
+	"warn":  state.Warning,
+	"error": state.Error,
+}
+
+// newTheme returns the material theme of the given name, see config.Themes.
+func newTheme(name string) *material.Theme {
+	th := material.NewTheme(gofont.Collection())
+	if name == "dark" {
+		th.Palette.Bg = color.NRGBA{A: 0xff, R: 0x20, G: 0x21, B: 0x24}
+		th.Palette.Fg = color.NRGBA{A: 0xff, R: 0xe8, G: 0xea, B: 0xed}
+	}
+	return th
+}
@@ -2,12 +2,15 @@ package screen
 
 import (
 	"eklase/state"
-	"log"
+	"fmt"
+	"image"
 	"strings"
 
 	"image/color"
 
 	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
 	"gioui.org/text"
 	"gioui.org/widget"
 	"gioui.org/widget/material"
@@ -42,7 +45,7 @@ func AddStudent(th *material.Theme, state *state.State) Screen {
 		)
 	}
 	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
-		matCloseBut := material.Button(th, &close, "Close")
+		matCloseBut := material.Button(th, &close, "Back")
 		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
 		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
 		matSaveBut := material.Button(th, &save, "Save")
@@ -54,26 +57,27 @@ func AddStudent(th *material.Theme, state *state.State) Screen {
 			layout.Rigid(enabledIfNameOK(rowInset(matSaveBut.Layout))),
 		)
 	}
-	return func(gtx layout.Context) (Screen, layout.Dimensions) {
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
 		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
 			layout.Rigid(rowInset(editsRowLayout)),
 			layout.Rigid(rowInset(buttonsRowLayout)),
 		)
 		if close.Clicked() {
-			return MainMenu(th, state), d
+			nav.Back()
 		}
 		if save.Clicked() {
-			err := state.AddStudent(
+			_, err := state.AddStudent(
 				strings.TrimSpace(name.Text()),
 				strings.TrimSpace(surname.Text()),
 			)
 			if err != nil {
-				// TODO: Show an error toast.
-				log.Printf("unable to add student: %v", err)
+				state.NotifyError("Unable to add student", err)
+				return d
 			}
-			return MainMenu(th, state), d
+			notifyInfo(state, "Student added.")
+			nav.Back()
 		}
-		return nil, d
+		return d
 	}
 }
 
@@ -107,7 +111,7 @@ func AddClass(th *material.Theme, state *state.State) Screen {
 		)
 	}
 	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
-		matCloseBut := material.Button(th, &close, "Close")
+		matCloseBut := material.Button(th, &close, "Back")
 		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
 		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
 		matSaveBut := material.Button(th, &save, "Save")
@@ -119,90 +123,96 @@ func AddClass(th *material.Theme, state *state.State) Screen {
 			layout.Rigid(enabledIfNameOK(rowInset(matSaveBut.Layout))),
 		)
 	}
-	return func(gtx layout.Context) (Screen, layout.Dimensions) {
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
 		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
 			layout.Rigid(rowInset(editsRowLayout)),
 			layout.Rigid(rowInset(buttonsRowLayout)),
 		)
 		if close.Clicked() {
-			return MainMenu(th, state), d
+			nav.Back()
 		}
 		if save.Clicked() {
-			err := state.AddClass(
+			_, err := state.AddClass(
 				strings.TrimSpace(year.Text()),
 				strings.TrimSpace(modifier.Text()),
 			)
 			if err != nil {
-				log.Printf("unable to add class: %v", err)
+				state.NotifyError("Unable to add class", err)
+				return d
 			}
-			return MainMenu(th, state), d
+			notifyInfo(state, "Class added.")
+			nav.Back()
 		}
-		return nil, d
+		return d
 	}
 }
 
-func AssignClassToStudent(th *material.Theme, state *state.State, student_id int) Screen {
-	var (
-		year     widget.Editor
-		modifier widget.Editor
+// AssignClassToStudent defines a screen layout for enrolling a student into
+// one of the existing classes.
+func AssignClassToStudent(th *material.Theme, state *state.State, studentID int) Screen {
+	var close widget.Clickable
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
 
-		close widget.Clickable
-		save  widget.Clickable
-	)
-	enabledIfNameOK := func(w layout.Widget) layout.Widget {
-		return func(gtx layout.Context) layout.Dimensions {
-			year := strings.TrimSpace(year.Text())
-			modifier := strings.TrimSpace(modifier.Text())
-			if year == "" || modifier == "" {
-				gtx = gtx.Disabled()
-			} else if strings.ContainsAny(year, "qwertyuiopasdfghjklzxcvbnm.,_/?;:!@#$%^&*()[]{}`~ ") {
-				gtx = gtx.Disabled()
-			} else if strings.ContainsAny(modifier, "1234567890.,_/?;:!@#$%^&*()[]{}`~ ") {
-				gtx = gtx.Disabled()
-			}
-			return w(gtx)
-		}
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	classes, err := state.Classes()
+	if err != nil {
+		state.NotifyError("Unable to load classes", err)
 	}
-	editsRowLayout := func(gtx layout.Context) layout.Dimensions {
-		return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
-			layout.Flexed(1, material.Editor(th, &year, "Year").Layout),
-			layout.Rigid(spacer.Layout),
-			layout.Flexed(1, material.Editor(th, &modifier, "Modifier").Layout),
-		)
+
+	assign := make([]widget.Clickable, len(classes))
+
+	classesLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(classes), func(gtx layout.Context, index int) layout.Dimensions {
+			class := classes[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					matAssignBut := material.Button(th, &assign[index], "Assign")
+					matAssignBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matAssignBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%s.%s", class.Year, class.Modifier)).Layout)),
+						layout.Rigid(rowInset(matAssignBut.Layout)),
+					)
+				})),
+			)
+		})
 	}
-	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
-		matCloseBut := material.Button(th, &close, "Close")
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
 		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
 		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
-		matSaveBut := material.Button(th, &save, "Save")
-		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
-		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
-		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
-			layout.Rigid(rowInset(matCloseBut.Layout)),
-			layout.Rigid(spacer.Layout),
-			layout.Rigid(enabledIfNameOK(rowInset(matSaveBut.Layout))),
-		)
-	}
-	return func(gtx layout.Context) (Screen, layout.Dimensions) {
 		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
-			layout.Rigid(rowInset(editsRowLayout)),
-			layout.Rigid(rowInset(buttonsRowLayout)),
+			layout.Flexed(0.05, rowInset(material.Body1(th, "Class").Layout)),
+			layout.Flexed(1, rowInset(classesLayout)),
+			layout.Rigid(rowInset(matCloseBut.Layout)),
 		)
 		if close.Clicked() {
-			return ListGroup(th, state), d
+			nav.Back()
 		}
-		if save.Clicked() {
-			err := state.AssignClassToStudent(
-				strings.TrimSpace(year.Text()),
-				strings.TrimSpace(modifier.Text()),
-				student_id,
-			)
-			if err != nil {
-				log.Printf("unable to add class: %v", err)
+		for i := range assign {
+			if assign[i].Clicked() {
+				if err := state.AssignClassToStudent(studentID, classes[i].ID); err != nil {
+					state.NotifyError("Unable to assign class", err)
+					return d
+				}
+				nav.Back()
 			}
-			state.AssignClassToStudent(year.Text(), modifier.Text(), student_id)
-			return ListGroup(th, state), d
 		}
-		return nil, d
+		return d
 	}
 }
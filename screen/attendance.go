@@ -0,0 +1,308 @@
+package screen
+
+import (
+	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// dayLessons is the number of lessons shown for a day in the register, and in
+// the timetable unless more are scheduled.
+const dayLessons = 8
+
+// presenceCodes are the short labels of the attendance statuses shown in the
+// register cells.
+var presenceCodes = map[storage.AttendanceEntry]string{
+	{Status: storage.Present}:                          "-",
+	{Status: storage.Absent}:                           "A",
+	{Status: storage.Late}:                             "L",
+	{Status: storage.Excused, Reason: storage.Illness}: "I",
+	{Status: storage.Excused, Reason: storage.Family}:  "F",
+}
+
+// presenceCode returns the label of the status of e.
+func presenceCode(e storage.AttendanceEntry) string {
+	return presenceCodes[storage.AttendanceEntry{Status: e.Status, Reason: e.Reason}]
+}
+
+// AttendanceRegister defines a screen layout for marking the attendance of a
+// class on a day. Every cell is a lesson of a student, and a click on it
+// moves to the next status.
+func AttendanceRegister(th *material.Theme, state *state.State, classID int) Screen {
+	var (
+		close  widget.Clickable
+		prev   widget.Clickable
+		next   widget.Clickable
+		totals widget.Clickable
+		date   widget.Editor
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+	date.SingleLine = true
+	date.SetText(time.Now().Format("2006-01-02"))
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+	absentColor := color.NRGBA{A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e}
+
+	var (
+		title    string
+		students []storage.GroupEntry
+		entries  map[int]map[int]storage.AttendanceEntry // By student ID and lesson.
+		cells    [][dayLessons]widget.Clickable          // Buttons of the rows in students.
+		loaded   string                                  // Date the entries were loaded for.
+		revision = -1                                    // State revision the entries were loaded at.
+	)
+	if class, err := state.Class(classID); err == nil {
+		title = fmt.Sprintf("%s.%s", class.Year, class.Modifier)
+	}
+
+	day := func() (time.Time, bool) {
+		d, err := time.Parse("2006-01-02", strings.TrimSpace(date.Text()))
+		return d, err == nil
+	}
+	load := func(d string) {
+		var err error
+		if students, err = state.ClassStudents(classID); err != nil {
+			state.NotifyError("Unable to load students", err)
+		}
+		cells = make([][dayLessons]widget.Clickable, len(students))
+		all, err := state.Attendance(classID, d)
+		if err != nil {
+			state.NotifyError("Unable to load attendance", err)
+		}
+		entries = make(map[int]map[int]storage.AttendanceEntry)
+		for _, e := range all {
+			if entries[e.StudentID] == nil {
+				entries[e.StudentID] = make(map[int]storage.AttendanceEntry)
+			}
+			entries[e.StudentID][e.Lesson] = e
+		}
+	}
+	entry := func(studentID, lesson int) storage.AttendanceEntry {
+		if e, ok := entries[studentID][lesson]; ok {
+			return e
+		}
+		return storage.AttendanceEntry{StudentID: studentID, Date: loaded, Lesson: lesson, Status: storage.Present}
+	}
+
+	cellSize := unit.Dp(36)
+	studentsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(students), func(gtx layout.Context, index int) layout.Dimensions {
+			student := students[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					children := []layout.FlexChild{
+						layout.Flexed(1, rowInset(material.Body1(th, student.Surname+" "+student.Name).Layout)),
+					}
+					for lesson := 1; lesson <= dayLessons; lesson++ {
+						e := entry(student.StudentID, lesson)
+						matCellBut := material.Button(th, &cells[index][lesson-1], presenceCode(e))
+						matCellBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+						if e.Status != storage.Present {
+							matCellBut.Background = absentColor
+						}
+						matCellBut.Inset = layout.UniformInset(unit.Dp(4))
+						children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							gtx.Constraints = layout.Exact(image.Pt(gtx.Px(cellSize), gtx.Px(cellSize)))
+							return in.Layout(gtx, matCellBut.Layout)
+						}))
+					}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx, children...)
+				})),
+			)
+		})
+	}
+	headerLayout := func(gtx layout.Context) layout.Dimensions {
+		children := []layout.FlexChild{
+			layout.Flexed(1, rowInset(material.Body1(th, "Student").Layout)),
+		}
+		for lesson := 1; lesson <= dayLessons; lesson++ {
+			label := material.Body1(th, fmt.Sprint(lesson))
+			label.Alignment = text.Middle
+			children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				gtx.Constraints = layout.Exact(image.Pt(gtx.Px(cellSize), gtx.Px(cellSize)))
+				return in.Layout(gtx, label.Layout)
+			}))
+		}
+		// Align the lesson numbers with the cells, which are inset twice.
+		return rowInset(rowInset(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Alignment: layout.Middle}.Layout(gtx, children...)
+		}))(gtx)
+	}
+	dateRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matPrevBut := material.Button(th, &prev, "Previous day")
+		matPrevBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matPrevBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matNextBut := material.Button(th, &next, "Next day")
+		matNextBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matNextBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, title).Layout)),
+			layout.Rigid(rowInset(matPrevBut.Layout)),
+			layout.Flexed(1, rowInset(material.Editor(th, &date, "Date, e.g. 2025-09-30").Layout)),
+			layout.Rigid(rowInset(matNextBut.Layout)),
+		)
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matTotalsBut := material.Button(th, &totals, "Totals")
+		matTotalsBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matTotalsBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		legend := "- present, A absent, L late, I excused (illness), F excused (family)"
+		return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(rowInset(matTotalsBut.Layout)),
+			layout.Flexed(1, rowInset(material.Caption(th, legend).Layout)),
+		)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		d, ok := day()
+		if ok {
+			if s := d.Format("2006-01-02"); s != loaded || state.Revision() != revision {
+				loaded, revision = s, state.Revision()
+				load(loaded)
+			}
+		}
+		dims := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(dateRowLayout)),
+			layout.Rigid(headerLayout),
+			layout.Flexed(1, rowInset(func(gtx layout.Context) layout.Dimensions {
+				if !ok {
+					return material.Body1(th, "Enter a date like 2025-09-30.").Layout(gtx)
+				}
+				return studentsLayout(gtx)
+			})),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		if ok {
+			for i := range cells {
+				for lesson := 1; lesson <= dayLessons; lesson++ {
+					if cells[i][lesson-1].Clicked() {
+						e := nextPresence(entry(students[i].StudentID, lesson))
+						if err := state.SetAttendance(e); err != nil {
+							state.NotifyError("Unable to mark attendance", err)
+						}
+					}
+				}
+			}
+		}
+		if prev.Clicked() && ok {
+			date.SetText(d.AddDate(0, 0, -1).Format("2006-01-02"))
+		}
+		if next.Clicked() && ok {
+			date.SetText(d.AddDate(0, 0, 1).Format("2006-01-02"))
+		}
+		if totals.Clicked() {
+			nav.Push(AbsenceTotals(th, state, classID))
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return dims
+	}
+}
+
+// AbsenceTotals defines a screen layout listing how many lessons each student
+// of a class missed between two dates.
+func AbsenceTotals(th *material.Theme, state *state.State, classID int) Screen {
+	var (
+		close    widget.Clickable
+		from, to widget.Editor
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+	from.SingleLine, to.SingleLine = true, true
+	from.SetText(currentSchoolYearStart().Format("2006-01-02"))
+	to.SetText(time.Now().Format("2006-01-02"))
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	var (
+		totals   []storage.AbsenceTotal
+		loaded   [2]string // Range the totals were loaded for.
+		revision = -1      // State revision the totals were loaded at.
+	)
+
+	totalsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(totals), func(gtx layout.Context, index int) layout.Dimensions {
+			t := totals[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(material.Body1(th, fmt.Sprintf("%s %s %d %d %d", t.Surname, t.Name, t.Absent, t.Late, t.Excused)).Layout)),
+			)
+		})
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		r := [2]string{strings.TrimSpace(from.Text()), strings.TrimSpace(to.Text())}
+		if r != loaded || state.Revision() != revision {
+			loaded, revision = r, state.Revision()
+			var err error
+			if totals, err = state.AbsenceTotals(classID, r[0], r[1]); err != nil {
+				state.NotifyError("Unable to count absences", err)
+			}
+		}
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{}.Layout(gtx,
+					layout.Flexed(1, rowInset(material.Editor(th, &from, "From, e.g. 2025-09-01").Layout)),
+					layout.Flexed(1, rowInset(material.Editor(th, &to, "To, e.g. 2025-12-31").Layout)),
+				)
+			}),
+			layout.Flexed(0.05, rowInset(material.Body1(th, fmt.Sprintf("%s %s %s %s", "Student", "Absent", "Late", "Excused")).Layout)),
+			layout.Flexed(1, rowInset(totalsLayout)),
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+		)
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// nextPresence is state.NextPresence, which the screens cannot reach because
+// their state parameter shadows the package.
+func nextPresence(e storage.AttendanceEntry) storage.AttendanceEntry {
+	return state.NextPresence(e)
+}
@@ -0,0 +1,117 @@
+package screen
+
+import (
+	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// auditLimit is the number of the newest audit entries shown at once.
+const auditLimit = 500
+
+// AuditLog defines a screen layout for browsing the recorded changes, filtered
+// by entity and by the user who made them.
+func AuditLog(th *material.Theme, state *state.State) Screen {
+	var (
+		entity widget.Enum // Empty value for all entities.
+		actor  widget.Editor
+		close  widget.Clickable
+	)
+	actor.SingleLine = true
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+	entities := widget.List{List: layout.List{Axis: layout.Horizontal}}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	var (
+		entries  []storage.AuditEntry
+		filter   storage.AuditFilter // Filter the entries were loaded with.
+		revision = -1                // State revision the entries were loaded at.
+	)
+
+	entitiesLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &entities).Layout(gtx, len(storage.AuditedEntities)+1, func(gtx layout.Context, index int) layout.Dimensions {
+			if index == 0 {
+				return material.RadioButton(th, &entity, "", "all").Layout(gtx)
+			}
+			e := storage.AuditedEntities[index-1]
+			return material.RadioButton(th, &entity, e, strings.ReplaceAll(e, "_", " ")).Layout(gtx)
+		})
+	}
+	entriesLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(entries), func(gtx layout.Context, index int) layout.Dimensions {
+			e := entries[index]
+			change := e.New.String
+			switch e.Action {
+			case storage.AuditUpdate:
+				change = e.Old.String + " → " + e.New.String
+			case storage.AuditDelete:
+				change = e.Old.String
+			}
+			actor := e.Actor
+			if actor == "" {
+				actor = "nobody"
+			}
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+						layout.Rigid(material.Body1(th, fmt.Sprintf("%s %s %s %s %d", e.At, actor, e.Action, e.Entity, e.EntityID)).Layout),
+						layout.Rigid(material.Caption(th, change).Layout),
+					)
+				})),
+			)
+		})
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		f := storage.AuditFilter{Entity: entity.Value, Actor: strings.TrimSpace(actor.Text()), Limit: auditLimit}
+		if r := state.Revision(); r != revision || f != filter {
+			revision, filter = r, f
+			var err error
+			if entries, err = state.AuditLog(f); err != nil {
+				state.NotifyError("Unable to load the audit log", err)
+			}
+		}
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(entitiesLayout)),
+			layout.Rigid(rowInset(material.Editor(th, &actor, "User, empty for everybody").Layout)),
+			layout.Flexed(0.05, rowInset(material.Body1(th, fmt.Sprintf("%s %s %s %s %s", "Time", "User", "Action", "Entity", "ID")).Layout)),
+			layout.Flexed(1, rowInset(entriesLayout)),
+			layout.Rigid(rowInset(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+					layout.Rigid(rowInset(matCloseBut.Layout)),
+				)
+			})),
+		)
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
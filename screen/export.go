@@ -0,0 +1,139 @@
+package screen
+
+import (
+	"eklase/export"
+	"eklase/state"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/text"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// Export defines a screen layout for exporting the list of the given name,
+// see state.Exports, to a CSV, JSON or XLSX file with the chosen columns.
+func Export(th *material.Theme, state *state.State, name string) Screen {
+	var (
+		close   widget.Clickable
+		save    widget.Clickable
+		format  widget.Enum
+		path    widget.Editor
+		columns []string
+		checks  []widget.Bool // Whether the columns are exported.
+	)
+	path.SingleLine = true
+	format.Value = string(export.CSV)
+	path.SetText(name + ".csv")
+	shown := format.Value // Format whose extension path has.
+
+	if table, err := state.ExportTable(name); err != nil {
+		state.NotifyError("Unable to load the columns", err)
+	} else {
+		columns = table.Columns
+	}
+	checks = make([]widget.Bool, len(columns))
+	for i := range checks {
+		checks[i].Value = true
+	}
+
+	formatLayout := func(gtx layout.Context) layout.Dimensions {
+		children := make([]layout.FlexChild, 0, len(export.Formats))
+		for _, f := range export.Formats {
+			children = append(children, layout.Rigid(material.RadioButton(th, &format, string(f), strings.ToUpper(string(f))).Layout))
+		}
+		return layout.Flex{}.Layout(gtx, children...)
+	}
+	columnsLayout := func(gtx layout.Context) layout.Dimensions {
+		children := make([]layout.FlexChild, 0, len(columns))
+		for i, c := range columns {
+			children = append(children, layout.Rigid(rowInset(material.CheckBox(th, &checks[i], c).Layout)))
+		}
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, "Export")
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if strings.TrimSpace(path.Text()) == "" || len(selected(columns, checks)) == 0 {
+					gtx = gtx.Disabled()
+				}
+				return rowInset(matSaveBut.Layout)(gtx)
+			}),
+		)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if format.Value != shown {
+			// Keep the extension of the file in line with the format.
+			p := path.Text()
+			if strings.EqualFold(filepath.Ext(p), "."+shown) {
+				path.SetText(strings.TrimSuffix(p, filepath.Ext(p)) + "." + format.Value)
+			}
+			shown = format.Value
+		}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, "Export "+name).Layout)),
+			layout.Rigid(rowInset(formatLayout)),
+			layout.Rigid(rowInset(material.Editor(th, &path, "Path of the file").Layout)),
+			layout.Flexed(1, rowInset(columnsLayout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		if save.Clicked() {
+			p := strings.TrimSpace(path.Text())
+			if err := exportFile(state, name, selected(columns, checks), export.Format(format.Value), p); err != nil {
+				state.NotifyError("Unable to export "+name, err)
+				return d
+			}
+			notifyInfo(state, fmt.Sprintf("Exported %s to %s.", name, p))
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// selected returns the checked columns.
+func selected(columns []string, checks []widget.Bool) []string {
+	var s []string
+	for i, c := range columns {
+		if checks[i].Value {
+			s = append(s, c)
+		}
+	}
+	return s
+}
+
+// exportFile writes the columns of the named list to the file at path. The
+// file is removed again if writing fails.
+func exportFile(s *state.State, name string, columns []string, f export.Format, path string) error {
+	table, err := s.ExportTable(name)
+	if err != nil {
+		return err
+	}
+	if table, err = table.Select(columns); err != nil {
+		return err
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := export.Write(out, table, f); err != nil {
+		out.Close()
+		os.Remove(path)
+		return err
+	}
+	return out.Close()
+}
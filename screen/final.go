@@ -0,0 +1,208 @@
+package screen
+
+import (
+	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// FinalGrades defines a screen layout for finalizing the term and year marks
+// of a class in the subject of a teaching assignment. Every student gets a
+// proposed mark derived from their grades, which the teacher may override
+// with a reason before saving.
+func FinalGrades(th *material.Theme, state *state.State, a storage.AssignmentEntry) Screen {
+	var (
+		close  widget.Clickable
+		save   widget.Clickable
+		period widget.Enum // Value is the term ID, "0" for the whole year.
+
+		schoolYear storage.SchoolYearEntry
+		terms      []storage.TermEntry
+		rows       []finalGradeRow
+		marks      []widget.Editor    // Final marks of the rows.
+		reasons    []widget.Editor    // Reasons of overrides in the rows.
+		reopen     []widget.Clickable // Buttons of the rows.
+		loaded     string             // Period the rows were loaded for.
+		revision   = -1               // State revision the rows were loaded at.
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+	period.Value = "0"
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	var err error
+	if !a.SchoolYearID.Valid {
+		state.NotifyError("Unable to load final grades", &storage.Error{Op: fmt.Sprintf("find school year of assignment %d", a.ID), Kind: storage.ErrNotFound})
+	} else if schoolYear, err = state.SchoolYear(int(a.SchoolYearID.Int64)); err != nil {
+		state.NotifyError("Unable to load school year", err)
+	} else if terms, err = state.Terms(schoolYear.ID); err != nil {
+		state.NotifyError("Unable to load terms", err)
+	}
+	title := fmt.Sprintf("%s.%s %s, %s", a.Year, a.Modifier, a.Subject, a.SchoolYear)
+
+	load := func() {
+		rows, marks, reasons, reopen = nil, nil, nil, nil
+		loaded = period.Value
+		if schoolYear.ID == 0 {
+			return
+		}
+		termID, _ := strconv.Atoi(period.Value)
+		var err error
+		if rows, err = state.FinalGradeSheet(a.ClassID, a.SubjectID, schoolYear.ID, termID); err != nil {
+			state.NotifyError("Unable to load final grades", err)
+		}
+		marks = make([]widget.Editor, len(rows))
+		reasons = make([]widget.Editor, len(rows))
+		reopen = make([]widget.Clickable, len(rows))
+		for i, r := range rows {
+			marks[i].SingleLine = true
+			reasons[i].SingleLine = true
+			if r.Final.ID != 0 {
+				marks[i].SetText(string(r.Final.Mark))
+				reasons[i].SetText(r.Final.Reason)
+			} else {
+				marks[i].SetText(string(r.Proposal.Mark))
+			}
+		}
+	}
+
+	periodLayout := func(gtx layout.Context) layout.Dimensions {
+		children := []layout.FlexChild{
+			layout.Rigid(material.RadioButton(th, &period, "0", "Whole year").Layout),
+		}
+		for _, t := range terms {
+			children = append(children, layout.Rigid(material.RadioButton(th, &period, strconv.Itoa(t.ID), t.Name).Layout))
+		}
+		return layout.Flex{}.Layout(gtx, children...)
+	}
+	rowsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(rows), func(gtx layout.Context, index int) layout.Dimensions {
+			r := rows[index]
+			average := "-"
+			if r.Proposal.Count > 0 {
+				average = fmt.Sprintf("%.2f", r.Proposal.Average)
+			}
+			status := "Open"
+			if r.Final.ID != 0 {
+				status = "Final"
+			}
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+					matReopenBut := material.Button(th, &reopen[index], "Reopen")
+					matReopenBut.Background = color.NRGBA{A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e}
+					matReopenBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Flexed(2, rowInset(material.Body1(th, r.Student.Surname+" "+r.Student.Name).Layout)),
+						layout.Flexed(1, rowInset(material.Body1(th, average).Layout)),
+						layout.Flexed(1, rowInset(material.Body1(th, string(r.Proposal.Mark)).Layout)),
+						layout.Flexed(1, rowInset(material.Editor(th, &marks[index], "Mark").Layout)),
+						layout.Flexed(3, rowInset(material.Editor(th, &reasons[index], "Reason of an override").Layout)),
+						layout.Flexed(1, rowInset(material.Body2(th, status).Layout)),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							if r.Final.ID == 0 {
+								gtx = gtx.Disabled()
+							}
+							return rowInset(matReopenBut.Layout)(gtx)
+						}),
+					)
+				}),
+			)
+		})
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, "Finalize")
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if len(rows) == 0 {
+					gtx = gtx.Disabled()
+				}
+				return rowInset(matSaveBut.Layout)(gtx)
+			}),
+		)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision || period.Value != loaded {
+			revision = r
+			load()
+		}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, title).Layout)),
+			layout.Rigid(rowInset(periodLayout)),
+			layout.Rigid(rowInset(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{}.Layout(gtx,
+					layout.Flexed(2, rowInset(material.Body1(th, "Student").Layout)),
+					layout.Flexed(1, rowInset(material.Body1(th, "Average").Layout)),
+					layout.Flexed(1, rowInset(material.Body1(th, "Proposed").Layout)),
+					layout.Flexed(1, rowInset(material.Body1(th, "Final").Layout)),
+					layout.Flexed(5, rowInset(material.Body1(th, "Reason").Layout)),
+				)
+			})),
+			layout.Flexed(1, rowInset(rowsLayout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		for i := range reopen {
+			if reopen[i].Clicked() && rows[i].Final.ID != 0 {
+				if err := state.ReopenFinalGrade(rows[i].Final.ID); err != nil {
+					state.NotifyError("Unable to reopen final grade", err)
+				}
+			}
+		}
+		if save.Clicked() {
+			finals := make([]storage.FinalGradeEntry, 0, len(rows))
+			for i, r := range rows {
+				mark, err := storage.ParseMark(marks[i].Text())
+				if err != nil {
+					state.NotifyError("Unable to finalize grades", err)
+					return d
+				}
+				finals = append(finals, r.Finalize(mark, strings.TrimSpace(reasons[i].Text())))
+			}
+			if err := state.FinalizeGrades(finals); err != nil {
+				state.NotifyError("Unable to finalize grades", err)
+				return d
+			}
+			notifyInfo(state, "Final grades saved.")
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// finalGradeRow names state.FinalGradeRow for the screens, whose state
+// parameter shadows the package.
+type finalGradeRow = state.FinalGradeRow
@@ -0,0 +1,514 @@
+package screen
+
+import (
+	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gioui.org/gesture"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// Sizes of the gradebook cells. All cells have the same size, which keeps the
+// columns of the separately scrolled rows aligned.
+var (
+	gradeNameWidth  = unit.Dp(160)
+	gradeCellWidth  = unit.Dp(56)
+	gradeCellHeight = unit.Dp(32)
+)
+
+// gradeKeys are the keys handled by the gradebook while it has the focus.
+const gradeKeys = "[←,→,↑,↓,⏎,⌤,⌫,⌦]|(Shift)-Tab"
+
+// maxMarkInput is the longest text accepted in a cell, "neieskaitīts".
+const maxMarkInput = 12
+
+// gradeCorrectionReason is recorded when a mark is changed in the gradebook.
+const gradeCorrectionReason = "Corrected in the gradebook"
+
+// assessmentLabels name the kinds of assessment of the dates added to the
+// gradebook.
+var assessmentLabels = map[storage.Assessment]string{
+	storage.DailyWork: "Daily",
+	storage.Test:      "Test",
+	storage.Exam:      "Exam",
+}
+
+// assessmentMarks follow the dates of tests and exams in the header of the
+// gradebook. Daily work is not marked.
+var assessmentMarks = map[storage.Assessment]string{
+	storage.Test: "T",
+	storage.Exam: "E",
+}
+
+// gradeCell identifies a cell of the gradebook.
+type gradeCell struct {
+	studentID int
+	date      string
+}
+
+// Gradebook defines a spreadsheet-like screen layout for grading a class in
+// the subject of a teaching assignment. Students are rows and dates are
+// columns. Typed marks are kept as unsaved edits until they are saved in one
+// batch.
+//
+// A cell shows a single grade. If a student got several grades on the same
+// day, the latest one is shown and edited.
+func Gradebook(th *material.Theme, state *state.State, a storage.AssignmentEntry) Screen {
+	var (
+		close   widget.Clickable
+		save    widget.Clickable
+		addDate widget.Clickable
+		date    widget.Editor
+		kind    widget.Enum // Value is the kind of assessment of the added date.
+
+		rowList widget.List // Vertical, rows are students.
+		header  widget.List // Horizontal, its position is shared by every row.
+		rows    []layout.List
+
+		keyTag       bool // Tag of the keyboard input of the grid.
+		focused      bool
+		requestFocus = true
+		reveal       bool // Scroll the cursor into view in the next frame.
+		fresh        bool // The next typed text replaces the cell.
+		clicks       = make(map[gradeCell]*gesture.Click)
+		cursorRow    int
+		cursorCol    int
+		viewSize     image.Point // Size of the grid cells area in the last frame.
+	)
+	rowList.Axis = layout.Vertical
+	header.Axis = layout.Horizontal
+	date.SingleLine = true
+	date.SetText(time.Now().Format("2006-01-02"))
+	kind.Value = string(storage.DailyWork)
+
+	var (
+		students []storage.GroupEntry
+		dates    []string
+		extra    = make(map[string]storage.Assessment)    // Dates added without grades yet.
+		kinds    = make(map[string]storage.Assessment)    // Kind of assessment by date.
+		stored   = make(map[gradeCell]storage.GradeEntry) // Saved grades.
+		edits    = make(map[gradeCell]string)             // Unsaved edits.
+		revision = -1                                     // State revision the grades were loaded at.
+	)
+
+	load := func() {
+		var err error
+		if students, err = state.ClassStudents(a.ClassID); err != nil {
+			state.NotifyError("Unable to load students", err)
+		}
+		rows = make([]layout.List, len(students))
+
+		grades, err := state.ClassGrades(a.ClassID, a.SubjectID)
+		if err != nil {
+			state.NotifyError("Unable to load grades", err)
+		}
+		stored = make(map[gradeCell]storage.GradeEntry)
+		seen := make(map[string]bool)
+		dates = dates[:0]
+		for _, g := range grades {
+			// Grades are ordered by date and ID, so the latest one wins.
+			stored[gradeCell{g.StudentID, g.Date}] = g
+			if !seen[g.Date] {
+				seen[g.Date] = true
+				dates = append(dates, g.Date)
+				kinds[g.Date] = g.Kind
+			}
+		}
+		for d, k := range extra {
+			if !seen[d] {
+				dates = append(dates, d)
+				kinds[d] = k
+			}
+		}
+		sort.Strings(dates)
+	}
+
+	cellText := func(c gradeCell) (string, bool) {
+		if t, ok := edits[c]; ok {
+			return t, true
+		}
+		return string(stored[c].Mark), false
+	}
+	setCellText := func(c gradeCell, t string) {
+		if t == string(stored[c].Mark) {
+			delete(edits, c)
+			return
+		}
+		edits[c] = t
+	}
+	valid := func() bool {
+		for _, t := range edits {
+			if _, err := storage.ParseMark(t); t != "" && err != nil {
+				return false
+			}
+		}
+		return true
+	}
+	cursor := func() (gradeCell, bool) {
+		if cursorRow >= len(students) || cursorCol >= len(dates) {
+			return gradeCell{}, false
+		}
+		return gradeCell{students[cursorRow].StudentID, dates[cursorCol]}, true
+	}
+	move := func(drow, dcol int) {
+		cursorRow += drow
+		cursorCol += dcol
+		if cursorRow >= len(students) {
+			cursorRow = len(students) - 1
+		}
+		if cursorCol >= len(dates) {
+			cursorCol = len(dates) - 1
+		}
+		if cursorRow < 0 {
+			cursorRow = 0
+		}
+		if cursorCol < 0 {
+			cursorCol = 0
+		}
+		fresh, reveal = true, true
+	}
+
+	// handleKeys processes navigation and typing while the grid is focused.
+	handleKeys := func(gtx layout.Context) {
+		for _, e := range gtx.Events(&keyTag) {
+			switch e := e.(type) {
+			case key.FocusEvent:
+				focused = e.Focus
+			case key.Event:
+				if e.State != key.Press {
+					break
+				}
+				c, ok := cursor()
+				switch e.Name {
+				case key.NameUpArrow:
+					move(-1, 0)
+				case key.NameDownArrow, key.NameReturn, key.NameEnter:
+					move(1, 0)
+				case key.NameLeftArrow:
+					move(0, -1)
+				case key.NameRightArrow:
+					move(0, 1)
+				case key.NameTab:
+					if e.Modifiers.Contain(key.ModShift) {
+						move(0, -1)
+					} else {
+						move(0, 1)
+					}
+				case key.NameDeleteBackward:
+					if t, _ := cellText(c); ok && t != "" {
+						_, size := utf8.DecodeLastRuneInString(t)
+						setCellText(c, t[:len(t)-size])
+					}
+					fresh = false
+				case key.NameDeleteForward:
+					if ok {
+						setCellText(c, "")
+					}
+				}
+			case key.EditEvent:
+				c, ok := cursor()
+				if !ok {
+					break
+				}
+				t, _ := cellText(c)
+				if fresh {
+					t = ""
+				}
+				fresh = false
+				t += strings.TrimSpace(e.Text)
+				if utf8.RuneCountInString(t) <= maxMarkInput {
+					setCellText(c, t)
+				}
+			}
+		}
+	}
+
+	// scrollIntoView adjusts the list positions so that the cursor is fully
+	// visible.
+	scrollIntoView := func(gtx layout.Context) {
+		if !reveal || viewSize == (image.Point{}) {
+			return
+		}
+		reveal = false
+		reveal1 := func(pos *layout.Position, index, cell, view int) {
+			visible := view / cell
+			if visible < 1 {
+				visible = 1
+			}
+			switch {
+			case index < pos.First || index == pos.First && pos.Offset > 0:
+				pos.First, pos.Offset = index, 0
+			case index >= pos.First+visible:
+				pos.First, pos.Offset = index-visible+1, 0
+			}
+		}
+		reveal1(&rowList.Position, cursorRow, gtx.Px(gradeCellHeight), viewSize.Y)
+		reveal1(&header.Position, cursorCol, gtx.Px(gradeCellWidth), viewSize.X)
+	}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+	unsavedColor := color.NRGBA{A: 0xff, R: 0xf5, G: 0xe0, B: 0x8a}
+	invalidColor := color.NRGBA{A: 0xff, R: 0xe8, G: 0x9a, B: 0x8f}
+	cursorColor := color.NRGBA{A: 0xff, R: 0x2e, G: 0x5c, B: 0x34}
+
+	// fixedSize lays out w in a cell of the given size with a background.
+	fixedSize := func(gtx layout.Context, width unit.Value, bg color.NRGBA, w layout.Widget) layout.Dimensions {
+		size := image.Pt(gtx.Px(width), gtx.Px(gradeCellHeight))
+		gtx.Constraints = layout.Exact(size)
+		paint.FillShape(gtx.Ops, bg, clip.Rect{Max: size}.Op())
+		layout.W.Layout(gtx, rowInset(w))
+		return layout.Dimensions{Size: size}
+	}
+	cellLayout := func(gtx layout.Context, row, col int) layout.Dimensions {
+		c := gradeCell{students[row].StudentID, dates[col]}
+		t, edited := cellText(c)
+		bg := lightContrast
+		if row%2 == 0 {
+			bg = darkContrast
+		}
+		if edited {
+			bg = unsavedColor
+			if _, err := storage.ParseMark(t); t != "" && err != nil {
+				bg = invalidColor
+			}
+		}
+		click := clicks[c]
+		if click == nil {
+			click = new(gesture.Click)
+			clicks[c] = click
+		}
+		dims := fixedSize(gtx, gradeCellWidth, bg, func(gtx layout.Context) layout.Dimensions {
+			label := material.Body1(th, t)
+			if edited {
+				label.Font.Weight = text.Bold
+			}
+			return label.Layout(gtx)
+		})
+		if row == cursorRow && col == cursorCol {
+			border := widget.Border{Color: cursorColor, Width: unit.Dp(2)}
+			if !focused {
+				border.Width = unit.Dp(1)
+			}
+			border.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return dims
+			})
+		}
+		area := clip.Rect{Max: dims.Size}.Push(gtx.Ops)
+		click.Add(gtx.Ops)
+		area.Pop()
+		return dims
+	}
+
+	gridLayout := func(gtx layout.Context) layout.Dimensions {
+		handleKeys(gtx)
+		for c, click := range clicks {
+			for _, e := range click.Events(gtx) {
+				if e.Type != gesture.TypeClick {
+					continue
+				}
+				for i, s := range students {
+					if s.StudentID == c.studentID {
+						cursorRow = i
+					}
+				}
+				for i, d := range dates {
+					if d == c.date {
+						cursorCol = i
+					}
+				}
+				fresh, requestFocus = true, true
+			}
+		}
+		scrollIntoView(gtx)
+
+		area := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+		key.InputOp{Tag: &keyTag, Keys: gradeKeys}.Add(gtx.Ops)
+		if requestFocus {
+			key.FocusOp{Tag: &keyTag}.Add(gtx.Ops)
+			requestFocus = false
+		}
+		area.Pop()
+
+		headerLayout := func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return fixedSize(gtx, gradeNameWidth, color.NRGBA{}, material.Body1(th, "Student").Layout)
+				}),
+				layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+					viewSize.X = gtx.Constraints.Max.X
+					return material.List(th, &header).Layout(gtx, len(dates), func(gtx layout.Context, col int) layout.Dimensions {
+						day, err := time.Parse("2006-01-02", dates[col])
+						label := dates[col]
+						if err == nil {
+							label = day.Format("02.01.")
+						}
+						if mark, ok := assessmentMarks[kinds[dates[col]]]; ok {
+							// Shorter, so that the mark fits into the cell.
+							return fixedSize(gtx, gradeCellWidth, color.NRGBA{}, material.Body2(th, strings.TrimSuffix(label, ".")+" "+mark).Layout)
+						}
+						return fixedSize(gtx, gradeCellWidth, color.NRGBA{}, material.Body1(th, label).Layout)
+					})
+				}),
+			)
+		}
+		rowsLayout := func(gtx layout.Context) layout.Dimensions {
+			viewSize.Y = gtx.Constraints.Max.Y
+			return material.List(th, &rowList).Layout(gtx, len(students), func(gtx layout.Context, row int) layout.Dimensions {
+				s := students[row]
+				bg := lightContrast
+				if row%2 == 0 {
+					bg = darkContrast
+				}
+				return layout.Flex{}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return fixedSize(gtx, gradeNameWidth, bg, material.Body1(th, s.Surname+" "+s.Name).Layout)
+					}),
+					layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+						// Every row scrolls on its own but starts from the
+						// shared position. A row scrolled by the user moves
+						// the others in the next frame.
+						l := &rows[row]
+						l.Axis = layout.Horizontal
+						l.Position = header.Position
+						dims := l.Layout(gtx, len(dates), func(gtx layout.Context, col int) layout.Dimensions {
+							return cellLayout(gtx, row, col)
+						})
+						if l.Position.First != header.Position.First || l.Position.Offset != header.Position.Offset {
+							header.Position = l.Position
+							op.InvalidateOp{}.Add(gtx.Ops)
+						}
+						return dims
+					}),
+				)
+			})
+		}
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(headerLayout),
+			layout.Flexed(1, rowsLayout),
+		)
+	}
+
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matAddDateBut := material.Button(th, &addDate, "Add date")
+		matAddDateBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matAddDateBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, fmt.Sprintf("Save (%d)", len(edits)))
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Flexed(1, rowInset(material.Editor(th, &date, "Date, e.g. 2025-09-30").Layout)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				children := make([]layout.FlexChild, len(storage.Assessments))
+				for i, k := range storage.Assessments {
+					children[i] = layout.Rigid(material.RadioButton(th, &kind, string(k), assessmentLabels[k]).Layout)
+				}
+				return layout.Flex{Alignment: layout.Middle}.Layout(gtx, children...)
+			}),
+			layout.Rigid(rowInset(matAddDateBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if len(edits) == 0 || !valid() {
+					gtx = gtx.Disabled()
+				}
+				return rowInset(matSaveBut.Layout)(gtx)
+			}),
+		)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			load()
+			move(0, 0)
+		}
+		title := fmt.Sprintf("%s.%s %s, %s", a.Year, a.Modifier, a.Subject, a.SchoolYear)
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, title).Layout)),
+			layout.Flexed(1, rowInset(gridLayout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		if addDate.Clicked() {
+			d := strings.TrimSpace(date.Text())
+			if day, err := time.Parse("2006-01-02", d); err != nil {
+				state.NotifyError("Unable to add date", &storage.ValidationError{Field: "date", Reason: "must look like 2025-09-30"})
+			} else {
+				d = day.Format("2006-01-02")
+				extra[d] = storage.Assessment(kind.Value)
+				load()
+				for i := range dates {
+					if dates[i] == d {
+						cursorCol = i
+					}
+				}
+				move(0, 0)
+				requestFocus = true
+			}
+		}
+		if save.Clicked() {
+			var changes []storage.GradeChange
+			for c, t := range edits {
+				old, exists := stored[c]
+				mark, _ := storage.ParseMark(t)
+				switch {
+				case exists:
+					changes = append(changes, storage.GradeChange{ID: old.ID, Mark: mark, Reason: gradeCorrectionReason})
+				case t != "":
+					changes = append(changes, storage.GradeChange{
+						StudentID: c.studentID,
+						SubjectID: a.SubjectID,
+						TeacherID: a.TeacherID,
+						Date:      c.date,
+						Kind:      kinds[c.date],
+						Mark:      mark,
+					})
+				}
+			}
+			// Apply the changes in a stable order, which keeps new IDs
+			// predictable.
+			sort.Slice(changes, func(i, j int) bool {
+				a, b := changes[i], changes[j]
+				switch {
+				case a.ID != b.ID:
+					return a.ID < b.ID
+				case a.Date != b.Date:
+					return a.Date < b.Date
+				}
+				return a.StudentID < b.StudentID
+			})
+			if err := state.SaveGrades(changes); err != nil {
+				state.NotifyError("Unable to save grades", err)
+				return d
+			}
+			edits = make(map[gradeCell]string)
+			notifyInfo(state, "Grades saved.")
+			requestFocus = true
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
@@ -0,0 +1,142 @@
+package screen
+
+import (
+	"fmt"
+	"image"
+	"testing"
+	"time"
+
+	"eklase/state"
+	"eklase/storage"
+
+	"gioui.org/f32"
+	"gioui.org/font/gofont"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/router"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+)
+
+// gradebookFixture returns a state with a class of two students, one of them
+// graded, and the teaching assignment of the class.
+func gradebookFixture(t *testing.T) (*state.State, storage.AssignmentEntry) {
+	t.Helper()
+	st := state.New(storage.NewMemory())
+	must := func(_ int, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(st.AddTeacher("Ilze", "Kalniņa"))
+	must(st.AddSubject("Matemātika"))
+	must(st.AddClass("5", "a"))
+	must(st.AddStudent("Anna", "Bērziņa"))
+	must(st.AddStudent("Jānis", "Ozols"))
+	for _, id := range []int{1, 2} {
+		if err := st.AssignClassToStudent(id, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(st.AddGrade(1, 1, 1, "2025-09-01", "5"))
+	must(st.AddAssignment(1, 1, 1))
+	assignments, err := st.ClassAssignments(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st, assignments[0]
+}
+
+func TestGradebookTypingAndSave(t *testing.T) {
+	st, a := gradebookFixture(t)
+	var (
+		r    router.Router
+		ops  op.Ops
+		size = image.Pt(800, 600)
+	)
+	screen := Gradebook(material.NewTheme(gofont.Collection()), st, a)
+	nav := NewRouter(screen)
+	frame := func(events ...event.Event) {
+		r.Queue(events...)
+		ops.Reset()
+		gtx := layout.Context{
+			Ops:         &ops,
+			Now:         time.Now(),
+			Queue:       &r,
+			Metric:      unit.Metric{PxPerDp: 1, PxPerSp: 1},
+			Constraints: layout.Exact(size),
+		}
+		screen(gtx, nav)
+		r.Frame(&ops)
+	}
+	press := func(name string) event.Event {
+		return key.Event{Name: name, State: key.Press}
+	}
+
+	frame()
+	frame(key.EditEvent{Text: "8"})
+	frame(press(key.NameDownArrow))
+	frame(key.EditEvent{Text: "n"}, key.EditEvent{Text: "v"})
+	frame(press(key.NameUpArrow))
+	if grades, _ := st.Grades(storage.GradeFilter{}); len(grades) != 1 || grades[0].Mark != "5" {
+		t.Fatalf("grades before saving = %+v, want only the 5", grades)
+	}
+
+	// Save is the last button in the bottom right corner.
+	at := f32.Pt(float32(size.X-30), float32(size.Y-25))
+	frame(
+		pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: at},
+		pointer.Event{Type: pointer.Release, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: at},
+	)
+	frame()
+
+	grades, err := st.Grades(storage.GradeFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(grades); len(grades) != 2 || grades[0].Mark != "8" || !grades[0].Corrected || grades[1].Mark != storage.NotGraded || grades[1].StudentID != 2 {
+		t.Errorf("grades after saving = %s, want a corrected 8 and a new nv of the second student", got)
+	}
+}
+
+// BenchmarkGradebookFrame lays out a full class of 35 students graded on 60
+// days, which must stay well below a frame.
+func BenchmarkGradebookFrame(b *testing.B) {
+	st := state.New(storage.NewMemory())
+	st.AddTeacher("Ilze", "Kalniņa")
+	st.AddSubject("Matemātika")
+	st.AddClass("5", "a")
+	st.AddAssignment(1, 1, 1)
+	for i := 1; i <= 35; i++ {
+		st.AddStudent("Anna", "Bērziņa")
+		st.AssignClassToStudent(i, 1)
+		day := time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC)
+		for j := 0; j < 60; j++ {
+			st.AddGrade(i, 1, 1, day.AddDate(0, 0, j).Format("2006-01-02"), storage.Mark(fmt.Sprint(1+j%10)))
+		}
+	}
+	assignments, _ := st.ClassAssignments(1)
+	screen := Gradebook(material.NewTheme(gofont.Collection()), st, assignments[0])
+	nav := NewRouter(screen)
+	var (
+		r   router.Router
+		ops op.Ops
+	)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ops.Reset()
+		gtx := layout.Context{
+			Ops:         &ops,
+			Now:         time.Now(),
+			Queue:       &r,
+			Metric:      unit.Metric{PxPerDp: 1, PxPerSp: 1},
+			Constraints: layout.Exact(image.Pt(1280, 800)),
+		}
+		screen(gtx, nav)
+		r.Frame(&ops)
+	}
+}
@@ -0,0 +1,51 @@
+package screen
+
+import (
+	"eklase/state"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/widget/material"
+)
+
+// HistoryIndicator returns a widget naming the changes that Ctrl+Z undoes and
+// Ctrl+Shift+Z redoes, with the number of further changes in the history. It
+// is empty while there is nothing to undo or redo. Lay it out on top of the
+// current screen.
+func HistoryIndicator(th *material.Theme, state *state.State) layout.Widget {
+	background := color.NRGBA{A: 0x33, R: 0x5e, G: 0x9c, B: 0x64}
+	return func(gtx layout.Context) layout.Dimensions {
+		h := state.History()
+		var parts []string
+		if len(h.Undo) > 0 {
+			parts = append(parts, historyPart("Ctrl+Z undoes", h.Undo))
+		}
+		if len(h.Redo) > 0 {
+			parts = append(parts, historyPart("Ctrl+Shift+Z redoes", h.Redo))
+		}
+		if len(parts) == 0 {
+			return layout.Dimensions{}
+		}
+		gtx.Constraints.Min = image.Point{}
+		return layout.Stack{}.Layout(gtx,
+			layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+				paint.FillShape(gtx.Ops, background, clip.Rect{Max: gtx.Constraints.Min}.Op())
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			}),
+			layout.Stacked(rowInset(material.Caption(th, strings.Join(parts, " · ")).Layout)),
+		)
+	}
+}
+
+// historyPart describes the latest of changes and how many precede it.
+func historyPart(action string, changes []string) string {
+	if len(changes) == 1 {
+		return fmt.Sprintf("%s %s", action, changes[0])
+	}
+	return fmt.Sprintf("%s %s (%d more)", action, changes[0], len(changes)-1)
+}
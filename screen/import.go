@@ -0,0 +1,214 @@
+package screen
+
+import (
+	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// ImportStudents defines a screen layout for importing students from a CSV
+// file. The file is previewed first, showing which rows are new, duplicate or
+// invalid, and only the new rows are imported, all of them or none.
+func ImportStudents(th *material.Theme, state *state.State) Screen {
+	var (
+		close    widget.Clickable
+		preview  widget.Clickable
+		save     widget.Clickable
+		path     widget.Editor
+		delim    widget.Editor
+		nameCol  widget.Editor // Header of the name column, if not a usual one.
+		surnCol  widget.Editor // Header of the surname column, if not a usual one.
+		classCol widget.Editor // Header of the class column, if not a usual one.
+
+		report    importReport
+		previewed bool // Whether report holds a preview of the file.
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+	for _, e := range []*widget.Editor{&path, &delim, &nameCol, &surnCol, &classCol} {
+		e.SingleLine = true
+	}
+	delim.SetText(",")
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	options := func() (csvOptions, bool) {
+		opts := csvOptions{Columns: make(map[string]importField)}
+		d := delim.Text()
+		if strings.EqualFold(d, "tab") { // A tab cannot be typed into the editor.
+			d = "\t"
+		}
+		if utf8.RuneCountInString(d) != 1 {
+			return opts, false
+		}
+		opts.Delimiter, _ = utf8.DecodeRuneInString(d)
+		for i, e := range []*widget.Editor{&nameCol, &surnCol, &classCol} {
+			if h := strings.TrimSpace(e.Text()); h != "" {
+				opts.Columns[h] = importFields[i]
+			}
+		}
+		return opts, true
+	}
+
+	editsLayout := func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{}.Layout(gtx,
+					layout.Flexed(4, rowInset(material.Editor(th, &path, "Path of the CSV file").Layout)),
+					layout.Flexed(1, rowInset(material.Editor(th, &delim, "Delimiter, e.g. ; or tab").Layout)),
+				)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{}.Layout(gtx,
+					layout.Flexed(1, rowInset(material.Editor(th, &nameCol, "Name column, e.g. Vārds").Layout)),
+					layout.Flexed(1, rowInset(material.Editor(th, &surnCol, "Surname column, e.g. Uzvārds").Layout)),
+					layout.Flexed(1, rowInset(material.Editor(th, &classCol, "Class column, e.g. Klase").Layout)),
+				)
+			}),
+		)
+	}
+	rowsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(report.Rows), func(gtx layout.Context, index int) layout.Dimensions {
+			r := report.Rows[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Flexed(1, rowInset(material.Body1(th, fmt.Sprint(r.Line)).Layout)),
+						layout.Flexed(2, rowInset(material.Body1(th, r.Entry.Name).Layout)),
+						layout.Flexed(2, rowInset(material.Body1(th, r.Entry.Surname).Layout)),
+						layout.Flexed(1, rowInset(material.Body1(th, r.Class).Layout)),
+						layout.Flexed(1, rowInset(material.Body2(th, r.Status.String()).Layout)),
+						layout.Flexed(4, rowInset(material.Body2(th, r.Reason).Layout)),
+					)
+				}),
+			)
+		})
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matPreviewBut := material.Button(th, &preview, "Preview")
+		matPreviewBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matPreviewBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, "Import")
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if strings.TrimSpace(path.Text()) == "" {
+					gtx = gtx.Disabled()
+				}
+				return rowInset(matPreviewBut.Layout)(gtx)
+			}),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if !previewed || report.New == 0 {
+					gtx = gtx.Disabled()
+				}
+				return rowInset(matSaveBut.Layout)(gtx)
+			}),
+		)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		summary := "Preview the file to see what would be imported."
+		if previewed {
+			summary = fmt.Sprintf("%d new, %d duplicate and %d invalid rows. Only the new ones are imported.",
+				report.New, report.Duplicates, report.Invalid)
+			if len(report.Classes) > 0 {
+				summary += fmt.Sprintf(" Classes %s are added.", strings.Join(report.Classes, ", "))
+			}
+		}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(editsLayout)),
+			layout.Rigid(rowInset(material.Body1(th, summary).Layout)),
+			layout.Rigid(rowInset(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{}.Layout(gtx,
+					layout.Flexed(1, rowInset(material.Body1(th, "Line").Layout)),
+					layout.Flexed(2, rowInset(material.Body1(th, "Name").Layout)),
+					layout.Flexed(2, rowInset(material.Body1(th, "Surname").Layout)),
+					layout.Flexed(1, rowInset(material.Body1(th, "Class").Layout)),
+					layout.Flexed(1, rowInset(material.Body1(th, "Status").Layout)),
+					layout.Flexed(4, rowInset(material.Body1(th, "Reason").Layout)),
+				)
+			})),
+			layout.Flexed(1, rowInset(rowsLayout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		if preview.Clicked() {
+			report, previewed = importReport{}, false
+			opts, ok := options()
+			if !ok {
+				state.NotifyError("Unable to preview the file", errDelimiter)
+				return d
+			}
+			f, err := os.Open(strings.TrimSpace(path.Text()))
+			if err != nil {
+				state.NotifyError("Unable to open the file", err)
+				return d
+			}
+			report, err = state.PreviewImport(f, opts)
+			f.Close()
+			if err != nil {
+				state.NotifyError("Unable to preview the file", err)
+				return d
+			}
+			previewed = true
+		}
+		if save.Clicked() && previewed {
+			n, err := state.Import(report)
+			if err != nil {
+				state.NotifyError("Unable to import students", err)
+				return d
+			}
+			report, previewed = importReport{}, false
+			notifyInfo(state, fmt.Sprintf("%d students imported.", n))
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// errDelimiter rejects delimiters that are not a single character.
+var errDelimiter = &storage.ValidationError{Field: "delimiter", Reason: "must be a single character"}
+
+// Names of state types for the screens, whose state parameter shadows the
+// package.
+type (
+	importReport = state.ImportReport
+	importField  = state.ImportField
+	csvOptions   = state.CSVOptions
+)
+
+// importFields are the fields whose columns can be named on the screen.
+var importFields = []importField{state.FieldName, state.FieldSurname, state.FieldClass}
@@ -0,0 +1,277 @@
+package screen
+
+import (
+	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// LessonJournal defines a screen layout for recording the topics of the
+// lessons of a teaching assignment together with the homework given. A
+// listed lesson can be loaded into the form below the list and changed.
+func LessonJournal(th *material.Theme, state *state.State, a storage.AssignmentEntry) Screen {
+	var (
+		close widget.Clickable
+		save  widget.Clickable
+		blank widget.Clickable
+
+		date     widget.Editor
+		topic    widget.Editor
+		homework widget.Editor
+		dueDate  widget.Editor
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+	date.SingleLine = true
+	dueDate.SingleLine = true
+	topic.SingleLine = true
+	date.SetText(time.Now().Format("2006-01-02"))
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	var (
+		lessons  []storage.LessonEntry
+		edit     []widget.Clickable // Buttons of the rows in lessons.
+		remove   []widget.Clickable // Buttons of the rows in lessons.
+		editing  int                // ID of the lesson in the form, 0 for a new one.
+		revision = -1               // State revision the lessons were loaded at.
+	)
+	title := fmt.Sprintf("%s.%s %s, %s %s", a.Year, a.Modifier, a.Subject, a.TeacherName, a.TeacherSurname)
+
+	// reset empties the form for the next lesson, keeping the date.
+	reset := func() {
+		editing = 0
+		topic.SetText("")
+		homework.SetText("")
+		dueDate.SetText("")
+	}
+
+	lessonsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(lessons), func(gtx layout.Context, index int) layout.Dimensions {
+			l := lessons[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					matEditBut := material.Button(th, &edit[index], "Edit")
+					matEditBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matEditBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matDeleteBut := material.Button(th, &remove[index], "Delete")
+					matDeleteBut.Background = color.NRGBA{A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e}
+					matDeleteBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					work := "-"
+					if l.Homework != "" {
+						work = fmt.Sprintf("%s (due %s)", l.Homework, l.DueDate)
+					}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Flexed(1, rowInset(material.Body1(th, fmt.Sprintf("%s %s\n%s", l.Date, l.Topic, work)).Layout)),
+						layout.Rigid(rowInset(matEditBut.Layout)),
+						layout.Rigid(rowInset(matDeleteBut.Layout)),
+					)
+				})),
+			)
+		})
+	}
+	formLayout := func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{}.Layout(gtx,
+					layout.Flexed(1, rowInset(material.Editor(th, &date, "Date, e.g. 2025-09-30").Layout)),
+					layout.Flexed(3, rowInset(material.Editor(th, &topic, "Topic").Layout)),
+				)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{}.Layout(gtx,
+					layout.Flexed(1, rowInset(material.Editor(th, &dueDate, "Due date").Layout)),
+					layout.Flexed(3, rowInset(material.Editor(th, &homework, "Homework, if any").Layout)),
+				)
+			}),
+		)
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matBlankBut := material.Button(th, &blank, "New lesson")
+		matBlankBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matBlankBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, "Save")
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(rowInset(matBlankBut.Layout)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if strings.TrimSpace(topic.Text()) == "" {
+					gtx = gtx.Disabled()
+				}
+				return rowInset(matSaveBut.Layout)(gtx)
+			}),
+		)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			var err error
+			if lessons, err = state.Lessons(a.ClassID, a.SubjectID); err != nil {
+				state.NotifyError("Unable to load lessons", err)
+			}
+			edit = make([]widget.Clickable, len(lessons))
+			remove = make([]widget.Clickable, len(lessons))
+		}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, title).Layout)),
+			layout.Flexed(1, rowInset(lessonsLayout)),
+			layout.Rigid(rowInset(formLayout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		for i := range edit {
+			if edit[i].Clicked() {
+				l := lessons[i]
+				editing = l.ID
+				date.SetText(l.Date)
+				topic.SetText(l.Topic)
+				homework.SetText(l.Homework)
+				dueDate.SetText(l.DueDate)
+			}
+			if remove[i].Clicked() {
+				if err := state.DeleteLesson(lessons[i].ID); err != nil {
+					state.NotifyError("Unable to delete lesson", err)
+				} else if lessons[i].ID == editing {
+					reset()
+				}
+			}
+		}
+		if blank.Clicked() {
+			reset()
+		}
+		if save.Clicked() {
+			l := storage.LessonEntry{
+				ID:        editing,
+				ClassID:   a.ClassID,
+				SubjectID: a.SubjectID,
+				Date:      strings.TrimSpace(date.Text()),
+				Topic:     strings.TrimSpace(topic.Text()),
+				Homework:  strings.TrimSpace(homework.Text()),
+				DueDate:   strings.TrimSpace(dueDate.Text()),
+			}
+			var err error
+			if editing == 0 {
+				_, err = state.AddLesson(l)
+			} else {
+				err = state.UpdateLesson(l)
+			}
+			if err != nil {
+				state.NotifyError("Unable to save lesson", err)
+			} else {
+				notifyInfo(state, "Lesson saved.")
+				reset()
+			}
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// Homework defines a screen layout listing the homework of a class that is
+// due today or later, soonest first.
+func Homework(th *material.Theme, state *state.State, classID int) Screen {
+	var close widget.Clickable
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	var (
+		title    string
+		homework []storage.LessonEntry
+		revision = -1 // State revision the homework was loaded at.
+	)
+	if class, err := state.Class(classID); err == nil {
+		title = fmt.Sprintf("%s.%s", class.Year, class.Modifier)
+	}
+
+	homeworkLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(homework), func(gtx layout.Context, index int) layout.Dimensions {
+			l := homework[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+					return layout.Flex{}.Layout(gtx,
+						layout.Flexed(1, rowInset(material.Body1(th, l.DueDate).Layout)),
+						layout.Flexed(1, rowInset(material.Body1(th, l.Subject).Layout)),
+						layout.Flexed(3, rowInset(material.Body1(th, l.Homework).Layout)),
+						layout.Flexed(1, rowInset(material.Body2(th, l.Date).Layout)),
+					)
+				}),
+			)
+		})
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			var err error
+			if homework, err = state.UpcomingHomework(classID, time.Now()); err != nil {
+				state.NotifyError("Unable to load homework", err)
+			}
+		}
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, title).Layout)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{}.Layout(gtx,
+					layout.Flexed(1, rowInset(material.Body1(th, "Due").Layout)),
+					layout.Flexed(1, rowInset(material.Body1(th, "Subject").Layout)),
+					layout.Flexed(3, rowInset(material.Body1(th, "Homework").Layout)),
+					layout.Flexed(1, rowInset(material.Body1(th, "Given").Layout)),
+				)
+			}),
+			layout.Flexed(1, rowInset(homeworkLayout)),
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+		)
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
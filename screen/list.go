@@ -2,10 +2,11 @@ package screen
 
 import (
 	"eklase/state"
+	"eklase/storage"
 	"fmt"
 	"image"
 	"image/color"
-	"log"
+	"strconv"
 
 	"gioui.org/layout"
 	"gioui.org/op/clip"
@@ -15,10 +16,22 @@ import (
 	"gioui.org/widget/material"
 )
 
-// ListStudent defines a screen layout for listing existing students.
+// ListStudent defines a screen layout for listing existing students, which
+// can be searched by name and sorted by the column headers.
 func ListStudent(th *material.Theme, state *state.State) Screen {
-	var close widget.Clickable
+	var (
+		close      widget.Clickable
+		importFile widget.Clickable
+		exportFile widget.Clickable
+		search     widget.Editor
+	)
+	search.SingleLine = true
 	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+	columns := []column{
+		{label: "ID", key: storage.SortID},
+		{label: "Surname", key: storage.SortSurname},
+		{label: "Name", key: storage.SortName},
+	}
 
 	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
 	lightContrast := th.ContrastBg
@@ -26,12 +39,12 @@ func ListStudent(th *material.Theme, state *state.State) Screen {
 	darkContrast := th.ContrastBg
 	darkContrast.A = 0x55
 
-	students, err := state.Students()
-	if err != nil {
-		// TODO: Show user an error toast.
-		log.Printf("failed to fetch students: %v", err)
-		return nil
-	}
+	var (
+		students []storage.StudentEntry
+		query    storage.Query // Search and order of the list.
+		loaded   storage.Query // Query the students were loaded with.
+		revision = -1          // State revision the students were loaded at.
+	)
 
 	studentsLayout := func(gtx layout.Context) layout.Dimensions {
 		return material.List(th, &list).Layout(gtx, len(students), func(gtx layout.Context, index int) layout.Dimensions {
@@ -51,25 +64,70 @@ func ListStudent(th *material.Theme, state *state.State) Screen {
 		})
 	}
 
-	return func(gtx layout.Context) (Screen, layout.Dimensions) {
-		matCloseBut := material.Button(th, &close, "Close")
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		sortClicks(columns, &query)
+		query.Search = search.Text()
+		if r := state.Revision(); r != revision || query != loaded {
+			revision, loaded = r, query
+			var err error
+			if students, err = state.SearchStudents(query); err != nil {
+				state.NotifyError("Unable to load students", err)
+			}
+		}
+		matCloseBut := material.Button(th, &close, "Back")
 		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
 		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matImportBut := material.Button(th, &importFile, "Import CSV")
+		matImportBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matImportBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matExportBut := material.Button(th, &exportFile, "Export")
+		matExportBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matExportBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
 		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
-			layout.Flexed(0.05, rowInset(material.Body1(th, fmt.Sprintf("%s %s %s", "ID", "Surname", "Name")).Layout)),
+			layout.Rigid(rowInset(material.Editor(th, &search, "Search by name").Layout)),
+			layout.Rigid(rowInset(headerLayout(th, columns, query))),
 			layout.Flexed(1, rowInset(studentsLayout)),
-			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(rowInset(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+					layout.Rigid(rowInset(matCloseBut.Layout)),
+					layout.Rigid(spacer.Layout),
+					layout.Rigid(rowInset(matImportBut.Layout)),
+					layout.Rigid(spacer.Layout),
+					layout.Rigid(rowInset(matExportBut.Layout)),
+				)
+			})),
 		)
+		if importFile.Clicked() {
+			nav.Push(ImportStudents(th, state))
+		}
+		if exportFile.Clicked() {
+			nav.Push(Export(th, state, "students"))
+		}
 		if close.Clicked() {
-			return MainMenu(th, state), d
+			nav.Back()
 		}
-		return nil, d
+		return d
 	}
 }
 
+// ListClass defines a screen layout for listing existing classes, which can
+// be searched, sorted by the column headers and filtered by school year.
 func ListClass(th *material.Theme, state *state.State) Screen {
-	var close widget.Clickable
+	var (
+		close      widget.Clickable
+		exportFile widget.Clickable
+		search     widget.Editor
+		schoolYear widget.Enum // School year ID, empty for all.
+	)
+	search.SingleLine = true
 	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+	filters := widget.List{List: layout.List{Axis: layout.Horizontal}}
+	columns := []column{
+		{label: "ID", key: storage.SortID},
+		{label: "School year", key: storage.SortSchoolYear},
+		{label: "Class", key: storage.SortClass},
+		{label: "Teacher"},
+	}
 
 	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
 	lightContrast := th.ContrastBg
@@ -77,11 +135,20 @@ func ListClass(th *material.Theme, state *state.State) Screen {
 	darkContrast := th.ContrastBg
 	darkContrast.A = 0x55
 
-	classes, err := state.Classes()
-	if err != nil {
-		log.Printf("failed to fetch classes: %v", err)
-		return nil
-	}
+	var (
+		classes    []storage.ClassEntry
+		teachers   map[int]string     // Teacher names by ID.
+		years      map[int]string     // School year names by ID.
+		setTeacher []widget.Clickable // Buttons of the rows in classes.
+		subjects   []widget.Clickable // Buttons of the rows in classes.
+		attendance []widget.Clickable // Buttons of the rows in classes.
+		timetable  []widget.Clickable // Buttons of the rows in classes.
+		homework   []widget.Clickable // Buttons of the rows in classes.
+		options    []option           // School years to filter by.
+		query      storage.Query      // Search, filter and order of the list.
+		loaded     storage.Query      // Query the classes were loaded with.
+		revision   = -1               // State revision the classes were loaded at.
+	)
 
 	classesLayout := func(gtx layout.Context) layout.Dimensions {
 		return material.List(th, &list).Layout(gtx, len(classes), func(gtx layout.Context, index int) layout.Dimensions {
@@ -96,30 +163,143 @@ func ListClass(th *material.Theme, state *state.State) Screen {
 					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
 					return layout.Dimensions{Size: gtx.Constraints.Min}
 				}),
-				layout.Stacked(rowInset(material.Body1(th, fmt.Sprintf("%v %s %s", class.ID, class.Year, class.Modifier)).Layout)),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					homeroom := "-"
+					if class.TeacherID.Valid {
+						homeroom = teachers[int(class.TeacherID.Int64)]
+					}
+					year := "-"
+					if class.SchoolYearID.Valid {
+						year = years[int(class.SchoolYearID.Int64)]
+					}
+					matTeacherBut := material.Button(th, &setTeacher[index], "Set teacher")
+					matTeacherBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matTeacherBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matSubjectsBut := material.Button(th, &subjects[index], "Subjects")
+					matSubjectsBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matSubjectsBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matAttendanceBut := material.Button(th, &attendance[index], "Attendance")
+					matAttendanceBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matAttendanceBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matTimetableBut := material.Button(th, &timetable[index], "Timetable")
+					matTimetableBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matTimetableBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matHomeworkBut := material.Button(th, &homework[index], "Homework")
+					matHomeworkBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matHomeworkBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%v %s %s %s %s", class.ID, year, class.Year, class.Modifier, homeroom)).Layout)),
+						layout.Rigid(rowInset(matTeacherBut.Layout)),
+						layout.Rigid(rowInset(matSubjectsBut.Layout)),
+						layout.Rigid(rowInset(matAttendanceBut.Layout)),
+						layout.Rigid(rowInset(matTimetableBut.Layout)),
+						layout.Rigid(rowInset(matHomeworkBut.Layout)),
+					)
+				})),
 			)
 		})
 	}
 
-	return func(gtx layout.Context) (Screen, layout.Dimensions) {
-		matCloseBut := material.Button(th, &close, "Close")
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		sortClicks(columns, &query)
+		query.Search = search.Text()
+		query.SchoolYearID, _ = strconv.Atoi(schoolYear.Value)
+		if r := state.Revision(); r != revision || query != loaded {
+			revision, loaded = r, query
+			var err error
+			if classes, err = state.SearchClasses(query); err != nil {
+				state.NotifyError("Unable to load classes", err)
+			}
+			setTeacher = make([]widget.Clickable, len(classes))
+			subjects = make([]widget.Clickable, len(classes))
+			attendance = make([]widget.Clickable, len(classes))
+			timetable = make([]widget.Clickable, len(classes))
+			homework = make([]widget.Clickable, len(classes))
+			teachers = make(map[int]string)
+			all, err := state.Teachers()
+			if err != nil {
+				state.NotifyError("Unable to load teachers", err)
+			}
+			for _, t := range all {
+				teachers[t.ID] = t.Name + " " + t.Surname
+			}
+			years = make(map[int]string)
+			schoolYears, err := state.SchoolYears()
+			if err != nil {
+				state.NotifyError("Unable to load school years", err)
+			}
+			options = []option{{"", "All school years"}}
+			for _, y := range schoolYears {
+				years[y.ID] = y.Name
+				options = append(options, option{strconv.Itoa(y.ID), y.Name})
+			}
+		}
+		matCloseBut := material.Button(th, &close, "Back")
 		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
 		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matExportBut := material.Button(th, &exportFile, "Export")
+		matExportBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matExportBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
 		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
-			layout.Flexed(0.05, rowInset(material.Body1(th, fmt.Sprintf("%s %s %s", "ID", "Year", "Modifier")).Layout)),
+			layout.Rigid(rowInset(material.Editor(th, &search, "Search by class or school year").Layout)),
+			layout.Rigid(rowInset(filterLayout(th, &filters, &schoolYear, options))),
+			layout.Rigid(rowInset(headerLayout(th, columns, query))),
 			layout.Flexed(1, rowInset(classesLayout)),
-			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(rowInset(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+					layout.Rigid(rowInset(matCloseBut.Layout)),
+					layout.Rigid(spacer.Layout),
+					layout.Rigid(rowInset(matExportBut.Layout)),
+				)
+			})),
 		)
+		for i := range setTeacher {
+			if setTeacher[i].Clicked() {
+				nav.Push(AssignTeacherToClass(th, state, classes[i].ID))
+			}
+			if subjects[i].Clicked() {
+				nav.Push(ListAssignment(th, state, classes[i].ID))
+			}
+			if attendance[i].Clicked() {
+				nav.Push(AttendanceRegister(th, state, classes[i].ID))
+			}
+			if timetable[i].Clicked() {
+				nav.Push(Timetable(th, state, classes[i].ID, 0))
+			}
+			if homework[i].Clicked() {
+				nav.Push(Homework(th, state, classes[i].ID))
+			}
+		}
+		if exportFile.Clicked() {
+			nav.Push(Export(th, state, "classes"))
+		}
 		if close.Clicked() {
-			return MainMenu(th, state), d
+			nav.Back()
 		}
-		return nil, d
+		return d
 	}
 }
 
+// unenrolledOption is the value of the filter of students enrolled nowhere.
+const unenrolledOption = "none"
+
+// ListGroup defines a screen layout for listing students with their classes,
+// which can be searched, sorted by the column headers and filtered by class.
 func ListGroup(th *material.Theme, state *state.State) Screen {
-	var close widget.Clickable
+	var (
+		close      widget.Clickable
+		exportFile widget.Clickable
+		search     widget.Editor
+		class      widget.Enum // Class ID, unenrolledOption or empty for all.
+	)
+	search.SingleLine = true
 	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+	filters := widget.List{List: layout.List{Axis: layout.Horizontal}}
+	columns := []column{
+		{label: "Name", key: storage.SortName},
+		{label: "Surname", key: storage.SortSurname},
+		{label: "Class", key: storage.SortClass},
+	}
 
 	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
 	lightContrast := th.ContrastBg
@@ -127,13 +307,15 @@ func ListGroup(th *material.Theme, state *state.State) Screen {
 	darkContrast := th.ContrastBg
 	darkContrast.A = 0x55
 
-	groups, err := state.Groups()
-	if err != nil {
-		log.Printf("failed to fetch groups: %v", err)
-		return nil
-	}
+	var (
+		groups   []storage.GroupEntry
+		options  []option      // Classes to filter by.
+		query    storage.Query // Search, filter and order of the list.
+		loaded   storage.Query // Query the groups were loaded with.
+		revision = -1          // State revision the groups were loaded at.
+	)
 
-	assign := make([]widget.Clickable, len(groups))
+	var assign []widget.Clickable // Buttons of the rows in groups.
 
 	groupsLayout := func(gtx layout.Context) layout.Dimensions {
 		return material.List(th, &list).Layout(gtx, len(groups), func(gtx layout.Context, index int) layout.Dimensions {
@@ -153,7 +335,7 @@ func ListGroup(th *material.Theme, state *state.State) Screen {
 					matAssignBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
 					matAssignBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
 					return layout.Flex{}.Layout(gtx,
-						layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%s %s %s %s ", group.Name.String, group.Surname.String, group.Year.String, group.Modifier.String)).Layout)),
+						layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%s %s %s %s ", group.Name, group.Surname, group.Year.String, group.Modifier.String)).Layout)),
 						layout.Rigid(rowInset(matAssignBut.Layout)),
 					)
 				})),
@@ -161,23 +343,59 @@ func ListGroup(th *material.Theme, state *state.State) Screen {
 		})
 	}
 
-	return func(gtx layout.Context) (Screen, layout.Dimensions) {
-		matCloseBut := material.Button(th, &close, "Close")
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		sortClicks(columns, &query)
+		query.Search = search.Text()
+		query.Unenrolled = class.Value == unenrolledOption
+		query.ClassID, _ = strconv.Atoi(class.Value)
+		if r := state.Revision(); r != revision || query != loaded {
+			if r != revision {
+				classes, err := state.SearchClasses(storage.Query{Sort: storage.SortClass})
+				if err != nil {
+					state.NotifyError("Unable to load classes", err)
+				}
+				options = []option{{"", "All students"}, {unenrolledOption, "Not enrolled"}}
+				for _, c := range classes {
+					options = append(options, option{strconv.Itoa(c.ID), c.Year + "." + c.Modifier})
+				}
+			}
+			revision, loaded = r, query
+			var err error
+			if groups, err = state.SearchGroups(query); err != nil {
+				state.NotifyError("Unable to load groups", err)
+			}
+			assign = make([]widget.Clickable, len(groups))
+		}
+		matCloseBut := material.Button(th, &close, "Back")
 		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
 		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matExportBut := material.Button(th, &exportFile, "Export")
+		matExportBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matExportBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
 		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
-			layout.Flexed(0.05, rowInset(material.Body1(th, fmt.Sprintf("%s %s %s %s", "Name", "Surname", "Year", "Modifier")).Layout)),
+			layout.Rigid(rowInset(material.Editor(th, &search, "Search by name or class").Layout)),
+			layout.Rigid(rowInset(filterLayout(th, &filters, &class, options))),
+			layout.Rigid(rowInset(headerLayout(th, columns, query))),
 			layout.Flexed(1, rowInset(groupsLayout)),
-			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(rowInset(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+					layout.Rigid(rowInset(matCloseBut.Layout)),
+					layout.Rigid(spacer.Layout),
+					layout.Rigid(rowInset(matExportBut.Layout)),
+				)
+			})),
 		)
 		for i := range assign {
 			if assign[i].Clicked() {
-				return AssignClassToStudent(th, state, i+1), d
+				nav.Push(AssignClassToStudent(th, state, groups[i].StudentID))
 			}
 		}
+		if exportFile.Clicked() {
+			nav.Push(Export(th, state, "groups"))
+		}
 		if close.Clicked() {
-			return MainMenu(th, state), d
+			nav.Back()
 		}
-		return nil, d
+		return d
 	}
 }
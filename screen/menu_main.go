@@ -2,6 +2,9 @@ package screen
 
 import (
 	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"strings"
 
 	"image/color"
 
@@ -19,9 +22,20 @@ func MainMenu(th *material.Theme, state *state.State) Screen {
 		listStudents widget.Clickable
 		listClasses  widget.Clickable
 		listGroups   widget.Clickable
+		addTeacher   widget.Clickable
+		listTeachers widget.Clickable
+		addSubject   widget.Clickable
+		listSubjects widget.Clickable
+		listAssigns  widget.Clickable
+		bells        widget.Clickable
+		schoolYears  widget.Clickable
+		users        widget.Clickable
+		auditLog     widget.Clickable
+		logout       widget.Clickable
 		quit         widget.Clickable
 	)
-	return func(gtx layout.Context) (Screen, layout.Dimensions) {
+	session, _ := state.Session()
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
 		matAddStudentButton := material.Button(th, &addStudent, "Add student")
 		matAddStudentButton.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x94}
 		matAddStudentButton.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
@@ -37,36 +51,120 @@ func MainMenu(th *material.Theme, state *state.State) Screen {
 		matListGroupsButton := material.Button(th, &listGroups, "List groups")
 		matListGroupsButton.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x54}
 		matListGroupsButton.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matAddTeacherButton := material.Button(th, &addTeacher, "Add teacher")
+		matAddTeacherButton.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x54}
+		matAddTeacherButton.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matListTeachersButton := material.Button(th, &listTeachers, "List teachers")
+		matListTeachersButton.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x54}
+		matListTeachersButton.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matAddSubjectButton := material.Button(th, &addSubject, "Add subject")
+		matAddSubjectButton.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x54}
+		matAddSubjectButton.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matListSubjectsButton := material.Button(th, &listSubjects, "List subjects")
+		matListSubjectsButton.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x54}
+		matListSubjectsButton.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matListAssignsButton := material.Button(th, &listAssigns, "List assignments")
+		matListAssignsButton.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x54}
+		matListAssignsButton.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matBellsButton := material.Button(th, &bells, "Bell schedule")
+		matBellsButton.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x54}
+		matBellsButton.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSchoolYearsButton := material.Button(th, &schoolYears, "School years")
+		matSchoolYearsButton.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x54}
+		matSchoolYearsButton.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matUsersButton := material.Button(th, &users, "Users")
+		matUsersButton.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x54}
+		matUsersButton.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matAuditLogButton := material.Button(th, &auditLog, "Audit log")
+		matAuditLogButton.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x54}
+		matAuditLogButton.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matLogoutBut := material.Button(th, &logout, "Log out")
+		matLogoutBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x44}
+		matLogoutBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
 		matQuitBut := material.Button(th, &quit, "Quit")
 		matQuitBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x44}
 		matQuitBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
 
+		// Only admins may manage users and read the audit log, the state
+		// refuses anybody else anyway.
+		adminOnly := func(w layout.Widget) layout.Widget {
+			return func(gtx layout.Context) layout.Dimensions {
+				if session.Role != storage.RoleAdmin {
+					return layout.Dimensions{}
+				}
+				return rowInset(w)(gtx)
+			}
+		}
+
 		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("Logged in as %s (%s)", session.Username, strings.ReplaceAll(string(session.Role), "_", " "))).Layout)),
 			layout.Rigid(rowInset(matAddStudentButton.Layout)),
 			layout.Rigid(rowInset(matAddClassButton.Layout)),
 			layout.Rigid(rowInset(matListStudentsButton.Layout)),
 			layout.Rigid(rowInset(matListClassesButton.Layout)),
 			layout.Rigid(rowInset(matListGroupsButton.Layout)),
+			layout.Rigid(rowInset(matAddTeacherButton.Layout)),
+			layout.Rigid(rowInset(matListTeachersButton.Layout)),
+			layout.Rigid(rowInset(matAddSubjectButton.Layout)),
+			layout.Rigid(rowInset(matListSubjectsButton.Layout)),
+			layout.Rigid(rowInset(matListAssignsButton.Layout)),
+			layout.Rigid(rowInset(matBellsButton.Layout)),
+			layout.Rigid(rowInset(matSchoolYearsButton.Layout)),
+			layout.Rigid(adminOnly(matUsersButton.Layout)),
+			layout.Rigid(adminOnly(matAuditLogButton.Layout)),
+			layout.Rigid(rowInset(matLogoutBut.Layout)),
 			layout.Rigid(rowInset(matQuitBut.Layout)),
 		)
 		if addStudent.Clicked() {
-			return AddStudent(th, state), d
+			nav.Push(AddStudent(th, state))
 		}
 		if addClass.Clicked() {
-			return AddClass(th, state), d
+			nav.Push(AddClass(th, state))
 		}
 		if listStudents.Clicked() {
-			return ListStudent(th, state), d
+			nav.Push(ListStudent(th, state))
 		}
 		if listClasses.Clicked() {
-			return ListClass(th, state), d
+			nav.Push(ListClass(th, state))
 		}
 		if listGroups.Clicked() {
-			return ListGroup(th, state), d
+			nav.Push(ListGroup(th, state))
+		}
+		if addTeacher.Clicked() {
+			nav.Push(AddTeacher(th, state))
+		}
+		if listTeachers.Clicked() {
+			nav.Push(ListTeacher(th, state))
+		}
+		if addSubject.Clicked() {
+			nav.Push(AddSubject(th, state))
+		}
+		if listSubjects.Clicked() {
+			nav.Push(ListSubject(th, state))
+		}
+		if listAssigns.Clicked() {
+			nav.Push(ListAssignment(th, state, 0))
+		}
+		if bells.Clicked() {
+			nav.Push(BellSchedule(th, state))
+		}
+		if schoolYears.Clicked() {
+			nav.Push(ListSchoolYear(th, state))
+		}
+		if users.Clicked() {
+			nav.Push(ListUser(th, state))
+		}
+		if auditLog.Clicked() {
+			nav.Push(AuditLog(th, state))
+		}
+		if logout.Clicked() {
+			state.Logout()
+			nav.Reset()
+			nav.Replace(Login(th, state))
 		}
 		if quit.Clicked() {
 			state.Quit()
 		}
-		return nil, d
+		return d
 	}
 }
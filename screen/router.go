@@ -0,0 +1,119 @@
+package screen
+
+import (
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+)
+
+// navKeys are the shortcuts handled by the router: Escape and Alt+← go back,
+// Alt+→ goes forward. Gio does not report the extra mouse buttons yet, so
+// mouse back/forward is not available.
+var navKeys = key.Set(key.NameEscape + "|Alt-[" + key.NameLeftArrow + "," + key.NameRightArrow + "]")
+
+// Router keeps the history of visited screens. Screens stay alive while they
+// are in the history, so going back returns to them unchanged, e.g. with the
+// same list scroll position.
+type Router struct {
+	stack   []Screen // Visited screens, the current one last. Never empty.
+	forward []Screen // Screens left by going back, the most recent last.
+
+	moves int // Number of navigations so far, to detect them during a frame.
+}
+
+// NewRouter returns a router showing root. Root is never popped.
+func NewRouter(root Screen) *Router {
+	return &Router{stack: []Screen{root}}
+}
+
+// Push shows s, remembering the current screen for Back.
+func (r *Router) Push(s Screen) {
+	if s == nil {
+		return
+	}
+	r.stack = append(r.stack, s)
+	r.forward = nil
+	r.moves++
+}
+
+// Replace shows s instead of the current screen, so Back skips the latter.
+func (r *Router) Replace(s Screen) {
+	if s == nil {
+		return
+	}
+	if len(r.stack) == 1 {
+		// Replacing the root makes s the new root.
+		r.stack[0] = s
+	} else {
+		r.stack[len(r.stack)-1] = s
+	}
+	r.forward = nil
+	r.moves++
+}
+
+// Back returns to the previous screen. It reports false if the current screen
+// is the root.
+func (r *Router) Back() bool {
+	if len(r.stack) == 1 {
+		return false
+	}
+	top := len(r.stack) - 1
+	r.forward = append(r.forward, r.stack[top])
+	r.stack = r.stack[:top]
+	r.moves++
+	return true
+}
+
+// Forward shows the screen most recently left with Back, if any.
+func (r *Router) Forward() bool {
+	if len(r.forward) == 0 {
+		return false
+	}
+	top := len(r.forward) - 1
+	r.stack = append(r.stack, r.forward[top])
+	r.forward = r.forward[:top]
+	r.moves++
+	return true
+}
+
+// Reset returns to the root screen and clears the history.
+func (r *Router) Reset() {
+	r.stack = r.stack[:1]
+	r.forward = nil
+	r.moves++
+}
+
+// Current returns the screen being shown.
+func (r *Router) Current() Screen {
+	return r.stack[len(r.stack)-1]
+}
+
+// Layout handles the navigation shortcuts and lays out the current screen.
+func (r *Router) Layout(gtx layout.Context) layout.Dimensions {
+	for _, e := range gtx.Events(r) {
+		e, ok := e.(key.Event)
+		if !ok || e.State != key.Press {
+			continue
+		}
+		switch e.Name {
+		case key.NameEscape, key.NameLeftArrow:
+			r.Back()
+		case key.NameRightArrow:
+			r.Forward()
+		}
+	}
+
+	moves := r.moves
+	d := r.Current()(gtx, r)
+	if r.moves != moves {
+		// The screen navigated away, draw its successor right away.
+		op.InvalidateOp{}.Add(gtx.Ops)
+	}
+
+	// Shortcuts reach the router unless a focused widget, e.g. an editor,
+	// handles them first.
+	defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+	key.InputOp{Tag: r, Keys: navKeys}.Add(gtx.Ops)
+	return d
+}
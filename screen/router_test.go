@@ -0,0 +1,48 @@
+package screen
+
+import (
+	"testing"
+
+	"gioui.org/layout"
+)
+
+// named returns a screen that records its name in *shown when laid out.
+func named(name string, shown *string) Screen {
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		*shown = name
+		return layout.Dimensions{}
+	}
+}
+
+func TestRouterHistory(t *testing.T) {
+	var shown string
+	current := func(r *Router) string {
+		r.Current()(layout.Context{}, r)
+		return shown
+	}
+
+	r := NewRouter(named("menu", &shown))
+	if r.Back() {
+		t.Error("Back() from the root succeeded")
+	}
+	r.Push(named("groups", &shown))
+	r.Push(named("assign", &shown))
+	if !r.Back() || current(r) != "groups" {
+		t.Errorf("after Back() showing %q, want groups", shown)
+	}
+	if !r.Forward() || current(r) != "assign" {
+		t.Errorf("after Forward() showing %q, want assign", shown)
+	}
+	r.Replace(named("students", &shown))
+	if !r.Back() || current(r) != "groups" {
+		t.Errorf("after Replace() and Back() showing %q, want groups", shown)
+	}
+	r.Push(named("classes", &shown))
+	if r.Forward() {
+		t.Error("Forward() after Push() succeeded")
+	}
+	r.Reset()
+	if current(r) != "menu" || r.Back() {
+		t.Errorf("after Reset() showing %q, want menu without history", shown)
+	}
+}
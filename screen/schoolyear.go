@@ -0,0 +1,639 @@
+package screen
+
+import (
+	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// ListSchoolYear defines a screen layout listing the school years together
+// with their terms and the promotion of their students.
+func ListSchoolYear(th *material.Theme, state *state.State) Screen {
+	var (
+		close       widget.Clickable
+		add         widget.Clickable
+		promotions  widget.Clickable
+		graduates   widget.Clickable
+		schoolYears []storage.SchoolYearEntry
+		terms       []widget.Clickable // Buttons of the rows in schoolYears.
+		promote     []widget.Clickable // Buttons of the rows in schoolYears.
+		remove      []widget.Clickable // Buttons of the rows in schoolYears.
+		revision    = -1               // State revision the school years were loaded at.
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	yearsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(schoolYears), func(gtx layout.Context, index int) layout.Dimensions {
+			y := schoolYears[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					matTermsBut := material.Button(th, &terms[index], "Terms")
+					matTermsBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matTermsBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matPromoteBut := material.Button(th, &promote[index], "Promote")
+					matPromoteBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matPromoteBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matDeleteBut := material.Button(th, &remove[index], "Delete")
+					matDeleteBut.Background = color.NRGBA{A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e}
+					matDeleteBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Flexed(1, rowInset(material.Body1(th, fmt.Sprintf("%s %s %s", y.Name, y.Starts, y.Ends)).Layout)),
+						layout.Rigid(rowInset(matTermsBut.Layout)),
+						layout.Rigid(rowInset(matPromoteBut.Layout)),
+						layout.Rigid(rowInset(matDeleteBut.Layout)),
+					)
+				})),
+			)
+		})
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matPromotionsBut := material.Button(th, &promotions, "Promotions")
+		matPromotionsBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matPromotionsBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matGraduatesBut := material.Button(th, &graduates, "Graduates")
+		matGraduatesBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matGraduatesBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matAddBut := material.Button(th, &add, "Add")
+		matAddBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matAddBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(rowInset(matPromotionsBut.Layout)),
+			layout.Rigid(rowInset(matGraduatesBut.Layout)),
+			layout.Rigid(rowInset(matAddBut.Layout)),
+		)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			var err error
+			if schoolYears, err = state.SchoolYears(); err != nil {
+				state.NotifyError("Unable to load school years", err)
+			}
+			terms = make([]widget.Clickable, len(schoolYears))
+			promote = make([]widget.Clickable, len(schoolYears))
+			remove = make([]widget.Clickable, len(schoolYears))
+		}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%s %s %s", "Name", "Starts", "Ends")).Layout)),
+			layout.Flexed(1, rowInset(yearsLayout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		for i := range schoolYears {
+			if terms[i].Clicked() {
+				nav.Push(ListTerm(th, state, schoolYears[i]))
+			}
+			if promote[i].Clicked() {
+				nav.Push(PromotionWizard(th, state, schoolYears[i]))
+			}
+			if remove[i].Clicked() {
+				if err := state.DeleteSchoolYear(schoolYears[i].ID); err != nil {
+					state.NotifyError("Unable to delete school year", err)
+				} else {
+					notifyInfo(state, "School year deleted.")
+				}
+			}
+		}
+		if add.Clicked() {
+			nav.Push(AddSchoolYear(th, state))
+		}
+		if promotions.Clicked() {
+			nav.Push(ListPromotion(th, state))
+		}
+		if graduates.Clicked() {
+			nav.Push(ListGraduate(th, state))
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// AddSchoolYear defines a screen layout for adding a school year. The form
+// starts with the year following the latest one, or with the current school
+// year when there is none.
+func AddSchoolYear(th *material.Theme, state *state.State) Screen {
+	var (
+		name   widget.Editor
+		starts widget.Editor
+		ends   widget.Editor
+
+		close widget.Clickable
+		save  widget.Clickable
+	)
+	name.SingleLine = true
+	starts.SingleLine = true
+	ends.SingleLine = true
+
+	schoolYears, err := state.SchoolYears()
+	if err != nil {
+		state.NotifyError("Unable to load school years", err)
+	}
+	if len(schoolYears) > 0 {
+		next := nextSchoolYear(schoolYears[len(schoolYears)-1])
+		name.SetText(next.Name)
+		starts.SetText(next.Starts)
+		ends.SetText(next.Ends)
+	} else {
+		start := currentSchoolYearStart()
+		name.SetText(currentSchoolYear())
+		starts.SetText(start.Format("2006-01-02"))
+		ends.SetText(start.AddDate(1, 0, -1).Format("2006-01-02"))
+	}
+
+	enabledIfFilled := func(w layout.Widget) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			if strings.TrimSpace(name.Text()) == "" || strings.TrimSpace(starts.Text()) == "" || strings.TrimSpace(ends.Text()) == "" {
+				gtx = gtx.Disabled()
+			}
+			return w(gtx)
+		}
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, "Save")
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(enabledIfFilled(rowInset(matSaveBut.Layout))),
+		)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Editor(th, &name, "Name, e.g. 2025/2026").Layout)),
+			layout.Rigid(rowInset(material.Editor(th, &starts, "Starts, e.g. 2025-09-01").Layout)),
+			layout.Rigid(rowInset(material.Editor(th, &ends, "Ends, e.g. 2026-08-31").Layout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		if close.Clicked() {
+			nav.Back()
+		}
+		if save.Clicked() {
+			y := storage.SchoolYearEntry{
+				Name:   strings.TrimSpace(name.Text()),
+				Starts: strings.TrimSpace(starts.Text()),
+				Ends:   strings.TrimSpace(ends.Text()),
+			}
+			if _, err := state.AddSchoolYear(y); err != nil {
+				state.NotifyError("Unable to add school year", err)
+				return d
+			}
+			notifyInfo(state, "School year added.")
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// ListTerm defines a screen layout listing the terms of a school year with a
+// form for adding another one below.
+func ListTerm(th *material.Theme, state *state.State, y storage.SchoolYearEntry) Screen {
+	var (
+		close  widget.Clickable
+		add    widget.Clickable
+		name   widget.Editor
+		starts widget.Editor
+		ends   widget.Editor
+
+		terms    []storage.TermEntry
+		remove   []widget.Clickable // Buttons of the rows in terms.
+		revision = -1               // State revision the terms were loaded at.
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+	name.SingleLine = true
+	starts.SingleLine = true
+	ends.SingleLine = true
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	termsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(terms), func(gtx layout.Context, index int) layout.Dimensions {
+			t := terms[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					matDeleteBut := material.Button(th, &remove[index], "Delete")
+					matDeleteBut.Background = color.NRGBA{A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e}
+					matDeleteBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Flexed(1, rowInset(material.Body1(th, fmt.Sprintf("%s %s %s", t.Name, t.Starts, t.Ends)).Layout)),
+						layout.Rigid(rowInset(matDeleteBut.Layout)),
+					)
+				})),
+			)
+		})
+	}
+	formLayout := func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{}.Layout(gtx,
+			layout.Flexed(2, rowInset(material.Editor(th, &name, "Name, e.g. 1st semester").Layout)),
+			layout.Flexed(1, rowInset(material.Editor(th, &starts, "Starts").Layout)),
+			layout.Flexed(1, rowInset(material.Editor(th, &ends, "Ends").Layout)),
+		)
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matAddBut := material.Button(th, &add, "Add")
+		matAddBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matAddBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if strings.TrimSpace(name.Text()) == "" {
+					gtx = gtx.Disabled()
+				}
+				return rowInset(matAddBut.Layout)(gtx)
+			}),
+		)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			var err error
+			if terms, err = state.Terms(y.ID); err != nil {
+				state.NotifyError("Unable to load terms", err)
+			}
+			remove = make([]widget.Clickable, len(terms))
+		}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%s %s %s", y.Name, y.Starts, y.Ends)).Layout)),
+			layout.Flexed(1, rowInset(termsLayout)),
+			layout.Rigid(rowInset(formLayout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		for i := range terms {
+			if remove[i].Clicked() {
+				if err := state.DeleteTerm(terms[i].ID); err != nil {
+					state.NotifyError("Unable to delete term", err)
+				}
+			}
+		}
+		if add.Clicked() {
+			t := storage.TermEntry{
+				SchoolYearID: y.ID,
+				Name:         strings.TrimSpace(name.Text()),
+				Starts:       strings.TrimSpace(starts.Text()),
+				Ends:         strings.TrimSpace(ends.Text()),
+			}
+			if _, err := state.AddTerm(t); err != nil {
+				state.NotifyError("Unable to add term", err)
+			} else {
+				notifyInfo(state, "Term added.")
+				name.SetText("")
+				starts.SetText("")
+				ends.SetText("")
+			}
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// PromotionWizard defines a screen layout for promoting the students of a
+// school year. The target school year is chosen first, then a preview of
+// what happens with every class is shown before anything is changed.
+func PromotionWizard(th *material.Theme, state *state.State, from storage.SchoolYearEntry) Screen {
+	var (
+		close   widget.Clickable
+		next    widget.Clickable
+		create  widget.Clickable
+		promote widget.Clickable
+		target  widget.Enum // Value is the school year ID.
+
+		preview  bool // Whether the preview of the chosen target is shown.
+		later    []storage.SchoolYearEntry
+		missing  storage.SchoolYearEntry // The year following from, if it does not exist.
+		steps    []promotionStep
+		revision = -1 // State revision the school years were loaded at.
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	targetsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(later), func(gtx layout.Context, index int) layout.Dimensions {
+			y := later[index]
+			return material.RadioButton(th, &target, strconv.Itoa(y.ID), y.Name).Layout(gtx)
+		})
+	}
+	stepsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(steps), func(gtx layout.Context, index int) layout.Dimensions {
+			s := steps[index]
+			line := fmt.Sprintf("%s.%s → graduates, %d students", s.Class.Year, s.Class.Modifier, s.Students)
+			if s.Next != "" {
+				how := "new"
+				if s.Exists {
+					how = "existing"
+				}
+				line = fmt.Sprintf("%s.%s → %s.%s (%s), %d students", s.Class.Year, s.Class.Modifier, s.Next, s.Class.Modifier, how, s.Students)
+			}
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(material.Body1(th, line).Layout)),
+			)
+		})
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		children := []layout.FlexChild{
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+		}
+		if preview {
+			matPromoteBut := material.Button(th, &promote, "Promote")
+			matPromoteBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+			matPromoteBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+			children = append(children, layout.Rigid(rowInset(matPromoteBut.Layout)))
+		} else {
+			if missing.Name != "" {
+				matCreateBut := material.Button(th, &create, "Create "+missing.Name)
+				matCreateBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+				matCreateBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+				children = append(children, layout.Rigid(rowInset(matCreateBut.Layout)))
+			}
+			matNextBut := material.Button(th, &next, "Next")
+			matNextBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+			matNextBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+			children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if target.Value == "" {
+					gtx = gtx.Disabled()
+				}
+				return rowInset(matNextBut.Layout)(gtx)
+			}))
+		}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx, children...)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			schoolYears, err := state.SchoolYears()
+			if err != nil {
+				state.NotifyError("Unable to load school years", err)
+			}
+			later = later[:0]
+			missing = nextSchoolYear(from)
+			for _, y := range schoolYears {
+				if y.Starts > from.Starts {
+					later = append(later, y)
+				}
+				if y.Name == missing.Name {
+					missing = storage.SchoolYearEntry{}
+				}
+			}
+		}
+		title := fmt.Sprintf("Promote %s to", from.Name)
+		body := targetsLayout
+		if preview {
+			body = stepsLayout
+		}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, title).Layout)),
+			layout.Flexed(1, rowInset(body)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		if create.Clicked() {
+			if id, err := state.AddSchoolYear(missing); err != nil {
+				state.NotifyError("Unable to add school year", err)
+			} else {
+				target.Value = strconv.Itoa(id)
+			}
+		}
+		if next.Clicked() {
+			toYearID, _ := strconv.Atoi(target.Value)
+			var err error
+			if steps, err = state.PromotionPlan(from.ID, toYearID); err != nil {
+				state.NotifyError("Unable to preview promotion", err)
+			} else {
+				preview = true
+			}
+		}
+		if promote.Clicked() {
+			toYearID, _ := strconv.Atoi(target.Value)
+			if _, err := state.Promote(from.ID, toYearID); err != nil {
+				state.NotifyError("Unable to promote students", err)
+				return d
+			}
+			notifyInfo(state, "Students promoted.")
+			nav.Back()
+		}
+		if close.Clicked() {
+			if preview {
+				preview = false
+			} else {
+				nav.Back()
+			}
+		}
+		return d
+	}
+}
+
+// ListPromotion defines a screen layout listing the promotions done so far.
+// The latest one can be undone.
+func ListPromotion(th *material.Theme, state *state.State) Screen {
+	var (
+		close      widget.Clickable
+		undo       widget.Clickable
+		promotions []storage.PromotionEntry
+		revision   = -1 // State revision the promotions were loaded at.
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	promotionsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(promotions), func(gtx layout.Context, index int) layout.Dimensions {
+			p := promotions[index]
+			line := fmt.Sprintf("%s → %s, %s, %d promoted, %d graduated", p.FromYear, p.ToYear, p.PromotedAt, p.Promoted, p.Graduated)
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					children := []layout.FlexChild{
+						layout.Flexed(1, rowInset(material.Body1(th, line).Layout)),
+					}
+					if index == len(promotions)-1 {
+						matUndoBut := material.Button(th, &undo, "Undo")
+						matUndoBut.Background = color.NRGBA{A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e}
+						matUndoBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+						children = append(children, layout.Rigid(rowInset(matUndoBut.Layout)))
+					}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx, children...)
+				})),
+			)
+		})
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			var err error
+			if promotions, err = state.Promotions(); err != nil {
+				state.NotifyError("Unable to load promotions", err)
+			}
+		}
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Flexed(1, rowInset(promotionsLayout)),
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+		)
+		if undo.Clicked() && len(promotions) > 0 {
+			if err := state.UndoPromotion(promotions[len(promotions)-1].ID); err != nil {
+				state.NotifyError("Unable to undo promotion", err)
+			} else {
+				notifyInfo(state, "Promotion undone.")
+			}
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// ListGraduate defines a screen layout listing the students who finished
+// school.
+func ListGraduate(th *material.Theme, state *state.State) Screen {
+	var (
+		close     widget.Clickable
+		graduates []storage.GraduateEntry
+		revision  = -1 // State revision the graduates were loaded at.
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	graduatesLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(graduates), func(gtx layout.Context, index int) layout.Dimensions {
+			g := graduates[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(material.Body1(th, fmt.Sprintf("%s %s.%s %s %s", g.SchoolYear, g.Year, g.Modifier, g.Surname, g.Name)).Layout)),
+			)
+		})
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			var err error
+			if graduates, err = state.Graduates(); err != nil {
+				state.NotifyError("Unable to load graduates", err)
+			}
+		}
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%s %s %s", "School year", "Class", "Student")).Layout)),
+			layout.Flexed(1, rowInset(graduatesLayout)),
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+		)
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// promotionStep names state.PromotionStep for the screens, whose state
+// parameter shadows the package.
+type promotionStep = state.PromotionStep
+
+// nextSchoolYear returns the school year following y. It lives outside the
+// screens because their state parameter shadows the package.
+func nextSchoolYear(y storage.SchoolYearEntry) storage.SchoolYearEntry {
+	return state.NextSchoolYear(y)
+}
@@ -5,8 +5,9 @@ import (
 	"gioui.org/unit"
 )
 
-// Screen defines the current layout.
-type Screen func(gtx layout.Context) (Screen, layout.Dimensions)
+// Screen defines the current layout. It navigates to other screens through
+// the router, e.g. nav.Push(AddStudent(th, state)) or nav.Back().
+type Screen func(gtx layout.Context, nav *Router) layout.Dimensions
 
 var (
 	s      = unit.Dp(5)
@@ -0,0 +1,84 @@
+package screen
+
+import (
+	"eklase/storage"
+
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// column is a header of a list. Clicking it sorts the list by its key, or
+// reverses the order if the list is sorted by it already. Columns with an
+// empty key cannot be sorted by.
+type column struct {
+	label string
+	key   storage.SortKey
+	click widget.Clickable
+}
+
+// sortClicks applies the clicks on the headers to q. Call it before loading
+// the list, so that it is sorted in the same frame.
+func sortClicks(columns []column, q *storage.Query) {
+	for i := range columns {
+		c := &columns[i]
+		if !c.click.Clicked() || c.key == "" {
+			continue
+		}
+		if sortKey(*q) == c.key {
+			q.Desc = !q.Desc
+		} else {
+			q.Sort, q.Desc = c.key, false
+		}
+	}
+}
+
+// sortKey returns what q sorts by.
+func sortKey(q storage.Query) storage.SortKey {
+	if q.Sort == "" {
+		return storage.SortID
+	}
+	return q.Sort
+}
+
+// headerLayout lays out the headers of a list sorted by q, marking the sorted
+// column with an arrow.
+func headerLayout(th *material.Theme, columns []column, q storage.Query) layout.Widget {
+	return func(gtx layout.Context) layout.Dimensions {
+		children := make([]layout.FlexChild, 0, len(columns))
+		for i := range columns {
+			c := &columns[i]
+			label := c.label
+			if c.key != "" && c.key == sortKey(q) {
+				if q.Desc {
+					label += " ▼"
+				} else {
+					label += " ▲"
+				}
+			}
+			children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if c.key == "" {
+					return rowInset(material.Body1(th, label).Layout)(gtx)
+				}
+				return material.Clickable(gtx, &c.click, rowInset(material.Body1(th, label).Layout))
+			}))
+		}
+		return layout.Flex{}.Layout(gtx, children...)
+	}
+}
+
+// option is a choice of a filter.
+type option struct {
+	value string
+	label string
+}
+
+// filterLayout lays out the options of a filter as a row of radio buttons,
+// which scrolls if it does not fit.
+func filterLayout(th *material.Theme, list *widget.List, filter *widget.Enum, options []option) layout.Widget {
+	return func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, list).Layout(gtx, len(options), func(gtx layout.Context, index int) layout.Dimensions {
+			return material.RadioButton(th, filter, options[index].value, options[index].label).Layout(gtx)
+		})
+	}
+}
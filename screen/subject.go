@@ -0,0 +1,362 @@
+package screen
+
+import (
+	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// AddSubject defines a screen layout for adding a new subject.
+func AddSubject(th *material.Theme, state *state.State) Screen {
+	var (
+		name widget.Editor
+
+		close widget.Clickable
+		save  widget.Clickable
+	)
+	name.SingleLine = true
+	enabledIfNameOK := func(w layout.Widget) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			if strings.TrimSpace(name.Text()) == "" {
+				gtx = gtx.Disabled()
+			}
+			return w(gtx)
+		}
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, "Save")
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(enabledIfNameOK(rowInset(matSaveBut.Layout))),
+		)
+	}
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Editor(th, &name, "Subject, e.g. Mathematics").Layout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		if close.Clicked() {
+			nav.Back()
+		}
+		if save.Clicked() {
+			if _, err := state.AddSubject(strings.TrimSpace(name.Text())); err != nil {
+				state.NotifyError("Unable to add subject", err)
+				return d
+			}
+			notifyInfo(state, "Subject added.")
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// ListSubject defines a screen layout for listing and deleting subjects.
+func ListSubject(th *material.Theme, state *state.State) Screen {
+	var close widget.Clickable
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	var (
+		subjects []storage.SubjectEntry
+		remove   []widget.Clickable // Buttons of the rows in subjects.
+		revision = -1               // State revision the subjects were loaded at.
+	)
+
+	subjectsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(subjects), func(gtx layout.Context, index int) layout.Dimensions {
+			subject := subjects[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					matDeleteBut := material.Button(th, &remove[index], "Delete")
+					matDeleteBut.Background = color.NRGBA{A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e}
+					matDeleteBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Rigid(rowInset(material.Body1(th, subject.Name).Layout)),
+						layout.Rigid(rowInset(matDeleteBut.Layout)),
+					)
+				})),
+			)
+		})
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			var err error
+			if subjects, err = state.Subjects(); err != nil {
+				state.NotifyError("Unable to load subjects", err)
+			}
+			remove = make([]widget.Clickable, len(subjects))
+		}
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Flexed(0.05, rowInset(material.Body1(th, "Subject").Layout)),
+			layout.Flexed(1, rowInset(subjectsLayout)),
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+		)
+		for i := range remove {
+			if remove[i].Clicked() {
+				if err := state.DeleteSubject(subjects[i].ID); err != nil {
+					state.NotifyError("Unable to delete subject", err)
+				}
+			}
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// ListAssignment defines a screen layout for browsing teaching assignments.
+// If classID is not 0, only the assignments of that class are listed and new
+// ones can be added.
+func ListAssignment(th *material.Theme, state *state.State, classID int) Screen {
+	var (
+		close widget.Clickable
+		add   widget.Clickable
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	var (
+		assignments []storage.AssignmentEntry
+		remove      []widget.Clickable // Buttons of the rows in assignments.
+		grades      []widget.Clickable // Buttons of the rows in assignments.
+		lessons     []widget.Clickable // Buttons of the rows in assignments.
+		finals      []widget.Clickable // Buttons of the rows in assignments.
+		revision    = -1               // State revision the assignments were loaded at.
+	)
+
+	assignmentsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(assignments), func(gtx layout.Context, index int) layout.Dimensions {
+			a := assignments[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					matDeleteBut := material.Button(th, &remove[index], "Delete")
+					matDeleteBut.Background = color.NRGBA{A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e}
+					matDeleteBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matGradesBut := material.Button(th, &grades[index], "Grades")
+					matGradesBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matGradesBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matLessonsBut := material.Button(th, &lessons[index], "Lessons")
+					matLessonsBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matLessonsBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matFinalsBut := material.Button(th, &finals[index], "Finals")
+					matFinalsBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matFinalsBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%s %s.%s %s %s %s", a.SchoolYear, a.Year, a.Modifier, a.Subject, a.TeacherName, a.TeacherSurname)).Layout)),
+						layout.Rigid(rowInset(matGradesBut.Layout)),
+						layout.Rigid(rowInset(matLessonsBut.Layout)),
+						layout.Rigid(rowInset(matFinalsBut.Layout)),
+						layout.Rigid(rowInset(matDeleteBut.Layout)),
+					)
+				})),
+			)
+		})
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			var err error
+			if classID == 0 {
+				assignments, err = state.Assignments()
+			} else {
+				assignments, err = state.ClassAssignments(classID)
+			}
+			if err != nil {
+				state.NotifyError("Unable to load teaching assignments", err)
+			}
+			remove = make([]widget.Clickable, len(assignments))
+			grades = make([]widget.Clickable, len(assignments))
+			lessons = make([]widget.Clickable, len(assignments))
+			finals = make([]widget.Clickable, len(assignments))
+		}
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matAddBut := material.Button(th, &add, "Add")
+		matAddBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matAddBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Flexed(0.05, rowInset(material.Body1(th, fmt.Sprintf("%s %s %s %s", "School year", "Class", "Subject", "Teacher")).Layout)),
+			layout.Flexed(1, rowInset(assignmentsLayout)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if classID == 0 {
+					return rowInset(matCloseBut.Layout)(gtx)
+				}
+				return layout.Flex{}.Layout(gtx,
+					layout.Rigid(rowInset(matCloseBut.Layout)),
+					layout.Rigid(rowInset(matAddBut.Layout)),
+				)
+			}),
+		)
+		for i := range remove {
+			if remove[i].Clicked() {
+				if err := state.DeleteAssignment(assignments[i].ID); err != nil {
+					state.NotifyError("Unable to delete teaching assignment", err)
+				}
+			}
+			if grades[i].Clicked() {
+				nav.Push(Gradebook(th, state, assignments[i]))
+			}
+			if lessons[i].Clicked() {
+				nav.Push(LessonJournal(th, state, assignments[i]))
+			}
+			if finals[i].Clicked() {
+				nav.Push(FinalGrades(th, state, assignments[i]))
+			}
+		}
+		if add.Clicked() {
+			nav.Push(AddAssignment(th, state, classID))
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// AddAssignment defines a screen layout for choosing a teacher and a subject
+// taught to a class during its school year.
+func AddAssignment(th *material.Theme, state *state.State, classID int) Screen {
+	var (
+		teacher widget.Enum // Value is the teacher ID.
+		subject widget.Enum // Value is the subject ID.
+
+		close widget.Clickable
+		save  widget.Clickable
+	)
+	teacherList := widget.List{List: layout.List{Axis: layout.Vertical}}
+	subjectList := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	teachers, err := state.Teachers()
+	if err != nil {
+		state.NotifyError("Unable to load teachers", err)
+	}
+	subjects, err := state.Subjects()
+	if err != nil {
+		state.NotifyError("Unable to load subjects", err)
+	}
+
+	enabledIfChosen := func(w layout.Widget) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			if teacher.Value == "" || subject.Value == "" {
+				gtx = gtx.Disabled()
+			}
+			return w(gtx)
+		}
+	}
+	choicesLayout := func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{}.Layout(gtx,
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				return material.List(th, &teacherList).Layout(gtx, len(teachers), func(gtx layout.Context, index int) layout.Dimensions {
+					t := teachers[index]
+					return material.RadioButton(th, &teacher, strconv.Itoa(t.ID), t.Name+" "+t.Surname).Layout(gtx)
+				})
+			}),
+			layout.Rigid(spacer.Layout),
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				return material.List(th, &subjectList).Layout(gtx, len(subjects), func(gtx layout.Context, index int) layout.Dimensions {
+					s := subjects[index]
+					return material.RadioButton(th, &subject, strconv.Itoa(s.ID), s.Name).Layout(gtx)
+				})
+			}),
+		)
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, "Save")
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(enabledIfChosen(rowInset(matSaveBut.Layout))),
+		)
+	}
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%s %s", "Teacher", "Subject")).Layout)),
+			layout.Flexed(1, rowInset(choicesLayout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		if close.Clicked() {
+			nav.Back()
+		}
+		if save.Clicked() {
+			teacherID, _ := strconv.Atoi(teacher.Value)
+			subjectID, _ := strconv.Atoi(subject.Value)
+			if _, err := state.AddAssignment(teacherID, subjectID, classID); err != nil {
+				state.NotifyError("Unable to add teaching assignment", err)
+				return d
+			}
+			notifyInfo(state, "Teaching assignment added.")
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// currentSchoolYear returns the school year of today. It lives outside the
+// screens because their state parameter shadows the package.
+func currentSchoolYear() string {
+	return state.SchoolYearOf(time.Now())
+}
+
+// currentSchoolYearStart returns the first day of the school year of today.
+func currentSchoolYearStart() time.Time {
+	return state.SchoolYearStart(time.Now())
+}
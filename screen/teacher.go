@@ -0,0 +1,291 @@
+package screen
+
+import (
+	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// AddTeacher defines a screen layout for adding a new teacher.
+func AddTeacher(th *material.Theme, state *state.State) Screen {
+	return teacherForm(th, state, 0)
+}
+
+// EditTeacher defines a screen layout for renaming or deleting a teacher.
+func EditTeacher(th *material.Theme, state *state.State, id int) Screen {
+	return teacherForm(th, state, id)
+}
+
+// teacherForm edits the teacher with the given ID, or adds a new one if the
+// ID is 0.
+func teacherForm(th *material.Theme, state *state.State, id int) Screen {
+	var (
+		name    widget.Editor
+		surname widget.Editor
+
+		close  widget.Clickable
+		save   widget.Clickable
+		remove widget.Clickable
+	)
+	name.SingleLine, surname.SingleLine = true, true
+	if id != 0 {
+		teacher, err := state.Teacher(id)
+		if err != nil {
+			state.NotifyError("Unable to load teacher", err)
+		}
+		name.SetText(teacher.Name)
+		surname.SetText(teacher.Surname)
+	}
+
+	enabledIfNameOK := func(w layout.Widget) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			name := strings.TrimSpace(name.Text())
+			surname := strings.TrimSpace(surname.Text())
+			if name == "" || surname == "" {
+				gtx = gtx.Disabled()
+			}
+			return w(gtx)
+		}
+	}
+	editsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+			layout.Flexed(1, material.Editor(th, &name, "First name").Layout),
+			layout.Rigid(spacer.Layout),
+			layout.Flexed(1, material.Editor(th, &surname, "Last name").Layout),
+		)
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, "Save")
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		children := []layout.FlexChild{
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+		}
+		if id != 0 {
+			matDeleteBut := material.Button(th, &remove, "Delete")
+			matDeleteBut.Background = color.NRGBA{A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e}
+			matDeleteBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+			children = append(children,
+				layout.Rigid(rowInset(matDeleteBut.Layout)),
+				layout.Rigid(spacer.Layout),
+			)
+		}
+		children = append(children, layout.Rigid(enabledIfNameOK(rowInset(matSaveBut.Layout))))
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx, children...)
+	}
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(editsRowLayout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		if close.Clicked() {
+			nav.Back()
+		}
+		if remove.Clicked() {
+			if err := state.DeleteTeacher(id); err != nil {
+				state.NotifyError("Unable to delete teacher", err)
+				return d
+			}
+			notifyInfo(state, "Teacher deleted.")
+			nav.Back()
+		}
+		if save.Clicked() {
+			var err error
+			if id == 0 {
+				_, err = state.AddTeacher(strings.TrimSpace(name.Text()), strings.TrimSpace(surname.Text()))
+			} else {
+				err = state.UpdateTeacher(id, strings.TrimSpace(name.Text()), strings.TrimSpace(surname.Text()))
+			}
+			if err != nil {
+				state.NotifyError("Unable to save teacher", err)
+				return d
+			}
+			notifyInfo(state, "Teacher saved.")
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// ListTeacher defines a screen layout for listing existing teachers.
+func ListTeacher(th *material.Theme, state *state.State) Screen {
+	var close widget.Clickable
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	var (
+		teachers  []storage.TeacherEntry
+		edit      []widget.Clickable // Buttons of the rows in teachers.
+		timetable []widget.Clickable // Buttons of the rows in teachers.
+		revision  = -1               // State revision the teachers were loaded at.
+	)
+
+	teachersLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(teachers), func(gtx layout.Context, index int) layout.Dimensions {
+			teacher := teachers[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					matEditBut := material.Button(th, &edit[index], "Edit")
+					matEditBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matEditBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matTimetableBut := material.Button(th, &timetable[index], "Timetable")
+					matTimetableBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matTimetableBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%v %s %s", teacher.ID, teacher.Surname, teacher.Name)).Layout)),
+						layout.Rigid(rowInset(matEditBut.Layout)),
+						layout.Rigid(rowInset(matTimetableBut.Layout)),
+					)
+				})),
+			)
+		})
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			var err error
+			if teachers, err = state.Teachers(); err != nil {
+				state.NotifyError("Unable to load teachers", err)
+			}
+			edit = make([]widget.Clickable, len(teachers))
+			timetable = make([]widget.Clickable, len(teachers))
+		}
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Flexed(0.05, rowInset(material.Body1(th, fmt.Sprintf("%s %s %s", "ID", "Surname", "Name")).Layout)),
+			layout.Flexed(1, rowInset(teachersLayout)),
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+		)
+		for i := range edit {
+			if edit[i].Clicked() {
+				nav.Push(EditTeacher(th, state, teachers[i].ID))
+			}
+			if timetable[i].Clicked() {
+				nav.Push(Timetable(th, state, 0, teachers[i].ID))
+			}
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// AssignTeacherToClass defines a screen layout for choosing the homeroom
+// teacher of a class.
+func AssignTeacherToClass(th *material.Theme, state *state.State, classID int) Screen {
+	var (
+		close widget.Clickable
+		none  widget.Clickable
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	teachers, err := state.Teachers()
+	if err != nil {
+		state.NotifyError("Unable to load teachers", err)
+	}
+
+	assign := make([]widget.Clickable, len(teachers))
+
+	teachersLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(teachers), func(gtx layout.Context, index int) layout.Dimensions {
+			teacher := teachers[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					matAssignBut := material.Button(th, &assign[index], "Assign")
+					matAssignBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matAssignBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%s %s", teacher.Name, teacher.Surname)).Layout)),
+						layout.Rigid(rowInset(matAssignBut.Layout)),
+					)
+				})),
+			)
+		})
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matNoneBut := material.Button(th, &none, "No teacher")
+		matNoneBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matNoneBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Flexed(0.05, rowInset(material.Body1(th, "Homeroom teacher").Layout)),
+			layout.Flexed(1, rowInset(teachersLayout)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{}.Layout(gtx,
+					layout.Rigid(rowInset(matCloseBut.Layout)),
+					layout.Rigid(rowInset(matNoneBut.Layout)),
+				)
+			}),
+		)
+		if close.Clicked() {
+			nav.Back()
+		}
+		if none.Clicked() {
+			if err := state.ClearClassTeacher(classID); err != nil {
+				state.NotifyError("Unable to clear teacher", err)
+				return d
+			}
+			nav.Back()
+		}
+		for i := range assign {
+			if assign[i].Clicked() {
+				if err := state.SetClassTeacher(classID, teachers[i].ID); err != nil {
+					state.NotifyError("Unable to assign teacher", err)
+					return d
+				}
+				nav.Back()
+			}
+		}
+		return d
+	}
+}
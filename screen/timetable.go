@@ -0,0 +1,432 @@
+package screen
+
+import (
+	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// Timetable defines a week grid screen layout with the lessons of a class, or
+// of a teacher if classID is 0. Lessons can be added to and removed from the
+// timetable of a class.
+func Timetable(th *material.Theme, state *state.State, classID, teacherID int) Screen {
+	var (
+		close  widget.Clickable
+		add    widget.Clickable
+		remove widget.Clickable
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+	selectedColor := color.NRGBA{A: 0xff, R: 0xf5, G: 0xe0, B: 0x8a}
+
+	var (
+		title    string
+		bells    map[int]storage.BellEntry
+		slots    map[[2]int]storage.SlotEntry // By weekday and lesson.
+		lessons  int                          // Number of rows.
+		cells    map[int]*widget.Clickable    // By slot ID.
+		selected int                          // ID of the selected slot.
+		revision = -1                         // State revision the timetable was loaded at.
+	)
+	if classID != 0 {
+		if class, err := state.Class(classID); err == nil {
+			title = fmt.Sprintf("%s.%s", class.Year, class.Modifier)
+		}
+	} else if teacher, err := state.Teacher(teacherID); err == nil {
+		title = teacher.Name + " " + teacher.Surname
+	}
+
+	load := func() {
+		all, err := state.Bells()
+		if err != nil {
+			state.NotifyError("Unable to load the bell schedule", err)
+		}
+		lessons = dayLessons
+		bells = make(map[int]storage.BellEntry)
+		for _, b := range all {
+			bells[b.Lesson] = b
+			if b.Lesson > lessons {
+				lessons = b.Lesson
+			}
+		}
+		var entries []storage.SlotEntry
+		if classID != 0 {
+			entries, err = state.ClassTimetable(classID)
+		} else {
+			entries, err = state.TeacherTimetable(teacherID)
+		}
+		if err != nil {
+			state.NotifyError("Unable to load the timetable", err)
+		}
+		slots = make(map[[2]int]storage.SlotEntry)
+		cells = make(map[int]*widget.Clickable)
+		found := false
+		for _, s := range entries {
+			slots[[2]int{s.Weekday, s.Lesson}] = s
+			cells[s.ID] = new(widget.Clickable)
+			if s.Lesson > lessons {
+				lessons = s.Lesson
+			}
+			found = found || s.ID == selected
+		}
+		if !found {
+			selected = 0
+		}
+	}
+
+	cellText := func(s storage.SlotEntry) string {
+		var lines []string
+		if classID != 0 {
+			lines = append(lines, s.Subject, s.TeacherSurname)
+		} else {
+			lines = append(lines, s.Year+"."+s.Modifier+" "+s.Subject)
+		}
+		if s.Room != "" {
+			lines = append(lines, s.Room)
+		}
+		return strings.Join(lines, "\n")
+	}
+	rowLayout := func(gtx layout.Context, lesson int) layout.Dimensions {
+		label := strconv.Itoa(lesson)
+		if b, ok := bells[lesson]; ok {
+			label += "\n" + b.Starts + "-" + b.Ends
+		}
+		children := []layout.FlexChild{
+			layout.Flexed(0.6, rowInset(material.Body1(th, label).Layout)),
+		}
+		for day := int(time.Monday); day <= storage.SchoolDays; day++ {
+			s, ok := slots[[2]int{day, lesson}]
+			children = append(children, layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				if !ok {
+					return layout.Dimensions{Size: image.Pt(gtx.Constraints.Min.X, 0)}
+				}
+				w := rowInset(material.Body2(th, cellText(s)).Layout)
+				if classID == 0 {
+					return w(gtx)
+				}
+				return cells[s.ID].Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					if s.ID != selected {
+						return w(gtx)
+					}
+					return layout.Stack{}.Layout(gtx,
+						layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+							paint.FillShape(gtx.Ops, selectedColor, clip.Rect{Max: gtx.Constraints.Min}.Op())
+							return layout.Dimensions{Size: gtx.Constraints.Min}
+						}),
+						layout.Stacked(w),
+					)
+				})
+			}))
+		}
+		return layout.Flex{}.Layout(gtx, children...)
+	}
+	gridLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, lessons, func(gtx layout.Context, index int) layout.Dimensions {
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+					return rowLayout(gtx, index+1)
+				}),
+			)
+		})
+	}
+	headerLayout := func(gtx layout.Context) layout.Dimensions {
+		children := []layout.FlexChild{
+			layout.Flexed(0.6, rowInset(material.Body1(th, "Lesson").Layout)),
+		}
+		for day := int(time.Monday); day <= storage.SchoolDays; day++ {
+			children = append(children, layout.Flexed(1, rowInset(material.Body1(th, time.Weekday(day).String()).Layout)))
+		}
+		return layout.Flex{}.Layout(gtx, children...)
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		if classID == 0 {
+			return rowInset(matCloseBut.Layout)(gtx)
+		}
+		matAddBut := material.Button(th, &add, "Add lesson")
+		matAddBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matAddBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matRemoveBut := material.Button(th, &remove, "Delete lesson")
+		matRemoveBut.Background = color.NRGBA{A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e}
+		matRemoveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(rowInset(matAddBut.Layout)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if selected == 0 {
+					gtx = gtx.Disabled()
+				}
+				return rowInset(matRemoveBut.Layout)(gtx)
+			}),
+		)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			load()
+		}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, title).Layout)),
+			layout.Rigid(headerLayout),
+			layout.Flexed(1, rowInset(gridLayout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		for id, c := range cells {
+			if c.Clicked() {
+				selected = id
+			}
+		}
+		if add.Clicked() {
+			nav.Push(AddSlot(th, state, classID))
+		}
+		if remove.Clicked() && selected != 0 {
+			if err := state.DeleteSlot(selected); err != nil {
+				state.NotifyError("Unable to delete lesson", err)
+			}
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// AddSlot defines a screen layout for adding a weekly lesson to the timetable
+// of a class. The subject and the teacher are chosen among the teaching
+// assignments of the class.
+func AddSlot(th *material.Theme, state *state.State, classID int) Screen {
+	var (
+		day        widget.Enum // Value is the weekday number.
+		lesson     widget.Enum // Value is the lesson number.
+		assignment widget.Enum // Value is the index in assignments.
+		room       widget.Editor
+
+		close widget.Clickable
+		save  widget.Clickable
+	)
+	assignmentList := widget.List{List: layout.List{Axis: layout.Vertical}}
+	room.SingleLine = true
+
+	// Each subject and teacher pair is offered once, even if it is assigned
+	// in several school years.
+	var assignments []storage.AssignmentEntry
+	all, err := state.ClassAssignments(classID)
+	if err != nil {
+		state.NotifyError("Unable to load teaching assignments", err)
+	}
+	seen := make(map[[2]int]bool)
+	for _, a := range all {
+		if k := [2]int{a.SubjectID, a.TeacherID}; !seen[k] {
+			seen[k] = true
+			assignments = append(assignments, a)
+		}
+	}
+
+	enabledIfChosen := func(w layout.Widget) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			if day.Value == "" || lesson.Value == "" || assignment.Value == "" {
+				gtx = gtx.Disabled()
+			}
+			return w(gtx)
+		}
+	}
+	daysLayout := func(gtx layout.Context) layout.Dimensions {
+		var children []layout.FlexChild
+		for d := int(time.Monday); d <= storage.SchoolDays; d++ {
+			children = append(children, layout.Rigid(material.RadioButton(th, &day, strconv.Itoa(d), time.Weekday(d).String()).Layout))
+		}
+		return layout.Flex{}.Layout(gtx, children...)
+	}
+	lessonsLayout := func(gtx layout.Context) layout.Dimensions {
+		var children []layout.FlexChild
+		for l := 1; l <= storage.MaxLessons; l++ {
+			children = append(children, layout.Rigid(material.RadioButton(th, &lesson, strconv.Itoa(l), strconv.Itoa(l)).Layout))
+		}
+		return layout.Flex{}.Layout(gtx, children...)
+	}
+	assignmentsLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &assignmentList).Layout(gtx, len(assignments), func(gtx layout.Context, index int) layout.Dimensions {
+			a := assignments[index]
+			return material.RadioButton(th, &assignment, strconv.Itoa(index), a.Subject+", "+a.TeacherName+" "+a.TeacherSurname).Layout(gtx)
+		})
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, "Save")
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(enabledIfChosen(rowInset(matSaveBut.Layout))),
+		)
+	}
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(daysLayout)),
+			layout.Rigid(rowInset(lessonsLayout)),
+			layout.Flexed(1, rowInset(assignmentsLayout)),
+			layout.Rigid(rowInset(material.Editor(th, &room, "Room, e.g. 101").Layout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		if close.Clicked() {
+			nav.Back()
+		}
+		if save.Clicked() {
+			weekday, _ := strconv.Atoi(day.Value)
+			number, _ := strconv.Atoi(lesson.Value)
+			index, _ := strconv.Atoi(assignment.Value)
+			a := assignments[index]
+			slot := storage.SlotEntry{
+				ClassID:   classID,
+				Weekday:   weekday,
+				Lesson:    number,
+				SubjectID: a.SubjectID,
+				TeacherID: a.TeacherID,
+				Room:      strings.TrimSpace(room.Text()),
+			}
+			if _, err := state.AddSlot(slot); err != nil {
+				state.NotifyError("Unable to add lesson", err)
+				return d
+			}
+			notifyInfo(state, "Lesson added.")
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// BellSchedule defines a screen layout for editing the start and end times of
+// the lessons. Clearing both times of a lesson removes it.
+func BellSchedule(th *material.Theme, state *state.State) Screen {
+	var (
+		close widget.Clickable
+		save  widget.Clickable
+
+		starts [storage.MaxLessons]widget.Editor
+		ends   [storage.MaxLessons]widget.Editor
+		saved  = make(map[int]storage.BellEntry) // By lesson.
+	)
+	bells, err := state.Bells()
+	if err != nil {
+		state.NotifyError("Unable to load the bell schedule", err)
+	}
+	for i := range starts {
+		starts[i].SingleLine, ends[i].SingleLine = true, true
+	}
+	for _, b := range bells {
+		saved[b.Lesson] = b
+		starts[b.Lesson-1].SetText(b.Starts)
+		ends[b.Lesson-1].SetText(b.Ends)
+	}
+
+	rowLayout := func(gtx layout.Context, i int) layout.Dimensions {
+		return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+			layout.Flexed(0.3, rowInset(material.Body1(th, strconv.Itoa(i+1)).Layout)),
+			layout.Flexed(1, rowInset(material.Editor(th, &starts[i], "Starts, e.g. 08:30").Layout)),
+			layout.Flexed(1, rowInset(material.Editor(th, &ends[i], "Ends, e.g. 09:10").Layout)),
+		)
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, "Save")
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(rowInset(matSaveBut.Layout)),
+		)
+	}
+
+	// apply stores the changed lessons. Removed lessons go first and the
+	// others in order, so that moving the whole schedule does not trip the
+	// overlap check on the way.
+	apply := func() error {
+		for i := range starts {
+			b := storage.BellEntry{Lesson: i + 1, Starts: strings.TrimSpace(starts[i].Text()), Ends: strings.TrimSpace(ends[i].Text())}
+			if _, ok := saved[b.Lesson]; ok && b.Starts == "" && b.Ends == "" {
+				if err := state.DeleteBell(b.Lesson); err != nil {
+					return err
+				}
+				delete(saved, b.Lesson)
+			}
+		}
+		for i := range starts {
+			b := storage.BellEntry{Lesson: i + 1, Starts: strings.TrimSpace(starts[i].Text()), Ends: strings.TrimSpace(ends[i].Text())}
+			if b == saved[b.Lesson] || b.Starts == "" && b.Ends == "" {
+				continue
+			}
+			if err := state.SetBell(b); err != nil {
+				return err
+			}
+			saved[b.Lesson] = b
+		}
+		return nil
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		children := []layout.FlexChild{
+			layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%s %s %s", "Lesson", "Starts", "Ends")).Layout)),
+		}
+		for i := range starts {
+			i := i
+			children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return rowLayout(gtx, i)
+			}))
+		}
+		children = append(children,
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			}),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+		if save.Clicked() {
+			if err := apply(); err != nil {
+				state.NotifyError("Unable to save the bell schedule", err)
+				return d
+			}
+			notifyInfo(state, "Bell schedule saved.")
+			nav.Back()
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
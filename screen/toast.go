@@ -0,0 +1,108 @@
+package screen
+
+import (
+	"eklase/state"
+	"image"
+	"image/color"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// toastTimeout is how long a notification stays on screen, by severity.
+// Errors stay longer so there is time to read them.
+var toastTimeout = map[state.Severity]time.Duration{
+	state.Info:    3 * time.Second,
+	state.Warning: 6 * time.Second,
+	state.Error:   10 * time.Second,
+}
+
+// toastColor is the background of a notification, by severity.
+var toastColor = map[state.Severity]color.NRGBA{
+	state.Info:    {A: 0xff, R: 0x5e, G: 0x9c, B: 0x64},
+	state.Warning: {A: 0xff, R: 0xc9, G: 0x8b, B: 0x2a},
+	state.Error:   {A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e},
+}
+
+// Toasts draws the notifications queued in the state, newest at the bottom.
+// They disappear after a timeout or when dismissed. Lay it out on top of the
+// current screen.
+type Toasts struct {
+	th    *material.Theme
+	state *state.State
+
+	dismiss map[int]*widget.Clickable // Close buttons by notification ID.
+}
+
+// NewToasts returns a notification layer for the given state.
+func NewToasts(th *material.Theme, state *state.State) *Toasts {
+	return &Toasts{th: th, state: state, dismiss: make(map[int]*widget.Clickable)}
+}
+
+// Layout draws the notifications and expires the old ones.
+func (t *Toasts) Layout(gtx layout.Context) layout.Dimensions {
+	var next time.Time // Earliest expiry among the visible notifications.
+	var children []layout.FlexChild
+	visible := make(map[int]bool)
+	for _, n := range t.state.Notifications() {
+		expiry := n.Posted.Add(toastTimeout[n.Severity])
+		dismiss := t.dismiss[n.ID]
+		if dismiss == nil {
+			dismiss = new(widget.Clickable)
+			t.dismiss[n.ID] = dismiss
+		}
+		if dismiss.Clicked() || !gtx.Now.Before(expiry) {
+			t.state.Dismiss(n.ID)
+			continue
+		}
+		if next.IsZero() || expiry.Before(next) {
+			next = expiry
+		}
+		visible[n.ID] = true
+		children = append(children, layout.Rigid(rowInset(t.toast(n, dismiss))))
+	}
+	// Forget the buttons of notifications that are gone.
+	for id := range t.dismiss {
+		if !visible[id] {
+			delete(t.dismiss, id)
+		}
+	}
+	if !next.IsZero() {
+		op.InvalidateOp{At: next}.Add(gtx.Ops)
+	}
+	gtx.Constraints.Min = image.Point{}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// toast lays out a single notification with its close button.
+func (t *Toasts) toast(n state.Notification, dismiss *widget.Clickable) layout.Widget {
+	return func(gtx layout.Context) layout.Dimensions {
+		return layout.Stack{}.Layout(gtx,
+			layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+				paint.FillShape(gtx.Ops, toastColor[n.Severity], clip.Rect{Max: gtx.Constraints.Min}.Op())
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			}),
+			layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+				msg := material.Body1(t.th, n.Message)
+				msg.Color = color.NRGBA{A: 0xff, R: 0xff, G: 0xff, B: 0xff}
+				closeBut := material.Button(t.th, dismiss, "×")
+				closeBut.Background = color.NRGBA{A: 0x33}
+				return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(rowInset(msg.Layout)),
+					layout.Rigid(rowInset(closeBut.Layout)),
+				)
+			}),
+		)
+	}
+}
+
+// notifyInfo posts a confirmation of a successful action. Screens name their
+// state parameter after the package, hence the helper.
+func notifyInfo(s *state.State, message string) {
+	s.Notify(state.Info, message)
+}
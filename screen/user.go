@@ -0,0 +1,321 @@
+package screen
+
+import (
+	"eklase/state"
+	"eklase/storage"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// Login defines the screen layout shown before the main menu, which asks for
+// a username and a password. Until any user exists, it creates the first
+// admin instead.
+func Login(th *material.Theme, state *state.State) Screen {
+	var (
+		username widget.Editor
+		password widget.Editor
+		login    widget.Clickable
+		quit     widget.Clickable
+	)
+	username.SingleLine = true
+	username.Submit = true
+	password.SingleLine = true
+	password.Submit = true
+	password.Mask = '•'
+
+	setup := false // Whether the first admin is created.
+	if ok, err := state.HasUsers(); err != nil {
+		state.NotifyError("Unable to load users", err)
+	} else {
+		setup = !ok
+	}
+
+	enabledIfFilled := func(w layout.Widget) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			if strings.TrimSpace(username.Text()) == "" || password.Text() == "" {
+				gtx = gtx.Disabled()
+			}
+			return w(gtx)
+		}
+	}
+	submitted := func(e *widget.Editor) bool {
+		for _, ev := range e.Events() {
+			if _, ok := ev.(widget.SubmitEvent); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		title, action := "Log in", "Log in"
+		if setup {
+			title, action = "Create the first admin, who can add the other users", "Create admin"
+		}
+		matLoginBut := material.Button(th, &login, action)
+		matLoginBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matLoginBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matQuitBut := material.Button(th, &quit, "Quit")
+		matQuitBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x44}
+		matQuitBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Body1(th, title).Layout)),
+			layout.Rigid(rowInset(material.Editor(th, &username, "Username").Layout)),
+			layout.Rigid(rowInset(material.Editor(th, &password, "Password").Layout)),
+			layout.Rigid(rowInset(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+					layout.Rigid(rowInset(matQuitBut.Layout)),
+					layout.Rigid(spacer.Layout),
+					layout.Rigid(enabledIfFilled(rowInset(matLoginBut.Layout))),
+				)
+			})),
+		)
+		// Enter in either editor logs in like the button.
+		submit := login.Clicked()
+		if submitted(&username) || submitted(&password) {
+			submit = strings.TrimSpace(username.Text()) != "" && password.Text() != ""
+		}
+		if submit {
+			name := strings.TrimSpace(username.Text())
+			var err error
+			if setup {
+				err = state.Setup(name, password.Text())
+			} else {
+				err = state.Login(name, password.Text())
+			}
+			password.SetText("")
+			if err != nil {
+				state.NotifyError("Unable to log in", err)
+				return d
+			}
+			nav.Replace(MainMenu(th, state))
+		}
+		if quit.Clicked() {
+			state.Quit()
+		}
+		return d
+	}
+}
+
+// ListUser defines a screen layout for listing the users who can log in.
+func ListUser(th *material.Theme, state *state.State) Screen {
+	var (
+		close widget.Clickable
+		add   widget.Clickable
+	)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	th.ContrastBg = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+	lightContrast := th.ContrastBg
+	lightContrast.A = 0x33
+	darkContrast := th.ContrastBg
+	darkContrast.A = 0x55
+
+	var (
+		users    []storage.UserEntry
+		edit     []widget.Clickable // Buttons of the rows in users.
+		remove   []widget.Clickable // Buttons of the rows in users.
+		revision = -1               // State revision the users were loaded at.
+	)
+
+	usersLayout := func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &list).Layout(gtx, len(users), func(gtx layout.Context, index int) layout.Dimensions {
+			user := users[index]
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					color := lightContrast
+					if index%2 == 0 {
+						color = darkContrast
+					}
+					max := image.Pt(gtx.Constraints.Max.X, gtx.Constraints.Min.Y)
+					paint.FillShape(gtx.Ops, color, clip.Rect{Max: max}.Op())
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(rowInset(func(gtx layout.Context) layout.Dimensions {
+					matEditBut := material.Button(th, &edit[index], "Edit")
+					matEditBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+					matEditBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					matDeleteBut := material.Button(th, &remove[index], "Delete")
+					matDeleteBut.Background = color.NRGBA{A: 0xff, R: 0xb0, G: 0x3a, B: 0x2e}
+					matDeleteBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Rigid(rowInset(material.Body1(th, fmt.Sprintf("%v %s %s", user.ID, user.Username, user.Role)).Layout)),
+						layout.Rigid(rowInset(matEditBut.Layout)),
+						layout.Rigid(rowInset(matDeleteBut.Layout)),
+					)
+				})),
+			)
+		})
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		if r := state.Revision(); r != revision {
+			revision = r
+			var err error
+			if users, err = state.Users(); err != nil {
+				state.NotifyError("Unable to load users", err)
+			}
+			edit = make([]widget.Clickable, len(users))
+			remove = make([]widget.Clickable, len(users))
+		}
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matAddBut := material.Button(th, &add, "Add user")
+		matAddBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matAddBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Flexed(0.05, rowInset(material.Body1(th, fmt.Sprintf("%s %s %s", "ID", "Username", "Role")).Layout)),
+			layout.Flexed(1, rowInset(usersLayout)),
+			layout.Rigid(rowInset(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+					layout.Rigid(rowInset(matCloseBut.Layout)),
+					layout.Rigid(spacer.Layout),
+					layout.Rigid(rowInset(matAddBut.Layout)),
+				)
+			})),
+		)
+		for i := range edit {
+			if edit[i].Clicked() {
+				nav.Push(EditUser(th, state, users[i].ID))
+			}
+			if remove[i].Clicked() {
+				if err := state.DeleteUser(users[i].ID); err != nil {
+					state.NotifyError("Unable to delete user", err)
+				} else {
+					notifyInfo(state, "User deleted.")
+				}
+			}
+		}
+		if add.Clicked() {
+			nav.Push(EditUser(th, state, 0))
+		}
+		if close.Clicked() {
+			nav.Back()
+		}
+		return d
+	}
+}
+
+// EditUser defines a screen layout for adding a user, if id is 0, or for
+// changing one. Teachers are linked to their teacher entry.
+func EditUser(th *material.Theme, state *state.State, id int) Screen {
+	var (
+		username widget.Editor
+		password widget.Editor
+		role     widget.Enum
+		teacher  widget.Enum // Teacher ID as a string.
+		close    widget.Clickable
+		save     widget.Clickable
+	)
+	username.SingleLine = true
+	password.SingleLine = true
+	password.Mask = '•'
+	role.Value = string(storage.RoleTeacher)
+	list := widget.List{List: layout.List{Axis: layout.Vertical}}
+
+	teachers, err := state.Teachers()
+	if err != nil {
+		state.NotifyError("Unable to load teachers", err)
+	}
+	passwordHint := "Password"
+	if id != 0 {
+		passwordHint = "New password, empty to keep the current one"
+		users, err := state.Users()
+		if err != nil {
+			state.NotifyError("Unable to load the user", err)
+		}
+		for _, u := range users {
+			if u.ID == id {
+				username.SetText(u.Username)
+				role.Value = string(u.Role)
+				if u.TeacherID.Valid {
+					teacher.Value = strconv.FormatInt(u.TeacherID.Int64, 10)
+				}
+			}
+		}
+	}
+
+	enabledIfFilled := func(w layout.Widget) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			if strings.TrimSpace(username.Text()) == "" || id == 0 && password.Text() == "" ||
+				storage.Role(role.Value).Teaches() && teacher.Value == "" {
+				gtx = gtx.Disabled()
+			}
+			return w(gtx)
+		}
+	}
+	rolesLayout := func(gtx layout.Context) layout.Dimensions {
+		children := make([]layout.FlexChild, 0, len(storage.Roles))
+		for _, r := range storage.Roles {
+			children = append(children, layout.Rigid(material.RadioButton(th, &role, string(r), strings.ReplaceAll(string(r), "_", " ")).Layout))
+		}
+		return layout.Flex{}.Layout(gtx, children...)
+	}
+	teachersLayout := func(gtx layout.Context) layout.Dimensions {
+		if !storage.Role(role.Value).Teaches() {
+			return layout.Dimensions{}
+		}
+		return material.List(th, &list).Layout(gtx, len(teachers), func(gtx layout.Context, index int) layout.Dimensions {
+			t := teachers[index]
+			return material.RadioButton(th, &teacher, strconv.Itoa(t.ID), fmt.Sprintf("%s %s", t.Surname, t.Name)).Layout(gtx)
+		})
+	}
+	buttonsRowLayout := func(gtx layout.Context) layout.Dimensions {
+		matCloseBut := material.Button(th, &close, "Back")
+		matCloseBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matCloseBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		matSaveBut := material.Button(th, &save, "Save")
+		matSaveBut.Background = color.NRGBA{A: 0xff, R: 0x5e, G: 0x9c, B: 0x64}
+		matSaveBut.Font = text.Font{Variant: "Smallcaps", Weight: text.Bold, Style: text.Italic}
+		return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceStart}.Layout(gtx,
+			layout.Rigid(rowInset(matCloseBut.Layout)),
+			layout.Rigid(spacer.Layout),
+			layout.Rigid(enabledIfFilled(rowInset(matSaveBut.Layout))),
+		)
+	}
+
+	return func(gtx layout.Context, nav *Router) layout.Dimensions {
+		d := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(rowInset(material.Editor(th, &username, "Username").Layout)),
+			layout.Rigid(rowInset(material.Editor(th, &password, passwordHint).Layout)),
+			layout.Rigid(rowInset(rolesLayout)),
+			layout.Flexed(1, rowInset(teachersLayout)),
+			layout.Rigid(rowInset(buttonsRowLayout)),
+		)
+		if close.Clicked() {
+			nav.Back()
+		}
+		if save.Clicked() {
+			teacherID := 0
+			if storage.Role(role.Value).Teaches() {
+				teacherID, _ = strconv.Atoi(teacher.Value)
+			}
+			name := strings.TrimSpace(username.Text())
+			var err error
+			if id == 0 {
+				_, err = state.AddUser(name, password.Text(), storage.Role(role.Value), teacherID)
+			} else if err = state.UpdateUser(id, name, storage.Role(role.Value), teacherID); err == nil && password.Text() != "" {
+				err = state.SetPassword(id, password.Text())
+			}
+			if err != nil {
+				state.NotifyError("Unable to save user", err)
+				return d
+			}
+			notifyInfo(state, "User saved.")
+			nav.Back()
+		}
+		return d
+	}
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"fmt"
+
+	"eklase/storage"
+)
+
+// Attendance returns the attendance entries of a class on a day. Students
+// without an entry were present.
+func (h *State) Attendance(classID int, date string) ([]storage.AttendanceEntry, error) {
+	return h.storage.Attendance(classID, date)
+}
+
+// SetAttendance records the attendance of a student at a lesson.
+func (v *State) SetAttendance(e storage.AttendanceEntry) error {
+	op := fmt.Sprintf("set attendance of student %d", e.StudentID)
+	classID, err := v.studentClass(e.StudentID)
+	if err != nil {
+		return err
+	}
+	if err := v.requireClass(op, classID); err != nil {
+		return err
+	}
+	// Students without an entry were present.
+	old := storage.AttendanceEntry{StudentID: e.StudentID, Date: e.Date, Lesson: e.Lesson, Status: storage.Present}
+	entries, err := v.storage.Attendance(classID, e.Date)
+	if err != nil {
+		return err
+	}
+	for _, a := range entries {
+		if a.StudentID == e.StudentID && a.Lesson == e.Lesson {
+			old = a
+		}
+	}
+	student := v.history.ref("students", e.StudentID)
+	// set returns a function recording a for the student held by the cell.
+	set := func(a storage.AttendanceEntry) func() error {
+		return func() error {
+			a.StudentID = *student
+			return v.storage.SetAttendance(a)
+		}
+	}
+	return v.done(v.storage.SetAttendance(e), command{name: "set attendance", undo: set(old), redo: set(e)})
+}
+
+// AbsenceTotals returns how many lessons each student of a class missed
+// between from and to, e.g. during a term.
+func (h *State) AbsenceTotals(classID int, from, to string) ([]storage.AbsenceTotal, error) {
+	return h.storage.AbsenceTotals(classID, from, to)
+}
+
+// NextPresence returns the status that follows e in the register, where a
+// click on a student cycles through present, absent, late, excused for
+// illness and excused for family reasons.
+func NextPresence(e storage.AttendanceEntry) storage.AttendanceEntry {
+	switch {
+	case e.Status == storage.Absent:
+		e.Status, e.Reason = storage.Late, ""
+	case e.Status == storage.Late:
+		e.Status, e.Reason = storage.Excused, storage.Illness
+	case e.Status == storage.Excused && e.Reason == storage.Illness:
+		e.Status, e.Reason = storage.Excused, storage.Family
+	case e.Status == storage.Excused:
+		e.Status, e.Reason = storage.Present, ""
+	default:
+		e.Status, e.Reason = storage.Absent, ""
+	}
+	return e
+}
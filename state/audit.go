@@ -0,0 +1,12 @@
+package state
+
+import "eklase/storage"
+
+// AuditLog returns the recorded changes matching f, newest first. Only admins
+// may read who changed what.
+func (h *State) AuditLog(f storage.AuditFilter) ([]storage.AuditEntry, error) {
+	if err := h.requireAdmin("list audit log"); err != nil {
+		return nil, err
+	}
+	return h.storage.AuditLog(f)
+}
@@ -0,0 +1,420 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"eklase/storage"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Limits of the length of passwords. bcrypt ignores anything past 72 bytes.
+const (
+	minPasswordLength = 8 // In characters.
+	maxPasswordBytes  = 72
+)
+
+// decoyHash is compared against when a username is not known, so that logging
+// in takes as long as with a wrong password and does not reveal who exists.
+const decoyHash = "$2a$10$muY/CpuU3XvwRaOAslWGAu8d3SUZqVtYI/kGMrbOsz41/m7/mB.Gq"
+
+// Session is the user who is logged in.
+type Session struct {
+	UserID    int // Zero for the System session.
+	Username  string
+	Role      storage.Role
+	TeacherID int // Teacher entry of teachers, zero for other roles.
+}
+
+// System is the session of tools with direct access to the database, such as
+// the command line. It may do everything an admin may.
+var System = Session{Username: "system", Role: storage.RoleAdmin}
+
+// Session returns who is logged in, false if nobody is.
+func (h *State) Session() (Session, bool) {
+	if h.session == nil {
+		return Session{}, false
+	}
+	return *h.session, true
+}
+
+// Login checks the password of a user and starts their session. ErrLogin is
+// returned if the username or the password is wrong.
+func (v *State) Login(username, password string) error {
+	u, err := v.storage.UserByName(username)
+	if errors.Is(err, storage.ErrNotFound) {
+		bcrypt.CompareHashAndPassword([]byte(decoyHash), []byte(password))
+		return &storage.Error{Op: "log in", Kind: ErrLogin}
+	}
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return &storage.Error{Op: "log in", Kind: ErrLogin}
+	}
+	v.setSession(sessionOf(u))
+	v.bump()
+	return nil
+}
+
+// Resume starts the session of a user who logged in earlier, e.g. to a server
+// handing out tokens, without checking the password again. The user is read
+// anew, so that a changed role applies, and ErrLogin is returned if they were
+// deleted meanwhile.
+func (v *State) Resume(userID int) error {
+	u, err := v.storage.User(userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return &storage.Error{Op: "resume session", Kind: ErrLogin}
+	}
+	if err != nil {
+		return err
+	}
+	v.setSession(sessionOf(u))
+	v.bump()
+	return nil
+}
+
+// sessionOf returns the session of a user.
+func sessionOf(u storage.UserEntry) *Session {
+	return &Session{
+		UserID:    u.ID,
+		Username:  u.Username,
+		Role:      u.Role,
+		TeacherID: int(u.TeacherID.Int64),
+	}
+}
+
+// Logout ends the session. Nothing can be changed until somebody logs in.
+func (v *State) Logout() {
+	v.setSession(nil)
+	v.bump()
+}
+
+// setSession starts a session, or ends it if s is nil, and records the
+// following changes in the audit log for its user. The history is dropped
+// when the session ends or passes to another user, the System session
+// included, and kept only when the same user logs in again meanwhile or
+// their entry changes.
+func (v *State) setSession(s *Session) {
+	if s == nil || v.session == nil || s.UserID != v.session.UserID {
+		v.history = history{}
+	}
+	v.session = s
+	if s == nil {
+		v.storage.SetActor("")
+	} else {
+		v.storage.SetActor(s.Username)
+	}
+}
+
+// HasUsers reports whether any user exists. Until then, the first admin is
+// created with Setup.
+func (h *State) HasUsers() (bool, error) {
+	users, err := h.storage.Users()
+	return len(users) > 0, err
+}
+
+// Setup creates the first admin and logs them in. It is refused once any user
+// exists.
+func (v *State) Setup(username, password string) error {
+	if ok, err := v.HasUsers(); err != nil || ok {
+		if err != nil {
+			return err
+		}
+		return &storage.Error{Op: "set up the first user", Kind: ErrForbidden}
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	// The first admin is recorded as adding themselves.
+	v.storage.SetActor(username)
+	_, err = v.storage.AddUser(storage.UserEntry{Username: username, PasswordHash: hash, Role: storage.RoleAdmin})
+	if err != nil {
+		return err
+	}
+	return v.Login(username, password)
+}
+
+// hashPassword checks the length of a password and returns its bcrypt hash.
+func hashPassword(password string) (string, error) {
+	if len([]rune(password)) < minPasswordLength {
+		return "", &storage.ValidationError{Field: "password", Reason: fmt.Sprintf("must have at least %d characters", minPasswordLength)}
+	}
+	if len(password) > maxPasswordBytes {
+		return "", &storage.ValidationError{Field: "password", Reason: "is too long"}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// Users returns the users who can log in. Only admins may list them.
+func (h *State) Users() ([]storage.UserEntry, error) {
+	if err := h.requireAdmin("list users"); err != nil {
+		return nil, err
+	}
+	return h.storage.Users()
+}
+
+// AddUser creates a user with a role and returns its ID. Teachers are linked
+// to their teacher entry, others take a zero teacherID.
+func (v *State) AddUser(username, password string, role storage.Role, teacherID int) (int, error) {
+	if err := v.requireAdmin("add user"); err != nil {
+		return 0, err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return 0, err
+	}
+	u := storage.UserEntry{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+		TeacherID:    sql.NullInt64{Int64: int64(teacherID), Valid: teacherID != 0},
+	}
+	add := v.userOf(u)
+	id, err := v.storage.AddUser(u)
+	return id, v.added(err, "add user", "users", id, v.storage.DeleteUser, func() (int, error) {
+		return v.storage.AddUser(add())
+	})
+}
+
+// userOf returns a function returning u with the current ID of its teacher.
+func (v *State) userOf(u storage.UserEntry) func() storage.UserEntry {
+	var teacher *int
+	if u.TeacherID.Valid {
+		teacher = v.history.ref("teachers", int(u.TeacherID.Int64))
+	}
+	return func() storage.UserEntry {
+		if teacher != nil {
+			u.TeacherID.Int64 = int64(*teacher)
+		}
+		return u
+	}
+}
+
+// updateUser returns a function storing u as the user held by the cell. The
+// session follows the changes of its own user.
+func (v *State) updateUser(user *int, u storage.UserEntry) func() error {
+	update := v.userOf(u)
+	return func() error {
+		u := update()
+		u.ID = *user
+		if err := v.storage.UpdateUser(u); err != nil {
+			return err
+		}
+		if v.session != nil && v.session.UserID == u.ID {
+			v.setSession(sessionOf(u))
+		}
+		return nil
+	}
+}
+
+// UpdateUser changes the username, role and teacher of a user. The last admin
+// cannot be made anything else.
+func (v *State) UpdateUser(id int, username string, role storage.Role, teacherID int) error {
+	op := fmt.Sprintf("update user %d", id)
+	if err := v.requireAdmin(op); err != nil {
+		return err
+	}
+	u, err := v.storage.User(id)
+	if err != nil {
+		return err
+	}
+	if role != storage.RoleAdmin {
+		if err := v.keepAdmin(u, "role", "of the last admin must stay admin"); err != nil {
+			return err
+		}
+	}
+	old := u
+	u.Username, u.Role = username, role
+	u.TeacherID = sql.NullInt64{Int64: int64(teacherID), Valid: teacherID != 0}
+	user := v.history.ref("users", id)
+	update := v.updateUser(user, u)
+	return v.done(update(), command{name: "update user", undo: v.updateUser(user, old), redo: update})
+}
+
+// SetPassword changes the password of a user. Users may change their own
+// password, admins that of anybody.
+func (v *State) SetPassword(id int, password string) error {
+	op := fmt.Sprintf("set password of user %d", id)
+	if v.session == nil || v.session.UserID != id {
+		if err := v.requireAdmin(op); err != nil {
+			return err
+		}
+	}
+	u, err := v.storage.User(id)
+	if err != nil {
+		return err
+	}
+	old := u
+	if u.PasswordHash, err = hashPassword(password); err != nil {
+		return err
+	}
+	user := v.history.ref("users", id)
+	update := v.updateUser(user, u)
+	return v.done(update(), command{name: "set password", undo: v.updateUser(user, old), redo: update})
+}
+
+// DeleteUser removes a user other than the one logged in and the last admin.
+func (v *State) DeleteUser(id int) error {
+	op := fmt.Sprintf("delete user %d", id)
+	if err := v.requireAdmin(op); err != nil {
+		return err
+	}
+	if v.session.UserID == id {
+		return &storage.ValidationError{Field: "user", Reason: "must not be the one logged in"}
+	}
+	u, err := v.storage.User(id)
+	if err != nil {
+		return err
+	}
+	if err := v.keepAdmin(u, "user", "must not be the last admin"); err != nil {
+		return err
+	}
+	add := v.userOf(u)
+	return v.removed(v.storage.DeleteUser(id), "delete user", "users", id, v.storage.DeleteUser, func() (int, error) {
+		return v.storage.AddUser(add())
+	})
+}
+
+// keepAdmin returns a validation error of field if u is the only admin, so
+// that somebody can always manage the users.
+func (v *State) keepAdmin(u storage.UserEntry, field, reason string) error {
+	if u.Role != storage.RoleAdmin {
+		return nil
+	}
+	users, err := v.storage.Users()
+	if err != nil {
+		return err
+	}
+	for _, other := range users {
+		if other.ID != u.ID && other.Role == storage.RoleAdmin {
+			return nil
+		}
+	}
+	return &storage.ValidationError{Field: field, Reason: reason}
+}
+
+// forbidden returns the error of a change the session may not make.
+func forbidden(op string) error {
+	return &storage.Error{Op: op, Kind: ErrForbidden}
+}
+
+// requireAdmin returns ErrForbidden unless an admin is logged in.
+func (h *State) requireAdmin(op string) error {
+	if h.session == nil || h.session.Role != storage.RoleAdmin {
+		return forbidden(op)
+	}
+	return nil
+}
+
+// requireSubject returns ErrForbidden unless the session may record grades
+// and lessons of a subject in a class: admins may do so in every class,
+// teachers in the subjects assigned to them for the school year of the class.
+func (h *State) requireSubject(op string, classID, subjectID int) error {
+	if h.requireAdmin(op) == nil {
+		return nil
+	}
+	if h.session == nil || !h.session.Role.Teaches() {
+		return forbidden(op)
+	}
+	_, assignments, err := h.yearAssignments(classID)
+	if err != nil {
+		return err
+	}
+	for _, a := range assignments {
+		if a.TeacherID == h.session.TeacherID && a.SubjectID == subjectID {
+			return nil
+		}
+	}
+	return forbidden(op)
+}
+
+// requireClass returns ErrForbidden unless the session may record the
+// attendance of a class: admins may do so in every class, teachers in the
+// classes they teach during their school year and class teachers in their
+// homeroom class as well.
+func (h *State) requireClass(op string, classID int) error {
+	if h.requireAdmin(op) == nil {
+		return nil
+	}
+	if h.session == nil || !h.session.Role.Teaches() {
+		return forbidden(op)
+	}
+	class, assignments, err := h.yearAssignments(classID)
+	if err != nil {
+		return err
+	}
+	if h.session.Role == storage.RoleClassTeacher && class.TeacherID.Valid && int(class.TeacherID.Int64) == h.session.TeacherID {
+		return nil
+	}
+	for _, a := range assignments {
+		if a.TeacherID == h.session.TeacherID {
+			return nil
+		}
+	}
+	return forbidden(op)
+}
+
+// yearAssignments returns a class with its teaching assignments during its
+// school year, as assignments of past years grant nothing. A missing class,
+// e.g. the class 0 of students enrolled nowhere, has no assignments.
+func (h *State) yearAssignments(classID int) (storage.ClassEntry, []storage.AssignmentEntry, error) {
+	class, err := h.storage.Class(classID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return storage.ClassEntry{}, nil, nil
+	}
+	if err != nil {
+		return storage.ClassEntry{}, nil, err
+	}
+	assignments, err := h.storage.ClassAssignments(classID)
+	if err != nil {
+		return storage.ClassEntry{}, nil, err
+	}
+	var current []storage.AssignmentEntry
+	for _, a := range assignments {
+		if a.SchoolYearID == class.SchoolYearID {
+			current = append(current, a)
+		}
+	}
+	return class, current, nil
+}
+
+// studentClass returns the class a student is enrolled in. Students who are
+// not enrolled anywhere have the class 0, where only admins may change
+// anything.
+func (h *State) studentClass(studentID int) (int, error) {
+	g, err := h.storage.Group(studentID)
+	return int(g.ClassID.Int64), err
+}
+
+// requireGrade returns ErrForbidden unless the session may give a grade to a
+// student in a subject. Teachers other than admins give grades in their own
+// name only.
+func (h *State) requireGrade(op string, studentID, subjectID, teacherID int) error {
+	if h.session != nil && h.session.Role != storage.RoleAdmin && teacherID != h.session.TeacherID {
+		return forbidden(op)
+	}
+	classID, err := h.studentClass(studentID)
+	if err != nil {
+		return err
+	}
+	return h.requireSubject(op, classID, subjectID)
+}
+
+// requireGraded returns ErrForbidden unless the session may change an existing
+// grade, which is up to any teacher of its subject.
+func (h *State) requireGraded(op string, gradeID int) error {
+	g, err := h.storage.Grade(gradeID)
+	if err != nil {
+		return err
+	}
+	classID, err := h.studentClass(g.StudentID)
+	if err != nil {
+		return err
+	}
+	return h.requireSubject(op, classID, g.SubjectID)
+}
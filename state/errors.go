@@ -0,0 +1,49 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"eklase/storage"
+)
+
+var (
+	// ErrForbidden is returned when the user who is logged in, if anybody,
+	// may not make a change.
+	ErrForbidden = errors.New("permission denied")
+	// ErrLogin is returned when the username or the password is wrong.
+	ErrLogin = errors.New("wrong username or password")
+)
+
+// Message turns an error returned by the state into a short sentence that can
+// be shown to the user.
+func Message(err error) string {
+	var (
+		verr *storage.ValidationError
+		cerr *storage.ConflictError
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &verr):
+		return "The " + verr.Field + " " + verr.Reason + "."
+	case errors.As(err, &cerr):
+		s := cerr.Slot
+		return fmt.Sprintf("The %s is busy already: %s.%s has %s with %s %s at that time.",
+			cerr.Resource, s.Year, s.Modifier, s.Subject, s.TeacherName, s.TeacherSurname)
+	case errors.Is(err, storage.ErrNotFound):
+		return "The entry does not exist anymore."
+	case errors.Is(err, storage.ErrDuplicate):
+		return "Such an entry exists already."
+	case errors.Is(err, storage.ErrConstraint):
+		return "The change conflicts with other data, e.g. a class that still has students."
+	case errors.Is(err, storage.ErrDatabaseLocked):
+		return "The database is busy, please try again."
+	case errors.Is(err, ErrForbidden):
+		return "You are not allowed to do that."
+	case errors.Is(err, ErrLogin):
+		return "The username or the password is wrong."
+	default:
+		return "Something went wrong: " + err.Error()
+	}
+}
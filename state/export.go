@@ -0,0 +1,32 @@
+package state
+
+import (
+	"eklase/export"
+	"eklase/storage"
+)
+
+// Exports lists the names of the lists ExportTable returns.
+var Exports = []string{"students", "classes", "groups"}
+
+// ExportTable returns the list of the given name, see Exports, ready to be
+// written by export.Write.
+func (h *State) ExportTable(name string) (export.Table, error) {
+	var (
+		entries interface{}
+		err     error
+	)
+	switch name {
+	case "students":
+		entries, err = h.storage.Students()
+	case "classes":
+		entries, err = h.storage.Classes()
+	case "groups":
+		entries, err = h.storage.Groups()
+	default:
+		return export.Table{}, &storage.ValidationError{Field: "list", Reason: "must be students, classes or groups"}
+	}
+	if err != nil {
+		return export.Table{}, err
+	}
+	return export.FromEntries(name, entries)
+}
@@ -0,0 +1,250 @@
+package state
+
+import (
+	"database/sql"
+	"eklase/grading"
+	"eklase/storage"
+	"fmt"
+)
+
+// GradingRules returns the rules final grades are proposed by.
+func (h *State) GradingRules() grading.Rules {
+	return h.rules
+}
+
+// SetGradingRules changes the rules final grades are proposed by. Like the
+// locale, they are configuration, which cannot be undone.
+func (v *State) SetGradingRules(r grading.Rules) error {
+	if err := v.requireAdmin("set grading rules"); err != nil {
+		return err
+	}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	v.rules = r
+	v.bump()
+	return nil
+}
+
+// FinalGradeRow is a student in the final grade sheet of a class.
+type FinalGradeRow struct {
+	Student      storage.GroupEntry
+	Proposal     grading.Proposal
+	Final        storage.FinalGradeEntry // Zero ID if the grade is not finalized yet.
+	SchoolYearID int
+	TermID       int // 0 for the whole school year.
+}
+
+// FinalGradeSheet returns the proposed and the finalized grades of the
+// students of a class in a subject for a term, or for the whole school year
+// if termID is 0. Proposals count the grades given during the period.
+func (h *State) FinalGradeSheet(classID, subjectID, schoolYearID, termID int) ([]FinalGradeRow, error) {
+	year, err := h.storage.SchoolYear(schoolYearID)
+	if err != nil {
+		return nil, err
+	}
+	from, to := year.Starts, year.Ends
+	if termID != 0 {
+		terms, err := h.storage.Terms(schoolYearID)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, t := range terms {
+			if t.ID == termID {
+				from, to, found = t.Starts, t.Ends, true
+			}
+		}
+		if !found {
+			return nil, &storage.Error{Op: "final grade sheet", Kind: storage.ErrNotFound}
+		}
+	}
+
+	students, err := h.ClassStudents(classID)
+	if err != nil {
+		return nil, err
+	}
+	grades, err := h.storage.Grades(storage.GradeFilter{ClassID: classID, SubjectID: subjectID, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	finals, err := h.storage.FinalGrades(storage.FinalGradeFilter{
+		ClassID:      classID,
+		SubjectID:    subjectID,
+		SchoolYearID: schoolYearID,
+		TermID:       termID,
+		YearOnly:     termID == 0,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byStudent := make(map[int][]storage.GradeEntry)
+	for _, g := range grades {
+		byStudent[g.StudentID] = append(byStudent[g.StudentID], g)
+	}
+	finalized := make(map[int]storage.FinalGradeEntry)
+	for _, f := range finals {
+		finalized[f.StudentID] = f
+	}
+	rows := make([]FinalGradeRow, 0, len(students))
+	for _, s := range students {
+		p := grading.Propose(byStudent[s.StudentID], h.rules)
+		p.StudentID, p.SubjectID = s.StudentID, subjectID
+		rows = append(rows, FinalGradeRow{
+			Student:      s,
+			Proposal:     p,
+			Final:        finalized[s.StudentID],
+			SchoolYearID: schoolYearID,
+			TermID:       termID,
+		})
+	}
+	return rows, nil
+}
+
+// Finalize returns the final grade of the row with mark as the final mark. A
+// mark other than the proposed one needs a reason.
+func (r FinalGradeRow) Finalize(mark storage.Mark, reason string) storage.FinalGradeEntry {
+	return storage.FinalGradeEntry{
+		StudentID:    r.Student.StudentID,
+		SubjectID:    r.Proposal.SubjectID,
+		SchoolYearID: r.SchoolYearID,
+		TermID:       sql.NullInt64{Int64: int64(r.TermID), Valid: r.TermID != 0},
+		Average:      r.Proposal.Average,
+		Proposed:     r.Proposal.Mark,
+		Mark:         mark,
+		Reason:       reason,
+	}
+}
+
+// FinalGrades returns the final grades matching f, e.g. those of a student.
+func (h *State) FinalGrades(f storage.FinalGradeFilter) ([]storage.FinalGradeEntry, error) {
+	return h.storage.FinalGrades(f)
+}
+
+// FinalizeGrades stores final grades, either all of them or none.
+func (v *State) FinalizeGrades(finals []storage.FinalGradeEntry) error {
+	var (
+		redo []func() storage.FinalGradeEntry
+		undo []func() storage.FinalGradeEntry // The final grades replaced.
+		news []func() storage.FinalGradeEntry // The final grades added.
+	)
+	for _, f := range finals {
+		if err := v.requireFinal("finalize grades", f); err != nil {
+			return err
+		}
+		old, ok, err := v.finalGradeLike(f)
+		if err != nil {
+			return err
+		}
+		redo = append(redo, v.finalOf(f))
+		if ok {
+			undo = append(undo, v.finalOf(old))
+		} else {
+			news = append(news, v.finalOf(f))
+		}
+	}
+	return v.done(v.storage.SaveFinalGrades(finals), command{
+		name: "finalize grades",
+		undo: func() error {
+			if err := v.saveFinalGrades(undo); err != nil {
+				return err
+			}
+			for _, f := range news {
+				if err := v.deleteFinalGradeLike(f()); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		redo: func() error { return v.saveFinalGrades(redo) },
+	})
+}
+
+// ReopenFinalGrade removes a final grade, so that it is proposed again.
+func (v *State) ReopenFinalGrade(id int) error {
+	op := fmt.Sprintf("reopen final grade %d", id)
+	f, err := v.storage.FinalGrade(id)
+	if err != nil {
+		return err
+	}
+	if err := v.requireFinal(op, f); err != nil {
+		return err
+	}
+	// Final grades are found by their student, subject and period, as they
+	// get another ID when they are saved anew.
+	final := v.finalOf(f)
+	return v.done(v.storage.DeleteFinalGrade(id), command{
+		name: "reopen final grade",
+		undo: func() error { return v.saveFinalGrades([]func() storage.FinalGradeEntry{final}) },
+		redo: func() error { return v.deleteFinalGradeLike(final()) },
+	})
+}
+
+// finalOf returns a function returning f with the current IDs of its student,
+// subject, school year and term.
+func (v *State) finalOf(f storage.FinalGradeEntry) func() storage.FinalGradeEntry {
+	student, subject, year := v.history.ref("students", f.StudentID), v.history.ref("subjects", f.SubjectID), v.history.ref("school_years", f.SchoolYearID)
+	var term *int
+	if f.TermID.Valid {
+		term = v.history.ref("terms", int(f.TermID.Int64))
+	}
+	return func() storage.FinalGradeEntry {
+		f.StudentID, f.SubjectID, f.SchoolYearID = *student, *subject, *year
+		if term != nil {
+			f.TermID.Int64 = int64(*term)
+		}
+		return f
+	}
+}
+
+// saveFinalGrades stores the final grades the functions return.
+func (v *State) saveFinalGrades(finals []func() storage.FinalGradeEntry) error {
+	if len(finals) == 0 {
+		return nil
+	}
+	entries := make([]storage.FinalGradeEntry, len(finals))
+	for i, f := range finals {
+		entries[i] = f()
+	}
+	return v.storage.SaveFinalGrades(entries)
+}
+
+// finalGradeLike returns the stored final grade of the same student, subject
+// and period as f, if there is one.
+func (h *State) finalGradeLike(f storage.FinalGradeEntry) (storage.FinalGradeEntry, bool, error) {
+	finals, err := h.storage.FinalGrades(storage.FinalGradeFilter{
+		StudentID:    f.StudentID,
+		SubjectID:    f.SubjectID,
+		SchoolYearID: f.SchoolYearID,
+		TermID:       int(f.TermID.Int64),
+		YearOnly:     !f.TermID.Valid,
+	})
+	if err != nil || len(finals) == 0 {
+		return storage.FinalGradeEntry{}, false, err
+	}
+	return finals[0], true, nil
+}
+
+// deleteFinalGradeLike removes the final grade of the same student, subject
+// and period as f.
+func (v *State) deleteFinalGradeLike(f storage.FinalGradeEntry) error {
+	old, ok, err := v.finalGradeLike(f)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &storage.Error{Op: "delete final grade", Kind: storage.ErrNotFound}
+	}
+	return v.storage.DeleteFinalGrade(old.ID)
+}
+
+// requireFinal returns ErrForbidden unless the session may finalize the grade
+// of a student in a subject, which is up to the teachers of the subject.
+func (h *State) requireFinal(op string, f storage.FinalGradeEntry) error {
+	classID, err := h.studentClass(f.StudentID)
+	if err != nil {
+		return err
+	}
+	return h.requireSubject(op, classID, f.SubjectID)
+}
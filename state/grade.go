@@ -0,0 +1,199 @@
+package state
+
+import (
+	"fmt"
+
+	"eklase/storage"
+)
+
+// Grades returns the grades matching f, e.g. those of one class in one
+// subject.
+func (h *State) Grades(f storage.GradeFilter) ([]storage.GradeEntry, error) {
+	return h.storage.Grades(f)
+}
+
+// StudentGrades returns every grade of a student.
+func (h *State) StudentGrades(studentID int) ([]storage.GradeEntry, error) {
+	return h.storage.Grades(storage.GradeFilter{StudentID: studentID})
+}
+
+// ClassGrades returns the grades of the students of a class in a subject.
+func (h *State) ClassGrades(classID, subjectID int) ([]storage.GradeEntry, error) {
+	return h.storage.Grades(storage.GradeFilter{ClassID: classID, SubjectID: subjectID})
+}
+
+// SubjectGrades returns every grade given in a subject.
+func (h *State) SubjectGrades(subjectID int) ([]storage.GradeEntry, error) {
+	return h.storage.Grades(storage.GradeFilter{SubjectID: subjectID})
+}
+
+// AddGrade records a grade given by a teacher on date, e.g. "2025-09-30", and
+// returns its ID.
+func (v *State) AddGrade(studentID, subjectID, teacherID int, date string, mark storage.Mark) (int, error) {
+	if err := v.requireGrade("add grade", studentID, subjectID, teacherID); err != nil {
+		return 0, err
+	}
+	student, subject, teacher := v.history.ref("students", studentID), v.history.ref("subjects", subjectID), v.history.ref("teachers", teacherID)
+	id, err := v.storage.AddGrade(studentID, subjectID, teacherID, date, mark)
+	return id, v.added(err, "add grade", "grades", id, v.storage.DeleteGrade, func() (int, error) {
+		return v.storage.AddGrade(*student, *subject, *teacher, date, mark)
+	})
+}
+
+// undoReason is the reason of the corrections undo restores marks with.
+const undoReason = "undo"
+
+// CorrectGrade changes the mark of a grade. The old mark and the reason are
+// kept in the history of the grade.
+func (v *State) CorrectGrade(id int, mark storage.Mark, reason string) error {
+	if err := v.requireGraded(fmt.Sprintf("correct grade %d", id), id); err != nil {
+		return err
+	}
+	old, err := v.storage.Grade(id)
+	if err != nil {
+		return err
+	}
+	grade := v.history.ref("grades", id)
+	return v.done(v.storage.CorrectGrade(id, mark, reason), command{
+		name: "correct grade",
+		undo: func() error { return v.storage.CorrectGrade(*grade, old.Mark, undoReason) },
+		redo: func() error { return v.storage.CorrectGrade(*grade, mark, reason) },
+	})
+}
+
+// GradeCorrections returns the correction history of a grade.
+func (h *State) GradeCorrections(gradeID int) ([]storage.GradeCorrection, error) {
+	return h.storage.GradeCorrections(gradeID)
+}
+
+// DeleteGrade removes a grade together with its history.
+func (v *State) DeleteGrade(id int) error {
+	if err := v.requireGraded(fmt.Sprintf("delete grade %d", id), id); err != nil {
+		return err
+	}
+	g, err := v.storage.Grade(id)
+	if err != nil {
+		return err
+	}
+	add := v.gradeOf(g)
+	return v.removed(v.storage.DeleteGrade(id), "delete grade", "grades", id, v.storage.DeleteGrade, func() (int, error) {
+		ids, err := v.storage.SaveGrades([]storage.GradeChange{add()})
+		if err != nil {
+			return 0, err
+		}
+		return ids[0], nil
+	})
+}
+
+// gradeOf returns a function returning the change that adds g anew, with the
+// current IDs of its student, subject and teacher.
+func (v *State) gradeOf(g storage.GradeEntry) func() storage.GradeChange {
+	student, subject, teacher := v.history.ref("students", g.StudentID), v.history.ref("subjects", g.SubjectID), v.history.ref("teachers", g.TeacherID)
+	return func() storage.GradeChange {
+		return storage.GradeChange{
+			StudentID: *student,
+			SubjectID: *subject,
+			TeacherID: *teacher,
+			Date:      g.Date,
+			Kind:      g.Kind,
+			Mark:      g.Mark,
+		}
+	}
+}
+
+// SaveGrades applies a batch of grade changes, e.g. everything typed into the
+// gradebook, either all of them or none.
+func (v *State) SaveGrades(changes []storage.GradeChange) error {
+	steps := make([]*gradeStep, len(changes))
+	for i, c := range changes {
+		var err error
+		if c.ID != 0 {
+			err = v.requireGraded(fmt.Sprintf("save grade %d", c.ID), c.ID)
+		} else {
+			err = v.requireGrade("save grades", c.StudentID, c.SubjectID, c.TeacherID)
+		}
+		if err != nil {
+			return err
+		}
+		if steps[i], err = v.gradeStepOf(c); err != nil {
+			return err
+		}
+	}
+	return v.done(v.saveGrades(steps, false), command{
+		name: "save grades",
+		undo: func() error { return v.saveGrades(steps, true) },
+		redo: func() error { return v.saveGrades(steps, false) },
+	})
+}
+
+// gradeStep is a change in a batch saved by SaveGrades.
+type gradeStep struct {
+	grade *int                       // Cell of the grade, set once it is added.
+	redo  func() storage.GradeChange // Makes the change.
+	undo  func() storage.GradeChange // Reverts the change.
+}
+
+// gradeStepOf returns the step making and reverting change c.
+func (v *State) gradeStepOf(c storage.GradeChange) (*gradeStep, error) {
+	if c.ID == 0 {
+		s := &gradeStep{grade: new(int)}
+		s.redo = v.gradeOf(storage.GradeEntry{
+			StudentID: c.StudentID,
+			SubjectID: c.SubjectID,
+			TeacherID: c.TeacherID,
+			Date:      c.Date,
+			Kind:      c.Kind,
+			Mark:      c.Mark,
+		})
+		s.undo = func() storage.GradeChange { return storage.GradeChange{ID: *s.grade} }
+		return s, nil
+	}
+	old, err := v.storage.Grade(c.ID)
+	if err != nil {
+		return nil, err
+	}
+	s := &gradeStep{grade: v.history.ref("grades", c.ID)}
+	if c.Mark == "" {
+		s.redo = func() storage.GradeChange { return storage.GradeChange{ID: *s.grade} }
+		s.undo = v.gradeOf(old)
+		return s, nil
+	}
+	s.redo = func() storage.GradeChange {
+		c.ID = *s.grade
+		return c
+	}
+	s.undo = func() storage.GradeChange {
+		return storage.GradeChange{ID: *s.grade, Mark: old.Mark, Reason: undoReason}
+	}
+	return s, nil
+}
+
+// saveGrades saves the changes of the steps, or reverts them in the reverse
+// order, and keeps the cells of the grades added and removed up to date.
+func (v *State) saveGrades(steps []*gradeStep, undo bool) error {
+	order := make([]*gradeStep, len(steps))
+	changes := make([]storage.GradeChange, len(steps))
+	for i, s := range steps {
+		if undo {
+			s = steps[len(steps)-1-i]
+			changes[i] = s.undo()
+		} else {
+			changes[i] = s.redo()
+		}
+		order[i] = s
+	}
+	ids, err := v.storage.SaveGrades(changes)
+	if err != nil {
+		return err
+	}
+	for i, c := range changes {
+		switch {
+		case c.ID == 0:
+			v.history.rebind("grades", order[i].grade, ids[0])
+			ids = ids[1:]
+		case c.Mark == "":
+			delete(v.history.ids, ref{"grades", c.ID})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package state
+
+// maxHistory is the most changes that can be undone.
+const maxHistory = 100
+
+// History describes the changes that Undo and Redo would revert and make
+// again. Every change of the data made through the state can be undone,
+// deletions too, which restore the entry under a new ID. Reverting a grade
+// or a deletion does not bring back the corrections of the grades, though.
+// The history belongs to the session: it is dropped when the user logs out
+// or somebody else logs in, and nothing can be undone while nobody is logged
+// in.
+type History struct {
+	Undo []string // Changes that can be undone, the latest first.
+	Redo []string // Undone changes that can be made again, the latest first.
+}
+
+// command is a recorded change, which can be reverted and made again.
+type command struct {
+	name string       // What the change did, e.g. "enroll student".
+	undo func() error // Reverts the change.
+	redo func() error // Makes the change again after undo.
+}
+
+// ref identifies an entry by its entity, named like its table, and ID.
+type ref struct {
+	entity string
+	id     int
+}
+
+// history keeps the changes of the session that can be undone. Entries that
+// undo deletes and redo adds anew get another ID, so commands refer to them
+// through shared cells holding their current IDs.
+type history struct {
+	done   []command    // Changes that can be undone, the latest last.
+	undone []command    // Undone changes, the latest last.
+	ids    map[ref]*int // Cells of the IDs of existing entries, by the ID.
+}
+
+// History returns the changes that can be undone and redone.
+func (h *State) History() History {
+	return History{Undo: names(h.history.done), Redo: names(h.history.undone)}
+}
+
+// names returns the names of the commands, the latest first.
+func names(commands []command) []string {
+	var names []string
+	for i := len(commands) - 1; i >= 0; i-- {
+		names = append(names, commands[i].name)
+	}
+	return names
+}
+
+// Undo reverts the latest change that can be undone and returns its name, or
+// an empty string if there is none. If the change cannot be reverted, e.g.
+// as somebody else changed the data meanwhile, the history is cleared.
+func (v *State) Undo() (string, error) {
+	if v.session == nil {
+		return "", forbidden("undo")
+	}
+	n := len(v.history.done)
+	if n == 0 {
+		return "", nil
+	}
+	c := v.history.done[n-1]
+	if err := c.undo(); err != nil {
+		v.history = history{}
+		return c.name, err
+	}
+	v.history.done = v.history.done[:n-1]
+	v.history.undone = append(v.history.undone, c)
+	v.bump()
+	return c.name, nil
+}
+
+// Redo makes the latest undone change again and returns its name, or an
+// empty string if there is none. Like Undo, it clears the history if the
+// change cannot be made.
+func (v *State) Redo() (string, error) {
+	if v.session == nil {
+		return "", forbidden("redo")
+	}
+	n := len(v.history.undone)
+	if n == 0 {
+		return "", nil
+	}
+	c := v.history.undone[n-1]
+	if err := c.redo(); err != nil {
+		v.history = history{}
+		return c.name, err
+	}
+	v.history.undone = v.history.undone[:n-1]
+	v.history.done = append(v.history.done, c)
+	v.bump()
+	return c.name, nil
+}
+
+// done records a change that can be undone and bumps the revision, unless err
+// reports that it failed. Redoing the older undone changes is not possible
+// any more.
+func (v *State) done(err error, c command) error {
+	if err != nil {
+		return err
+	}
+	v.history.undone = nil
+	v.history.done = append(v.history.done, c)
+	if n := len(v.history.done); n > maxHistory {
+		v.history.done = append([]command(nil), v.history.done[n-maxHistory:]...)
+	}
+	v.bump()
+	return nil
+}
+
+// bump changes the revision, so that screens reload, without recording a
+// change that can be undone, e.g. after logging in.
+func (v *State) bump() {
+	v.revision++
+}
+
+// added records the addition of an entry, which undo removes and redo adds
+// anew.
+func (v *State) added(err error, name, entity string, id int, remove func(id int) error, add func() (int, error)) error {
+	if err != nil {
+		return err
+	}
+	cell := v.history.ref(entity, id)
+	return v.done(nil, command{
+		name: name,
+		undo: func() error { return v.history.remove(entity, cell, remove) },
+		redo: func() error { return v.history.add(entity, cell, add) },
+	})
+}
+
+// removed records the removal of an entry, which undo restores anew and redo
+// removes again.
+func (v *State) removed(err error, name, entity string, id int, remove func(id int) error, restore func() (int, error)) error {
+	if err != nil {
+		return err
+	}
+	cell := v.history.ref(entity, id)
+	delete(v.history.ids, ref{entity, id})
+	return v.done(nil, command{
+		name: name,
+		undo: func() error { return v.history.add(entity, cell, restore) },
+		redo: func() error { return v.history.remove(entity, cell, remove) },
+	})
+}
+
+// add adds an entry anew and points its cell to the new ID.
+func (h *history) add(entity string, cell *int, add func() (int, error)) error {
+	id, err := add()
+	if err != nil {
+		return err
+	}
+	h.rebind(entity, cell, id)
+	return nil
+}
+
+// remove removes the entry held by the cell, which is kept for adding it
+// anew.
+func (h *history) remove(entity string, cell *int, remove func(id int) error) error {
+	if err := remove(*cell); err != nil {
+		return err
+	}
+	delete(h.ids, ref{entity, *cell})
+	return nil
+}
+
+// rebind points the cell of an entry to the ID it was added anew under.
+func (h *history) rebind(entity string, cell *int, id int) {
+	*cell = id
+	if h.ids == nil {
+		h.ids = make(map[ref]*int)
+	}
+	h.ids[ref{entity, id}] = cell
+}
+
+// ref returns the cell holding the current ID of an existing entry.
+func (h *history) ref(entity string, id int) *int {
+	if h.ids == nil {
+		h.ids = make(map[ref]*int)
+	}
+	cell, ok := h.ids[ref{entity, id}]
+	if !ok {
+		cell = &id
+		h.ids[ref{entity, id}] = cell
+	}
+	return cell
+}
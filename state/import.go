@@ -0,0 +1,355 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"eklase/storage"
+)
+
+// ImportField is a column of an imported file.
+type ImportField string
+
+// Fields read from imported files. The class is written like "5.a" or "5a".
+const (
+	FieldName    ImportField = "name"
+	FieldSurname ImportField = "surname"
+	FieldClass   ImportField = "class"
+)
+
+// fieldAliases are the headers recognized without CSVOptions.Columns, in lower
+// case.
+var fieldAliases = map[string]ImportField{
+	"name":       FieldName,
+	"first name": FieldName,
+	"vārds":      FieldName,
+	"surname":    FieldSurname,
+	"last name":  FieldSurname,
+	"uzvārds":    FieldSurname,
+	"class":      FieldClass,
+	"klase":      FieldClass,
+}
+
+// CSVOptions configure how a CSV file is read.
+type CSVOptions struct {
+	// Delimiter separates the fields, ',' if zero. Excel uses ';' in
+	// locales with a decimal comma.
+	Delimiter rune
+	// Columns maps headers of the file to fields, ignoring case. Other
+	// headers are recognized by their usual names, e.g. "Uzvārds", and
+	// ignored if unknown.
+	Columns map[string]ImportField
+}
+
+// ImportStatus tells what happens to an imported row.
+type ImportStatus int
+
+const (
+	ImportNew       ImportStatus = iota // The row will be added.
+	ImportDuplicate                     // The student exists already and is skipped.
+	ImportInvalid                       // The row cannot be added and is skipped.
+)
+
+func (s ImportStatus) String() string {
+	switch s {
+	case ImportNew:
+		return "new"
+	case ImportDuplicate:
+		return "duplicate"
+	default:
+		return "invalid"
+	}
+}
+
+// ImportRow is a row of an imported file.
+type ImportRow struct {
+	Line   int    // Line of the file the row starts on.
+	Class  string // Class as written in the file.
+	Entry  storage.ImportEntry
+	Status ImportStatus
+	Reason string // Why a row is a duplicate or invalid.
+}
+
+// ImportReport describes what an import would do, so that it can be reviewed
+// before anything is stored.
+type ImportReport struct {
+	Rows       []ImportRow
+	New        int
+	Duplicates int
+	Invalid    int
+	Classes    []string // Classes the new rows add, e.g. "5.a".
+}
+
+// PreviewImport reads students from a CSV file with a header row and reports
+// which of them are new, which exist already, either in the database or
+// earlier in the file, and which are invalid, and which classes of the latest
+// school year are added for them. Nothing is stored. A UTF-8 byte order mark,
+// as written by Excel, is skipped.
+func (h *State) PreviewImport(r io.Reader, opts CSVOptions) (ImportReport, error) {
+	records, err := readCSV(r, opts.Delimiter)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	if len(records) == 0 {
+		return ImportReport{}, &storage.ValidationError{Field: "file", Reason: "is empty"}
+	}
+	columns, err := mapColumns(records[0].fields, opts.Columns)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	students, err := h.storage.Students()
+	if err != nil {
+		return ImportReport{}, err
+	}
+	seen := make(map[string]int) // Line of each student by key, 0 if stored.
+	for _, s := range students {
+		seen[personKey(s.Name, s.Surname)] = 0
+	}
+	classes, err := h.latestClasses()
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	var report ImportReport
+	for _, record := range records[1:] {
+		row := ImportRow{Line: record.line}
+		field := func(f ImportField) string {
+			if c, ok := columns[f]; ok && c < len(record.fields) {
+				return strings.TrimSpace(record.fields[c])
+			}
+			return ""
+		}
+		row.Entry.Name, row.Entry.Surname, row.Class = field(FieldName), field(FieldSurname), field(FieldClass)
+		if row.Entry.Name == "" && row.Entry.Surname == "" && row.Class == "" {
+			continue // Blank rows left by spreadsheets.
+		}
+		key := personKey(row.Entry.Name, row.Entry.Surname)
+		line, dup := seen[key]
+		classOK := parseClass(row.Class, &row.Entry)
+		invalid := row.Entry.Validate()
+		switch {
+		case !utf8.ValidString(strings.Join(record.fields, "")):
+			row.Status, row.Reason = ImportInvalid, "The file is not in UTF-8."
+		case !classOK:
+			row.Status, row.Reason = ImportInvalid, "The class must look like 5.a."
+		case invalid != nil:
+			row.Status, row.Reason = ImportInvalid, Message(invalid)
+		case dup && line == 0:
+			row.Status, row.Reason = ImportDuplicate, "Such a student exists already."
+		case dup:
+			row.Status, row.Reason = ImportDuplicate, fmt.Sprintf("The student is on line %d already.", line)
+		default:
+			row.Status = ImportNew
+			seen[key] = row.Line
+		}
+		switch row.Status {
+		case ImportNew:
+			report.New++
+			e := row.Entry
+			if _, ok := storage.FindClass(classes, e.Year, e.Modifier); e.Year != "" && !ok {
+				classes = append(classes, storage.ClassEntry{Year: e.Year, Modifier: e.Modifier})
+				report.Classes = append(report.Classes, e.Year+"."+e.Modifier)
+			}
+		case ImportDuplicate:
+			report.Duplicates++
+		default:
+			report.Invalid++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	return report, nil
+}
+
+// Import adds the new rows of a report made by PreviewImport in a single
+// transaction and returns how many students were added. Nothing is added if
+// any of them fails.
+func (v *State) Import(report ImportReport) (int, error) {
+	if err := v.requireAdmin("import students"); err != nil {
+		return 0, err
+	}
+	var entries []storage.ImportEntry
+	for _, r := range report.Rows {
+		if r.Status == ImportNew {
+			entries = append(entries, r.Entry)
+		}
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	var students, classes []*int // Cells of the entries the import added.
+	// add imports the entries and points the cells to the entries added.
+	add := func() error {
+		before, err := v.storage.Classes()
+		if err != nil {
+			return err
+		}
+		ids, err := v.storage.ImportStudents(entries)
+		if err != nil {
+			return err
+		}
+		after, err := v.storage.Classes()
+		if err != nil {
+			return err
+		}
+		students, classes = rebindAll(&v.history, "students", students, ids), rebindAll(&v.history, "classes", classes, newClasses(before, after))
+		return nil
+	}
+	remove := func() error {
+		for _, cell := range students {
+			if err := v.history.remove("students", cell, v.storage.DeleteStudent); err != nil {
+				return err
+			}
+		}
+		for _, cell := range classes {
+			if err := v.history.remove("classes", cell, v.storage.DeleteClass); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := add()
+	return len(students), v.done(err, command{name: "import students", undo: remove, redo: add})
+}
+
+// newClasses returns the IDs of the classes in after that are not in before.
+func newClasses(before, after []storage.ClassEntry) []int {
+	old := make(map[int]bool, len(before))
+	for _, c := range before {
+		old[c.ID] = true
+	}
+	var ids []int
+	for _, c := range after {
+		if !old[c.ID] {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// rebindAll points the cells to the IDs of entries added anew, in order, and
+// returns as many cells as there are IDs. Missing cells are made, e.g. for
+// entries added the first time.
+func rebindAll(h *history, entity string, cells []*int, ids []int) []*int {
+	for i, id := range ids {
+		if i == len(cells) {
+			cells = append(cells, new(int))
+		}
+		h.rebind(entity, cells[i], id)
+	}
+	return cells[:len(ids)]
+}
+
+// latestClasses returns the classes of the latest school year, which imported
+// students are enrolled into.
+func (h *State) latestClasses() ([]storage.ClassEntry, error) {
+	years, err := h.storage.SchoolYears()
+	if err != nil {
+		return nil, err
+	}
+	var latest sql.NullInt64
+	if len(years) > 0 {
+		latest = sql.NullInt64{Int64: int64(years[len(years)-1].ID), Valid: true}
+	}
+	classes, err := h.storage.Classes()
+	if err != nil {
+		return nil, err
+	}
+	var entries []storage.ClassEntry
+	for _, c := range classes {
+		if c.SchoolYearID == latest {
+			entries = append(entries, c)
+		}
+	}
+	return entries, nil
+}
+
+// csvRecord is a record of a CSV file.
+type csvRecord struct {
+	line   int // Line the record starts on.
+	fields []string
+}
+
+// readCSV reads every record of a CSV file delimited by delimiter, skipping a
+// byte order mark.
+func readCSV(r io.Reader, delimiter rune) ([]csvRecord, error) {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+	cr := csv.NewReader(br)
+	if delimiter != 0 {
+		cr.Comma = delimiter
+	}
+	cr.FieldsPerRecord = -1 // Spreadsheets drop empty trailing fields.
+	var records []csvRecord
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			return nil, &storage.ValidationError{Field: "file", Reason: fmt.Sprintf("is not valid CSV on line %d", perr.StartLine)}
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		records = append(records, csvRecord{line: line, fields: fields})
+	}
+}
+
+// mapColumns returns the column of each field found in the header.
+func mapColumns(header []string, columns map[string]ImportField) (map[ImportField]int, error) {
+	names := make(map[string]ImportField, len(fieldAliases)+len(columns))
+	for alias, f := range fieldAliases {
+		names[alias] = f
+	}
+	for h, f := range columns {
+		names[strings.ToLower(strings.TrimSpace(h))] = f
+	}
+	found := make(map[ImportField]int)
+	for i, h := range header {
+		if f, ok := names[strings.ToLower(strings.TrimSpace(h))]; ok {
+			if _, dup := found[f]; dup {
+				return nil, &storage.ValidationError{Field: "header", Reason: fmt.Sprintf("names the %s column twice", f)}
+			}
+			found[f] = i
+		}
+	}
+	for _, f := range []ImportField{FieldName, FieldSurname} {
+		if _, ok := found[f]; !ok {
+			return nil, &storage.ValidationError{Field: "header", Reason: fmt.Sprintf("has no %s column", f)}
+		}
+	}
+	return found, nil
+}
+
+// parseClass splits a class like "5.a", "5a" or "5 a" into the year and the
+// modifier of e. An empty class is fine too.
+func parseClass(class string, e *storage.ImportEntry) bool {
+	if class == "" {
+		return true
+	}
+	i := strings.IndexFunc(class, func(r rune) bool { return !unicode.IsDigit(r) })
+	if i <= 0 {
+		return false
+	}
+	e.Year = class[:i]
+	e.Modifier = strings.TrimLeft(class[i:], ". ")
+	return e.Modifier != ""
+}
+
+// personKey identifies a student by name and surname regardless of case.
+func personKey(name, surname string) string {
+	return strings.ToLower(name) + "\x00" + strings.ToLower(surname)
+}
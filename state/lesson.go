@@ -0,0 +1,86 @@
+package state
+
+import (
+	"eklase/storage"
+	"fmt"
+	"time"
+)
+
+// Lessons returns the recorded lessons of a subject in a class, oldest first.
+func (h *State) Lessons(classID, subjectID int) ([]storage.LessonEntry, error) {
+	return h.storage.Lessons(storage.LessonFilter{ClassID: classID, SubjectID: subjectID})
+}
+
+// AddLesson records the topic of a lesson and the homework given, if any, and
+// returns its ID.
+func (v *State) AddLesson(l storage.LessonEntry) (int, error) {
+	if err := v.requireSubject("add lesson", l.ClassID, l.SubjectID); err != nil {
+		return 0, err
+	}
+	add := v.lessonOf(l)
+	id, err := v.storage.AddLesson(l)
+	return id, v.added(err, "add lesson", "lessons", id, v.storage.DeleteLesson, func() (int, error) {
+		return v.storage.AddLesson(add())
+	})
+}
+
+// UpdateLesson changes the date, topic and homework of a lesson.
+func (v *State) UpdateLesson(l storage.LessonEntry) error {
+	if err := v.requireLesson(fmt.Sprintf("update lesson %d", l.ID), l.ID); err != nil {
+		return err
+	}
+	old, err := v.storage.Lesson(l.ID)
+	if err != nil {
+		return err
+	}
+	lesson := v.history.ref("lessons", l.ID)
+	// update returns a function storing the lesson held by the cell as l.
+	update := func(l storage.LessonEntry) func() error {
+		return func() error {
+			l.ID = *lesson
+			return v.storage.UpdateLesson(l)
+		}
+	}
+	return v.done(v.storage.UpdateLesson(l), command{name: "update lesson", undo: update(old), redo: update(l)})
+}
+
+// DeleteLesson removes a recorded lesson.
+func (v *State) DeleteLesson(id int) error {
+	if err := v.requireLesson(fmt.Sprintf("delete lesson %d", id), id); err != nil {
+		return err
+	}
+	l, err := v.storage.Lesson(id)
+	if err != nil {
+		return err
+	}
+	add := v.lessonOf(l)
+	return v.removed(v.storage.DeleteLesson(id), "delete lesson", "lessons", id, v.storage.DeleteLesson, func() (int, error) {
+		return v.storage.AddLesson(add())
+	})
+}
+
+// lessonOf returns a function returning l with the current IDs of its class
+// and subject.
+func (v *State) lessonOf(l storage.LessonEntry) func() storage.LessonEntry {
+	class, subject := v.history.ref("classes", l.ClassID), v.history.ref("subjects", l.SubjectID)
+	return func() storage.LessonEntry {
+		l.ClassID, l.SubjectID = *class, *subject
+		return l
+	}
+}
+
+// requireLesson returns ErrForbidden unless the session may change a recorded
+// lesson. Its class and subject are those stored, as they cannot change.
+func (h *State) requireLesson(op string, id int) error {
+	l, err := h.storage.Lesson(id)
+	if err != nil {
+		return err
+	}
+	return h.requireSubject(op, l.ClassID, l.SubjectID)
+}
+
+// UpcomingHomework returns the homework of a class that is due on the day of
+// t or later, soonest first.
+func (h *State) UpcomingHomework(classID int, t time.Time) ([]storage.LessonEntry, error) {
+	return h.storage.Homework(classID, t.Format("2006-01-02"))
+}
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Severity tells how important a notification is.
+type Severity int
+
+const (
+	// Info reports a successful action, e.g. a saved student.
+	Info Severity = iota
+	// Warning reports something the user should double-check.
+	Warning
+	// Error reports a failed action.
+	Error
+)
+
+// Notification is a short message for the user, e.g. a failed save.
+type Notification struct {
+	ID       int       // Unique identifier used for dismissing.
+	Severity Severity  // How important the message is.
+	Message  string    // Text shown to the user.
+	Posted   time.Time // When the notification was posted.
+}
+
+// SetLogLevel sets the least severe notifications that are logged. Only
+// errors are logged by default.
+func (v *State) SetLogLevel(s Severity) {
+	v.logLevel = s
+}
+
+// Notify queues a notification for the user.
+func (v *State) Notify(severity Severity, message string) {
+	v.log(severity, message)
+	v.queue(severity, message)
+}
+
+// NotifyError logs err and queues a user friendly description of it. The
+// context describes what failed, e.g. "Unable to add student".
+func (v *State) NotifyError(context string, err error) {
+	v.log(Error, fmt.Sprintf("%s: %v", context, err))
+	v.queue(Error, context+". "+Message(err))
+}
+
+// log writes message to the log if it is severe enough.
+func (v *State) log(severity Severity, message string) {
+	if severity >= v.logLevel {
+		log.Print(message)
+	}
+}
+
+// queue appends a notification to the queue.
+func (v *State) queue(severity Severity, message string) {
+	v.lastNotificationID++
+	v.notifications = append(v.notifications, Notification{
+		ID:       v.lastNotificationID,
+		Severity: severity,
+		Message:  message,
+		Posted:   time.Now(),
+	})
+}
+
+// Notifications returns the queued notifications, oldest first.
+func (v *State) Notifications() []Notification {
+	return v.notifications
+}
+
+// Dismiss removes a notification from the queue. Unknown IDs are ignored.
+func (v *State) Dismiss(id int) {
+	for i, n := range v.notifications {
+		if n.ID == id {
+			v.notifications = append(v.notifications[:i:i], v.notifications[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package state
+
+import (
+	"eklase/storage"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// SchoolYears returns every school year, oldest first.
+func (h *State) SchoolYears() ([]storage.SchoolYearEntry, error) {
+	return h.storage.SchoolYears()
+}
+
+// SchoolYear returns a single school year.
+func (h *State) SchoolYear(id int) (storage.SchoolYearEntry, error) {
+	return h.storage.SchoolYear(id)
+}
+
+// AddSchoolYear adds a school year and returns its ID.
+func (v *State) AddSchoolYear(y storage.SchoolYearEntry) (int, error) {
+	if err := v.requireAdmin("add school year"); err != nil {
+		return 0, err
+	}
+	id, err := v.storage.AddSchoolYear(y)
+	return id, v.added(err, "add school year", "school_years", id, v.storage.DeleteSchoolYear, func() (int, error) {
+		return v.storage.AddSchoolYear(y)
+	})
+}
+
+// DeleteSchoolYear removes a school year that has no classes, together with
+// its terms.
+func (v *State) DeleteSchoolYear(id int) error {
+	if err := v.requireAdmin("delete school year"); err != nil {
+		return err
+	}
+	y, err := v.storage.SchoolYear(id)
+	if err != nil {
+		return err
+	}
+	terms, err := v.storage.Terms(id)
+	if err != nil {
+		return err
+	}
+	cells := make([]*int, len(terms)) // Of the terms.
+	for i, t := range terms {
+		cells[i] = v.history.ref("terms", t.ID)
+	}
+	remove := func(id int) error {
+		if err := v.storage.DeleteSchoolYear(id); err != nil {
+			return err
+		}
+		for _, cell := range cells {
+			delete(v.history.ids, ref{"terms", *cell})
+		}
+		return nil
+	}
+	return v.removed(remove(id), "delete school year", "school_years", id, remove, func() (int, error) {
+		id, err := v.storage.AddSchoolYear(y)
+		if err != nil {
+			return 0, err
+		}
+		for i, t := range terms {
+			t.SchoolYearID = id
+			termID, err := v.storage.AddTerm(t)
+			if err != nil {
+				return 0, err
+			}
+			v.history.rebind("terms", cells[i], termID)
+		}
+		return id, nil
+	})
+}
+
+// Terms returns the terms of a school year in order.
+func (h *State) Terms(schoolYearID int) ([]storage.TermEntry, error) {
+	return h.storage.Terms(schoolYearID)
+}
+
+// AddTerm adds a term to a school year and returns its ID.
+func (v *State) AddTerm(t storage.TermEntry) (int, error) {
+	if err := v.requireAdmin("add term"); err != nil {
+		return 0, err
+	}
+	add := v.termOf(t)
+	id, err := v.storage.AddTerm(t)
+	return id, v.added(err, "add term", "terms", id, v.storage.DeleteTerm, func() (int, error) {
+		return v.storage.AddTerm(add())
+	})
+}
+
+// DeleteTerm removes a term.
+func (v *State) DeleteTerm(id int) error {
+	if err := v.requireAdmin("delete term"); err != nil {
+		return err
+	}
+	years, err := v.storage.SchoolYears()
+	if err != nil {
+		return err
+	}
+	var add func() storage.TermEntry
+	for _, y := range years {
+		terms, err := v.storage.Terms(y.ID)
+		if err != nil {
+			return err
+		}
+		for _, t := range terms {
+			if t.ID == id {
+				add = v.termOf(t)
+			}
+		}
+	}
+	if add == nil {
+		return &storage.Error{Op: fmt.Sprintf("delete term %d", id), Kind: storage.ErrNotFound}
+	}
+	return v.removed(v.storage.DeleteTerm(id), "delete term", "terms", id, v.storage.DeleteTerm, func() (int, error) {
+		return v.storage.AddTerm(add())
+	})
+}
+
+// termOf returns a function returning t with the current ID of its school
+// year.
+func (v *State) termOf(t storage.TermEntry) func() storage.TermEntry {
+	year := v.history.ref("school_years", t.SchoolYearID)
+	return func() storage.TermEntry {
+		t.SchoolYearID = *year
+		return t
+	}
+}
+
+// Promote moves the students of a school year into the next grade of a later
+// school year and returns the ID of the promotion, which can be undone.
+func (v *State) Promote(fromYearID, toYearID int) (int, error) {
+	if err := v.requireAdmin("promote"); err != nil {
+		return 0, err
+	}
+	from, to := v.history.ref("school_years", fromYearID), v.history.ref("school_years", toYearID)
+	id, err := v.storage.Promote(fromYearID, toYearID)
+	return id, v.added(err, "promote", "promotions", id, v.storage.UndoPromotion, func() (int, error) {
+		return v.storage.Promote(*from, *to)
+	})
+}
+
+// Promotions returns every promotion, oldest first.
+func (h *State) Promotions() ([]storage.PromotionEntry, error) {
+	return h.storage.Promotions()
+}
+
+// UndoPromotion reverts the latest promotion.
+func (v *State) UndoPromotion(id int) error {
+	if err := v.requireAdmin("undo promotion"); err != nil {
+		return err
+	}
+	promotions, err := v.storage.Promotions()
+	if err != nil {
+		return err
+	}
+	var promote func() (int, error)
+	for _, p := range promotions {
+		if p.ID == id {
+			from, to := v.history.ref("school_years", p.FromYearID), v.history.ref("school_years", p.ToYearID)
+			promote = func() (int, error) { return v.storage.Promote(*from, *to) }
+		}
+	}
+	if promote == nil {
+		return &storage.Error{Op: fmt.Sprintf("undo promotion %d", id), Kind: storage.ErrNotFound}
+	}
+	return v.removed(v.storage.UndoPromotion(id), "undo promotion", "promotions", id, v.storage.UndoPromotion, promote)
+}
+
+// Graduates returns the students who finished school.
+func (h *State) Graduates() ([]storage.GraduateEntry, error) {
+	return h.storage.Graduates()
+}
+
+// PromotionStep describes what a promotion will do with a class.
+type PromotionStep struct {
+	Class    storage.ClassEntry
+	Next     string // Grade of the class in the next school year, empty if its students graduate.
+	Students int    // Number of enrolled students.
+	Exists   bool   // Whether the class of the next grade exists already.
+}
+
+// PromotionPlan returns what promoting the school year fromYearID into
+// toYearID would do with each of its classes, in the order of the classes.
+func (h *State) PromotionPlan(fromYearID, toYearID int) ([]PromotionStep, error) {
+	classes, err := h.storage.Classes()
+	if err != nil {
+		return nil, err
+	}
+	groups, err := h.storage.Groups()
+	if err != nil {
+		return nil, err
+	}
+	students := make(map[int]int) // By class ID.
+	for _, g := range groups {
+		if g.ClassID.Valid {
+			students[int(g.ClassID.Int64)]++
+		}
+	}
+	existing := make(map[string]bool) // By year and modifier, e.g. "6.a".
+	for _, c := range classes {
+		if inSchoolYear(c, toYearID) {
+			existing[c.Year+"."+c.Modifier] = true
+		}
+	}
+
+	var steps []PromotionStep
+	for _, c := range classes {
+		if !inSchoolYear(c, fromYearID) {
+			continue
+		}
+		step := PromotionStep{Class: c, Students: students[c.ID]}
+		if y, _ := strconv.Atoi(c.Year); y < storage.FinalYear {
+			step.Next = strconv.Itoa(y + 1)
+			step.Exists = existing[step.Next+"."+c.Modifier]
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// inSchoolYear reports whether class c belongs to the school year.
+func inSchoolYear(c storage.ClassEntry, schoolYearID int) bool {
+	return c.SchoolYearID.Valid && int(c.SchoolYearID.Int64) == schoolYearID
+}
+
+// NextSchoolYear returns the school year following y, e.g. 2026/2027 with
+// the same days of the year for 2025/2026. Its ID is not set.
+func NextSchoolYear(y storage.SchoolYearEntry) storage.SchoolYearEntry {
+	var start int
+	fmt.Sscanf(y.Name, "%d/", &start)
+	return storage.SchoolYearEntry{
+		Name:   fmt.Sprintf("%d/%d", start+1, start+2),
+		Starts: shiftYear(y.Starts),
+		Ends:   shiftYear(y.Ends),
+	}
+}
+
+// shiftYear moves a date like "2025-09-01" one year later. Invalid dates are
+// returned unchanged.
+func shiftYear(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.AddDate(1, 0, 0).Format("2006-01-02")
+}
@@ -1,20 +1,59 @@
 package state
 
 import (
+	"database/sql"
+	"sort"
+
+	"eklase/grading"
 	"eklase/storage"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
 )
 
 // State is the application context (aka state). It provides access to the
 // features that do not depend on implementation e.g. (T)UI framework.
 type State struct {
-	storage *storage.Storage // Provides DB access.
+	storage storage.Storage // Provides DB access.
+
+	revision int      // Incremented by every successful change of the data.
+	session  *Session // Who is logged in, nil if nobody is.
+	history  history  // Changes of the session that can be undone.
+
+	rules    grading.Rules     // Rules final grades are proposed by.
+	collator *collate.Collator // Orders names in the language of the user.
+	logLevel Severity          // Least severe notifications that are logged.
+
+	notifications      []Notification // Queued messages for the user.
+	lastNotificationID int            // ID of the last queued notification.
 
 	quit bool // True if the application should exit.
 }
 
-// New returns a new state handler. Returns an error if any of the steps fails.
-func New(s *storage.Storage) *State {
-	return &State{storage: s}
+// New returns a new state handler. It starts with the System session, the
+// window logs out and asks for a login instead.
+func New(s storage.Storage) *State {
+	v := &State{
+		storage:  s,
+		rules:    grading.DefaultRules(),
+		collator: collate.New(language.Latvian),
+		logLevel: Error,
+	}
+	system := System
+	v.setSession(&system)
+	return v
+}
+
+// SetLocale sets the language names are ordered in, e.g. "lv", where Č
+// follows C instead of Z. Latvian is used by default.
+func (v *State) SetLocale(locale string) error {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return &storage.ValidationError{Field: "locale", Reason: "must be a language like lv or en"}
+	}
+	v.collator = collate.New(tag)
+	v.bump()
+	return nil
 }
 
 // Students returns students stored in the database.
@@ -30,17 +69,296 @@ func (h *State) Groups() ([]storage.GroupEntry, error) {
 	return h.storage.Groups()
 }
 
-// AddStudent adds a student to the database.
-func (v *State) AddStudent(name, surname string) error {
-	return v.storage.AddStudent(name, surname)
+// SearchStudents returns the students matching q, see storage.Query.
+func (h *State) SearchStudents(q storage.Query) ([]storage.StudentEntry, error) {
+	return h.storage.SearchStudents(q)
+}
+
+// SearchClasses returns the classes matching q.
+func (h *State) SearchClasses(q storage.Query) ([]storage.ClassEntry, error) {
+	return h.storage.SearchClasses(q)
+}
+
+// SearchGroups returns the students with their classes matching q.
+func (h *State) SearchGroups(q storage.Query) ([]storage.GroupEntry, error) {
+	return h.storage.SearchGroups(q)
+}
+
+// ClassStudents returns the students enrolled in a class ordered by surname
+// and name in the language set by SetLocale.
+func (h *State) ClassStudents(classID int) ([]storage.GroupEntry, error) {
+	groups, err := h.storage.Groups()
+	if err != nil {
+		return nil, err
+	}
+	var students []storage.GroupEntry
+	for _, g := range groups {
+		if g.ClassID.Valid && int(g.ClassID.Int64) == classID {
+			students = append(students, g)
+		}
+	}
+	sort.SliceStable(students, func(i, j int) bool {
+		if c := h.collator.CompareString(students[i].Surname, students[j].Surname); c != 0 {
+			return c < 0
+		}
+		return h.collator.CompareString(students[i].Name, students[j].Name) < 0
+	})
+	return students, nil
+}
+
+// Student returns a single student by its ID.
+func (h *State) Student(id int) (storage.StudentEntry, error) {
+	return h.storage.Student(id)
+}
+
+// Class returns a single class by its ID.
+func (h *State) Class(id int) (storage.ClassEntry, error) {
+	return h.storage.Class(id)
+}
+
+// Group returns the class membership of a single student.
+func (h *State) Group(studentID int) (storage.GroupEntry, error) {
+	return h.storage.Group(studentID)
+}
+
+// AddStudent adds a student to the database and returns its ID.
+func (v *State) AddStudent(name, surname string) (int, error) {
+	if err := v.requireAdmin("add student"); err != nil {
+		return 0, err
+	}
+	id, err := v.storage.AddStudent(name, surname)
+	return id, v.added(err, "add student", "students", id, v.storage.DeleteStudent, func() (int, error) {
+		return v.storage.AddStudent(name, surname)
+	})
+}
+
+// UpdateStudent renames an existing student.
+func (v *State) UpdateStudent(id int, name, surname string) error {
+	if err := v.requireAdmin("update student"); err != nil {
+		return err
+	}
+	old, err := v.storage.Student(id)
+	if err != nil {
+		return err
+	}
+	student := v.history.ref("students", id)
+	return v.done(v.storage.UpdateStudent(id, name, surname), command{
+		name: "update student",
+		undo: func() error { return v.storage.UpdateStudent(*student, old.Name, old.Surname) },
+		redo: func() error { return v.storage.UpdateStudent(*student, name, surname) },
+	})
+}
+
+// DeleteStudent removes a student together with their class membership,
+// grades, attendance and final grades. Undoing it brings them back, except
+// for the corrections of the grades and the promotions of the student.
+func (v *State) DeleteStudent(id int) error {
+	if err := v.requireAdmin("delete student"); err != nil {
+		return err
+	}
+	remove, restore, err := v.studentRemoval(id)
+	if err != nil {
+		return err
+	}
+	return v.removed(remove(id), "delete student", "students", id, remove, restore)
+}
+
+// studentRemoval returns functions removing a student and restoring them with
+// the class membership, grades, attendance and final grades they have now.
+func (v *State) studentRemoval(id int) (func(id int) error, func() (int, error), error) {
+	s, err := v.storage.Student(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	g, err := v.storage.Group(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	grades, err := v.storage.Grades(storage.GradeFilter{StudentID: id})
+	if err != nil {
+		return nil, nil, err
+	}
+	attendance, err := v.storage.StudentAttendance(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	finals, err := v.storage.FinalGrades(storage.FinalGradeFilter{StudentID: id})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var class *int // Nil if the student is not enrolled.
+	if g.ClassID.Valid {
+		class = v.history.ref("classes", int(g.ClassID.Int64))
+	}
+	cells := make([]*int, len(grades)) // Of the grades.
+	adds := make([]func() storage.GradeChange, len(grades))
+	for i, g := range grades {
+		cells[i], adds[i] = v.history.ref("grades", g.ID), v.gradeOf(g)
+	}
+	finalOf := make([]func() storage.FinalGradeEntry, len(finals))
+	for i, f := range finals {
+		finalOf[i] = v.finalOf(f)
+	}
+
+	remove := func(id int) error {
+		if err := v.storage.DeleteStudent(id); err != nil {
+			return err
+		}
+		for _, cell := range cells {
+			delete(v.history.ids, ref{"grades", *cell})
+		}
+		return nil
+	}
+	restore := func() (int, error) {
+		r := storage.StudentRecord{Student: s, Attendance: attendance}
+		if class != nil {
+			r.ClassID = sql.NullInt64{Int64: int64(*class), Valid: true}
+		}
+		for _, add := range adds {
+			c := add()
+			r.Grades = append(r.Grades, storage.GradeEntry{
+				SubjectID: c.SubjectID,
+				TeacherID: c.TeacherID,
+				Date:      c.Date,
+				Kind:      c.Kind,
+				Mark:      c.Mark,
+			})
+		}
+		for _, f := range finalOf {
+			r.Finals = append(r.Finals, f())
+		}
+		id, ids, err := v.storage.RestoreStudent(r)
+		if err != nil {
+			return 0, err
+		}
+		for i, cell := range cells {
+			v.history.rebind("grades", cell, ids[i])
+		}
+		return id, nil
+	}
+	return remove, restore, nil
+}
+
+// AddClass adds a class to the database and returns its ID.
+func (v *State) AddClass(year, modifier string) (int, error) {
+	if err := v.requireAdmin("add class"); err != nil {
+		return 0, err
+	}
+	id, err := v.storage.AddClass(year, modifier)
+	return id, v.added(err, "add class", "classes", id, v.storage.DeleteClass, func() (int, error) {
+		return v.storage.AddClass(year, modifier)
+	})
+}
+
+// UpdateClass changes the year and modifier of an existing class.
+func (v *State) UpdateClass(id int, year, modifier string) error {
+	if err := v.requireAdmin("update class"); err != nil {
+		return err
+	}
+	old, err := v.storage.Class(id)
+	if err != nil {
+		return err
+	}
+	class := v.history.ref("classes", id)
+	return v.done(v.storage.UpdateClass(id, year, modifier), command{
+		name: "update class",
+		undo: func() error { return v.storage.UpdateClass(*class, old.Year, old.Modifier) },
+		redo: func() error { return v.storage.UpdateClass(*class, year, modifier) },
+	})
+}
+
+// DeleteClass removes a class that has no students.
+func (v *State) DeleteClass(id int) error {
+	if err := v.requireAdmin("delete class"); err != nil {
+		return err
+	}
+	old, err := v.storage.Class(id)
+	if err != nil {
+		return err
+	}
+	var year, teacher *int
+	if old.SchoolYearID.Valid {
+		year = v.history.ref("school_years", int(old.SchoolYearID.Int64))
+	}
+	if old.TeacherID.Valid {
+		teacher = v.history.ref("teachers", int(old.TeacherID.Int64))
+	}
+	return v.removed(v.storage.DeleteClass(id), "delete class", "classes", id, v.storage.DeleteClass, func() (int, error) {
+		id, err := v.storage.AddClass(old.Year, old.Modifier)
+		if err != nil {
+			return 0, err
+		}
+		// Classes are added to the latest school year, which the class may
+		// not have been in.
+		c, err := v.storage.Class(id)
+		if err == nil && (c.SchoolYearID.Valid != (year != nil) || year != nil && c.SchoolYearID.Int64 != int64(*year)) {
+			err = &storage.Error{Op: "restore class", Kind: storage.ErrConstraint}
+		}
+		if err == nil && teacher != nil {
+			err = v.storage.SetClassTeacher(id, sql.NullInt64{Int64: int64(*teacher), Valid: true})
+		}
+		if err != nil {
+			v.storage.DeleteClass(id)
+			return 0, err
+		}
+		return id, nil
+	})
+}
+
+// AssignClassToStudent enrolls a student into an existing class.
+func (v *State) AssignClassToStudent(studentID, classID int) error {
+	if err := v.requireAdmin("enroll student"); err != nil {
+		return err
+	}
+	undo, err := v.reenroll(studentID)
+	if err != nil {
+		return err
+	}
+	student, class := v.history.ref("students", studentID), v.history.ref("classes", classID)
+	return v.done(v.storage.AssignClassToStudent(studentID, classID), command{
+		name: "enroll student",
+		undo: undo,
+		redo: func() error { return v.storage.AssignClassToStudent(*student, *class) },
+	})
+}
+
+// UnassignClassFromStudent removes a student from their class.
+func (v *State) UnassignClassFromStudent(studentID int) error {
+	if err := v.requireAdmin("unenroll student"); err != nil {
+		return err
+	}
+	undo, err := v.reenroll(studentID)
+	if err != nil {
+		return err
+	}
+	student := v.history.ref("students", studentID)
+	return v.done(v.storage.UnassignClassFromStudent(studentID), command{
+		name: "unenroll student",
+		undo: undo,
+		redo: func() error { return v.storage.UnassignClassFromStudent(*student) },
+	})
 }
 
-func (v *State) AddClass(year, modifier string) error {
-	return v.storage.AddClass(year, modifier)
+// reenroll returns a function restoring the current class membership of a
+// student, to undo changing it.
+func (v *State) reenroll(studentID int) (func() error, error) {
+	g, err := v.storage.Group(studentID)
+	if err != nil {
+		return nil, err
+	}
+	student := v.history.ref("students", studentID)
+	if !g.ClassID.Valid {
+		return func() error { return v.storage.UnassignClassFromStudent(*student) }, nil
+	}
+	class := v.history.ref("classes", int(g.ClassID.Int64))
+	return func() error { return v.storage.AssignClassToStudent(*student, *class) }, nil
 }
 
-func (v *State) AssignClassToStudent(year, modifier string, student_id int) error {
-	return v.storage.AssignClassToStudent(year, modifier, student_id)
+// Revision identifies the current version of the data. It changes whenever
+// the data is modified through the state, so screens can tell when to reload.
+func (v *State) Revision() int {
+	return v.revision
 }
 
 // Quit requests quitting the application.
@@ -0,0 +1,740 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"eklase/grading"
+	"eklase/storage"
+)
+
+func TestAssignClassToStudent(t *testing.T) {
+	s := New(storage.NewMemory())
+	studentID, err := s.AddStudent("Anna", "Bērziņa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	classID, err := s.AddClass("5", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.AssignClassToStudent(studentID, classID); err != nil {
+		t.Fatalf("AssignClassToStudent() failed: %v", err)
+	}
+	g, err := s.Group(studentID)
+	if err != nil {
+		t.Fatalf("Group() failed: %v", err)
+	}
+	if g.Year.String != "5" || g.Modifier.String != "a" {
+		t.Errorf("student is in %s.%s, want 5.a", g.Year.String, g.Modifier.String)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	s := New(storage.NewMemory())
+	if _, err := s.AddClass("5", "a"); err != nil {
+		t.Fatal(err)
+	}
+	_, dupErr := s.AddClass("5", "a")
+	_, validationErr := s.AddStudent("", "Ozols")
+	s.AddTeacher("Ilze", "Kalniņa")
+	s.AddSubject("Matemātika")
+	slot := storage.SlotEntry{ClassID: 1, Weekday: 1, Lesson: 1, SubjectID: 1, TeacherID: 1}
+	if _, err := s.AddSlot(slot); err != nil {
+		t.Fatal(err)
+	}
+	_, conflictErr := s.AddSlot(slot)
+
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{dupErr, "Such an entry exists already."},
+		{validationErr, "The name must not be empty."},
+		{s.DeleteClass(42), "The entry does not exist anymore."},
+		{conflictErr, "The class is busy already: 5.a has Matemātika with Ilze Kalniņa at that time."},
+		{forbidden("add class"), "You are not allowed to do that."},
+	} {
+		if got := Message(tc.err); got != tc.want {
+			t.Errorf("Message(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	s := New(storage.NewMemory())
+	s.Notify(Info, "Saved.")
+	_, err := s.AddStudent("", "Ozols")
+	s.NotifyError("Unable to add student", err)
+
+	got := s.Notifications()
+	if len(got) != 2 {
+		t.Fatalf("got %d notifications, want 2", len(got))
+	}
+	if got[1].Severity != Error || got[1].Message != "Unable to add student. The name must not be empty." {
+		t.Errorf("second notification = %+v", got[1])
+	}
+
+	s.Dismiss(got[0].ID)
+	s.Dismiss(42)
+	if got := s.Notifications(); len(got) != 1 || got[0].Severity != Error {
+		t.Errorf("Notifications() after Dismiss = %+v, want the error only", got)
+	}
+}
+
+func TestSchoolYearOf(t *testing.T) {
+	for _, tc := range []struct {
+		date time.Time
+		want string
+	}{
+		{time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC), "2025/2026"},
+		{time.Date(2026, time.August, 31, 0, 0, 0, 0, time.UTC), "2025/2026"},
+		{time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC), "2025/2026"},
+	} {
+		if got := SchoolYearOf(tc.date); got != tc.want {
+			t.Errorf("SchoolYearOf(%v) = %q, want %q", tc.date, got, tc.want)
+		}
+	}
+}
+
+func TestNextPresenceCycles(t *testing.T) {
+	e := storage.AttendanceEntry{StudentID: 1, Date: "2025-10-01", Lesson: 1, Status: storage.Present}
+	var got []string
+	for i := 0; i < 5; i++ {
+		e = NextPresence(e)
+		got = append(got, string(e.Status)+" "+string(e.Reason))
+	}
+	want := []string{"absent ", "late ", "excused illness", "excused family", "present "}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("NextPresence() cycle = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestUpcomingHomework(t *testing.T) {
+	s := New(storage.NewMemory())
+	classID, _ := s.AddClass("5", "a")
+	subjectID, _ := s.AddSubject("Matemātika")
+	for _, due := range []string{"2025-09-30", "2025-10-01"} {
+		l := storage.LessonEntry{ClassID: classID, SubjectID: subjectID, Date: "2025-09-29", Topic: "Daļskaitļi", Homework: "12. lpp.", DueDate: due}
+		if _, err := s.AddLesson(l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Homework due on the day itself is still upcoming.
+	today := time.Date(2025, time.October, 1, 18, 0, 0, 0, time.Local)
+	homework, err := s.UpcomingHomework(classID, today)
+	if err != nil {
+		t.Fatalf("UpcomingHomework() failed: %v", err)
+	}
+	if len(homework) != 1 || homework[0].DueDate != "2025-10-01" {
+		t.Errorf("UpcomingHomework() = %+v, want the homework due on 2025-10-01", homework)
+	}
+}
+
+func TestNextSchoolYear(t *testing.T) {
+	y := storage.SchoolYearEntry{ID: 1, Name: "2025/2026", Starts: "2025-09-01", Ends: "2026-08-31"}
+	want := storage.SchoolYearEntry{Name: "2026/2027", Starts: "2026-09-01", Ends: "2027-08-31"}
+	if got := NextSchoolYear(y); got != want {
+		t.Errorf("NextSchoolYear(%+v) = %+v, want %+v", y, got, want)
+	}
+}
+
+func TestPromotionPlan(t *testing.T) {
+	s := New(storage.NewMemory())
+	from, _ := s.AddSchoolYear(storage.SchoolYearEntry{Name: "2025/2026", Starts: "2025-09-01", Ends: "2026-08-31"})
+	fiveA, _ := s.AddClass("5", "a")
+	s.AddClass("12", "b")
+	studentID, _ := s.AddStudent("Anna", "Bērziņa")
+	if err := s.AssignClassToStudent(studentID, fiveA); err != nil {
+		t.Fatal(err)
+	}
+	to, _ := s.AddSchoolYear(NextSchoolYear(storage.SchoolYearEntry{Name: "2025/2026", Starts: "2025-09-01", Ends: "2026-08-31"}))
+	s.AddClass("6", "a")
+
+	steps, err := s.PromotionPlan(from, to)
+	if err != nil {
+		t.Fatalf("PromotionPlan() failed: %v", err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("PromotionPlan() = %+v, want 2 steps", steps)
+	}
+	if st := steps[0]; st.Class.ID != fiveA || st.Next != "6" || st.Students != 1 || !st.Exists {
+		t.Errorf("step of 5.a = %+v, want 1 student into the existing 6.a", st)
+	}
+	if st := steps[1]; st.Next != "" || st.Students != 0 {
+		t.Errorf("step of 12.b = %+v, want graduation", st)
+	}
+}
+
+func TestFinalGradeSheet(t *testing.T) {
+	s := New(storage.NewMemory())
+	year, _ := s.AddSchoolYear(storage.SchoolYearEntry{Name: "2025/2026", Starts: "2025-09-01", Ends: "2026-08-31"})
+	term, _ := s.AddTerm(storage.TermEntry{SchoolYearID: year, Name: "1. semestris", Starts: "2025-09-01", Ends: "2025-12-31"})
+	classID, _ := s.AddClass("5", "a")
+	teacherID, _ := s.AddTeacher("Ilze", "Kalniņa")
+	subjectID, _ := s.AddSubject("Matemātika")
+	anna, _ := s.AddStudent("Anna", "Bērziņa")
+	janis, _ := s.AddStudent("Jānis", "Ozols")
+	for _, id := range []int{anna, janis} {
+		if err := s.AssignClassToStudent(id, classID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SaveGrades([]storage.GradeChange{
+		{StudentID: anna, SubjectID: subjectID, TeacherID: teacherID, Date: "2025-10-01", Mark: "6"},
+		{StudentID: anna, SubjectID: subjectID, TeacherID: teacherID, Date: "2025-11-01", Kind: storage.Test, Mark: "9"},
+		{StudentID: anna, SubjectID: subjectID, TeacherID: teacherID, Date: "2026-02-01", Mark: "2"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := s.FinalGradeSheet(classID, subjectID, year, term)
+	if err != nil {
+		t.Fatalf("FinalGradeSheet() failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("FinalGradeSheet() = %+v, want 2 rows", rows)
+	}
+	// (6 + 2×9) / 3 = 8, the grade of February is in the next term.
+	if p := rows[0].Proposal; rows[0].Student.StudentID != anna || p.Average != 8 || p.Mark != "8" {
+		t.Errorf("proposal of Anna = %+v, want 8", p)
+	}
+	if p := rows[1].Proposal; p.StudentID != janis || p.SubjectID != subjectID || p.Mark != storage.NotGraded {
+		t.Errorf("proposal of Jānis = %+v, want nv", p)
+	}
+
+	if err := s.FinalizeGrades([]storage.FinalGradeEntry{
+		rows[0].Finalize("9", "Olimpiādes uzvarētāja"),
+	}); err != nil {
+		t.Fatalf("FinalizeGrades() failed: %v", err)
+	}
+	if rows, err = s.FinalGradeSheet(classID, subjectID, year, term); err != nil {
+		t.Fatal(err)
+	}
+	if f := rows[0].Final; f.ID == 0 || f.Mark != "9" || f.Proposed != "8" {
+		t.Errorf("final grade of Anna = %+v, want 9 overriding 8", f)
+	}
+	// The whole year is a different period: (6 + 2×9 + 2) / 4 = 6.5.
+	if rows, err = s.FinalGradeSheet(classID, subjectID, year, 0); err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].Final.ID != 0 || rows[0].Proposal.Mark != "7" {
+		t.Errorf("year row of Anna = %+v, want a proposal of 7 and no final grade", rows[0])
+	}
+}
+
+func TestClassStudentsLocale(t *testing.T) {
+	s := New(storage.NewMemory())
+	classID, _ := s.AddClass("5", "a")
+	for _, surname := range []string{"Zariņa", "Čakste", "Cīrulis"} {
+		id, _ := s.AddStudent("Anna", surname)
+		if err := s.AssignClassToStudent(id, classID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	surnames := func() string {
+		t.Helper()
+		students, err := s.ClassStudents(classID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, st := range students {
+			got = append(got, st.Surname)
+		}
+		return strings.Join(got, " ")
+	}
+	if got, want := surnames(), "Cīrulis Čakste Zariņa"; got != want {
+		t.Errorf("ClassStudents() in Latvian = %s, want %s", got, want)
+	}
+	if err := s.SetLocale("x-not a locale"); err == nil {
+		t.Error("SetLocale() of a bad locale succeeded, want an error")
+	}
+}
+
+func TestPreviewImport(t *testing.T) {
+	s := New(storage.NewMemory())
+	if _, err := s.AddStudent("Anna", "Zariņa"); err != nil {
+		t.Fatal(err)
+	}
+	file := "\xef\xbb\xbfVārds;Uzvārds;Grupa;Piezīmes\n" +
+		"Jānis;Bērziņš;5.a;\n" +
+		"anna;zariņa;5a;exists\n" +
+		"Jānis;Bērziņš;6.b;twice\n" +
+		";;;\n" +
+		"Līga;Kalna1;5.a;\n" +
+		"Pēteris;Ozols;a5;\n" +
+		"\"Marta\nMarija\";Liepa;;\n" +
+		"Juris;Egle\n"
+	report, err := s.PreviewImport(strings.NewReader(file), CSVOptions{
+		Delimiter: ';',
+		Columns:   map[string]ImportField{"grupa": FieldClass},
+	})
+	if err != nil {
+		t.Fatalf("PreviewImport() failed: %v", err)
+	}
+	var got []string
+	for _, r := range report.Rows {
+		got = append(got, fmt.Sprintf("%d:%s:%s", r.Line, r.Entry.Name, r.Status))
+	}
+	want := []string{
+		"2:Jānis:new", "3:anna:duplicate", "4:Jānis:duplicate", "6:Līga:invalid",
+		"7:Pēteris:invalid", "8:Marta\nMarija:invalid", "10:Juris:new",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("PreviewImport() rows = %q, want %q", got, want)
+	}
+	if report.New != 2 || report.Duplicates != 2 || report.Invalid != 3 {
+		t.Errorf("PreviewImport() counts %d, %d, %d; want 2, 2, 3", report.New, report.Duplicates, report.Invalid)
+	}
+	if e := report.Rows[0].Entry; e.Year != "5" || e.Modifier != "a" {
+		t.Errorf("class of %q = %s.%s, want 5.a", report.Rows[0].Class, e.Year, e.Modifier)
+	}
+	if fmt.Sprint(report.Classes) != "[5.a]" {
+		t.Errorf("PreviewImport() classes = %q, want [5.a]", report.Classes)
+	}
+
+	n, err := s.Import(report)
+	if err != nil || n != 2 {
+		t.Fatalf("Import() = %d, %v; want 2 students", n, err)
+	}
+	if students, _ := s.ClassStudents(1); len(students) != 1 || students[0].Name != "Jānis" {
+		t.Errorf("class 5.a after the import = %+v, want Jānis", students)
+	}
+
+	// The existing class is found regardless of case.
+	report, err = s.PreviewImport(strings.NewReader("name,surname,class\nLīga,Kalna,5.A\nMarta,Liepa,6.b\n"), CSVOptions{})
+	if err != nil {
+		t.Fatalf("PreviewImport() failed: %v", err)
+	}
+	if fmt.Sprint(report.Classes) != "[6.b]" {
+		t.Errorf("PreviewImport() classes = %q, want [6.b]", report.Classes)
+	}
+}
+
+func TestPreviewImportErrors(t *testing.T) {
+	s := New(storage.NewMemory())
+	for file, want := range map[string]string{
+		"":                         "The file is empty.",
+		"Name,Class\nAnna,5.a\n":   "The header has no surname column.",
+		"Name,Vārds,Surname\n":     "The header names the name column twice.",
+		"Name,Surname\n\"Anna,x\n": "The file is not valid CSV on line 2.",
+	} {
+		_, err := s.PreviewImport(strings.NewReader(file), CSVOptions{})
+		if Message(err) != want {
+			t.Errorf("PreviewImport(%q) = %q, want %q", file, Message(err), want)
+		}
+	}
+}
+
+func TestLogin(t *testing.T) {
+	s := New(storage.NewMemory())
+	s.Logout()
+	if _, ok := s.Session(); ok {
+		t.Fatal("Session() after Logout() is set, want nobody")
+	}
+	if _, err := s.AddStudent("Anna", "Bērziņa"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("AddStudent() without a session = %v, want %v", err, ErrForbidden)
+	}
+	if err := s.Setup("admin", "short"); !errors.Is(err, storage.ErrValidation) {
+		t.Errorf("Setup() with a short password = %v, want %v", err, storage.ErrValidation)
+	}
+	if err := s.Setup("admin", "correct horse"); err != nil {
+		t.Fatalf("Setup() failed: %v", err)
+	}
+	if err := s.Setup("other", "correct horse"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("second Setup() = %v, want %v", err, ErrForbidden)
+	}
+	s.Logout()
+
+	for _, tc := range []struct{ username, password string }{
+		{"admin", "wrong horse"},
+		{"nobody", "correct horse"},
+	} {
+		if err := s.Login(tc.username, tc.password); !errors.Is(err, ErrLogin) {
+			t.Errorf("Login(%s, %s) = %v, want %v", tc.username, tc.password, err, ErrLogin)
+		}
+	}
+	if err := s.Login("ADMIN", "correct horse"); err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	session, ok := s.Session()
+	if !ok || session.Username != "admin" || session.Role != storage.RoleAdmin {
+		t.Errorf("Session() = %+v, %t; want the admin", session, ok)
+	}
+	users, err := s.Users()
+	if err != nil || len(users) != 1 || users[0].PasswordHash == "correct horse" {
+		t.Errorf("Users() = %+v, %v; want one user with a hashed password", users, err)
+	}
+	if err := s.DeleteUser(session.UserID); !errors.Is(err, storage.ErrValidation) {
+		t.Errorf("DeleteUser() of oneself = %v, want %v", err, storage.ErrValidation)
+	}
+	if err := s.UpdateUser(session.UserID, "admin", storage.RoleSecretary, 0); !errors.Is(err, storage.ErrValidation) {
+		t.Errorf("UpdateUser() of the last admin to a secretary = %v, want %v", err, storage.ErrValidation)
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	s := New(storage.NewMemory())
+	class, _ := s.AddClass("5", "a")
+	other, _ := s.AddClass("6", "b")
+	student, _ := s.AddStudent("Anna", "Bērziņa")
+	s.AssignClassToStudent(student, class)
+	outsider, _ := s.AddStudent("Jānis", "Ozols")
+	s.AssignClassToStudent(outsider, other)
+	teacher, _ := s.AddTeacher("Ilze", "Kalniņa")
+	homeroom, _ := s.AddTeacher("Pēteris", "Liepa")
+	s.SetClassTeacher(other, homeroom)
+	maths, _ := s.AddSubject("Matemātika")
+	music, _ := s.AddSubject("Mūzika")
+	if _, err := s.AddAssignment(teacher, maths, class); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []struct {
+		name    string
+		role    storage.Role
+		teacher int
+	}{
+		{"ilze", storage.RoleTeacher, teacher},
+		{"peteris", storage.RoleClassTeacher, homeroom},
+		{"sekretare", storage.RoleSecretary, 0},
+	} {
+		if _, err := s.AddUser(u.name, "password", u.role, u.teacher); err != nil {
+			t.Fatalf("AddUser(%s) failed: %v", u.name, err)
+		}
+	}
+	mathsGrade, err := s.AddGrade(student, maths, teacher, "2025-09-30", "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	musicGrade, err := s.AddGrade(student, music, homeroom, "2025-09-30", "8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	as := func(username string) {
+		t.Helper()
+		if err := s.Login(username, "password"); err != nil {
+			t.Fatalf("Login(%s) failed: %v", username, err)
+		}
+	}
+	check := func(what string, err error, allowed bool) {
+		t.Helper()
+		if allowed && err != nil {
+			t.Errorf("%s = %v, want it allowed", what, err)
+		}
+		if !allowed && !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s = %v, want %v", what, err, ErrForbidden)
+		}
+	}
+	attendance := func(studentID int) error {
+		return s.SetAttendance(storage.AttendanceEntry{StudentID: studentID, Date: "2025-09-30", Lesson: 1, Status: storage.Absent})
+	}
+
+	as("ilze")
+	_, err = s.AddGrade(student, maths, teacher, "2025-10-01", "8")
+	check("teacher AddGrade() in their subject", err, true)
+	check("teacher CorrectGrade() in their subject", s.CorrectGrade(mathsGrade, "9", "Pārrakstīts"), true)
+	_, err = s.AddGrade(student, music, teacher, "2025-10-01", "8")
+	check("teacher AddGrade() in another subject", err, false)
+	_, err = s.AddGrade(student, maths, homeroom, "2025-10-01", "8")
+	check("teacher AddGrade() in the name of another teacher", err, false)
+	_, err = s.AddGrade(outsider, maths, teacher, "2025-10-01", "8")
+	check("teacher AddGrade() in another class", err, false)
+	check("teacher DeleteGrade() in another subject", s.DeleteGrade(musicGrade), false)
+	check("teacher SaveGrades() in another subject", s.SaveGrades([]storage.GradeChange{{ID: musicGrade, Mark: "9", Reason: "x"}}), false)
+	check("teacher SetAttendance() in their class", attendance(student), true)
+	check("teacher SetAttendance() in another class", attendance(outsider), false)
+	_, err = s.AddStudent("Līga", "Kalna")
+	check("teacher AddStudent()", err, false)
+	_, err = s.Users()
+	check("teacher Users()", err, false)
+
+	as("peteris")
+	check("class teacher SetAttendance() in their homeroom", attendance(outsider), true)
+	check("class teacher SetAttendance() in another class", attendance(student), false)
+	_, err = s.AddLesson(storage.LessonEntry{ClassID: other, SubjectID: maths, Date: "2025-09-30", Topic: "Daļskaitļi"})
+	check("class teacher AddLesson() in a subject they do not teach", err, false)
+
+	as("sekretare")
+	_, err = s.AddGrade(student, maths, 0, "2025-10-01", "8")
+	check("secretary AddGrade()", err, false)
+	check("secretary UpdateStudent()", s.UpdateStudent(student, "Anna", "Ozola"), false)
+	check("secretary SetAttendance()", attendance(student), false)
+	if _, err := s.Students(); err != nil {
+		t.Errorf("secretary Students() = %v, want it allowed", err)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	s := New(storage.NewMemory())
+	if _, err := s.AddUser("anna", "password", storage.RoleAdmin, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddUser("sekretare", "password", storage.RoleSecretary, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Login("anna", "password"); err != nil {
+		t.Fatal(err)
+	}
+	id, err := s.AddStudent("Jānis", "Ozols")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := s.AuditLog(storage.AuditFilter{Entity: "students"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Actor != "anna" || entries[0].EntityID != id {
+		t.Errorf("AuditLog() = %+v, want the student added by anna", entries)
+	}
+	entries, _ = s.AuditLog(storage.AuditFilter{Actor: "system"})
+	if len(entries) != 2 {
+		t.Errorf("AuditLog() of system = %+v, want the two users", entries)
+	}
+
+	if err := s.Login("sekretare", "password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AuditLog(storage.AuditFilter{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("AuditLog() as a secretary returned %v, want ErrForbidden", err)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	s := New(storage.NewMemory())
+	class, _ := s.AddClass("5", "a")
+	other, _ := s.AddClass("6", "b")
+	student, _ := s.AddStudent("Anna", "Bērziņa")
+	if err := s.AssignClassToStudent(student, class); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AssignClassToStudent(student, other); err != nil {
+		t.Fatal(err)
+	}
+	if h := s.History(); len(h.Undo) != 5 || h.Undo[0] != "enroll student" || len(h.Redo) != 0 {
+		t.Errorf("History() = %+v, want five changes, the enrollment latest", h)
+	}
+
+	// Undoing the misclick returns the student to their previous class.
+	revision := s.Revision()
+	if name, err := s.Undo(); err != nil || name != "enroll student" {
+		t.Fatalf("Undo() = %q, %v, want enroll student", name, err)
+	}
+	if g, _ := s.Group(student); int(g.ClassID.Int64) != class {
+		t.Errorf("after Undo() the student is in class %v, want %d", g.ClassID, class)
+	}
+	if s.Revision() == revision {
+		t.Error("Undo() did not change the revision")
+	}
+
+	// Undoing the addition of the student removes them, redoing adds them
+	// anew with another ID, which the later changes follow.
+	for i := 0; i < 2; i++ {
+		if _, err := s.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if students, _ := s.Students(); len(students) != 0 {
+		t.Errorf("after undoing the addition Students() = %+v, want none", students)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := s.Redo(); err != nil {
+			t.Fatalf("Redo() #%d failed: %v", i+1, err)
+		}
+	}
+	groups, _ := s.Groups()
+	if len(groups) != 1 || groups[0].Name != "Anna" || int(groups[0].ClassID.Int64) != other {
+		t.Errorf("after Redo() Groups() = %+v, want Anna in class %d", groups, other)
+	}
+	if name, err := s.Redo(); name != "" || err != nil {
+		t.Errorf("Redo() with nothing undone = %q, %v, want nothing", name, err)
+	}
+	student = groups[0].StudentID
+
+	// A deletion is undone by adding the class anew, which the earlier
+	// changes follow.
+	if err := s.DeleteClass(class); err != nil {
+		t.Fatal(err)
+	}
+	if name, err := s.Undo(); err != nil || name != "delete class" {
+		t.Fatalf("Undo() = %q, %v, want delete class", name, err)
+	}
+	if name, err := s.Undo(); err != nil || name != "enroll student" {
+		t.Fatalf("Undo() = %q, %v, want enroll student", name, err)
+	}
+	g, _ := s.Group(student)
+	if g.Year.String != "5" || g.Modifier.String != "a" || int(g.ClassID.Int64) == class {
+		t.Errorf("after undoing the deletion Group() = %+v, want 5.a under a new ID", g)
+	}
+
+	// Configuration is not recorded and keeps the history, logging in as
+	// somebody else clears it.
+	if err := s.SetLocale("en"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetGradingRules(grading.DefaultRules()); err != nil {
+		t.Fatal(err)
+	}
+	if h := s.History(); len(h.Undo) != 4 || len(h.Redo) != 2 {
+		t.Errorf("after SetLocale() History() = %+v, want it kept", h)
+	}
+	if _, err := s.AddUser("anna", "password", storage.RoleAdmin, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Login("anna", "password"); err != nil {
+		t.Fatal(err)
+	}
+	if h := s.History(); len(h.Undo) != 0 {
+		t.Errorf("after Login() of another user History() = %+v, want it empty", h)
+	}
+	if name, err := s.Undo(); name != "" || err != nil {
+		t.Errorf("Undo() with an empty history = %q, %v, want nothing", name, err)
+	}
+
+	// Logging out drops the history, and nothing is undone without a
+	// session.
+	if err := s.DeleteStudent(student); err != nil {
+		t.Fatal(err)
+	}
+	s.Logout()
+	if _, err := s.Undo(); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Undo() after Logout() = %v, want %v", err, ErrForbidden)
+	}
+	if _, err := s.Redo(); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Redo() after Logout() = %v, want %v", err, ErrForbidden)
+	}
+	if err := s.Login("anna", "password"); err != nil {
+		t.Fatal(err)
+	}
+	if h := s.History(); len(h.Undo) != 0 {
+		t.Errorf("after logging in again History() = %+v, want it empty", h)
+	}
+	if students, _ := s.Students(); len(students) != 0 {
+		t.Errorf("after Undo() without a session Students() = %+v, want none", students)
+	}
+}
+
+func TestUndoGrades(t *testing.T) {
+	s := New(storage.NewMemory())
+	class, _ := s.AddClass("5", "a")
+	teacher, _ := s.AddTeacher("Ilze", "Kalniņa")
+	subject, _ := s.AddSubject("Matemātika")
+	student, _ := s.AddStudent("Anna", "Bērziņa")
+	if err := s.AssignClassToStudent(student, class); err != nil {
+		t.Fatal(err)
+	}
+	corrected, _ := s.AddGrade(student, subject, teacher, "2025-10-01", "6")
+	deleted, _ := s.AddGrade(student, subject, teacher, "2025-10-02", "4")
+	marks := func() string {
+		t.Helper()
+		grades, err := s.StudentGrades(student)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var marks []string
+		for _, g := range grades {
+			marks = append(marks, g.Date+":"+string(g.Mark))
+		}
+		return strings.Join(marks, " ")
+	}
+
+	// Undoing a save of the gradebook reverts every change of it.
+	if err := s.SaveGrades([]storage.GradeChange{
+		{ID: corrected, Mark: "7", Reason: "Labots"},
+		{ID: deleted},
+		{StudentID: student, SubjectID: subject, TeacherID: teacher, Date: "2025-10-03", Mark: "9"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Undo() of SaveGrades() failed: %v", err)
+	}
+	if got, want := marks(), "2025-10-01:6 2025-10-02:4"; got != want {
+		t.Errorf("after Undo() grades = %q, want %q", got, want)
+	}
+	if _, err := s.Redo(); err != nil {
+		t.Fatalf("Redo() of SaveGrades() failed: %v", err)
+	}
+	if got, want := marks(), "2025-10-01:7 2025-10-03:9"; got != want {
+		t.Errorf("after Redo() grades = %q, want %q", got, want)
+	}
+
+	// The attendance goes back to what it was, present without an entry.
+	absent := storage.AttendanceEntry{StudentID: student, Date: "2025-10-01", Lesson: 1, Status: storage.Absent}
+	if err := s.SetAttendance(absent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Undo() of SetAttendance() failed: %v", err)
+	}
+	if entries, _ := s.Attendance(class, "2025-10-01"); len(entries) != 0 {
+		t.Errorf("after Undo() Attendance() = %+v, want none", entries)
+	}
+	if err := s.SetAttendance(absent); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting the student is undone with their class, grades and
+	// attendance, which the earlier changes follow.
+	if err := s.DeleteStudent(student); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Undo() of DeleteStudent() failed: %v", err)
+	}
+	students, _ := s.ClassStudents(class)
+	if len(students) != 1 || students[0].StudentID == student {
+		t.Fatalf("after Undo() ClassStudents() = %+v, want Anna under a new ID", students)
+	}
+	student = students[0].StudentID
+	if got, want := marks(), "2025-10-01:7 2025-10-03:9"; got != want {
+		t.Errorf("after Undo() grades = %q, want %q", got, want)
+	}
+	if entries, _ := s.Attendance(class, "2025-10-01"); len(entries) != 1 || entries[0].StudentID != student {
+		t.Errorf("after Undo() Attendance() = %+v, want the absence", entries)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.Undo(); err != nil {
+			t.Fatalf("Undo() #%d after the deletion failed: %v", i+1, err)
+		}
+	}
+	if got, want := marks(), "2025-10-01:6 2025-10-02:4"; got != want {
+		t.Errorf("after undoing the save grades = %q, want %q", got, want)
+	}
+
+	// A student without a class is restored with their grades too.
+	if err := s.UnassignClassFromStudent(student); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteStudent(student); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Undo() of DeleteStudent() without a class failed: %v", err)
+	}
+	all, _ := s.Students()
+	if len(all) != 1 {
+		t.Fatalf("after Undo() Students() = %+v, want Anna", all)
+	}
+	student = all[0].ID
+	if g, _ := s.Group(student); g.ClassID.Valid {
+		t.Errorf("after Undo() Group() = %+v, want no class", g)
+	}
+	if got, want := marks(), "2025-10-01:6 2025-10-02:4"; got != want {
+		t.Errorf("after Undo() grades = %q, want %q", got, want)
+	}
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Undo() of UnassignClassFromStudent() failed: %v", err)
+	}
+	if g, _ := s.Group(student); int(g.ClassID.Int64) != class {
+		t.Errorf("after Undo() Group() = %+v, want 5.a", g)
+	}
+}
@@ -0,0 +1,116 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"eklase/storage"
+)
+
+// Subjects returns subjects stored in the database.
+func (h *State) Subjects() ([]storage.SubjectEntry, error) {
+	return h.storage.Subjects()
+}
+
+// AddSubject adds a subject to the database and returns its ID.
+func (v *State) AddSubject(name string) (int, error) {
+	if err := v.requireAdmin("add subject"); err != nil {
+		return 0, err
+	}
+	id, err := v.storage.AddSubject(name)
+	return id, v.added(err, "add subject", "subjects", id, v.storage.DeleteSubject, func() (int, error) {
+		return v.storage.AddSubject(name)
+	})
+}
+
+// UpdateSubject renames an existing subject.
+func (v *State) UpdateSubject(id int, name string) error {
+	if err := v.requireAdmin("update subject"); err != nil {
+		return err
+	}
+	old, err := v.storage.Subject(id)
+	if err != nil {
+		return err
+	}
+	subject := v.history.ref("subjects", id)
+	return v.done(v.storage.UpdateSubject(id, name), command{
+		name: "update subject",
+		undo: func() error { return v.storage.UpdateSubject(*subject, old.Name) },
+		redo: func() error { return v.storage.UpdateSubject(*subject, name) },
+	})
+}
+
+// DeleteSubject removes a subject nobody teaches.
+func (v *State) DeleteSubject(id int) error {
+	if err := v.requireAdmin("delete subject"); err != nil {
+		return err
+	}
+	old, err := v.storage.Subject(id)
+	if err != nil {
+		return err
+	}
+	return v.removed(v.storage.DeleteSubject(id), "delete subject", "subjects", id, v.storage.DeleteSubject, func() (int, error) {
+		return v.storage.AddSubject(old.Name)
+	})
+}
+
+// Assignments returns every teaching assignment.
+func (h *State) Assignments() ([]storage.AssignmentEntry, error) {
+	return h.storage.Assignments()
+}
+
+// ClassAssignments returns who teaches what to a class.
+func (h *State) ClassAssignments(classID int) ([]storage.AssignmentEntry, error) {
+	return h.storage.ClassAssignments(classID)
+}
+
+// AddAssignment records that a teacher teaches a subject to a class during
+// the school year of the class.
+func (v *State) AddAssignment(teacherID, subjectID, classID int) (int, error) {
+	if err := v.requireAdmin("add assignment"); err != nil {
+		return 0, err
+	}
+	teacher, subject, class := v.history.ref("teachers", teacherID), v.history.ref("subjects", subjectID), v.history.ref("classes", classID)
+	id, err := v.storage.AddAssignment(teacherID, subjectID, classID)
+	return id, v.added(err, "add assignment", "teaching_assignments", id, v.storage.DeleteAssignment, func() (int, error) {
+		return v.storage.AddAssignment(*teacher, *subject, *class)
+	})
+}
+
+// DeleteAssignment removes a teaching assignment.
+func (v *State) DeleteAssignment(id int) error {
+	if err := v.requireAdmin("delete assignment"); err != nil {
+		return err
+	}
+	assignments, err := v.storage.Assignments()
+	if err != nil {
+		return err
+	}
+	var add func() (int, error)
+	for _, a := range assignments {
+		if a.ID == id {
+			teacher, subject, class := v.history.ref("teachers", a.TeacherID), v.history.ref("subjects", a.SubjectID), v.history.ref("classes", a.ClassID)
+			add = func() (int, error) { return v.storage.AddAssignment(*teacher, *subject, *class) }
+		}
+	}
+	if add == nil {
+		return &storage.Error{Op: fmt.Sprintf("delete assignment %d", id), Kind: storage.ErrNotFound}
+	}
+	return v.removed(v.storage.DeleteAssignment(id), "delete assignment", "teaching_assignments", id, v.storage.DeleteAssignment, add)
+}
+
+// SchoolYearOf returns the school year t falls in, e.g. "2025/2026" for any
+// day from 1 September 2025 to 31 August 2026.
+func SchoolYearOf(t time.Time) string {
+	start := SchoolYearStart(t).Year()
+	return fmt.Sprintf("%d/%d", start, start+1)
+}
+
+// SchoolYearStart returns 1 September of the school year t falls in.
+func SchoolYearStart(t time.Time) time.Time {
+	year := t.Year()
+	if t.Month() < time.September {
+		year--
+	}
+	return time.Date(year, time.September, 1, 0, 0, 0, 0, t.Location())
+}
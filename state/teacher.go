@@ -0,0 +1,103 @@
+package state
+
+import (
+	"database/sql"
+
+	"eklase/storage"
+)
+
+// Teachers returns teachers stored in the database.
+func (h *State) Teachers() ([]storage.TeacherEntry, error) {
+	return h.storage.Teachers()
+}
+
+// Teacher returns a single teacher by its ID.
+func (h *State) Teacher(id int) (storage.TeacherEntry, error) {
+	return h.storage.Teacher(id)
+}
+
+// AddTeacher adds a teacher to the database and returns its ID.
+func (v *State) AddTeacher(name, surname string) (int, error) {
+	if err := v.requireAdmin("add teacher"); err != nil {
+		return 0, err
+	}
+	id, err := v.storage.AddTeacher(name, surname)
+	return id, v.added(err, "add teacher", "teachers", id, v.storage.DeleteTeacher, func() (int, error) {
+		return v.storage.AddTeacher(name, surname)
+	})
+}
+
+// UpdateTeacher renames an existing teacher.
+func (v *State) UpdateTeacher(id int, name, surname string) error {
+	if err := v.requireAdmin("update teacher"); err != nil {
+		return err
+	}
+	old, err := v.storage.Teacher(id)
+	if err != nil {
+		return err
+	}
+	teacher := v.history.ref("teachers", id)
+	return v.done(v.storage.UpdateTeacher(id, name, surname), command{
+		name: "update teacher",
+		undo: func() error { return v.storage.UpdateTeacher(*teacher, old.Name, old.Surname) },
+		redo: func() error { return v.storage.UpdateTeacher(*teacher, name, surname) },
+	})
+}
+
+// DeleteTeacher removes a teacher that is not a homeroom teacher.
+func (v *State) DeleteTeacher(id int) error {
+	if err := v.requireAdmin("delete teacher"); err != nil {
+		return err
+	}
+	old, err := v.storage.Teacher(id)
+	if err != nil {
+		return err
+	}
+	return v.removed(v.storage.DeleteTeacher(id), "delete teacher", "teachers", id, v.storage.DeleteTeacher, func() (int, error) {
+		return v.storage.AddTeacher(old.Name, old.Surname)
+	})
+}
+
+// SetClassTeacher makes a teacher the homeroom teacher of a class.
+func (v *State) SetClassTeacher(classID, teacherID int) error {
+	if err := v.requireAdmin("set class teacher"); err != nil {
+		return err
+	}
+	return v.setClassTeacher("set class teacher", classID, teacherID)
+}
+
+// ClearClassTeacher removes the homeroom teacher of a class.
+func (v *State) ClearClassTeacher(classID int) error {
+	if err := v.requireAdmin("clear class teacher"); err != nil {
+		return err
+	}
+	return v.setClassTeacher("clear class teacher", classID, 0)
+}
+
+// setClassTeacher makes a teacher, or nobody if teacherID is 0, the homeroom
+// teacher of a class and records the change under name.
+func (v *State) setClassTeacher(name string, classID, teacherID int) error {
+	c, err := v.storage.Class(classID)
+	if err != nil {
+		return err
+	}
+	class := v.history.ref("classes", classID)
+	// set returns a function making the teacher held by the cell the class
+	// teacher, nobody if the cell is nil.
+	set := func(teacher *int) func() error {
+		return func() error {
+			if teacher == nil {
+				return v.storage.SetClassTeacher(*class, sql.NullInt64{})
+			}
+			return v.storage.SetClassTeacher(*class, sql.NullInt64{Int64: int64(*teacher), Valid: true})
+		}
+	}
+	var old, teacher *int
+	if c.TeacherID.Valid {
+		old = v.history.ref("teachers", int(c.TeacherID.Int64))
+	}
+	if teacherID != 0 {
+		teacher = v.history.ref("teachers", teacherID)
+	}
+	return v.done(set(teacher)(), command{name: name, undo: set(old), redo: set(teacher)})
+}
@@ -0,0 +1,115 @@
+package state
+
+import (
+	"fmt"
+
+	"eklase/storage"
+)
+
+// Bells returns the bell schedule.
+func (h *State) Bells() ([]storage.BellEntry, error) {
+	return h.storage.Bells()
+}
+
+// SetBell sets the start and end of a lesson, e.g. "08:30" and "09:10".
+func (v *State) SetBell(b storage.BellEntry) error {
+	if err := v.requireAdmin("set bell"); err != nil {
+		return err
+	}
+	undo, err := v.rering(b.Lesson)
+	if err != nil {
+		return err
+	}
+	return v.done(v.storage.SetBell(b), command{
+		name: "set bell",
+		undo: undo,
+		redo: func() error { return v.storage.SetBell(b) },
+	})
+}
+
+// DeleteBell removes a lesson from the bell schedule.
+func (v *State) DeleteBell(lesson int) error {
+	if err := v.requireAdmin("delete bell"); err != nil {
+		return err
+	}
+	undo, err := v.rering(lesson)
+	if err != nil {
+		return err
+	}
+	return v.done(v.storage.DeleteBell(lesson), command{
+		name: "delete bell",
+		undo: undo,
+		redo: func() error { return v.storage.DeleteBell(lesson) },
+	})
+}
+
+// rering returns a function restoring the current bell of a lesson, to undo
+// changing it.
+func (v *State) rering(lesson int) (func() error, error) {
+	bells, err := v.storage.Bells()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bells {
+		if b.Lesson == lesson {
+			return func() error { return v.storage.SetBell(b) }, nil
+		}
+	}
+	return func() error { return v.storage.DeleteBell(lesson) }, nil
+}
+
+// ClassTimetable returns the weekly lessons of a class.
+func (h *State) ClassTimetable(classID int) ([]storage.SlotEntry, error) {
+	return h.storage.Slots(storage.SlotFilter{ClassID: classID})
+}
+
+// TeacherTimetable returns the weekly lessons of a teacher.
+func (h *State) TeacherTimetable(teacherID int) ([]storage.SlotEntry, error) {
+	return h.storage.Slots(storage.SlotFilter{TeacherID: teacherID})
+}
+
+// AddSlot adds a weekly lesson to the timetable and returns its ID. It fails
+// with storage.ErrConflict if the class, the teacher or the room is busy.
+func (v *State) AddSlot(s storage.SlotEntry) (int, error) {
+	if err := v.requireAdmin("add timetable slot"); err != nil {
+		return 0, err
+	}
+	add := v.slotOf(s)
+	id, err := v.storage.AddSlot(s)
+	return id, v.added(err, "add timetable slot", "timetable", id, v.storage.DeleteSlot, func() (int, error) {
+		return v.storage.AddSlot(add())
+	})
+}
+
+// DeleteSlot removes a weekly lesson from the timetable.
+func (v *State) DeleteSlot(id int) error {
+	if err := v.requireAdmin("delete timetable slot"); err != nil {
+		return err
+	}
+	slots, err := v.storage.Slots(storage.SlotFilter{})
+	if err != nil {
+		return err
+	}
+	var add func() storage.SlotEntry
+	for _, s := range slots {
+		if s.ID == id {
+			add = v.slotOf(s)
+		}
+	}
+	if add == nil {
+		return &storage.Error{Op: fmt.Sprintf("delete timetable slot %d", id), Kind: storage.ErrNotFound}
+	}
+	return v.removed(v.storage.DeleteSlot(id), "delete timetable slot", "timetable", id, v.storage.DeleteSlot, func() (int, error) {
+		return v.storage.AddSlot(add())
+	})
+}
+
+// slotOf returns a function returning s with the current IDs of its class,
+// subject and teacher.
+func (v *State) slotOf(s storage.SlotEntry) func() storage.SlotEntry {
+	class, subject, teacher := v.history.ref("classes", s.ClassID), v.history.ref("subjects", s.SubjectID), v.history.ref("teachers", s.TeacherID)
+	return func() storage.SlotEntry {
+		s.ClassID, s.SubjectID, s.TeacherID = *class, *subject, *teacher
+		return s
+	}
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaxLessons is the number of lessons a school day can have.
+const MaxLessons = 10
+
+// Presence is the attendance of a student at a lesson.
+type Presence string
+
+const (
+	Present Presence = "present"
+	Absent  Presence = "absent"
+	Late    Presence = "late"
+	Excused Presence = "excused" // Absent with a reason, see AbsenceReason.
+)
+
+// AbsenceReason explains an excused absence.
+type AbsenceReason string
+
+const (
+	Illness AbsenceReason = "illness"
+	Family  AbsenceReason = "family"
+)
+
+// AttendanceEntry represents the attendance of a student at a lesson.
+// Students are present unless there is an entry saying otherwise.
+type AttendanceEntry struct {
+	StudentID int           `db:"student_id"`
+	Date      string        `db:"date"`   // E.g. "2025-09-30".
+	Lesson    int           `db:"lesson"` // Number of the lesson in the day, from 1.
+	Status    Presence      `db:"status"`
+	Reason    AbsenceReason `db:"reason"` // Set for excused absences only.
+}
+
+// AbsenceTotal counts the lessons a student missed or was late to.
+type AbsenceTotal struct {
+	StudentID int    `db:"student_id"`
+	Name      string `db:"name"`
+	Surname   string `db:"surname"`
+	Absent    int    `db:"absent"`
+	Late      int    `db:"late"`
+	Excused   int    `db:"excused"`
+}
+
+// AttendanceStore provides access to the attendance register.
+type AttendanceStore interface {
+	// Attendance returns the entries of the students of a class on a day,
+	// ordered by lesson and student.
+	Attendance(classID int, date string) ([]AttendanceEntry, error)
+	// StudentAttendance returns the entries of a student ordered by date and
+	// lesson.
+	StudentAttendance(studentID int) ([]AttendanceEntry, error)
+	// SetAttendance records the attendance of a student at a lesson.
+	// Marking a student present removes the entry.
+	SetAttendance(e AttendanceEntry) error
+	// AbsenceTotals returns the totals of every student of a class between
+	// from and to, inclusive, ordered by surname and name. Empty dates leave
+	// the range open.
+	AbsenceTotals(classID int, from, to string) ([]AbsenceTotal, error)
+}
+
+var (
+	selectAttendanceStmt = `SELECT attendance.student_id, attendance.date, attendance.lesson,
+		attendance.status, attendance.reason
+	FROM attendance
+	JOIN enrollments ON enrollments.student_id = attendance.student_id
+	WHERE enrollments.class_id = ? AND attendance.date = ?
+	ORDER BY attendance.lesson, attendance.student_id`
+	selectStudentAttendanceStmt = `SELECT student_id, date, lesson, status, reason
+	FROM attendance WHERE student_id = ? ORDER BY date, lesson`
+	upsertAttendanceStmt = `INSERT INTO attendance (student_id, date, lesson, status, reason) VALUES(?, ?, ?, ?, ?)
+	ON CONFLICT (student_id, date, lesson) DO UPDATE SET status = excluded.status, reason = excluded.reason`
+	deleteAttendanceStmt = `DELETE FROM attendance WHERE student_id = ? AND date = ? AND lesson = ?`
+	absenceTotalsStmt    = `SELECT students.id AS student_id, students.name, students.surname,
+		COUNT(CASE WHEN attendance.status = 'absent' THEN 1 END) AS absent,
+		COUNT(CASE WHEN attendance.status = 'late' THEN 1 END) AS late,
+		COUNT(CASE WHEN attendance.status = 'excused' THEN 1 END) AS excused
+	FROM enrollments
+	JOIN students ON students.id = enrollments.student_id
+	LEFT JOIN attendance ON attendance.student_id = students.id
+		AND (? = '' OR attendance.date >= ?) AND (? = '' OR attendance.date <= ?)
+	WHERE enrollments.class_id = ?
+	GROUP BY students.id
+	ORDER BY students.surname, students.name, students.id`
+)
+
+// validateAttendance checks an attendance entry before it is stored.
+func validateAttendance(e AttendanceEntry) error {
+	if _, err := time.Parse("2006-01-02", e.Date); err != nil {
+		return &ValidationError{Field: "date", Reason: "must look like 2025-09-30"}
+	}
+	if e.Lesson < 1 || e.Lesson > MaxLessons {
+		return &ValidationError{Field: "lesson", Reason: fmt.Sprintf("must be a number from 1 to %d", MaxLessons)}
+	}
+	switch e.Status {
+	case Present, Absent, Late:
+		if e.Reason != "" {
+			return &ValidationError{Field: "reason", Reason: "is only allowed for excused absences"}
+		}
+	case Excused:
+		if e.Reason != Illness && e.Reason != Family {
+			return &ValidationError{Field: "reason", Reason: "must be illness or family"}
+		}
+	default:
+		return &ValidationError{Field: "status", Reason: "must be present, absent, late or excused"}
+	}
+	return nil
+}
+
+// Attendance returns the entries of the students of a class on a day.
+func (s *SQLite) Attendance(classID int, date string) ([]AttendanceEntry, error) {
+	var entries []AttendanceEntry
+	if err := s.db.Select(&entries, selectAttendanceStmt, classID, date); err != nil {
+		return nil, wrap(fmt.Sprintf("list attendance of class %d", classID), err)
+	}
+	return entries, nil
+}
+
+// StudentAttendance returns the entries of a student.
+func (s *SQLite) StudentAttendance(studentID int) ([]AttendanceEntry, error) {
+	var entries []AttendanceEntry
+	if err := s.db.Select(&entries, selectStudentAttendanceStmt, studentID); err != nil {
+		return nil, wrap(fmt.Sprintf("list attendance of student %d", studentID), err)
+	}
+	return entries, nil
+}
+
+// SetAttendance records the attendance of a student at a lesson. The student
+// must exist.
+func (s *SQLite) SetAttendance(e AttendanceEntry) error {
+	op := fmt.Sprintf("set attendance of student %d", e.StudentID)
+	if err := validateAttendance(e); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	if e.Status == Present {
+		// Deleting a missing entry is fine, but the student must exist like
+		// for the other statuses.
+		if _, err := s.Student(e.StudentID); errors.Is(err, ErrNotFound) {
+			return &Error{Op: op, Kind: ErrConstraint}
+		} else if err != nil {
+			return err
+		}
+		if _, err := s.exec(deleteAttendanceStmt, e.StudentID, e.Date, e.Lesson); err != nil {
+			return wrap(op, err)
+		}
+		return nil
+	}
+	if _, err := s.exec(upsertAttendanceStmt, e.StudentID, e.Date, e.Lesson, e.Status, e.Reason); err != nil {
+		return wrap(op, err)
+	}
+	return nil
+}
+
+// AbsenceTotals returns the totals of every student of a class.
+func (s *SQLite) AbsenceTotals(classID int, from, to string) ([]AbsenceTotal, error) {
+	var entries []AbsenceTotal
+	if err := s.db.Select(&entries, absenceTotalsStmt, from, from, to, to, classID); err != nil {
+		return nil, wrap(fmt.Sprintf("count absences of class %d", classID), err)
+	}
+	return entries, nil
+}
+
+// attendanceKey identifies an attendance entry in Memory.
+type attendanceKey struct {
+	studentID int
+	date      string
+	lesson    int
+}
+
+// Attendance returns the entries of the students of a class on a day.
+func (m *Memory) Attendance(classID int, date string) ([]AttendanceEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []AttendanceEntry
+	for k, e := range m.attendance {
+		if k.date == date && m.enrollments[k.studentID] == classID {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Lesson != entries[j].Lesson {
+			return entries[i].Lesson < entries[j].Lesson
+		}
+		return entries[i].StudentID < entries[j].StudentID
+	})
+	return entries, nil
+}
+
+// StudentAttendance returns the entries of a student.
+func (m *Memory) StudentAttendance(studentID int) ([]AttendanceEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []AttendanceEntry
+	for k, e := range m.attendance {
+		if k.studentID == studentID {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		return entries[i].Lesson < entries[j].Lesson
+	})
+	return entries, nil
+}
+
+// SetAttendance records the attendance of a student at a lesson. The student
+// must exist.
+func (m *Memory) SetAttendance(e AttendanceEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	return m.setAttendance(e)
+}
+
+// setAttendance records the attendance of a student at a lesson. m.mu must be
+// held.
+func (m *Memory) setAttendance(e AttendanceEntry) error {
+	op := fmt.Sprintf("set attendance of student %d", e.StudentID)
+	if err := validateAttendance(e); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	if _, ok := m.students[e.StudentID]; !ok {
+		return &Error{Op: op, Kind: ErrConstraint}
+	}
+	k := attendanceKey{e.StudentID, e.Date, e.Lesson}
+	if e.Status == Present {
+		m.touch("attendance", k)
+		delete(m.attendance, k)
+		return nil
+	}
+	m.touch("attendance", k)
+	m.attendance[k] = e
+	return nil
+}
+
+// AbsenceTotals returns the totals of every student of a class.
+func (m *Memory) AbsenceTotals(classID int, from, to string) ([]AbsenceTotal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	totals := make(map[int]*AbsenceTotal)
+	for studentID, c := range m.enrollments {
+		if c == classID {
+			student := m.students[studentID]
+			totals[studentID] = &AbsenceTotal{StudentID: studentID, Name: student.Name, Surname: student.Surname}
+		}
+	}
+	for k, e := range m.attendance {
+		t, ok := totals[k.studentID]
+		if !ok || from != "" && k.date < from || to != "" && k.date > to {
+			continue
+		}
+		switch e.Status {
+		case Absent:
+			t.Absent++
+		case Late:
+			t.Late++
+		case Excused:
+			t.Excused++
+		}
+	}
+	entries := make([]AbsenceTotal, 0, len(totals))
+	for _, t := range totals {
+		entries = append(entries, *t)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case a.Surname != b.Surname:
+			return a.Surname < b.Surname
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		}
+		return a.StudentID < b.StudentID
+	})
+	return entries, nil
+}
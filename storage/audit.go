@@ -0,0 +1,366 @@
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditAction is the kind of change recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditInsert AuditAction = "insert"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+// AuditedEntities lists the entities whose changes are recorded in the audit
+// log, named like their tables. The history of grade corrections and
+// promotions is left out, as it is kept by the entities it refers to.
+var AuditedEntities = []string{
+	"students", "classes", "enrollments", "teachers", "subjects",
+	"teaching_assignments", "grades", "attendance", "bells", "timetable",
+	"lessons", "school_years", "terms", "promotions", "final_grades", "users",
+}
+
+// AuditEntry represents a recorded change of a single entity. Entries are
+// never changed or removed.
+type AuditEntry struct {
+	ID       int            `db:"id"`
+	At       string         `db:"at"`        // RFC 3339 time in UTC.
+	Actor    string         `db:"actor"`     // Who made the change, see AuditStore.SetActor.
+	Entity   string         `db:"entity"`    // One of AuditedEntities.
+	EntityID int            `db:"entity_id"` // Student ID of enrollments and attendance, lesson of bells.
+	Action   AuditAction    `db:"action"`
+	Old      sql.NullString `db:"old"` // JSON object of the columns, not valid for inserts.
+	New      sql.NullString `db:"new"` // JSON object of the columns, not valid for deletes.
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Entity   string
+	EntityID int
+	Actor    string
+	Limit    int // Most entries returned, the newest ones.
+}
+
+// AuditStore provides access to the audit log, which records every change in
+// the transaction of the change itself.
+type AuditStore interface {
+	// SetActor sets who the following changes are recorded for, e.g. a
+	// username.
+	SetActor(actor string)
+	// AuditLog returns the entries matching f, newest first.
+	AuditLog(f AuditFilter) ([]AuditEntry, error)
+}
+
+var (
+	setAuditActorStmt = `UPDATE audit_actor SET actor = ? WHERE id = 1`
+	selectAuditStmt   = `SELECT id, at, actor, entity, entity_id, action, old, new FROM audit_log`
+)
+
+// auditTriggers returns the statements creating the triggers that record the
+// changes of a table in audit_log, identified by the key column. Only the
+// given columns are recorded.
+func auditTriggers(table, key string, columns ...string) string {
+	object := func(row string) string {
+		pairs := make([]string, len(columns))
+		for i, c := range columns {
+			pairs[i] = fmt.Sprintf("'%s', %s.%s", c, row, c)
+		}
+		return "json_object(" + strings.Join(pairs, ", ") + ")"
+	}
+	var b strings.Builder
+	for _, t := range []struct {
+		event    string
+		action   AuditAction
+		row      string // Row holding the key.
+		old, new string
+	}{
+		{"INSERT", AuditInsert, "NEW", "NULL", object("NEW")},
+		{"UPDATE", AuditUpdate, "NEW", object("OLD"), object("NEW")},
+		{"DELETE", AuditDelete, "OLD", object("OLD"), "NULL"},
+	} {
+		fmt.Fprintf(&b, `
+		CREATE TRIGGER audit_%[1]s_%[2]s AFTER %[3]s ON %[1]s BEGIN
+			INSERT INTO audit_log (at, actor, entity, entity_id, action, old, new)
+			VALUES (strftime('%%Y-%%m-%%dT%%H:%%M:%%SZ', 'now'), (SELECT actor FROM audit_actor), '%[1]s', %[4]s.%[5]s, '%[2]s', %[6]s, %[7]s);
+		END;`, table, t.action, t.event, t.row, key, t.old, t.new)
+	}
+	return b.String()
+}
+
+// auditQuery builds the statement selecting the audit entries matching f.
+func auditQuery(f AuditFilter) (string, []interface{}) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if f.Entity != "" {
+		where = append(where, "entity = ?")
+		args = append(args, f.Entity)
+	}
+	if f.EntityID != 0 {
+		where = append(where, "entity_id = ?")
+		args = append(args, f.EntityID)
+	}
+	if f.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, f.Actor)
+	}
+	stmt := selectAuditStmt
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	stmt += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		stmt += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+	return stmt, args
+}
+
+// SetActor sets who the following changes are recorded for.
+func (s *SQLite) SetActor(actor string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actor = actor
+}
+
+// setActor records the actor for the triggers writing the audit log, in the
+// transaction of the change, so that other processes cannot interfere.
+func (s *SQLite) setActor(tx *sqlx.Tx) error {
+	s.mu.Lock()
+	actor := s.actor
+	s.mu.Unlock()
+	_, err := tx.Exec(setAuditActorStmt, actor)
+	return err
+}
+
+// exec runs a single changing statement in a transaction of its own, so that
+// the audit log names the actor. Errors are returned unwrapped.
+func (s *SQLite) exec(query string, args ...interface{}) (sql.Result, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.setActor(tx); err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return res, tx.Commit()
+}
+
+// AuditLog returns the audit entries matching f.
+func (s *SQLite) AuditLog(f AuditFilter) ([]AuditEntry, error) {
+	stmt, args := auditQuery(f)
+	var entries []AuditEntry
+	if err := s.db.Select(&entries, stmt, args...); err != nil {
+		return nil, wrap("list audit log", err)
+	}
+	return entries, nil
+}
+
+// SetActor sets who the following changes are recorded for.
+func (m *Memory) SetActor(actor string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.actor = actor
+}
+
+// AuditLog returns the audit entries matching f.
+func (m *Memory) AuditLog(f AuditFilter) ([]AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []AuditEntry
+	for i := len(m.auditLog) - 1; i >= 0; i-- {
+		e := m.auditLog[i]
+		if f.Entity != "" && e.Entity != f.Entity || f.EntityID != 0 && e.EntityID != f.EntityID || f.Actor != "" && e.Actor != f.Actor {
+			continue
+		}
+		if f.Limit > 0 && len(entries) == f.Limit {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// auditRef identifies a row of an audited entity: by its ID, the student ID
+// of enrollments or the lesson of bells as an int, attendance by its
+// attendanceKey.
+type auditRef struct {
+	entity string
+	key    interface{}
+}
+
+// touch notes that the current mutation is about to change a row, so that
+// audit can compare it with what it was before. It is called before every
+// change of an audited entity, like the triggers of SQLite fire for every
+// changed row. m.mu must be held.
+func (m *Memory) touch(entity string, key interface{}) {
+	ref := auditRef{entity, key}
+	if _, ok := m.touched[ref]; ok {
+		return
+	}
+	if m.touched == nil {
+		m.touched = make(map[auditRef]string)
+	}
+	m.touched[ref] = m.auditRow(ref)
+}
+
+// auditRow returns a row of an audited entity as a JSON object of its
+// columns, or an empty string if it does not exist. m.mu must be held.
+func (m *Memory) auditRow(ref auditRef) string {
+	var (
+		v  interface{}
+		ok bool
+	)
+	switch id, _ := ref.key.(int); ref.entity {
+	case "students":
+		v, ok = m.students[id]
+	case "classes":
+		v, ok = m.classes[id]
+	case "enrollments":
+		var classID int
+		classID, ok = m.enrollments[id]
+		v = struct {
+			StudentID int `db:"student_id"`
+			ClassID   int `db:"class_id"`
+		}{id, classID}
+	case "teachers":
+		v, ok = m.teachers[id]
+	case "subjects":
+		v, ok = m.subjects[id]
+	case "teaching_assignments":
+		v, ok = m.assignments[id]
+	case "grades":
+		v, ok = m.grades[id]
+	case "attendance":
+		v, ok = m.attendance[ref.key.(attendanceKey)]
+	case "bells":
+		v, ok = m.bells[id]
+	case "timetable":
+		v, ok = m.slots[id]
+	case "lessons":
+		v, ok = m.lessons[id]
+	case "school_years":
+		v, ok = m.schoolYears[id]
+	case "terms":
+		v, ok = m.terms[id]
+	case "promotions":
+		var p *promotion
+		if p, ok = m.promotions[id]; ok {
+			v = p.entry
+		}
+	case "final_grades":
+		v, ok = m.finalGrades[id]
+	case "users":
+		v, ok = m.users[id]
+	}
+	if !ok {
+		return ""
+	}
+	return auditJSON(v)
+}
+
+// audit records the rows a mutation changed, as noted by touch, like the
+// triggers of SQLite. It is deferred at the start of the mutation, after
+// locking m.mu:
+//
+//	defer m.audit()()
+//
+// Memory records the entries as it keeps them, which may differ from the
+// columns recorded by SQLite, e.g. in the names of referenced entries.
+func (m *Memory) audit() func() {
+	m.touched = nil
+	return func() {
+		refs := make([]auditRef, 0, len(m.touched))
+		for ref := range m.touched {
+			refs = append(refs, ref)
+		}
+		// Rows are recorded by entity and key, as the mutation may have
+		// touched them in the random order of a map.
+		sort.Slice(refs, func(i, j int) bool {
+			a, b := refs[i], refs[j]
+			if a.entity != b.entity {
+				return a.entity < b.entity
+			}
+			return auditKeyLess(a.key, b.key)
+		})
+		at := timestamp()
+		for _, ref := range refs {
+			old, new := m.touched[ref], m.auditRow(ref)
+			if old == new {
+				continue
+			}
+			e := AuditEntry{At: at, Actor: m.actor, Entity: ref.entity, Action: AuditUpdate}
+			switch k := ref.key.(type) {
+			case int:
+				e.EntityID = k
+			case attendanceKey:
+				e.EntityID = k.studentID
+			}
+			switch {
+			case old == "":
+				e.Action = AuditInsert
+			case new == "":
+				e.Action = AuditDelete
+			}
+			e.Old = sql.NullString{String: old, Valid: old != ""}
+			e.New = sql.NullString{String: new, Valid: new != ""}
+			m.lastAuditID++
+			e.ID = m.lastAuditID
+			m.auditLog = append(m.auditLog, e)
+		}
+		m.touched = nil
+	}
+}
+
+// auditKeyLess orders the keys of rows of the same entity.
+func auditKeyLess(a, b interface{}) bool {
+	if a, ok := a.(attendanceKey); ok {
+		b := b.(attendanceKey)
+		if a.studentID != b.studentID {
+			return a.studentID < b.studentID
+		}
+		if a.date != b.date {
+			return a.date < b.date
+		}
+		return a.lesson < b.lesson
+	}
+	return a.(int) < b.(int)
+}
+
+// auditJSON returns the fields of an entry as a JSON object keyed by their db
+// tags. Password hashes are left out.
+func auditJSON(entry interface{}) string {
+	v := reflect.ValueOf(entry)
+	object := make(map[string]interface{}, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get("db")
+		if name == "" || name == "password_hash" {
+			continue
+		}
+		value := v.Field(i).Interface()
+		if valuer, ok := value.(driver.Valuer); ok {
+			value, _ = valuer.Value()
+		}
+		object[name] = value
+	}
+	data, _ := json.Marshal(object)
+	return string(data)
+}
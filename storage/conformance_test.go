@@ -0,0 +1,1722 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// conformance lists the behaviour every Storage implementation must share.
+var conformance = []struct {
+	name string
+	test func(t *testing.T, s Storage)
+}{
+	{"IDsAreNotReused", testIDsAreNotReused},
+	{"Validation", testValidation},
+	{"DuplicateClass", testDuplicateClass},
+	{"TeacherCRUD", testTeacherCRUD},
+	{"ClassTeacher", testClassTeacher},
+	{"SubjectCRUD", testSubjectCRUD},
+	{"Assignments", testAssignments},
+	{"Grades", testGrades},
+	{"GradeCorrections", testGradeCorrections},
+	{"SaveGrades", testSaveGrades},
+	{"Attendance", testAttendance},
+	{"AbsenceTotals", testAbsenceTotals},
+	{"Bells", testBells},
+	{"Timetable", testTimetable},
+	{"Lessons", testLessons},
+	{"Homework", testHomework},
+	{"SchoolYears", testSchoolYears},
+	{"Terms", testTerms},
+	{"Promote", testPromote},
+	{"UndoPromotion", testUndoPromotion},
+	{"FinalGrades", testFinalGrades},
+	{"ImportStudents", testImportStudents},
+	{"Users", testUsers},
+	{"AuditLog", testAuditLog},
+	{"Search", testSearch},
+	{"AssignClassToStudent", testAssignClassToStudent},
+	{"AssignClassToStudentRejectsMissingClass", testAssignClassToStudentRejectsMissingClass},
+	{"StudentCRUD", testStudentCRUD},
+	{"ClassCRUD", testClassCRUD},
+	{"DeleteClassWithStudents", testDeleteClassWithStudents},
+	{"RestoreStudent", testRestoreStudent},
+	{"UnassignClassFromStudent", testUnassignClassFromStudent},
+}
+
+// runConformance runs the conformance suite against fresh storages created by
+// newStorage.
+func runConformance(t *testing.T, newStorage func(t *testing.T) Storage) {
+	for _, c := range conformance {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			s := newStorage(t)
+			t.Cleanup(func() { s.Close() })
+			c.test(t, s)
+		})
+	}
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) Storage {
+		s, err := New(filepath.Join(t.TempDir(), "school.db"))
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		return s
+	})
+}
+
+func TestMemoryConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) Storage {
+		return NewMemory()
+	})
+}
+
+func testIDsAreNotReused(t *testing.T, s Storage) {
+	first, err := s.AddStudent("Anna", "Bērziņa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteStudent(first); err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.AddStudent("Jānis", "Ozols")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first {
+		t.Errorf("AddStudent() reused ID %d of a deleted student", first)
+	}
+	students, err := s.Students()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(students) != 1 || students[0].ID != second {
+		t.Errorf("Students() = %+v, want only student %d", students, second)
+	}
+}
+
+func testAssignClassToStudent(t *testing.T, s Storage) {
+	if _, err := s.AddStudent("Anna", "Bērziņa"); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []string{"a", "b"} {
+		if _, err := s.AddClass("5", m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.AssignClassToStudent(1, 1); err != nil {
+		t.Fatalf("AssignClassToStudent(1, 1) failed: %v", err)
+	}
+	if err := s.AssignClassToStudent(1, 2); err != nil {
+		t.Fatalf("AssignClassToStudent(1, 2) failed: %v", err)
+	}
+	groups, err := s.Groups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("got %d groups, want 1", len(groups))
+	}
+	if g := groups[0]; g.ClassID.Int64 != 2 || g.Modifier.String != "b" {
+		t.Errorf("student is in class %d (%s), want 2 (b)", g.ClassID.Int64, g.Modifier.String)
+	}
+}
+
+func testAssignClassToStudentRejectsMissingClass(t *testing.T, s Storage) {
+	if _, err := s.AddStudent("Anna", "Bērziņa"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AssignClassToStudent(1, 42); !errors.Is(err, ErrConstraint) {
+		t.Errorf("AssignClassToStudent() with a missing class error = %v, want %v", err, ErrConstraint)
+	}
+	groups, err := s.Groups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].ClassID.Valid {
+		t.Errorf("groups = %+v, want one student without a class", groups)
+	}
+}
+
+func testStudentCRUD(t *testing.T, s Storage) {
+	id, err := s.AddStudent("Anna", "Bērziņa")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.UpdateStudent(id, "Anna", "Ozola"); err != nil {
+		t.Fatalf("UpdateStudent() failed: %v", err)
+	}
+	got, err := s.Student(id)
+	if err != nil {
+		t.Fatalf("Student() failed: %v", err)
+	}
+	if want := (StudentEntry{ID: id, Name: "Anna", Surname: "Ozola"}); got != want {
+		t.Errorf("Student() = %+v, want %+v", got, want)
+	}
+
+	if err := s.DeleteStudent(id); err != nil {
+		t.Fatalf("DeleteStudent() failed: %v", err)
+	}
+	if _, err := s.Student(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Student() after delete error = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.UpdateStudent(id, "Anna", "Ozola"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateStudent() after delete error = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.DeleteStudent(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteStudent() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testClassCRUD(t *testing.T, s Storage) {
+	id, err := s.AddClass("5", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.UpdateClass(id, "5", "b"); err != nil {
+		t.Fatalf("UpdateClass() failed: %v", err)
+	}
+	got, err := s.Class(id)
+	if err != nil {
+		t.Fatalf("Class() failed: %v", err)
+	}
+	if want := (ClassEntry{ID: id, Year: "5", Modifier: "b"}); got != want {
+		t.Errorf("Class() = %+v, want %+v", got, want)
+	}
+
+	if err := s.DeleteClass(id); err != nil {
+		t.Fatalf("DeleteClass() failed: %v", err)
+	}
+	if _, err := s.Class(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Class() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testDeleteClassWithStudents(t *testing.T, s Storage) {
+	studentID, err := s.AddStudent("Anna", "Bērziņa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	classID, err := s.AddClass("5", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AssignClassToStudent(studentID, classID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteClass(classID); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteClass() error = %v, want %v", err, ErrConstraint)
+	}
+	// Deleting the student removes the enrollment, so the class can go too.
+	if err := s.DeleteStudent(studentID); err != nil {
+		t.Fatalf("DeleteStudent() failed: %v", err)
+	}
+	if err := s.DeleteClass(classID); err != nil {
+		t.Errorf("DeleteClass() failed: %v", err)
+	}
+}
+
+func testUnassignClassFromStudent(t *testing.T, s Storage) {
+	studentID, err := s.AddStudent("Anna", "Bērziņa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	classID, err := s.AddClass("5", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AssignClassToStudent(studentID, classID); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := s.Group(studentID)
+	if err != nil {
+		t.Fatalf("Group() failed: %v", err)
+	}
+	if int(g.ClassID.Int64) != classID {
+		t.Errorf("Group().ClassID = %v, want %d", g.ClassID, classID)
+	}
+	if err := s.UnassignClassFromStudent(studentID); err != nil {
+		t.Fatalf("UnassignClassFromStudent() failed: %v", err)
+	}
+	if g, err := s.Group(studentID); err != nil || g.ClassID.Valid {
+		t.Errorf("Group() = %+v, %v; want no class", g, err)
+	}
+	if err := s.UnassignClassFromStudent(studentID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UnassignClassFromStudent() error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := s.Group(42); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Group(42) error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testValidation(t *testing.T, s Storage) {
+	id, err := s.AddStudent("Anna", "Bērziņa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		desc  string
+		err   error
+		field string
+	}{
+		{"empty name", func() error { _, err := s.AddStudent("", "Ozols"); return err }(), "name"},
+		{"digit in surname", func() error { _, err := s.AddStudent("Jānis", "Ozols2"); return err }(), "surname"},
+		{"rename to empty", s.UpdateStudent(id, "Anna", ""), "surname"},
+		{"year out of range", func() error { _, err := s.AddClass("13", "a"); return err }(), "year"},
+		{"digit in modifier", func() error { _, err := s.AddClass("5", "1"); return err }(), "modifier"},
+	} {
+		if !errors.Is(tc.err, ErrValidation) {
+			t.Errorf("%s: error = %v, want %v", tc.desc, tc.err, ErrValidation)
+			continue
+		}
+		var verr *ValidationError
+		if !errors.As(tc.err, &verr) || verr.Field != tc.field {
+			t.Errorf("%s: error = %v, want a validation error for %q", tc.desc, tc.err, tc.field)
+		}
+	}
+}
+
+func testDuplicateClass(t *testing.T, s Storage) {
+	if _, err := s.AddClass("5", "a"); err != nil {
+		t.Fatal(err)
+	}
+	id, err := s.AddClass("5", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddClass("5", "a"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("AddClass() of an existing class error = %v, want %v", err, ErrDuplicate)
+	}
+	if err := s.UpdateClass(id, "5", "a"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("UpdateClass() to an existing class error = %v, want %v", err, ErrDuplicate)
+	}
+	if err := s.UpdateClass(id, "5", "b"); err != nil {
+		t.Errorf("UpdateClass() without changes failed: %v", err)
+	}
+}
+
+func testTeacherCRUD(t *testing.T, s Storage) {
+	id, err := s.AddTeacher("Ilze", "Kalniņa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateTeacher(id, "Ilze", "Liepa"); err != nil {
+		t.Fatalf("UpdateTeacher() failed: %v", err)
+	}
+	got, err := s.Teacher(id)
+	if err != nil {
+		t.Fatalf("Teacher() failed: %v", err)
+	}
+	if want := (TeacherEntry{ID: id, Name: "Ilze", Surname: "Liepa"}); got != want {
+		t.Errorf("Teacher() = %+v, want %+v", got, want)
+	}
+	if _, err := s.AddTeacher("Ilze", ""); !errors.Is(err, ErrValidation) {
+		t.Errorf("AddTeacher() without surname error = %v, want %v", err, ErrValidation)
+	}
+	if err := s.DeleteTeacher(id); err != nil {
+		t.Fatalf("DeleteTeacher() failed: %v", err)
+	}
+	if teachers, err := s.Teachers(); err != nil || len(teachers) != 0 {
+		t.Errorf("Teachers() = %+v, %v; want none", teachers, err)
+	}
+	if err := s.DeleteTeacher(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteTeacher() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testClassTeacher(t *testing.T, s Storage) {
+	teacherID, err := s.AddTeacher("Ilze", "Kalniņa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	classID, err := s.AddClass("5", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	teacher := sql.NullInt64{Int64: int64(teacherID), Valid: true}
+
+	if err := s.SetClassTeacher(classID, teacher); err != nil {
+		t.Fatalf("SetClassTeacher() failed: %v", err)
+	}
+	// Renaming the class keeps its teacher.
+	if err := s.UpdateClass(classID, "6", "a"); err != nil {
+		t.Fatal(err)
+	}
+	class, err := s.Class(classID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if class.TeacherID != teacher {
+		t.Errorf("class teacher = %v, want %v", class.TeacherID, teacher)
+	}
+	if err := s.DeleteTeacher(teacherID); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteTeacher() of a homeroom teacher error = %v, want %v", err, ErrConstraint)
+	}
+	missing := sql.NullInt64{Int64: 42, Valid: true}
+	if err := s.SetClassTeacher(classID, missing); !errors.Is(err, ErrConstraint) {
+		t.Errorf("SetClassTeacher() with a missing teacher error = %v, want %v", err, ErrConstraint)
+	}
+	if err := s.SetClassTeacher(classID, sql.NullInt64{}); err != nil {
+		t.Fatalf("SetClassTeacher() to none failed: %v", err)
+	}
+	if err := s.DeleteTeacher(teacherID); err != nil {
+		t.Errorf("DeleteTeacher() failed: %v", err)
+	}
+}
+
+func testSubjectCRUD(t *testing.T, s Storage) {
+	id, err := s.AddSubject("Matemātika")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddSubject("Latviešu valoda"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddSubject("Matemātika"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("AddSubject() of an existing subject error = %v, want %v", err, ErrDuplicate)
+	}
+	if _, err := s.AddSubject(" "); !errors.Is(err, ErrValidation) {
+		t.Errorf("AddSubject() of a blank name error = %v, want %v", err, ErrValidation)
+	}
+	if err := s.UpdateSubject(id, "Algebra"); err != nil {
+		t.Fatalf("UpdateSubject() failed: %v", err)
+	}
+	subjects, err := s.Subjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subjects) != 2 || subjects[0].Name != "Algebra" || subjects[1].Name != "Latviešu valoda" {
+		t.Errorf("Subjects() = %+v, want Algebra and Latviešu valoda", subjects)
+	}
+	if err := s.DeleteSubject(id); err != nil {
+		t.Fatalf("DeleteSubject() failed: %v", err)
+	}
+	if _, err := s.Subject(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Subject() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testAssignments(t *testing.T, s Storage) {
+	teacherID, err := s.AddTeacher("Ilze", "Kalniņa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subjectID, err := s.AddSubject("Matemātika")
+	if err != nil {
+		t.Fatal(err)
+	}
+	class10, err := s.AddClass("10", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	class9, err := s.AddClass("9", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, classID := range []int{class10, class9} {
+		if _, err := s.AddAssignment(teacherID, subjectID, classID); err != nil {
+			t.Fatalf("AddAssignment() failed: %v", err)
+		}
+	}
+	if _, err := s.AddAssignment(teacherID, subjectID, class9); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("AddAssignment() of an existing assignment error = %v, want %v", err, ErrDuplicate)
+	}
+	if _, err := s.AddAssignment(teacherID, 42, class9); !errors.Is(err, ErrConstraint) {
+		t.Errorf("AddAssignment() with a missing subject error = %v, want %v", err, ErrConstraint)
+	}
+
+	all, err := s.Assignments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Classes are ordered numerically, 9 before 10.
+	if len(all) != 2 || all[0].ClassID != class9 || all[1].ClassID != class10 {
+		t.Fatalf("Assignments() = %+v, want 9.a and 10.a", all)
+	}
+	if a := all[0]; a.TeacherSurname != "Kalniņa" || a.Subject != "Matemātika" || a.Year != "9" || a.Modifier != "a" {
+		t.Errorf("Assignments()[0] = %+v, want names of the referenced entries", a)
+	}
+	mine, err := s.ClassAssignments(class10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mine) != 1 || mine[0].ClassID != class10 {
+		t.Errorf("ClassAssignments() = %+v, want one assignment of 10.a", mine)
+	}
+
+	for desc, err := range map[string]error{
+		"DeleteTeacher": s.DeleteTeacher(teacherID),
+		"DeleteSubject": s.DeleteSubject(subjectID),
+		"DeleteClass":   s.DeleteClass(class10),
+	} {
+		if !errors.Is(err, ErrConstraint) {
+			t.Errorf("%s() of a taught entry error = %v, want %v", desc, err, ErrConstraint)
+		}
+	}
+	if err := s.DeleteAssignment(mine[0].ID); err != nil {
+		t.Fatalf("DeleteAssignment() failed: %v", err)
+	}
+	if err := s.DeleteClass(class10); err != nil {
+		t.Errorf("DeleteClass() after deleting its assignment failed: %v", err)
+	}
+	if mine[0].SchoolYearID.Valid || mine[0].SchoolYear != "" {
+		t.Errorf("ClassAssignments() of a class without a school year = %+v, want no school year", mine[0])
+	}
+
+	// Assignments take the school year of their class.
+	year := addSchoolYear(t, s, 2025)
+	class8, err := s.AddClass("8", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddAssignment(teacherID, subjectID, class8); err != nil {
+		t.Fatalf("AddAssignment() failed: %v", err)
+	}
+	if mine, err = s.ClassAssignments(class8); err != nil {
+		t.Fatal(err)
+	}
+	if len(mine) != 1 || mine[0].SchoolYearID.Int64 != int64(year) || mine[0].SchoolYear != "2025/2026" {
+		t.Errorf("ClassAssignments() = %+v, want one assignment of 2025/2026", mine)
+	}
+}
+
+// gradeFixture adds a teacher, two subjects and two students in different
+// classes. It returns the IDs of the teacher, subjects, students and classes.
+func gradeFixture(t *testing.T, s Storage) (teacher int, subjects, students, classes [2]int) {
+	t.Helper()
+	var err error
+	if teacher, err = s.AddTeacher("Ilze", "Kalniņa"); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"Matemātika", "Sports"} {
+		if subjects[i], err = s.AddSubject(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, m := range []string{"a", "b"} {
+		if classes[i], err = s.AddClass("5", m); err != nil {
+			t.Fatal(err)
+		}
+		if students[i], err = s.AddStudent("Anna", "Bērziņa"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AssignClassToStudent(students[i], classes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return teacher, subjects, students, classes
+}
+
+func testRestoreStudent(t *testing.T, s Storage) {
+	teacher, subjects, students, classes := gradeFixture(t, s)
+	absent := AttendanceEntry{Date: "2025-10-01", Lesson: 1, Status: Absent}
+	r := StudentRecord{
+		Student: StudentEntry{Name: "Jānis", Surname: "Ozols"},
+		ClassID: sql.NullInt64{Int64: int64(classes[0]), Valid: true},
+		Grades: []GradeEntry{
+			{SubjectID: subjects[0], TeacherID: teacher, Date: "2025-10-01", Mark: "7", Kind: DailyWork},
+			{SubjectID: subjects[1], TeacherID: teacher, Date: "2025-10-02", Mark: Passed, Kind: Test},
+		},
+		Attendance: []AttendanceEntry{absent},
+	}
+	id, ids, err := s.RestoreStudent(r)
+	if err != nil {
+		t.Fatalf("RestoreStudent() failed: %v", err)
+	}
+	if g, err := s.Group(id); err != nil || g.Name != "Jānis" || int(g.ClassID.Int64) != classes[0] {
+		t.Errorf("Group() of the restored student = %+v, %v; want Jānis in class %d", g, err, classes[0])
+	}
+	grades, err := s.Grades(GradeFilter{StudentID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grades) != 2 || fmt.Sprint(ids) != fmt.Sprint([]int{grades[0].ID, grades[1].ID}) || grades[1].Kind != Test {
+		t.Errorf("Grades() = %+v, want the two grades with IDs %v", grades, ids)
+	}
+	absent.StudentID = id
+	if got, err := s.StudentAttendance(id); err != nil || len(got) != 1 || got[0] != absent {
+		t.Errorf("StudentAttendance() = %+v, %v; want %+v", got, err, absent)
+	}
+
+	// A missing subject fails the restore, which leaves nothing behind.
+	r.Grades[1].SubjectID = 42
+	if _, _, err := s.RestoreStudent(r); !errors.Is(err, ErrConstraint) {
+		t.Errorf("RestoreStudent() with a missing subject error = %v, want %v", err, ErrConstraint)
+	}
+	if all, err := s.Students(); err != nil || len(all) != len(students)+1 {
+		t.Errorf("Students() after a failed restore = %+v, %v; want %d", all, err, len(students)+1)
+	}
+	if all, err := s.Grades(GradeFilter{}); err != nil || len(all) != 2 {
+		t.Errorf("Grades() after a failed restore = %+v, %v; want the two restored before", all, err)
+	}
+}
+
+func testGrades(t *testing.T, s Storage) {
+	teacher, subjects, students, classes := gradeFixture(t, s)
+	for _, g := range []struct {
+		student, subject int
+		date             string
+		mark             Mark
+	}{
+		{students[0], subjects[0], "2025-10-01", "7"},
+		{students[0], subjects[1], "2025-09-15", Passed},
+		{students[1], subjects[0], "2025-09-20", NotGraded},
+		{students[1], subjects[0], "2025-12-01", "10"},
+	} {
+		if _, err := s.AddGrade(g.student, g.subject, teacher, g.date, g.mark); err != nil {
+			t.Fatalf("AddGrade(%v) failed: %v", g, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		filter GradeFilter
+		want   []Mark
+	}{
+		{GradeFilter{}, []Mark{Passed, NotGraded, "7", "10"}},
+		{GradeFilter{StudentID: students[0]}, []Mark{Passed, "7"}},
+		{GradeFilter{ClassID: classes[1]}, []Mark{NotGraded, "10"}},
+		{GradeFilter{SubjectID: subjects[0]}, []Mark{NotGraded, "7", "10"}},
+		{GradeFilter{ClassID: classes[0], SubjectID: subjects[0]}, []Mark{"7"}},
+		{GradeFilter{From: "2025-09-20", To: "2025-10-01"}, []Mark{NotGraded, "7"}},
+	} {
+		grades, err := s.Grades(tc.filter)
+		if err != nil {
+			t.Fatalf("Grades(%+v) failed: %v", tc.filter, err)
+		}
+		var got []Mark
+		for _, g := range grades {
+			got = append(got, g.Mark)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("Grades(%+v) = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+
+	if _, err := s.AddGrade(students[0], subjects[0], teacher, "2025-10-01", "11"); !errors.Is(err, ErrValidation) {
+		t.Errorf("AddGrade() of 11 error = %v, want %v", err, ErrValidation)
+	}
+	if _, err := s.AddGrade(students[0], subjects[0], teacher, "01.10.2025", "7"); !errors.Is(err, ErrValidation) {
+		t.Errorf("AddGrade() with a bad date error = %v, want %v", err, ErrValidation)
+	}
+	if _, err := s.AddGrade(42, subjects[0], teacher, "2025-10-01", "7"); !errors.Is(err, ErrConstraint) {
+		t.Errorf("AddGrade() of a missing student error = %v, want %v", err, ErrConstraint)
+	}
+	if err := s.DeleteSubject(subjects[1]); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteSubject() of a graded subject error = %v, want %v", err, ErrConstraint)
+	}
+	if err := s.DeleteTeacher(teacher); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteTeacher() of a grading teacher error = %v, want %v", err, ErrConstraint)
+	}
+
+	// Grades go away together with the student.
+	if err := s.DeleteStudent(students[1]); err != nil {
+		t.Fatal(err)
+	}
+	if grades, err := s.Grades(GradeFilter{SubjectID: subjects[0]}); err != nil || len(grades) != 1 {
+		t.Errorf("Grades() after deleting a student = %+v, %v; want one grade", grades, err)
+	}
+}
+
+func testGradeCorrections(t *testing.T, s Storage) {
+	teacher, subjects, students, _ := gradeFixture(t, s)
+	id, err := s.AddGrade(students[0], subjects[0], teacher, "2025-10-01", "6")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CorrectGrade(id, "8", "Nepareizi saskaitīti punkti"); err != nil {
+		t.Fatalf("CorrectGrade() failed: %v", err)
+	}
+	if err := s.CorrectGrade(id, NotGraded, "Darbs norakstīts"); err != nil {
+		t.Fatalf("CorrectGrade() failed: %v", err)
+	}
+	for desc, err := range map[string]error{
+		"same mark":  s.CorrectGrade(id, NotGraded, "Nekas"),
+		"no reason":  s.CorrectGrade(id, "9", " "),
+		"bad mark":   s.CorrectGrade(id, "0", "Nekas"),
+		"wrong case": s.CorrectGrade(id, "NI", "Nekas"),
+	} {
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("CorrectGrade() with %s error = %v, want %v", desc, err, ErrValidation)
+		}
+	}
+	if err := s.CorrectGrade(42, "9", "Nekas"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("CorrectGrade() of a missing grade error = %v, want %v", err, ErrNotFound)
+	}
+
+	g, err := s.Grade(id)
+	if err != nil {
+		t.Fatalf("Grade() failed: %v", err)
+	}
+	if g.Mark != NotGraded || !g.Corrected {
+		t.Errorf("Grade() = %+v, want a corrected nv", g)
+	}
+	history, err := s.GradeCorrections(id)
+	if err != nil {
+		t.Fatalf("GradeCorrections() failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("GradeCorrections() = %+v, want 2 corrections", history)
+	}
+	if c := history[0]; c.OldMark != "6" || c.NewMark != "8" || c.Reason != "Nepareizi saskaitīti punkti" || c.CorrectedAt == "" {
+		t.Errorf("first correction = %+v, want 6 to 8 with the reason and time", c)
+	}
+	if c := history[1]; c.OldMark != "8" || c.NewMark != NotGraded {
+		t.Errorf("second correction = %+v, want 8 to nv", c)
+	}
+
+	if err := s.DeleteGrade(id); err != nil {
+		t.Fatalf("DeleteGrade() failed: %v", err)
+	}
+	if history, err := s.GradeCorrections(id); err != nil || len(history) != 0 {
+		t.Errorf("GradeCorrections() after delete = %+v, %v; want none", history, err)
+	}
+	if _, err := s.Grade(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Grade() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testSaveGrades(t *testing.T, s Storage) {
+	teacher, subjects, students, _ := gradeFixture(t, s)
+	corrected, err := s.AddGrade(students[0], subjects[0], teacher, "2025-10-01", "6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := s.AddGrade(students[1], subjects[0], teacher, "2025-10-01", "4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := s.SaveGrades([]GradeChange{
+		{ID: corrected, Mark: "7", Reason: "Labots"},
+		{ID: deleted},
+		{StudentID: students[1], SubjectID: subjects[0], TeacherID: teacher, Date: "2025-10-08", Kind: Test, Mark: Passed},
+	})
+	if err != nil {
+		t.Fatalf("SaveGrades() failed: %v", err)
+	}
+	if grades, err := s.Grades(GradeFilter{StudentID: students[1]}); err != nil || len(grades) != 1 || grades[0].Kind != Test || fmt.Sprint(ids) != fmt.Sprint([]int{grades[0].ID}) {
+		t.Errorf("Grades() = %+v, %v; want the new grade of a test with ID in %v", grades, err, ids)
+	}
+	if g, err := s.Grade(corrected); err != nil || g.Kind != DailyWork {
+		t.Errorf("Grade() = %+v, %v; want the grade of daily work", g, err)
+	}
+	_, err = s.SaveGrades([]GradeChange{
+		{StudentID: students[0], SubjectID: subjects[0], TeacherID: teacher, Date: "2025-10-08", Kind: "quiz", Mark: "5"},
+	})
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("SaveGrades() of an unknown kind error = %v, want %v", err, ErrValidation)
+	}
+	want := func(desc string, marks ...Mark) {
+		t.Helper()
+		grades, err := s.Grades(GradeFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []Mark
+		for _, g := range grades {
+			got = append(got, g.Mark)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(marks) {
+			t.Errorf("grades %s = %v, want %v", desc, got, marks)
+		}
+	}
+	want("after SaveGrades()", "7", Passed)
+
+	// The last change fails, so none of them is applied.
+	_, err = s.SaveGrades([]GradeChange{
+		{ID: corrected, Mark: "8", Reason: "Labots"},
+		{StudentID: students[0], SubjectID: subjects[1], TeacherID: teacher, Date: "2025-10-09", Mark: "9"},
+		{ID: 42},
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("SaveGrades() with a missing grade error = %v, want %v", err, ErrNotFound)
+	}
+	want("after a failed SaveGrades()", "7", Passed)
+	if history, err := s.GradeCorrections(corrected); err != nil || len(history) != 1 {
+		t.Errorf("GradeCorrections() = %+v, %v; want only the first correction", history, err)
+	}
+}
+
+func testAttendance(t *testing.T, s Storage) {
+	_, _, students, classes := gradeFixture(t, s)
+	for _, e := range []AttendanceEntry{
+		{StudentID: students[0], Date: "2025-10-01", Lesson: 2, Status: Absent},
+		{StudentID: students[0], Date: "2025-10-01", Lesson: 1, Status: Late},
+		{StudentID: students[1], Date: "2025-10-01", Lesson: 1, Status: Absent},
+		{StudentID: students[0], Date: "2025-10-02", Lesson: 1, Status: Absent},
+	} {
+		if err := s.SetAttendance(e); err != nil {
+			t.Fatalf("SetAttendance(%+v) failed: %v", e, err)
+		}
+	}
+	// Changing and clearing entries.
+	excused := AttendanceEntry{StudentID: students[0], Date: "2025-10-01", Lesson: 2, Status: Excused, Reason: Illness}
+	if err := s.SetAttendance(excused); err != nil {
+		t.Fatalf("SetAttendance() of an excused absence failed: %v", err)
+	}
+	if err := s.SetAttendance(AttendanceEntry{StudentID: students[0], Date: "2025-10-01", Lesson: 1, Status: Present}); err != nil {
+		t.Fatalf("SetAttendance() of a present student failed: %v", err)
+	}
+
+	got, err := s.Attendance(classes[0], "2025-10-01")
+	if err != nil {
+		t.Fatalf("Attendance() failed: %v", err)
+	}
+	if len(got) != 1 || got[0] != excused {
+		t.Errorf("Attendance() = %+v, want only %+v", got, excused)
+	}
+	later := AttendanceEntry{StudentID: students[0], Date: "2025-10-02", Lesson: 1, Status: Absent}
+	if got, err := s.StudentAttendance(students[0]); err != nil || len(got) != 2 || got[0] != excused || got[1] != later {
+		t.Errorf("StudentAttendance() = %+v, %v; want %+v and %+v", got, err, excused, later)
+	}
+
+	for desc, e := range map[string]AttendanceEntry{
+		"bad date":           {StudentID: students[0], Date: "2025-13-01", Lesson: 1, Status: Absent},
+		"lesson 0":           {StudentID: students[0], Date: "2025-10-01", Lesson: 0, Status: Absent},
+		"unknown status":     {StudentID: students[0], Date: "2025-10-01", Lesson: 1, Status: "sick"},
+		"excused, no reason": {StudentID: students[0], Date: "2025-10-01", Lesson: 1, Status: Excused},
+		"reason when absent": {StudentID: students[0], Date: "2025-10-01", Lesson: 1, Status: Absent, Reason: Family},
+	} {
+		if err := s.SetAttendance(e); !errors.Is(err, ErrValidation) {
+			t.Errorf("SetAttendance() with %s error = %v, want %v", desc, err, ErrValidation)
+		}
+	}
+	for _, status := range []Presence{Absent, Present} {
+		e := AttendanceEntry{StudentID: 42, Date: "2025-10-01", Lesson: 1, Status: status}
+		if err := s.SetAttendance(e); !errors.Is(err, ErrConstraint) {
+			t.Errorf("SetAttendance() of a missing student as %s error = %v, want %v", status, err, ErrConstraint)
+		}
+	}
+}
+
+func testAbsenceTotals(t *testing.T, s Storage) {
+	_, _, students, classes := gradeFixture(t, s)
+	// A second student in the first class without any absences.
+	other, err := s.AddStudent("Jānis", "Zariņš")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AssignClassToStudent(other, classes[0]); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []AttendanceEntry{
+		{StudentID: students[0], Date: "2025-09-30", Lesson: 1, Status: Absent},
+		{StudentID: students[0], Date: "2025-10-01", Lesson: 1, Status: Absent},
+		{StudentID: students[0], Date: "2025-10-01", Lesson: 2, Status: Absent},
+		{StudentID: students[0], Date: "2025-10-01", Lesson: 3, Status: Late},
+		{StudentID: students[0], Date: "2025-10-02", Lesson: 1, Status: Excused, Reason: Family},
+		{StudentID: students[1], Date: "2025-10-01", Lesson: 1, Status: Absent},
+	} {
+		if err := s.SetAttendance(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	totals, err := s.AbsenceTotals(classes[0], "2025-10-01", "2025-10-02")
+	if err != nil {
+		t.Fatalf("AbsenceTotals() failed: %v", err)
+	}
+	want := []AbsenceTotal{
+		{StudentID: students[0], Name: "Anna", Surname: "Bērziņa", Absent: 2, Late: 1, Excused: 1},
+		{StudentID: other, Name: "Jānis", Surname: "Zariņš"},
+	}
+	if fmt.Sprint(totals) != fmt.Sprint(want) {
+		t.Errorf("AbsenceTotals() = %+v, want %+v", totals, want)
+	}
+	totals, err = s.AbsenceTotals(classes[0], "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 2 || totals[0].Absent != 3 {
+		t.Errorf("AbsenceTotals() without a range = %+v, want 3 absences of Anna", totals)
+	}
+}
+
+func testBells(t *testing.T, s Storage) {
+	for _, b := range []BellEntry{
+		{Lesson: 2, Starts: "09:25", Ends: "10:05"},
+		{Lesson: 1, Starts: "08:30", Ends: "09:10"},
+	} {
+		if err := s.SetBell(b); err != nil {
+			t.Fatalf("SetBell(%+v) failed: %v", b, err)
+		}
+	}
+	// Moving a lesson keeps a single entry for it.
+	if err := s.SetBell(BellEntry{Lesson: 2, Starts: "09:20", Ends: "10:00"}); err != nil {
+		t.Fatalf("SetBell() of an existing lesson failed: %v", err)
+	}
+	for desc, b := range map[string]BellEntry{
+		"bad time":        {Lesson: 3, Starts: "10:15", Ends: "25:00"},
+		"ends too early":  {Lesson: 3, Starts: "10:15", Ends: "10:15"},
+		"overlaps before": {Lesson: 3, Starts: "09:50", Ends: "10:30"},
+		"overlaps after":  {Lesson: 0, Starts: "08:00", Ends: "08:40"},
+	} {
+		if err := s.SetBell(b); !errors.Is(err, ErrValidation) {
+			t.Errorf("SetBell() with %s error = %v, want %v", desc, err, ErrValidation)
+		}
+	}
+	bells, err := s.Bells()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BellEntry{{1, "08:30", "09:10"}, {2, "09:20", "10:00"}}
+	if fmt.Sprint(bells) != fmt.Sprint(want) {
+		t.Errorf("Bells() = %v, want %v", bells, want)
+	}
+	if err := s.DeleteBell(1); err != nil {
+		t.Fatalf("DeleteBell() failed: %v", err)
+	}
+	if err := s.DeleteBell(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteBell() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testTimetable(t *testing.T, s Storage) {
+	teacher, subjects, _, classes := gradeFixture(t, s)
+	other, err := s.AddTeacher("Pēteris", "Liepa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	monday := SlotEntry{ClassID: classes[0], Weekday: 1, Lesson: 1, SubjectID: subjects[0], TeacherID: teacher, Room: "101"}
+	id, err := s.AddSlot(monday)
+	if err != nil {
+		t.Fatalf("AddSlot() failed: %v", err)
+	}
+	if _, err := s.AddSlot(SlotEntry{ClassID: classes[1], Weekday: 1, Lesson: 2, SubjectID: subjects[1], TeacherID: other}); err != nil {
+		t.Fatalf("AddSlot() failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		slot     SlotEntry
+		resource string
+	}{
+		{"class in two places", SlotEntry{ClassID: classes[0], Weekday: 1, Lesson: 1, SubjectID: subjects[1], TeacherID: other, Room: "102"}, "class"},
+		{"teacher double-booked", SlotEntry{ClassID: classes[1], Weekday: 1, Lesson: 1, SubjectID: subjects[0], TeacherID: teacher, Room: "102"}, "teacher"},
+		{"room double-booked", SlotEntry{ClassID: classes[1], Weekday: 1, Lesson: 1, SubjectID: subjects[1], TeacherID: other, Room: "101"}, "room"},
+	} {
+		_, err := s.AddSlot(tc.slot)
+		var cerr *ConflictError
+		if !errors.Is(err, ErrConflict) || !errors.As(err, &cerr) {
+			t.Errorf("AddSlot() with %s error = %v, want %v", tc.desc, err, ErrConflict)
+			continue
+		}
+		if cerr.Resource != tc.resource || cerr.Slot.ID != id || cerr.Slot.Subject != "Matemātika" {
+			t.Errorf("AddSlot() with %s conflict = %+v, want %s of slot %d with names", tc.desc, cerr, tc.resource, id)
+		}
+	}
+	// Lessons without a room never clash on the room.
+	if _, err := s.AddSlot(SlotEntry{ClassID: classes[0], Weekday: 2, Lesson: 2, SubjectID: subjects[1], TeacherID: teacher}); err != nil {
+		t.Errorf("AddSlot() without a room failed: %v", err)
+	}
+	if _, err := s.AddSlot(SlotEntry{ClassID: classes[0], Weekday: 6, Lesson: 1, SubjectID: subjects[0], TeacherID: teacher}); !errors.Is(err, ErrValidation) {
+		t.Errorf("AddSlot() on Saturday error = %v, want %v", err, ErrValidation)
+	}
+
+	mine, err := s.Slots(SlotFilter{TeacherID: teacher})
+	if err != nil {
+		t.Fatalf("Slots() failed: %v", err)
+	}
+	if len(mine) != 2 || mine[0].ID != id || mine[0].Year != "5" || mine[0].TeacherSurname != "Kalniņa" || mine[1].Weekday != 2 {
+		t.Errorf("Slots() of the teacher = %+v, want Monday and Tuesday with names", mine)
+	}
+	if all, err := s.Slots(SlotFilter{}); err != nil || len(all) != 3 {
+		t.Errorf("Slots() = %+v, %v; want 3 slots", all, err)
+	}
+	if err := s.DeleteClass(classes[1]); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteClass() of a class with lessons error = %v, want %v", err, ErrConstraint)
+	}
+	if err := s.DeleteSlot(id); err != nil {
+		t.Fatalf("DeleteSlot() failed: %v", err)
+	}
+	if err := s.DeleteSlot(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteSlot() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testLessons(t *testing.T, s Storage) {
+	_, subjects, _, classes := gradeFixture(t, s)
+	id, err := s.AddLesson(LessonEntry{ClassID: classes[0], SubjectID: subjects[0], Date: "2025-09-30", Topic: "Daļskaitļi"})
+	if err != nil {
+		t.Fatalf("AddLesson() failed: %v", err)
+	}
+	if _, err := s.AddLesson(LessonEntry{ClassID: classes[0], SubjectID: subjects[1], Date: "2025-09-29", Topic: "Skriešana"}); err != nil {
+		t.Fatalf("AddLesson() failed: %v", err)
+	}
+	if _, err := s.AddLesson(LessonEntry{ClassID: classes[0], SubjectID: 999, Date: "2025-09-30", Topic: "Nekas"}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("AddLesson() of a missing subject error = %v, want %v", err, ErrConstraint)
+	}
+	for desc, l := range map[string]LessonEntry{
+		"no topic":          {Date: "2025-09-30", Topic: " "},
+		"bad date":          {Date: "30.09.2025", Topic: "Daļskaitļi"},
+		"no due date":       {Date: "2025-09-30", Topic: "Daļskaitļi", Homework: "12. lpp."},
+		"due before lesson": {Date: "2025-09-30", Topic: "Daļskaitļi", Homework: "12. lpp.", DueDate: "2025-09-29"},
+		"due without work":  {Date: "2025-09-30", Topic: "Daļskaitļi", DueDate: "2025-10-01"},
+	} {
+		l.ClassID, l.SubjectID = classes[0], subjects[0]
+		if _, err := s.AddLesson(l); !errors.Is(err, ErrValidation) {
+			t.Errorf("AddLesson() with %s error = %v, want %v", desc, err, ErrValidation)
+		}
+	}
+
+	update := LessonEntry{ID: id, Date: "2025-09-30", Topic: "Daļskaitļu saskaitīšana", Homework: "12. lpp.\n1.-3. uzd.", DueDate: "2025-10-02"}
+	if err := s.UpdateLesson(update); err != nil {
+		t.Fatalf("UpdateLesson() failed: %v", err)
+	}
+	got, err := s.Lesson(id)
+	if err != nil {
+		t.Fatalf("Lesson() failed: %v", err)
+	}
+	if got.Topic != update.Topic || got.Homework != update.Homework || got.ClassID != classes[0] || got.Subject != "Matemātika" || got.Modifier != "a" {
+		t.Errorf("Lesson() after update = %+v, want the new topic and homework with names", got)
+	}
+	if err := s.UpdateLesson(LessonEntry{ID: 999, Date: "2025-09-30", Topic: "Nekas"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateLesson() of a missing lesson error = %v, want %v", err, ErrNotFound)
+	}
+
+	all, err := s.Lessons(LessonFilter{ClassID: classes[0]})
+	if err != nil {
+		t.Fatalf("Lessons() failed: %v", err)
+	}
+	if len(all) != 2 || all[0].Topic != "Skriešana" || all[1].ID != id {
+		t.Errorf("Lessons() = %+v, want 2 lessons ordered by date", all)
+	}
+	if some, err := s.Lessons(LessonFilter{SubjectID: subjects[0], From: "2025-10-01"}); err != nil || len(some) != 0 {
+		t.Errorf("Lessons() from October = %+v, %v; want none", some, err)
+	}
+	if err := s.DeleteSubject(subjects[1]); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteSubject() of a subject with lessons error = %v, want %v", err, ErrConstraint)
+	}
+	if err := s.DeleteLesson(id); err != nil {
+		t.Fatalf("DeleteLesson() failed: %v", err)
+	}
+	if _, err := s.Lesson(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lesson() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testHomework(t *testing.T, s Storage) {
+	_, subjects, _, classes := gradeFixture(t, s)
+	for _, l := range []LessonEntry{
+		{ClassID: classes[0], SubjectID: subjects[1], Date: "2025-09-29", Topic: "Skriešana", Homework: "Formas tērps", DueDate: "2025-10-06"},
+		{ClassID: classes[0], SubjectID: subjects[0], Date: "2025-09-30", Topic: "Daļskaitļi", Homework: "12. lpp.", DueDate: "2025-10-06"},
+		{ClassID: classes[0], SubjectID: subjects[0], Date: "2025-10-01", Topic: "Decimāldaļas", Homework: "13. lpp.", DueDate: "2025-10-02"},
+		{ClassID: classes[0], SubjectID: subjects[0], Date: "2025-09-22", Topic: "Atkārtošana", Homework: "10. lpp.", DueDate: "2025-09-23"},
+		{ClassID: classes[0], SubjectID: subjects[0], Date: "2025-10-02", Topic: "Kontroldarbs"},
+		{ClassID: classes[1], SubjectID: subjects[0], Date: "2025-10-01", Topic: "Daļskaitļi", Homework: "12. lpp.", DueDate: "2025-10-03"},
+	} {
+		if _, err := s.AddLesson(l); err != nil {
+			t.Fatalf("AddLesson(%+v) failed: %v", l, err)
+		}
+	}
+	homework, err := s.Homework(classes[0], "2025-10-01")
+	if err != nil {
+		t.Fatalf("Homework() failed: %v", err)
+	}
+	var got []string
+	for _, l := range homework {
+		got = append(got, l.DueDate+" "+l.Subject)
+	}
+	want := []string{"2025-10-02 Matemātika", "2025-10-06 Matemātika", "2025-10-06 Sports"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Homework() = %q, want %q", got, want)
+	}
+}
+
+// addSchoolYear adds the school year starting in September of start.
+func addSchoolYear(t *testing.T, s Storage, start int) int {
+	t.Helper()
+	id, err := s.AddSchoolYear(SchoolYearEntry{
+		Name:   fmt.Sprintf("%d/%d", start, start+1),
+		Starts: fmt.Sprintf("%d-09-01", start),
+		Ends:   fmt.Sprintf("%d-08-31", start+1),
+	})
+	if err != nil {
+		t.Fatalf("AddSchoolYear() failed: %v", err)
+	}
+	return id
+}
+
+func testSchoolYears(t *testing.T, s Storage) {
+	old, err := s.AddClass("5", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := s.Class(old); c.SchoolYearID.Valid {
+		t.Errorf("class added without school years is in school year %d, want none", c.SchoolYearID.Int64)
+	}
+	next := addSchoolYear(t, s, 2026)
+	first := addSchoolYear(t, s, 2025)
+	for desc, y := range map[string]SchoolYearEntry{
+		"bad name":      {Name: "2027", Starts: "2027-09-01", Ends: "2028-08-31"},
+		"ends early":    {Name: "2027/2028", Starts: "2027-09-01", Ends: "2027-08-31"},
+		"wrong start":   {Name: "2027/2028", Starts: "2028-09-01", Ends: "2029-08-31"},
+		"overlapping":   {Name: "2024/2025", Starts: "2024-09-01", Ends: "2025-09-01"},
+		"bad start day": {Name: "2027/2028", Starts: "2027-09-31", Ends: "2028-08-31"},
+	} {
+		if _, err := s.AddSchoolYear(y); !errors.Is(err, ErrValidation) {
+			t.Errorf("AddSchoolYear() with %s error = %v, want %v", desc, err, ErrValidation)
+		}
+	}
+	if _, err := s.AddSchoolYear(SchoolYearEntry{Name: "2025/2026", Starts: "2025-09-01", Ends: "2026-08-31"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("AddSchoolYear() of an existing name error = %v, want %v", err, ErrDuplicate)
+	}
+	years, err := s.SchoolYears()
+	if err != nil {
+		t.Fatalf("SchoolYears() failed: %v", err)
+	}
+	if len(years) != 2 || years[0].ID != first || years[1].ID != next {
+		t.Errorf("SchoolYears() = %+v, want 2025/2026 and 2026/2027", years)
+	}
+
+	// New classes go to the latest school year, so 5.a exists twice.
+	id, err := s.AddClass("5", "a")
+	if err != nil {
+		t.Fatalf("AddClass() of 5.a in a new school year failed: %v", err)
+	}
+	c, err := s.Class(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.SchoolYearID.Valid || int(c.SchoolYearID.Int64) != next {
+		t.Errorf("class is in school year %v, want %d", c.SchoolYearID, next)
+	}
+	if _, err := s.AddClass("5", "a"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("AddClass() of a second 5.a in a school year error = %v, want %v", err, ErrDuplicate)
+	}
+	if err := s.DeleteSchoolYear(next); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteSchoolYear() with classes error = %v, want %v", err, ErrConstraint)
+	}
+	if err := s.DeleteSchoolYear(first); err != nil {
+		t.Fatalf("DeleteSchoolYear() failed: %v", err)
+	}
+	if _, err := s.SchoolYear(first); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SchoolYear() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testTerms(t *testing.T, s Storage) {
+	year := addSchoolYear(t, s, 2025)
+	autumn, err := s.AddTerm(TermEntry{SchoolYearID: year, Name: "1. semestris", Starts: "2025-09-01", Ends: "2025-12-31"})
+	if err != nil {
+		t.Fatalf("AddTerm() failed: %v", err)
+	}
+	if _, err := s.AddTerm(TermEntry{SchoolYearID: year, Name: "2. semestris", Starts: "2026-01-05", Ends: "2026-05-29"}); err != nil {
+		t.Fatalf("AddTerm() failed: %v", err)
+	}
+	for desc, term := range map[string]TermEntry{
+		"no name":         {SchoolYearID: year, Starts: "2026-06-01", Ends: "2026-06-30"},
+		"overlapping":     {SchoolYearID: year, Name: "Vasara", Starts: "2026-05-20", Ends: "2026-06-30"},
+		"outside of year": {SchoolYearID: year, Name: "Vasara", Starts: "2026-06-01", Ends: "2026-09-30"},
+	} {
+		if _, err := s.AddTerm(term); !errors.Is(err, ErrValidation) {
+			t.Errorf("AddTerm() with %s error = %v, want %v", desc, err, ErrValidation)
+		}
+	}
+	if _, err := s.AddTerm(TermEntry{SchoolYearID: 999, Name: "Vasara", Starts: "2026-06-01", Ends: "2026-06-30"}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("AddTerm() of a missing school year error = %v, want %v", err, ErrConstraint)
+	}
+	terms, err := s.Terms(year)
+	if err != nil {
+		t.Fatalf("Terms() failed: %v", err)
+	}
+	if len(terms) != 2 || terms[0].ID != autumn {
+		t.Errorf("Terms() = %+v, want both semesters in order", terms)
+	}
+	if err := s.DeleteTerm(autumn); err != nil {
+		t.Fatalf("DeleteTerm() failed: %v", err)
+	}
+	if err := s.DeleteSchoolYear(year); err != nil {
+		t.Fatalf("DeleteSchoolYear() with a term failed: %v", err)
+	}
+	if terms, err := s.Terms(year); err != nil || len(terms) != 0 {
+		t.Errorf("Terms() of a deleted school year = %+v, %v; want none", terms, err)
+	}
+}
+
+// promotionFixture creates the school years 2025/2026 and 2026/2027. The
+// first one has 5.a with two students, an empty 5.b and 12.a with a student.
+// The second one has 6.b already.
+func promotionFixture(t *testing.T, s Storage) (from, to int, classes map[string]int, students [3]int) {
+	t.Helper()
+	from = addSchoolYear(t, s, 2025)
+	classes = make(map[string]int)
+	for _, c := range [][2]string{{"5", "a"}, {"5", "b"}, {"12", "a"}} {
+		id, err := s.AddClass(c[0], c[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		classes[c[0]+"."+c[1]] = id
+	}
+	for i, c := range []string{"5.a", "5.a", "12.a"} {
+		id, err := s.AddStudent("Anna", "Bērziņa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AssignClassToStudent(id, classes[c]); err != nil {
+			t.Fatal(err)
+		}
+		students[i] = id
+	}
+	to = addSchoolYear(t, s, 2026)
+	id, err := s.AddClass("6", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	classes["next 6.b"] = id
+	return from, to, classes, students
+}
+
+// yearClasses returns the classes of a school year as "5.a" strings.
+func yearClasses(t *testing.T, s Storage, schoolYearID int) []string {
+	t.Helper()
+	all, err := s.Classes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range all {
+		if c.SchoolYearID.Valid && int(c.SchoolYearID.Int64) == schoolYearID {
+			names = append(names, c.Year+"."+c.Modifier)
+		}
+	}
+	return names
+}
+
+func testPromote(t *testing.T, s Storage) {
+	from, to, classes, students := promotionFixture(t, s)
+	if _, err := s.Promote(to, from); !errors.Is(err, ErrValidation) {
+		t.Errorf("Promote() into an earlier school year error = %v, want %v", err, ErrValidation)
+	}
+	id, err := s.Promote(from, to)
+	if err != nil {
+		t.Fatalf("Promote() failed: %v", err)
+	}
+	if got, want := fmt.Sprint(yearClasses(t, s, to)), "[6.b 6.a]"; got != want {
+		t.Errorf("classes of the next school year = %s, want %s", got, want)
+	}
+	for _, studentID := range students[:2] {
+		g, err := s.Group(studentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Year.String != "6" || g.Modifier.String != "a" {
+			t.Errorf("student %d is in %s.%s after promotion, want 6.a", studentID, g.Year.String, g.Modifier.String)
+		}
+	}
+	if g, err := s.Group(students[2]); err != nil || g.ClassID.Valid {
+		t.Errorf("graduate is in class %v, %v; want none", g.ClassID, err)
+	}
+	graduates, err := s.Graduates()
+	if err != nil {
+		t.Fatalf("Graduates() failed: %v", err)
+	}
+	if len(graduates) != 1 || graduates[0].StudentID != students[2] || graduates[0].ClassID != classes["12.a"] || graduates[0].SchoolYear != "2025/2026" {
+		t.Errorf("Graduates() = %+v, want the student of 12.a in 2025/2026", graduates)
+	}
+	promotions, err := s.Promotions()
+	if err != nil {
+		t.Fatalf("Promotions() failed: %v", err)
+	}
+	want := PromotionEntry{ID: id, FromYearID: from, ToYearID: to, FromYear: "2025/2026", ToYear: "2026/2027", Promoted: 2, Graduated: 1}
+	if len(promotions) != 1 || promotions[0].PromotedAt == "" {
+		t.Fatalf("Promotions() = %+v, want %+v", promotions, want)
+	}
+	got := promotions[0]
+	got.PromotedAt = ""
+	if got != want {
+		t.Errorf("Promotions() = %+v, want %+v", got, want)
+	}
+	if _, err := s.Promote(from, to); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Promote() twice error = %v, want %v", err, ErrDuplicate)
+	}
+	if err := s.DeleteClass(classes["12.a"]); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteClass() of a graduated class error = %v, want %v", err, ErrConstraint)
+	}
+}
+
+func testUndoPromotion(t *testing.T, s Storage) {
+	from, to, classes, students := promotionFixture(t, s)
+	first, err := s.Promote(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := addSchoolYear(t, s, 2027)
+	second, err := s.Promote(to, last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UndoPromotion(first); !errors.Is(err, ErrValidation) {
+		t.Errorf("UndoPromotion() of an earlier promotion error = %v, want %v", err, ErrValidation)
+	}
+	if err := s.UndoPromotion(second); err != nil {
+		t.Fatalf("UndoPromotion() failed: %v", err)
+	}
+
+	// A class created by the promotion that is in use blocks the undo.
+	all, err := s.Classes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := all[len(all)-1]
+	other, err := s.AddStudent("Jānis", "Zariņš")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AssignClassToStudent(other, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UndoPromotion(first); !errors.Is(err, ErrConstraint) {
+		t.Errorf("UndoPromotion() with a class in use error = %v, want %v", err, ErrConstraint)
+	}
+	if g, _ := s.Group(students[0]); int(g.ClassID.Int64) != created.ID {
+		t.Errorf("failed undo moved the student to class %v, want %d", g.ClassID, created.ID)
+	}
+	if err := s.UnassignClassFromStudent(other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.UndoPromotion(first); err != nil {
+		t.Fatalf("UndoPromotion() failed: %v", err)
+	}
+	for i, c := range []string{"5.a", "5.a", "12.a"} {
+		if g, _ := s.Group(students[i]); int(g.ClassID.Int64) != classes[c] {
+			t.Errorf("student %d is in class %v after undo, want %s", students[i], g.ClassID, c)
+		}
+	}
+	if got, want := fmt.Sprint(yearClasses(t, s, to)), "[6.b]"; got != want {
+		t.Errorf("classes of the next school year after undo = %s, want %s", got, want)
+	}
+	if graduates, err := s.Graduates(); err != nil || len(graduates) != 0 {
+		t.Errorf("Graduates() after undo = %+v, %v; want none", graduates, err)
+	}
+	if promotions, err := s.Promotions(); err != nil || len(promotions) != 0 {
+		t.Errorf("Promotions() after undo = %+v, %v; want none", promotions, err)
+	}
+	if err := s.UndoPromotion(first); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UndoPromotion() twice error = %v, want %v", err, ErrNotFound)
+	}
+	// The school year can be promoted again.
+	if _, err := s.Promote(from, to); err != nil {
+		t.Errorf("Promote() after undo failed: %v", err)
+	}
+}
+
+func testImportStudents(t *testing.T, s Storage) {
+	year := addSchoolYear(t, s, 2025)
+	existing, err := s.AddClass("5", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := s.ImportStudents([]ImportEntry{
+		{Name: "Anna", Surname: "Zariņa", Year: "5", Modifier: "A"},
+		{Name: "Jānis", Surname: "Bērziņš", Year: "6", Modifier: "b"},
+		{Name: "Pēteris", Surname: "Ozols"},
+		{Name: "Marta", Surname: "Liepa", Year: "6", Modifier: "B"},
+	})
+	if err != nil {
+		t.Fatalf("ImportStudents() failed: %v", err)
+	}
+	if len(ids) != 4 {
+		t.Fatalf("ImportStudents() = %v, want 4 IDs", ids)
+	}
+	g, err := s.Group(ids[0])
+	if err != nil || int(g.ClassID.Int64) != existing {
+		t.Errorf("Group(%d) = %+v, %v; want the existing class %d", ids[0], g, err, existing)
+	}
+	g, err = s.Group(ids[1])
+	if err != nil || !g.ClassID.Valid || g.Year.String != "6" {
+		t.Fatalf("Group(%d) = %+v, %v; want a new class 6.b", ids[1], g, err)
+	}
+	if c, err := s.Class(int(g.ClassID.Int64)); err != nil || int(c.SchoolYearID.Int64) != year {
+		t.Errorf("Class(%d) = %+v, %v; want it in the school year %d", g.ClassID.Int64, c, err, year)
+	}
+	if g, err := s.Group(ids[2]); err != nil || g.ClassID.Valid {
+		t.Errorf("Group(%d) = %+v, %v; want no class", ids[2], g, err)
+	}
+	// Modifiers match regardless of case.
+	if g2, err := s.Group(ids[3]); err != nil || g2.ClassID != g.ClassID {
+		t.Errorf("Group(%d) = %+v, %v; want the class 6.b", ids[3], g2, err)
+	}
+
+	// A bad entry at the end leaves nothing behind.
+	_, err = s.ImportStudents([]ImportEntry{
+		{Name: "Līga", Surname: "Kalna", Year: "7", Modifier: "c"},
+		{Name: "", Surname: "Liepa"},
+	})
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("ImportStudents() with an empty name = %v, want %v", err, ErrValidation)
+	}
+	students, _ := s.Students()
+	classes, _ := s.Classes()
+	if len(students) != 4 || len(classes) != 2 {
+		t.Errorf("after a failed import there are %d students and %d classes, want 4 and 2", len(students), len(classes))
+	}
+}
+
+func testUsers(t *testing.T, s Storage) {
+	teacher, err := s.AddTeacher("Ilze", "Kalniņa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	teacherID := sql.NullInt64{Int64: int64(teacher), Valid: true}
+
+	admin, err := s.AddUser(UserEntry{Username: "admin", PasswordHash: "x", Role: RoleAdmin})
+	if err != nil {
+		t.Fatalf("AddUser(admin) failed: %v", err)
+	}
+	id, err := s.AddUser(UserEntry{Username: "ilze", PasswordHash: "y", Role: RoleTeacher, TeacherID: teacherID})
+	if err != nil {
+		t.Fatalf("AddUser(ilze) failed: %v", err)
+	}
+	if u, err := s.UserByName("ILZE"); err != nil || u.ID != id || u.TeacherID != teacherID {
+		t.Errorf("UserByName(ILZE) = %+v, %v; want user %d of teacher %d", u, err, id, teacher)
+	}
+	if _, err := s.UserByName("nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UserByName(nobody) = %v, want %v", err, ErrNotFound)
+	}
+
+	for _, tc := range []struct {
+		name string
+		u    UserEntry
+		want error
+	}{
+		{"a username differing in case", UserEntry{Username: "Admin", PasswordHash: "x", Role: RoleSecretary}, ErrDuplicate},
+		{"a username with a space", UserEntry{Username: "a b", PasswordHash: "x", Role: RoleSecretary}, ErrValidation},
+		{"no password", UserEntry{Username: "anna", Role: RoleSecretary}, ErrValidation},
+		{"an unknown role", UserEntry{Username: "anna", PasswordHash: "x", Role: "student"}, ErrValidation},
+		{"a teacher without a teacher", UserEntry{Username: "anna", PasswordHash: "x", Role: RoleClassTeacher}, ErrValidation},
+		{"a secretary with a teacher", UserEntry{Username: "anna", PasswordHash: "x", Role: RoleSecretary, TeacherID: teacherID}, ErrValidation},
+		{"a missing teacher", UserEntry{Username: "anna", PasswordHash: "x", Role: RoleTeacher, TeacherID: sql.NullInt64{Int64: 42, Valid: true}}, ErrConstraint},
+	} {
+		if _, err := s.AddUser(tc.u); !errors.Is(err, tc.want) {
+			t.Errorf("AddUser() with %s = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	if err := s.DeleteTeacher(teacher); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteTeacher() of a user = %v, want %v", err, ErrConstraint)
+	}
+	u := UserEntry{ID: id, Username: "ilze.k", PasswordHash: "z", Role: RoleClassTeacher, TeacherID: teacherID}
+	if err := s.UpdateUser(u); err != nil {
+		t.Fatalf("UpdateUser() failed: %v", err)
+	}
+	if got, err := s.User(id); err != nil || got != u {
+		t.Errorf("User(%d) = %+v, %v; want %+v", id, got, err, u)
+	}
+	u.Username = "ADMIN"
+	if err := s.UpdateUser(u); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("UpdateUser() to a taken username = %v, want %v", err, ErrDuplicate)
+	}
+	if err := s.UpdateUser(UserEntry{ID: 42, Username: "x", PasswordHash: "x", Role: RoleAdmin}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateUser(42) = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := s.DeleteUser(id); err != nil {
+		t.Fatalf("DeleteUser() failed: %v", err)
+	}
+	if err := s.DeleteUser(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second DeleteUser() = %v, want %v", err, ErrNotFound)
+	}
+	if users, err := s.Users(); err != nil || len(users) != 1 || users[0].ID != admin {
+		t.Errorf("Users() = %+v, %v; want only the admin", users, err)
+	}
+}
+
+func testAuditLog(t *testing.T, s Storage) {
+	s.SetActor("anna")
+	student, err := s.AddStudent("Līga", "Kalna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := s.AddClass("5", "a")
+	second, _ := s.AddClass("5", "b")
+	if err := s.AssignClassToStudent(student, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AssignClassToStudent(student, second); err != nil {
+		t.Fatal(err)
+	}
+	s.SetActor("bob")
+	if err := s.UpdateStudent(student, "Līga", "Ozola"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddClass("5", "a"); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("AddClass() of a duplicate = %v, want %v", err, ErrDuplicate)
+	}
+	if _, err := s.AddUser(UserEntry{Username: "bob", PasswordHash: "secret-hash", Role: RoleAdmin}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteStudent(student); err != nil {
+		t.Fatal(err)
+	}
+
+	moves, err := s.AuditLog(AuditFilter{Entity: "enrollments", EntityID: student})
+	if err != nil {
+		t.Fatalf("AuditLog() failed: %v", err)
+	}
+	moved := false
+	for _, e := range moves {
+		if e.Actor == "anna" && strings.Contains(e.New.String, fmt.Sprintf(`"class_id":%d`, second)) {
+			moved = true
+		}
+	}
+	if !moved {
+		t.Errorf("AuditLog(enrollments) = %+v, want the move to class %d by anna", moves, second)
+	}
+	if last := moves[0]; last.Action != AuditDelete || last.Actor != "bob" || last.New.Valid {
+		t.Errorf("newest enrollment entry = %+v, want its removal with the student by bob", last)
+	}
+
+	changes, err := s.AuditLog(AuditFilter{Entity: "students", Actor: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Action != AuditDelete || changes[1].Action != AuditUpdate {
+		t.Fatalf("AuditLog(students by bob) = %+v, want the update and the deletion, newest first", changes)
+	}
+	update := changes[1]
+	if update.EntityID != student || !strings.Contains(update.Old.String, `"surname":"Kalna"`) || !strings.Contains(update.New.String, `"surname":"Ozola"`) {
+		t.Errorf("update entry = %+v, want the old and the new surname", update)
+	}
+	if _, err := time.Parse(time.RFC3339, update.At); err != nil {
+		t.Errorf("update entry at %q, want an RFC 3339 time: %v", update.At, err)
+	}
+
+	all, err := s.AuditLog(AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	classes := 0
+	for _, e := range all {
+		if strings.Contains(e.Old.String+e.New.String, "secret-hash") {
+			t.Errorf("entry %+v contains a password hash", e)
+		}
+		if e.Entity == "classes" {
+			classes++
+		}
+	}
+	if classes != 2 {
+		t.Errorf("AuditLog() has %d class entries, want 2 as the duplicate was rejected", classes)
+	}
+	if newest, err := s.AuditLog(AuditFilter{Limit: 1}); err != nil || len(newest) != 1 || newest[0].ID != all[0].ID {
+		t.Errorf("AuditLog(Limit: 1) = %+v, %v; want the newest entry only", newest, err)
+	}
+}
+
+func testFinalGrades(t *testing.T, s Storage) {
+	_, subjects, students, classes := gradeFixture(t, s)
+	year := addSchoolYear(t, s, 2025)
+	other := addSchoolYear(t, s, 2026)
+	term, err := s.AddTerm(TermEntry{SchoolYearID: year, Name: "1. semestris", Starts: "2025-09-01", Ends: "2025-12-31"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	termID := sql.NullInt64{Int64: int64(term), Valid: true}
+
+	if err := s.SaveFinalGrades([]FinalGradeEntry{
+		{StudentID: students[0], SubjectID: subjects[0], SchoolYearID: year, TermID: termID, Average: 6.5, Proposed: "7", Mark: "7"},
+		{StudentID: students[0], SubjectID: subjects[0], SchoolYearID: year, Average: 6.4, Proposed: "6", Mark: "7", Reason: "Olimpiāde"},
+		{StudentID: students[1], SubjectID: subjects[0], SchoolYearID: year, TermID: termID, Average: 4, Proposed: "4", Mark: "4"},
+	}); err != nil {
+		t.Fatalf("SaveFinalGrades() failed: %v", err)
+	}
+	marks := func(f FinalGradeFilter) string {
+		t.Helper()
+		finals, err := s.FinalGrades(f)
+		if err != nil {
+			t.Fatalf("FinalGrades(%+v) failed: %v", f, err)
+		}
+		var got []string
+		for _, g := range finals {
+			if g.FinalizedAt == "" {
+				t.Errorf("final grade %+v has no time of finalizing", g)
+			}
+			got = append(got, fmt.Sprintf("%d:%s", g.StudentID, g.Mark))
+		}
+		return fmt.Sprint(got)
+	}
+	for _, tc := range []struct {
+		filter FinalGradeFilter
+		want   string
+	}{
+		{FinalGradeFilter{}, fmt.Sprintf("[%d:7 %[1]d:7 %d:4]", students[0], students[1])},
+		{FinalGradeFilter{ClassID: classes[1]}, fmt.Sprintf("[%d:4]", students[1])},
+		{FinalGradeFilter{TermID: term}, fmt.Sprintf("[%d:7 %d:4]", students[0], students[1])},
+		{FinalGradeFilter{SchoolYearID: year, YearOnly: true}, fmt.Sprintf("[%d:7]", students[0])},
+		{FinalGradeFilter{SchoolYearID: other}, "[]"},
+	} {
+		if got := marks(tc.filter); got != tc.want {
+			t.Errorf("FinalGrades(%+v) = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+
+	// Saving again replaces the grade of the same period.
+	if err := s.SaveFinalGrades([]FinalGradeEntry{
+		{StudentID: students[1], SubjectID: subjects[0], SchoolYearID: year, TermID: termID, Average: 4, Proposed: "4", Mark: "5", Reason: "Labots"},
+	}); err != nil {
+		t.Fatalf("SaveFinalGrades() of a replacement failed: %v", err)
+	}
+	if got, want := marks(FinalGradeFilter{TermID: term}), fmt.Sprintf("[%d:7 %d:5]", students[0], students[1]); got != want {
+		t.Errorf("FinalGrades() after a replacement = %v, want %v", got, want)
+	}
+
+	// Nothing of a failing batch is stored.
+	for _, tc := range []struct {
+		desc  string
+		final FinalGradeEntry
+		want  error
+	}{
+		{"an override without a reason", FinalGradeEntry{StudentID: students[1], SubjectID: subjects[1], SchoolYearID: year, Proposed: "4", Mark: "5"}, ErrValidation},
+		{"a bad mark", FinalGradeEntry{StudentID: students[1], SubjectID: subjects[1], SchoolYearID: year, Proposed: "4", Mark: "11"}, ErrValidation},
+		{"a term of another year", FinalGradeEntry{StudentID: students[1], SubjectID: subjects[1], SchoolYearID: other, TermID: termID, Proposed: "4", Mark: "4"}, ErrValidation},
+		{"a missing student", FinalGradeEntry{StudentID: 42, SubjectID: subjects[1], SchoolYearID: year, Proposed: "4", Mark: "4"}, ErrConstraint},
+	} {
+		err := s.SaveFinalGrades([]FinalGradeEntry{
+			{StudentID: students[1], SubjectID: subjects[1], SchoolYearID: year, Proposed: Passed, Mark: Passed},
+			tc.final,
+		})
+		if !errors.Is(err, tc.want) {
+			t.Errorf("SaveFinalGrades() with %s error = %v, want %v", tc.desc, err, tc.want)
+		}
+	}
+	if got := marks(FinalGradeFilter{SubjectID: subjects[1]}); got != "[]" {
+		t.Errorf("FinalGrades() after failed saves = %v, want none", got)
+	}
+
+	// Final grades keep their subject, school year and term.
+	if err := s.DeleteSubject(subjects[0]); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteSubject() with final grades error = %v, want %v", err, ErrConstraint)
+	}
+	if err := s.DeleteTerm(term); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteTerm() with final grades error = %v, want %v", err, ErrConstraint)
+	}
+	if err := s.DeleteSchoolYear(year); !errors.Is(err, ErrConstraint) {
+		t.Errorf("DeleteSchoolYear() with final grades error = %v, want %v", err, ErrConstraint)
+	}
+
+	finals, err := s.FinalGrades(FinalGradeFilter{StudentID: students[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.FinalGrade(finals[0].ID); err != nil || got != finals[0] {
+		t.Errorf("FinalGrade() = %+v, %v, want %+v", got, err, finals[0])
+	}
+	if err := s.DeleteFinalGrade(finals[0].ID); err != nil {
+		t.Fatalf("DeleteFinalGrade() failed: %v", err)
+	}
+	if err := s.DeleteFinalGrade(finals[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteFinalGrade() twice error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := s.FinalGrade(finals[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FinalGrade() after delete error = %v, want %v", err, ErrNotFound)
+	}
+	// Final grades go away together with the student.
+	if err := s.DeleteStudent(students[1]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := marks(FinalGradeFilter{}), fmt.Sprintf("[%d:7]", students[0]); got != want {
+		t.Errorf("FinalGrades() after deleting a student = %v, want %v", got, want)
+	}
+}
+
+func testSearch(t *testing.T, s Storage) {
+	year, err := s.AddSchoolYear(SchoolYearEntry{Name: "2025/2026", Starts: "2025-09-01", Ends: "2026-08-31"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenth, _ := s.AddClass("10", "a")
+	fifth, _ := s.AddClass("5", "b")
+	berzins, _ := s.AddStudent("Jānis", "Bērziņš")
+	ozola, _ := s.AddStudent("Anna", "Ozola")
+	cakste, _ := s.AddStudent("Ēriks", "Čakste")
+	s.AssignClassToStudent(berzins, fifth)
+	s.AssignClassToStudent(ozola, tenth)
+
+	studentIDs := func(q Query) []int {
+		t.Helper()
+		entries, err := s.SearchStudents(q)
+		if err != nil {
+			t.Fatalf("SearchStudents(%+v) failed: %v", q, err)
+		}
+		var ids []int
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+	for _, c := range []struct {
+		q    Query
+		want []int
+	}{
+		{Query{}, []int{berzins, ozola, cakste}},
+		{Query{Search: "berzins"}, []int{berzins}},
+		{Query{Search: "BĒRZ jan"}, []int{berzins}},
+		{Query{Search: "eriks cak"}, []int{cakste}},
+		{Query{Search: "nobody"}, nil},
+		{Query{Sort: SortSurname}, []int{berzins, cakste, ozola}},
+		{Query{Sort: SortName, Desc: true}, []int{berzins, cakste, ozola}},
+		{Query{Desc: true}, []int{cakste, ozola, berzins}},
+	} {
+		if got := studentIDs(c.q); fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("SearchStudents(%+v) = %v, want %v", c.q, got, c.want)
+		}
+	}
+	if _, err := s.SearchStudents(Query{Sort: SortClass}); !errors.Is(err, ErrValidation) {
+		t.Errorf("SearchStudents() sorted by class returned %v, want ErrValidation", err)
+	}
+
+	classes, err := s.SearchClasses(Query{Sort: SortClass, SchoolYearID: year})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(classes) != 2 || classes[0].ID != fifth || classes[1].ID != tenth {
+		t.Errorf("SearchClasses() by class = %+v, want 5.b before 10.a", classes)
+	}
+	if classes, _ := s.SearchClasses(Query{Search: "10.a"}); len(classes) != 1 || classes[0].ID != tenth {
+		t.Errorf("SearchClasses(10.a) = %+v, want 10.a", classes)
+	}
+
+	groupIDs := func(q Query) []int {
+		t.Helper()
+		entries, err := s.SearchGroups(q)
+		if err != nil {
+			t.Fatalf("SearchGroups(%+v) failed: %v", q, err)
+		}
+		var ids []int
+		for _, e := range entries {
+			ids = append(ids, e.StudentID)
+		}
+		return ids
+	}
+	for _, c := range []struct {
+		q    Query
+		want []int
+	}{
+		{Query{Sort: SortClass}, []int{berzins, ozola, cakste}},
+		{Query{Sort: SortClass, Desc: true}, []int{cakste, ozola, berzins}},
+		{Query{ClassID: tenth}, []int{ozola}},
+		{Query{Unenrolled: true}, []int{cakste}},
+		{Query{Search: "5.b"}, []int{berzins}},
+		{Query{Search: "ozola", ClassID: fifth}, nil},
+	} {
+		if got := groupIDs(c.q); fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("SearchGroups(%+v) = %v, want %v", c.q, got, c.want)
+		}
+	}
+}
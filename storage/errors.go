@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+var (
+	// ErrNotFound is returned when the requested entry does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when an equal entry exists already, e.g. a
+	// second 5.a class.
+	ErrDuplicate = errors.New("already exists")
+	// ErrConstraint is returned when a change would break the integrity of
+	// the data, e.g. deleting a class that still has students in it.
+	ErrConstraint = errors.New("constraint violation")
+	// ErrDatabaseLocked is returned when another process holds the database
+	// for longer than the busy timeout.
+	ErrDatabaseLocked = errors.New("database is locked")
+	// ErrValidation is returned when the input is rejected before reaching
+	// the database. Use errors.As with *ValidationError for the details.
+	ErrValidation = errors.New("invalid input")
+	// ErrConflict is returned when a timetable slot clashes with an existing
+	// one. Use errors.As with *ConflictError for the details.
+	ErrConflict = errors.New("timetable conflict")
+)
+
+// Error describes a failed storage operation. Kind is one of the Err*
+// sentinels above, or nil if the cause is not known, and can be tested with
+// errors.Is.
+type Error struct {
+	Op   string // Operation that failed, e.g. "add class".
+	Kind error  // Category of the failure.
+	Err  error  // Underlying error, if any.
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Kind == nil:
+		return fmt.Sprintf("%s: %v", e.Op, e.Err)
+	case e.Err == nil:
+		return fmt.Sprintf("%s: %v", e.Op, e.Kind)
+	default:
+		return fmt.Sprintf("%s: %v: %v", e.Op, e.Kind, e.Err)
+	}
+}
+
+// Is reports whether target is the kind of e.
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError describes a rejected input field.
+type ValidationError struct {
+	Field  string // Name of the field, e.g. "surname".
+	Reason string // Human readable explanation.
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// Is reports whether target is ErrValidation.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// ConflictError describes a timetable slot that would double-book a class, a
+// teacher or a room.
+type ConflictError struct {
+	Resource string    // What is double-booked: "class", "teacher" or "room".
+	Slot     SlotEntry // The existing slot, including the names.
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s is busy on day %d, lesson %d", e.Resource, e.Slot.Weekday, e.Slot.Lesson)
+}
+
+// Is reports whether target is ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// wrap annotates an error returned by the database with the operation and
+// the kind of the failure detected from the SQLite result code.
+func wrap(op string, err error) error {
+	return &Error{Op: op, Kind: kindOf(err), Err: err}
+}
+
+// kindOf maps err to one of the Err* sentinels, or nil if it is not known.
+func kindOf(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var e *sqlite.Error
+	if !errors.As(err, &e) {
+		return nil
+	}
+	switch e.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return ErrDuplicate
+	}
+	switch e.Code() & 0xff { // Primary result code.
+	case sqlite3.SQLITE_CONSTRAINT:
+		return ErrConstraint
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return ErrDatabaseLocked
+	}
+	return nil
+}
+
+// checkAffected returns ErrNotFound if res reports that no rows were changed.
+func checkAffected(op string, res sql.Result) error {
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return wrap(op, err)
+	}
+	if cnt == 0 {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// FinalGradeEntry represents the finalized mark of a student in a subject for
+// a term or for a whole school year.
+type FinalGradeEntry struct {
+	ID           int           `db:"id"`
+	StudentID    int           `db:"student_id"`
+	SubjectID    int           `db:"subject_id"`
+	SchoolYearID int           `db:"school_year_id"`
+	TermID       sql.NullInt64 `db:"term_id"` // Not valid for the mark of the whole year.
+	Average      float64       `db:"average"` // Average the mark was proposed from, 0 without numeric grades.
+	Proposed     Mark          `db:"proposed"`
+	Mark         Mark          `db:"mark"`
+	Reason       string        `db:"reason"`       // Why Mark overrides Proposed.
+	FinalizedAt  string        `db:"finalized_at"` // UTC time in RFC 3339 format.
+}
+
+// FinalGradeFilter selects final grades. Zero fields match every final grade.
+type FinalGradeFilter struct {
+	StudentID    int
+	ClassID      int // Students currently enrolled in the class.
+	SubjectID    int
+	SchoolYearID int
+	TermID       int
+	YearOnly     bool // Only the marks of whole school years, not of terms.
+}
+
+// FinalGradeStore provides access to the final grades of terms and school
+// years, which are kept alongside the grades they were derived from.
+type FinalGradeStore interface {
+	// FinalGrades returns the final grades matching f ordered by student,
+	// subject and school year, the terms before the whole year.
+	FinalGrades(f FinalGradeFilter) ([]FinalGradeEntry, error)
+	// FinalGrade returns a single final grade by its ID.
+	FinalGrade(id int) (FinalGradeEntry, error)
+	// SaveFinalGrades stores a batch of final grades, either all of them or
+	// none. A final grade replaces the one of the same student, subject and
+	// period. The ID and FinalizedAt of the entries are ignored.
+	SaveFinalGrades(finals []FinalGradeEntry) error
+	// DeleteFinalGrade removes a final grade, e.g. to reopen it.
+	DeleteFinalGrade(id int) error
+}
+
+var (
+	selectFinalGradesStmt = `SELECT id, student_id, subject_id, school_year_id, term_id,
+		average, proposed, mark, reason, finalized_at
+	FROM final_grades`
+	selectFinalGradeStmt   = selectFinalGradesStmt + ` WHERE id = ?`
+	selectFinalGradeIDStmt = `SELECT id FROM final_grades
+	WHERE student_id = ? AND subject_id = ? AND school_year_id = ? AND IFNULL(term_id, 0) = ?`
+	selectTermYearStmt    = `SELECT school_year_id FROM terms WHERE id = ?`
+	insertFinalGradeStmt  = `INSERT INTO final_grades (student_id, subject_id, school_year_id, term_id, average, proposed, mark, reason, finalized_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	updateFinalGradeStmt  = `UPDATE final_grades SET average = ?, proposed = ?, mark = ?, reason = ?, finalized_at = ? WHERE id = ?`
+	deleteFinalGradeStmt  = `DELETE FROM final_grades WHERE id = ?`
+	finalGradesOrderBySQL = ` ORDER BY student_id, subject_id, school_year_id, term_id IS NULL, term_id`
+)
+
+// finalGradeQuery builds the statement selecting the final grades matching f.
+func finalGradeQuery(f FinalGradeFilter) (string, []interface{}) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if f.StudentID != 0 {
+		where = append(where, `student_id = ?`)
+		args = append(args, f.StudentID)
+	}
+	if f.ClassID != 0 {
+		where = append(where, `student_id IN (SELECT student_id FROM enrollments WHERE class_id = ?)`)
+		args = append(args, f.ClassID)
+	}
+	if f.SubjectID != 0 {
+		where = append(where, `subject_id = ?`)
+		args = append(args, f.SubjectID)
+	}
+	if f.SchoolYearID != 0 {
+		where = append(where, `school_year_id = ?`)
+		args = append(args, f.SchoolYearID)
+	}
+	if f.TermID != 0 {
+		where = append(where, `term_id = ?`)
+		args = append(args, f.TermID)
+	}
+	if f.YearOnly {
+		where = append(where, `term_id IS NULL`)
+	}
+	stmt := selectFinalGradesStmt
+	if len(where) > 0 {
+		stmt += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	return stmt + finalGradesOrderBySQL, args
+}
+
+// validateFinalGrade checks the fields of a final grade before it is stored.
+func validateFinalGrade(f FinalGradeEntry) error {
+	if !f.Mark.Valid() {
+		return &ValidationError{Field: "mark", Reason: "must be a number from 1 to 10, i, ni or nv"}
+	}
+	if !f.Proposed.Valid() {
+		return &ValidationError{Field: "proposed", Reason: "must be a number from 1 to 10, i, ni or nv"}
+	}
+	if f.Average < 0 || f.Average > 10 {
+		return &ValidationError{Field: "average", Reason: "must be from 0 to 10"}
+	}
+	if f.Mark != f.Proposed && strings.TrimSpace(f.Reason) == "" {
+		return &ValidationError{Field: "reason", Reason: "must explain why the proposed mark is overridden"}
+	}
+	return nil
+}
+
+// errTermOfOtherYear is returned for final grades of a term that does not
+// belong to their school year.
+var errTermOfOtherYear = &ValidationError{Field: "term", Reason: "must belong to the school year"}
+
+// FinalGrades returns the final grades matching f.
+func (s *SQLite) FinalGrades(f FinalGradeFilter) ([]FinalGradeEntry, error) {
+	stmt, args := finalGradeQuery(f)
+	var entries []FinalGradeEntry
+	if err := s.db.Select(&entries, stmt, args...); err != nil {
+		return nil, wrap("list final grades", err)
+	}
+	return entries, nil
+}
+
+// FinalGrade returns a single final grade by its ID.
+func (s *SQLite) FinalGrade(id int) (FinalGradeEntry, error) {
+	var entry FinalGradeEntry
+	if err := s.db.Get(&entry, selectFinalGradeStmt, id); err != nil {
+		return FinalGradeEntry{}, wrap(fmt.Sprintf("get final grade %d", id), err)
+	}
+	return entry, nil
+}
+
+// SaveFinalGrades stores a batch of final grades in a single transaction. The
+// student, subject, school year and term must exist.
+func (s *SQLite) SaveFinalGrades(finals []FinalGradeEntry) error {
+	const op = "save final grades"
+	return s.inTx(op, func(tx *sqlx.Tx) error {
+		return saveFinalGrades(tx, op, finals)
+	})
+}
+
+// saveFinalGrades stores final grades within tx.
+func saveFinalGrades(tx *sqlx.Tx, op string, finals []FinalGradeEntry) error {
+	now := timestamp()
+	for _, f := range finals {
+		if err := validateFinalGrade(f); err != nil {
+			return &Error{Op: op, Kind: ErrValidation, Err: err}
+		}
+		if f.TermID.Valid {
+			var yearID int
+			if err := tx.Get(&yearID, selectTermYearStmt, f.TermID.Int64); errors.Is(err, sql.ErrNoRows) {
+				return &Error{Op: op, Kind: ErrConstraint}
+			} else if err != nil {
+				return wrap(op, err)
+			}
+			if yearID != f.SchoolYearID {
+				return &Error{Op: op, Kind: ErrValidation, Err: errTermOfOtherYear}
+			}
+		}
+		var id int
+		err := tx.Get(&id, selectFinalGradeIDStmt, f.StudentID, f.SubjectID, f.SchoolYearID, f.TermID.Int64)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			_, err = tx.Exec(insertFinalGradeStmt, f.StudentID, f.SubjectID, f.SchoolYearID, f.TermID,
+				f.Average, f.Proposed, f.Mark, f.Reason, now)
+		case err == nil:
+			_, err = tx.Exec(updateFinalGradeStmt, f.Average, f.Proposed, f.Mark, f.Reason, now, id)
+		}
+		if err != nil {
+			return wrap(op, err)
+		}
+	}
+	return nil
+}
+
+// DeleteFinalGrade removes a final grade.
+func (s *SQLite) DeleteFinalGrade(id int) error {
+	op := fmt.Sprintf("delete final grade %d", id)
+	res, err := s.exec(deleteFinalGradeStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// FinalGrades returns the final grades matching f.
+func (m *Memory) FinalGrades(f FinalGradeFilter) ([]FinalGradeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []FinalGradeEntry
+	for _, g := range m.finalGrades {
+		switch {
+		case f.StudentID != 0 && g.StudentID != f.StudentID,
+			f.ClassID != 0 && m.enrollments[g.StudentID] != f.ClassID,
+			f.SubjectID != 0 && g.SubjectID != f.SubjectID,
+			f.SchoolYearID != 0 && g.SchoolYearID != f.SchoolYearID,
+			f.TermID != 0 && g.TermID.Int64 != int64(f.TermID),
+			f.YearOnly && g.TermID.Valid:
+			continue
+		}
+		entries = append(entries, g)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case a.StudentID != b.StudentID:
+			return a.StudentID < b.StudentID
+		case a.SubjectID != b.SubjectID:
+			return a.SubjectID < b.SubjectID
+		case a.SchoolYearID != b.SchoolYearID:
+			return a.SchoolYearID < b.SchoolYearID
+		case a.TermID.Valid != b.TermID.Valid:
+			return a.TermID.Valid
+		}
+		return a.TermID.Int64 < b.TermID.Int64
+	})
+	return entries, nil
+}
+
+// FinalGrade returns a single final grade by its ID.
+func (m *Memory) FinalGrade(id int) (FinalGradeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, ok := m.finalGrades[id]
+	if !ok {
+		return FinalGradeEntry{}, &Error{Op: fmt.Sprintf("get final grade %d", id), Kind: ErrNotFound}
+	}
+	return g, nil
+}
+
+// SaveFinalGrades stores a batch of final grades. Every entry is checked
+// before anything changes.
+func (m *Memory) SaveFinalGrades(finals []FinalGradeEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	return m.saveFinalGrades("save final grades", finals)
+}
+
+// saveFinalGrades stores final grades after checking every one of them. m.mu
+// must be held.
+func (m *Memory) saveFinalGrades(op string, finals []FinalGradeEntry) error {
+	for _, f := range finals {
+		if err := validateFinalGrade(f); err != nil {
+			return &Error{Op: op, Kind: ErrValidation, Err: err}
+		}
+		_, studentOK := m.students[f.StudentID]
+		_, subjectOK := m.subjects[f.SubjectID]
+		_, yearOK := m.schoolYears[f.SchoolYearID]
+		if !studentOK || !subjectOK || !yearOK {
+			return &Error{Op: op, Kind: ErrConstraint}
+		}
+		if f.TermID.Valid {
+			t, ok := m.terms[int(f.TermID.Int64)]
+			if !ok {
+				return &Error{Op: op, Kind: ErrConstraint}
+			}
+			if t.SchoolYearID != f.SchoolYearID {
+				return &Error{Op: op, Kind: ErrValidation, Err: errTermOfOtherYear}
+			}
+		}
+	}
+
+	now := timestamp()
+	for _, f := range finals {
+		f.ID, f.FinalizedAt = 0, now
+		for id, g := range m.finalGrades {
+			if g.StudentID == f.StudentID && g.SubjectID == f.SubjectID &&
+				g.SchoolYearID == f.SchoolYearID && g.TermID.Int64 == f.TermID.Int64 {
+				f.ID = id
+				break
+			}
+		}
+		if f.ID == 0 {
+			m.lastFinalGradeID++
+			f.ID = m.lastFinalGradeID
+		}
+		m.touch("final_grades", f.ID)
+		m.finalGrades[f.ID] = f
+	}
+	return nil
+}
+
+// DeleteFinalGrade removes a final grade.
+func (m *Memory) DeleteFinalGrade(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if _, ok := m.finalGrades[id]; !ok {
+		return &Error{Op: fmt.Sprintf("delete final grade %d", id), Kind: ErrNotFound}
+	}
+	m.touch("final_grades", id)
+	delete(m.finalGrades, id)
+	return nil
+}
+
+// hasFinalGrade reports whether any final grade matches. m.mu must be held.
+func (m *Memory) hasFinalGrade(match func(FinalGradeEntry) bool) bool {
+	for _, g := range m.finalGrades {
+		if match(g) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,561 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Mark is the value of a single grade: "1" to "10" on the numeric scale, or
+// one of the non-numeric markers below.
+type Mark string
+
+const (
+	// Passed is "ieskaitīts", a pass on a pass/fail assessment.
+	Passed Mark = "i"
+	// Failed is "neieskaitīts", a fail on a pass/fail assessment.
+	Failed Mark = "ni"
+	// NotGraded is "nav vērtējuma", written when a student could not be
+	// graded, e.g. because they missed the test.
+	NotGraded Mark = "nv"
+)
+
+// ParseMark converts user input into a Mark. Besides the stored forms it
+// accepts the full words "ieskaitīts" and "neieskaitīts", in any case.
+func ParseMark(s string) (Mark, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "ieskaitīts", "ieskaitits":
+		return Passed, nil
+	case "neieskaitīts", "neieskaitits":
+		return Failed, nil
+	}
+	if m := Mark(s); m.Valid() {
+		return m, nil
+	}
+	return "", &ValidationError{Field: "mark", Reason: "must be a number from 1 to 10, i, ni or nv"}
+}
+
+// Valid reports whether m is a known mark.
+func (m Mark) Valid() bool {
+	if _, ok := m.Numeric(); ok {
+		return true
+	}
+	return m == Passed || m == Failed || m == NotGraded
+}
+
+// Numeric returns the value of a mark on the 1 to 10 scale. It reports false
+// for the non-numeric markers.
+func (m Mark) Numeric() (int, bool) {
+	n, err := strconv.Atoi(string(m))
+	if err != nil || n < 1 || n > 10 || strconv.Itoa(n) != string(m) {
+		return 0, false
+	}
+	return n, true
+}
+
+// Assessment is the kind of work a grade was given for. Final grades weigh the
+// kinds differently.
+type Assessment string
+
+const (
+	// DailyWork is "ikdienas darbs", e.g. homework or an answer in class.
+	DailyWork Assessment = "daily"
+	// Test is "pārbaudes darbs", a test at the end of a topic.
+	Test Assessment = "test"
+	// Exam is "eksāmens" or another work covering a whole term.
+	Exam Assessment = "exam"
+)
+
+// Assessments lists the kinds of assessment from the lightest to the heaviest.
+var Assessments = []Assessment{DailyWork, Test, Exam}
+
+// Valid reports whether a is a known kind of assessment.
+func (a Assessment) Valid() bool {
+	for _, known := range Assessments {
+		if a == known {
+			return true
+		}
+	}
+	return false
+}
+
+// GradeEntry represents a row for a single grade.
+type GradeEntry struct {
+	ID        int        `db:"id"`
+	StudentID int        `db:"student_id"`
+	SubjectID int        `db:"subject_id"`
+	TeacherID int        `db:"teacher_id"` // Teacher who gave the grade.
+	Date      string     `db:"date"`       // Day of the assessment, e.g. "2025-09-30".
+	Mark      Mark       `db:"mark"`
+	Kind      Assessment `db:"kind"`
+	Corrected bool       `db:"corrected"` // Whether Mark replaces an earlier one.
+}
+
+// GradeCorrection records a change of the mark of a grade.
+type GradeCorrection struct {
+	ID          int    `db:"id"`
+	GradeID     int    `db:"grade_id"`
+	OldMark     Mark   `db:"old_mark"`
+	NewMark     Mark   `db:"new_mark"`
+	Reason      string `db:"reason"`
+	CorrectedAt string `db:"corrected_at"` // UTC time in RFC 3339 format.
+}
+
+// GradeFilter selects grades. Zero fields match every grade.
+type GradeFilter struct {
+	StudentID int
+	ClassID   int // Students currently enrolled in the class.
+	SubjectID int
+	From, To  string // Inclusive range of dates, e.g. a term.
+}
+
+// GradeStore provides access to grades and their correction history.
+type GradeStore interface {
+	// Grades returns the grades matching f ordered by date.
+	Grades(f GradeFilter) ([]GradeEntry, error)
+	// Grade returns a single grade by its ID.
+	Grade(id int) (GradeEntry, error)
+	// AddGrade records a new grade of DailyWork and returns its ID.
+	AddGrade(studentID, subjectID, teacherID int, date string, mark Mark) (int, error)
+	// CorrectGrade changes the mark of a grade and records the old one
+	// together with the reason.
+	CorrectGrade(id int, mark Mark, reason string) error
+	// GradeCorrections returns the correction history of a grade, oldest
+	// first.
+	GradeCorrections(gradeID int) ([]GradeCorrection, error)
+	// DeleteGrade removes a grade together with its history.
+	DeleteGrade(id int) error
+	// SaveGrades applies a batch of changes, either all of them or none, and
+	// returns the IDs of the added grades in the order of the changes.
+	SaveGrades(changes []GradeChange) ([]int, error)
+}
+
+// GradeChange is a single change in a batch passed to SaveGrades. A zero ID
+// adds a new grade, an empty Mark deletes the grade with the ID and anything
+// else corrects its mark.
+type GradeChange struct {
+	ID        int
+	StudentID int // The fields below are used for new grades only.
+	SubjectID int
+	TeacherID int
+	Date      string
+	Kind      Assessment // DailyWork if empty.
+	Mark      Mark
+	Reason    string // Reason of a correction.
+}
+
+var (
+	selectGradesStmt = `SELECT id, student_id, subject_id, teacher_id, date, mark, kind,
+		EXISTS (SELECT 1 FROM grade_corrections WHERE grade_id = grades.id) AS corrected
+	FROM grades`
+	selectGradeStmt           = selectGradesStmt + ` WHERE id = ?`
+	insertGradeStmt           = `INSERT INTO grades (student_id, subject_id, teacher_id, date, mark, kind) VALUES(?, ?, ?, ?, ?, ?)`
+	selectGradeMarkStmt       = `SELECT mark FROM grades WHERE id = ?`
+	updateGradeMarkStmt       = `UPDATE grades SET mark = ? WHERE id = ?`
+	insertGradeCorrectionStmt = `INSERT INTO grade_corrections (grade_id, old_mark, new_mark, reason, corrected_at) VALUES(?, ?, ?, ?, ?)`
+	selectGradeCorrections    = `SELECT id, grade_id, old_mark, new_mark, reason, corrected_at
+	FROM grade_corrections WHERE grade_id = ? ORDER BY id`
+	deleteGradeStmt = `DELETE FROM grades WHERE id = ?`
+)
+
+// kind returns the kind of assessment of a new grade.
+func (c GradeChange) kind() Assessment {
+	if c.Kind == "" {
+		return DailyWork
+	}
+	return c.Kind
+}
+
+// gradeQuery builds the statement selecting the grades matching f.
+func gradeQuery(f GradeFilter) (string, []interface{}) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if f.StudentID != 0 {
+		where = append(where, `student_id = ?`)
+		args = append(args, f.StudentID)
+	}
+	if f.ClassID != 0 {
+		where = append(where, `student_id IN (SELECT student_id FROM enrollments WHERE class_id = ?)`)
+		args = append(args, f.ClassID)
+	}
+	if f.SubjectID != 0 {
+		where = append(where, `subject_id = ?`)
+		args = append(args, f.SubjectID)
+	}
+	if f.From != "" {
+		where = append(where, `date >= ?`)
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		where = append(where, `date <= ?`)
+		args = append(args, f.To)
+	}
+	stmt := selectGradesStmt
+	if len(where) > 0 {
+		stmt += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	return stmt + ` ORDER BY date, id`, args
+}
+
+// validateGrade checks the fields of a grade before they are stored.
+func validateGrade(date string, mark Mark, kind Assessment) error {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return &ValidationError{Field: "date", Reason: "must look like 2025-09-30"}
+	}
+	if !mark.Valid() {
+		return &ValidationError{Field: "mark", Reason: "must be a number from 1 to 10, i, ni or nv"}
+	}
+	if !kind.Valid() {
+		return &ValidationError{Field: "kind", Reason: "must be daily, test or exam"}
+	}
+	return nil
+}
+
+// validateCorrection checks a correction of a grade whose mark is old.
+func validateCorrection(old, mark Mark, reason string) error {
+	if !mark.Valid() {
+		return &ValidationError{Field: "mark", Reason: "must be a number from 1 to 10, i, ni or nv"}
+	}
+	if mark == old {
+		return &ValidationError{Field: "mark", Reason: "is the same as before"}
+	}
+	if strings.TrimSpace(reason) == "" {
+		return &ValidationError{Field: "reason", Reason: "must not be empty"}
+	}
+	return nil
+}
+
+// timestamp returns the current time as stored with corrections and other
+// records of when something happened.
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// Grades returns the grades matching f.
+func (s *SQLite) Grades(f GradeFilter) ([]GradeEntry, error) {
+	stmt, args := gradeQuery(f)
+	var entries []GradeEntry
+	if err := s.db.Select(&entries, stmt, args...); err != nil {
+		return nil, wrap("list grades", err)
+	}
+	return entries, nil
+}
+
+// Grade returns a single grade by its ID.
+func (s *SQLite) Grade(id int) (GradeEntry, error) {
+	var entry GradeEntry
+	if err := s.db.Get(&entry, selectGradeStmt, id); err != nil {
+		return GradeEntry{}, wrap(fmt.Sprintf("get grade %d", id), err)
+	}
+	return entry, nil
+}
+
+// AddGrade records a new grade and returns its ID. The student, subject and
+// teacher must exist.
+func (s *SQLite) AddGrade(studentID, subjectID, teacherID int, date string, mark Mark) (int, error) {
+	return addGrade(s.db, studentID, subjectID, teacherID, date, mark, DailyWork)
+}
+
+// addGrade inserts a grade using e, which is either the database or a
+// transaction.
+func addGrade(e sqlx.Execer, studentID, subjectID, teacherID int, date string, mark Mark, kind Assessment) (int, error) {
+	if err := validateGrade(date, mark, kind); err != nil {
+		return 0, &Error{Op: "add grade", Kind: ErrValidation, Err: err}
+	}
+	res, err := e.Exec(insertGradeStmt, studentID, subjectID, teacherID, date, mark, kind)
+	if err != nil {
+		return 0, wrap("add grade", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, wrap("add grade", err)
+	}
+	return int(id), nil
+}
+
+// CorrectGrade changes the mark of a grade. The previous mark is kept in the
+// correction history together with the reason.
+func (s *SQLite) CorrectGrade(id int, mark Mark, reason string) error {
+	return s.inTx(fmt.Sprintf("correct grade %d", id), func(tx *sqlx.Tx) error {
+		return correctGrade(tx, id, mark, reason)
+	})
+}
+
+// correctGrade changes the mark of a grade within tx.
+func correctGrade(tx *sqlx.Tx, id int, mark Mark, reason string) error {
+	op := fmt.Sprintf("correct grade %d", id)
+	var old Mark
+	if err := tx.Get(&old, selectGradeMarkStmt, id); err != nil {
+		return wrap(op, err)
+	}
+	if err := validateCorrection(old, mark, reason); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	if _, err := tx.Exec(updateGradeMarkStmt, mark, id); err != nil {
+		return wrap(op, err)
+	}
+	if _, err := tx.Exec(insertGradeCorrectionStmt, id, old, mark, reason, timestamp()); err != nil {
+		return wrap(op, err)
+	}
+	return nil
+}
+
+// GradeCorrections returns the correction history of a grade.
+func (s *SQLite) GradeCorrections(gradeID int) ([]GradeCorrection, error) {
+	var entries []GradeCorrection
+	if err := s.db.Select(&entries, selectGradeCorrections, gradeID); err != nil {
+		return nil, wrap(fmt.Sprintf("list corrections of grade %d", gradeID), err)
+	}
+	return entries, nil
+}
+
+// DeleteGrade removes a grade together with its history.
+func (s *SQLite) DeleteGrade(id int) error {
+	return deleteGrade(s.db, id)
+}
+
+// deleteGrade removes a grade using e, which is either the database or a
+// transaction.
+func deleteGrade(e sqlx.Execer, id int) error {
+	op := fmt.Sprintf("delete grade %d", id)
+	res, err := e.Exec(deleteGradeStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// SaveGrades applies a batch of changes in a single transaction.
+func (s *SQLite) SaveGrades(changes []GradeChange) ([]int, error) {
+	var ids []int
+	err := s.inTx("save grades", func(tx *sqlx.Tx) error {
+		for _, c := range changes {
+			var err error
+			switch {
+			case c.ID == 0:
+				var id int
+				id, err = addGrade(tx, c.StudentID, c.SubjectID, c.TeacherID, c.Date, c.Mark, c.kind())
+				ids = append(ids, id)
+			case c.Mark == "":
+				err = deleteGrade(tx, c.ID)
+			default:
+				err = correctGrade(tx, c.ID, c.Mark, c.Reason)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Grades returns the grades matching f.
+func (m *Memory) Grades(f GradeFilter) ([]GradeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []GradeEntry
+	for _, g := range m.grades {
+		switch {
+		case f.StudentID != 0 && g.StudentID != f.StudentID,
+			f.ClassID != 0 && m.enrollments[g.StudentID] != f.ClassID,
+			f.SubjectID != 0 && g.SubjectID != f.SubjectID,
+			f.From != "" && g.Date < f.From,
+			f.To != "" && g.Date > f.To:
+			continue
+		}
+		entries = append(entries, m.grade(g))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// Grade returns a single grade by its ID.
+func (m *Memory) Grade(id int) (GradeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, ok := m.grades[id]
+	if !ok {
+		return GradeEntry{}, &Error{Op: fmt.Sprintf("get grade %d", id), Kind: ErrNotFound}
+	}
+	return m.grade(g), nil
+}
+
+// grade fills in whether g has been corrected. m.mu must be held.
+func (m *Memory) grade(g GradeEntry) GradeEntry {
+	for _, c := range m.corrections {
+		if c.GradeID == g.ID {
+			g.Corrected = true
+			break
+		}
+	}
+	return g
+}
+
+// AddGrade records a new grade and returns its ID. The student, subject and
+// teacher must exist.
+func (m *Memory) AddGrade(studentID, subjectID, teacherID int, date string, mark Mark) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	return m.addGrade(studentID, subjectID, teacherID, date, mark, DailyWork)
+}
+
+// addGrade is AddGrade without locking. m.mu must be held.
+func (m *Memory) addGrade(studentID, subjectID, teacherID int, date string, mark Mark, kind Assessment) (int, error) {
+	const op = "add grade"
+	if err := validateGrade(date, mark, kind); err != nil {
+		return 0, &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	_, studentOK := m.students[studentID]
+	_, subjectOK := m.subjects[subjectID]
+	_, teacherOK := m.teachers[teacherID]
+	if !studentOK || !subjectOK || !teacherOK {
+		return 0, &Error{Op: op, Kind: ErrConstraint}
+	}
+	m.lastGradeID++
+	m.touch("grades", m.lastGradeID)
+	m.grades[m.lastGradeID] = GradeEntry{
+		ID:        m.lastGradeID,
+		StudentID: studentID,
+		SubjectID: subjectID,
+		TeacherID: teacherID,
+		Date:      date,
+		Mark:      mark,
+		Kind:      kind,
+	}
+	return m.lastGradeID, nil
+}
+
+// CorrectGrade changes the mark of a grade. The previous mark is kept in the
+// correction history together with the reason.
+func (m *Memory) CorrectGrade(id int, mark Mark, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	return m.correctGrade(id, mark, reason)
+}
+
+// correctGrade is CorrectGrade without locking. m.mu must be held.
+func (m *Memory) correctGrade(id int, mark Mark, reason string) error {
+	op := fmt.Sprintf("correct grade %d", id)
+	g, ok := m.grades[id]
+	if !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	if err := validateCorrection(g.Mark, mark, reason); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	m.lastCorrectionID++
+	m.corrections = append(m.corrections, GradeCorrection{
+		ID:          m.lastCorrectionID,
+		GradeID:     id,
+		OldMark:     g.Mark,
+		NewMark:     mark,
+		Reason:      reason,
+		CorrectedAt: timestamp(),
+	})
+	g.Mark = mark
+	m.touch("grades", id)
+	m.grades[id] = g
+	return nil
+}
+
+// GradeCorrections returns the correction history of a grade.
+func (m *Memory) GradeCorrections(gradeID int) ([]GradeCorrection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []GradeCorrection
+	for _, c := range m.corrections {
+		if c.GradeID == gradeID {
+			entries = append(entries, c)
+		}
+	}
+	return entries, nil
+}
+
+// DeleteGrade removes a grade together with its history.
+func (m *Memory) DeleteGrade(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if _, ok := m.grades[id]; !ok {
+		return &Error{Op: fmt.Sprintf("delete grade %d", id), Kind: ErrNotFound}
+	}
+	m.deleteGrade(id)
+	return nil
+}
+
+// deleteGrade removes a grade and its corrections. m.mu must be held.
+func (m *Memory) deleteGrade(id int) {
+	m.touch("grades", id)
+	delete(m.grades, id)
+	kept := m.corrections[:0]
+	for _, c := range m.corrections {
+		if c.GradeID != id {
+			kept = append(kept, c)
+		}
+	}
+	m.corrections = kept
+}
+
+// SaveGrades applies a batch of changes. If any of them fails, the grades are
+// restored to what they were before the call.
+func (m *Memory) SaveGrades(changes []GradeChange) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	grades := make(map[int]GradeEntry, len(m.grades))
+	for id, g := range m.grades {
+		grades[id] = g
+	}
+	corrections := append([]GradeCorrection(nil), m.corrections...)
+	lastGradeID, lastCorrectionID := m.lastGradeID, m.lastCorrectionID
+
+	var ids []int
+	for _, c := range changes {
+		var err error
+		switch {
+		case c.ID == 0:
+			var id int
+			id, err = m.addGrade(c.StudentID, c.SubjectID, c.TeacherID, c.Date, c.Mark, c.kind())
+			ids = append(ids, id)
+		case c.Mark == "":
+			if _, ok := m.grades[c.ID]; !ok {
+				err = &Error{Op: fmt.Sprintf("delete grade %d", c.ID), Kind: ErrNotFound}
+				break
+			}
+			m.deleteGrade(c.ID)
+		default:
+			err = m.correctGrade(c.ID, c.Mark, c.Reason)
+		}
+		if err != nil {
+			m.grades, m.corrections = grades, corrections
+			m.lastGradeID, m.lastCorrectionID = lastGradeID, lastCorrectionID
+			return nil, err
+		}
+	}
+	return ids, nil
+}
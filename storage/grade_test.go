@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMark(t *testing.T) {
+	for in, want := range map[string]Mark{
+		"1":            "1",
+		" 10 ":         "10",
+		"i":            Passed,
+		"Ieskaitīts":   Passed,
+		"neieskaitīts": Failed,
+		"NI":           Failed,
+		"nv":           NotGraded,
+	} {
+		got, err := ParseMark(in)
+		if err != nil || got != want {
+			t.Errorf("ParseMark(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0", "11", "07", "+5", "7.5", "x"} {
+		if got, err := ParseMark(in); !errors.Is(err, ErrValidation) {
+			t.Errorf("ParseMark(%q) = %q, %v; want %v", in, got, err, ErrValidation)
+		}
+	}
+}
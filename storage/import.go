@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ImportEntry is a student added in bulk, e.g. from a CSV file, together with
+// the class they are enrolled in. Year and Modifier are empty for students
+// without a class.
+type ImportEntry struct {
+	Name     string
+	Surname  string
+	Year     string
+	Modifier string
+}
+
+// Validate checks the entry the same way ImportStudents does, so that bad
+// entries can be reported before anything is stored.
+func (e ImportEntry) Validate() error {
+	if err := validatePerson(e.Name, e.Surname); err != nil {
+		return err
+	}
+	if e.Year == "" && e.Modifier == "" {
+		return nil
+	}
+	return validateClass(e.Year, e.Modifier)
+}
+
+// FindClass returns the class of classes an import entry of the year and the
+// modifier is enrolled into. Modifiers match regardless of case, so "5.A"
+// finds 5.a, unless a class of the exact modifier exists too.
+func FindClass(classes []ClassEntry, year, modifier string) (ClassEntry, bool) {
+	var (
+		found ClassEntry
+		ok    bool
+	)
+	for _, c := range classes {
+		switch {
+		case c.Year != year:
+		case c.Modifier == modifier:
+			return c, true
+		case !ok && strings.EqualFold(c.Modifier, modifier):
+			found, ok = c, true
+		}
+	}
+	return found, ok
+}
+
+// ImportStore adds students in bulk.
+type ImportStore interface {
+	// ImportStudents adds the students and enrolls them into their classes
+	// of the latest school year, adding the classes that do not exist yet.
+	// Classes are found with FindClass. It returns the IDs of the students in the order of entries. Nothing is
+	// stored if any entry fails.
+	ImportStudents(entries []ImportEntry) ([]int, error)
+}
+
+const (
+	// Classes are looked up in the school year new classes are added to.
+	selectLatestClassesStmt = `SELECT id, year, modifier, teacher_id, school_year_id FROM classes
+		WHERE IFNULL(school_year_id, 0) = IFNULL((SELECT id FROM school_years ORDER BY starts DESC, id DESC LIMIT 1), 0)
+		AND year = ? ORDER BY id`
+)
+
+// ImportStudents adds the students and enrolls them into their classes of the
+// latest school year in a single transaction.
+func (s *SQLite) ImportStudents(entries []ImportEntry) ([]int, error) {
+	const op = "import students"
+	ids := make([]int, 0, len(entries))
+	err := s.inTx(op, func(tx *sqlx.Tx) error {
+		for _, e := range entries {
+			if err := e.Validate(); err != nil {
+				return &Error{Op: op, Kind: ErrValidation, Err: err}
+			}
+			res, err := tx.Exec(insertStudentsStmt, e.Name, e.Surname)
+			if err != nil {
+				return wrap(op, err)
+			}
+			id, err := res.LastInsertId()
+			if err != nil {
+				return wrap(op, err)
+			}
+			ids = append(ids, int(id))
+			if e.Year == "" {
+				continue
+			}
+			var classes []ClassEntry
+			if err := tx.Select(&classes, selectLatestClassesStmt, e.Year); err != nil {
+				return wrap(op, err)
+			}
+			class, ok := FindClass(classes, e.Year, e.Modifier)
+			classID := int64(class.ID)
+			if !ok {
+				res, err := tx.Exec(insertClassesStmt, e.Year, e.Modifier)
+				if err == nil {
+					classID, err = res.LastInsertId()
+				}
+				if err != nil {
+					return wrap(op, err)
+				}
+			}
+			if _, err := tx.Exec(enrollStudentStmt, id, classID); err != nil {
+				return wrap(op, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ImportStudents adds the students and enrolls them into their classes of the
+// latest school year. Every entry is checked before anything is changed.
+func (m *Memory) ImportStudents(entries []ImportEntry) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	for _, e := range entries {
+		if err := e.Validate(); err != nil {
+			return nil, &Error{Op: "import students", Kind: ErrValidation, Err: err}
+		}
+	}
+	var schoolYearID sql.NullInt64
+	if y, ok := m.latestSchoolYear(); ok {
+		schoolYearID = sql.NullInt64{Int64: int64(y.ID), Valid: true}
+	}
+	ids := make([]int, 0, len(entries))
+	for _, e := range entries {
+		m.lastStudentID++
+		id := m.lastStudentID
+		m.touch("students", id)
+		m.students[id] = StudentEntry{ID: id, Name: e.Name, Surname: e.Surname}
+		ids = append(ids, id)
+		if e.Year == "" {
+			continue
+		}
+		var classes []ClassEntry
+		for _, c := range m.classes {
+			if c.SchoolYearID == schoolYearID {
+				classes = append(classes, c)
+			}
+		}
+		sort.Slice(classes, func(i, j int) bool { return classes[i].ID < classes[j].ID })
+		class, ok := FindClass(classes, e.Year, e.Modifier)
+		if !ok {
+			class.ID = m.addClass(ClassEntry{Year: e.Year, Modifier: e.Modifier, SchoolYearID: schoolYearID})
+		}
+		m.touch("enrollments", id)
+		m.enrollments[id] = class.ID
+	}
+	return ids, nil
+}
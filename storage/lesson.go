@@ -0,0 +1,331 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxTextLength is the longest accepted lesson topic or homework, in
+// characters.
+const maxTextLength = 1000
+
+// LessonEntry represents what was taught to a class at a lesson of a subject
+// and the homework given, if any. Names of the referenced entries are
+// included for display.
+type LessonEntry struct {
+	ID        int    `db:"id"`
+	ClassID   int    `db:"class_id"`
+	SubjectID int    `db:"subject_id"`
+	Date      string `db:"date"` // E.g. "2025-09-30".
+	Topic     string `db:"topic"`
+	Homework  string `db:"homework"` // Empty if none was given.
+	DueDate   string `db:"due_date"` // Set together with Homework only.
+
+	Year     string `db:"year"`
+	Modifier string `db:"modifier"`
+	Subject  string `db:"subject"`
+}
+
+// LessonFilter selects lessons. Zero fields match every lesson.
+type LessonFilter struct {
+	ClassID   int
+	SubjectID int
+	From, To  string // Inclusive range of dates.
+}
+
+// LessonStore provides access to lesson topics and homework.
+type LessonStore interface {
+	// Lessons returns the lessons matching f ordered by date.
+	Lessons(f LessonFilter) ([]LessonEntry, error)
+	// Lesson returns a single lesson by its ID.
+	Lesson(id int) (LessonEntry, error)
+	// AddLesson records a new lesson and returns its ID.
+	AddLesson(l LessonEntry) (int, error)
+	// UpdateLesson changes the date, topic and homework of an existing
+	// lesson. The class and subject stay the same.
+	UpdateLesson(l LessonEntry) error
+	// DeleteLesson removes a lesson.
+	DeleteLesson(id int) error
+	// Homework returns the lessons of a class with homework due on from or
+	// later, ordered by due date and subject.
+	Homework(classID int, from string) ([]LessonEntry, error)
+}
+
+var (
+	selectLessonsStmt = `SELECT lessons.id, lessons.class_id, lessons.subject_id, lessons.date,
+		lessons.topic, lessons.homework, lessons.due_date,
+		classes.year, classes.modifier, subjects.name AS subject
+	FROM lessons
+	JOIN classes ON classes.id = lessons.class_id
+	JOIN subjects ON subjects.id = lessons.subject_id
+	WHERE (? = 0 OR lessons.class_id = ?) AND (? = 0 OR lessons.subject_id = ?)
+		AND (? = '' OR lessons.date >= ?) AND (? = '' OR lessons.date <= ?)
+	ORDER BY lessons.date, lessons.id`
+	selectLessonStmt = `SELECT lessons.id, lessons.class_id, lessons.subject_id, lessons.date,
+		lessons.topic, lessons.homework, lessons.due_date,
+		classes.year, classes.modifier, subjects.name AS subject
+	FROM lessons
+	JOIN classes ON classes.id = lessons.class_id
+	JOIN subjects ON subjects.id = lessons.subject_id
+	WHERE lessons.id = ?`
+	selectHomeworkStmt = `SELECT lessons.id, lessons.class_id, lessons.subject_id, lessons.date,
+		lessons.topic, lessons.homework, lessons.due_date,
+		classes.year, classes.modifier, subjects.name AS subject
+	FROM lessons
+	JOIN classes ON classes.id = lessons.class_id
+	JOIN subjects ON subjects.id = lessons.subject_id
+	WHERE lessons.class_id = ? AND lessons.homework <> '' AND lessons.due_date >= ?
+	ORDER BY lessons.due_date, subjects.name, lessons.id`
+	insertLessonStmt = `INSERT INTO lessons (class_id, subject_id, date, topic, homework, due_date) VALUES(?, ?, ?, ?, ?, ?)`
+	updateLessonStmt = `UPDATE lessons SET date = ?, topic = ?, homework = ?, due_date = ? WHERE id = ?`
+	deleteLessonStmt = `DELETE FROM lessons WHERE id = ?`
+)
+
+// validateLesson checks the fields of a lesson before it is stored. Homework
+// needs a due date that is not before the lesson.
+func validateLesson(l LessonEntry) error {
+	if _, err := time.Parse("2006-01-02", l.Date); err != nil {
+		return &ValidationError{Field: "date", Reason: "must look like 2025-09-30"}
+	}
+	if strings.TrimSpace(l.Topic) == "" {
+		return &ValidationError{Field: "topic", Reason: "must not be empty"}
+	}
+	if err := validateText("topic", l.Topic); err != nil {
+		return err
+	}
+	if err := validateText("homework", l.Homework); err != nil {
+		return err
+	}
+	if l.Homework == "" {
+		if l.DueDate != "" {
+			return &ValidationError{Field: "due date", Reason: "is only allowed together with homework"}
+		}
+		return nil
+	}
+	if strings.TrimSpace(l.Homework) == "" {
+		return &ValidationError{Field: "homework", Reason: "must not be blank"}
+	}
+	if _, err := time.Parse("2006-01-02", l.DueDate); err != nil {
+		return &ValidationError{Field: "due date", Reason: "must look like 2025-09-30"}
+	}
+	if l.DueDate < l.Date {
+		return &ValidationError{Field: "due date", Reason: "must not be before the lesson"}
+	}
+	return nil
+}
+
+// validateText accepts free text of up to maxTextLength characters. Line
+// breaks and tabs are allowed, other control characters are not.
+func validateText(field, value string) error {
+	if len([]rune(value)) > maxTextLength {
+		return &ValidationError{Field: field, Reason: "is too long"}
+	}
+	for _, r := range value {
+		if !unicode.IsPrint(r) && !strings.ContainsRune("\n\t", r) {
+			return &ValidationError{Field: field, Reason: "must not contain control characters"}
+		}
+	}
+	return nil
+}
+
+// Lessons returns the lessons matching f.
+func (s *SQLite) Lessons(f LessonFilter) ([]LessonEntry, error) {
+	var entries []LessonEntry
+	err := s.db.Select(&entries, selectLessonsStmt,
+		f.ClassID, f.ClassID, f.SubjectID, f.SubjectID, f.From, f.From, f.To, f.To)
+	if err != nil {
+		return nil, wrap("list lessons", err)
+	}
+	return entries, nil
+}
+
+// Lesson returns a single lesson by its ID.
+func (s *SQLite) Lesson(id int) (LessonEntry, error) {
+	var entry LessonEntry
+	if err := s.db.Get(&entry, selectLessonStmt, id); err != nil {
+		return LessonEntry{}, wrap(fmt.Sprintf("get lesson %d", id), err)
+	}
+	return entry, nil
+}
+
+// AddLesson records a new lesson and returns its ID. The class and subject
+// must exist.
+func (s *SQLite) AddLesson(l LessonEntry) (int, error) {
+	const op = "add lesson"
+	if err := validateLesson(l); err != nil {
+		return 0, &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	res, err := s.exec(insertLessonStmt, l.ClassID, l.SubjectID, l.Date, l.Topic, l.Homework, l.DueDate)
+	if err != nil {
+		return 0, wrap(op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, wrap(op, err)
+	}
+	return int(id), nil
+}
+
+// UpdateLesson changes the date, topic and homework of an existing lesson.
+func (s *SQLite) UpdateLesson(l LessonEntry) error {
+	op := fmt.Sprintf("update lesson %d", l.ID)
+	if err := validateLesson(l); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	res, err := s.exec(updateLessonStmt, l.Date, l.Topic, l.Homework, l.DueDate, l.ID)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// DeleteLesson removes a lesson.
+func (s *SQLite) DeleteLesson(id int) error {
+	op := fmt.Sprintf("delete lesson %d", id)
+	res, err := s.exec(deleteLessonStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// Homework returns the lessons of a class with homework due on from or later.
+func (s *SQLite) Homework(classID int, from string) ([]LessonEntry, error) {
+	var entries []LessonEntry
+	if err := s.db.Select(&entries, selectHomeworkStmt, classID, from); err != nil {
+		return nil, wrap(fmt.Sprintf("list homework of class %d", classID), err)
+	}
+	return entries, nil
+}
+
+// Lessons returns the lessons matching f.
+func (m *Memory) Lessons(f LessonFilter) ([]LessonEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := m.listLessons(func(l LessonEntry) bool {
+		return (f.ClassID == 0 || l.ClassID == f.ClassID) && (f.SubjectID == 0 || l.SubjectID == f.SubjectID) &&
+			(f.From == "" || l.Date >= f.From) && (f.To == "" || l.Date <= f.To)
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// listLessons returns the lessons matching keep with the names of the
+// referenced entries filled in, in no particular order. m.mu must be held.
+func (m *Memory) listLessons(keep func(LessonEntry) bool) []LessonEntry {
+	var entries []LessonEntry
+	for _, l := range m.lessons {
+		if keep(l) {
+			entries = append(entries, m.lesson(l))
+		}
+	}
+	return entries
+}
+
+// lesson fills in the names of the entries l refers to. m.mu must be held.
+func (m *Memory) lesson(l LessonEntry) LessonEntry {
+	class, subject := m.classes[l.ClassID], m.subjects[l.SubjectID]
+	l.Year, l.Modifier = class.Year, class.Modifier
+	l.Subject = subject.Name
+	return l
+}
+
+// Lesson returns a single lesson by its ID.
+func (m *Memory) Lesson(id int) (LessonEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.lessons[id]
+	if !ok {
+		return LessonEntry{}, &Error{Op: fmt.Sprintf("get lesson %d", id), Kind: ErrNotFound}
+	}
+	return m.lesson(l), nil
+}
+
+// AddLesson records a new lesson and returns its ID. The class and subject
+// must exist.
+func (m *Memory) AddLesson(l LessonEntry) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	const op = "add lesson"
+	if err := validateLesson(l); err != nil {
+		return 0, &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	_, classOK := m.classes[l.ClassID]
+	_, subjectOK := m.subjects[l.SubjectID]
+	if !classOK || !subjectOK {
+		return 0, &Error{Op: op, Kind: ErrConstraint}
+	}
+	m.lastLessonID++
+	l.ID = m.lastLessonID
+	l.Year, l.Modifier, l.Subject = "", "", ""
+	m.touch("lessons", l.ID)
+	m.lessons[l.ID] = l
+	return l.ID, nil
+}
+
+// UpdateLesson changes the date, topic and homework of an existing lesson.
+func (m *Memory) UpdateLesson(l LessonEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("update lesson %d", l.ID)
+	if err := validateLesson(l); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	old, ok := m.lessons[l.ID]
+	if !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	old.Date, old.Topic, old.Homework, old.DueDate = l.Date, l.Topic, l.Homework, l.DueDate
+	m.touch("lessons", l.ID)
+	m.lessons[l.ID] = old
+	return nil
+}
+
+// DeleteLesson removes a lesson.
+func (m *Memory) DeleteLesson(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if _, ok := m.lessons[id]; !ok {
+		return &Error{Op: fmt.Sprintf("delete lesson %d", id), Kind: ErrNotFound}
+	}
+	m.touch("lessons", id)
+	delete(m.lessons, id)
+	return nil
+}
+
+// Homework returns the lessons of a class with homework due on from or later.
+func (m *Memory) Homework(classID int, from string) ([]LessonEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := m.listLessons(func(l LessonEntry) bool {
+		return l.ClassID == classID && l.Homework != "" && l.DueDate >= from
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case a.DueDate != b.DueDate:
+			return a.DueDate < b.DueDate
+		case a.Subject != b.Subject:
+			return a.Subject < b.Subject
+		}
+		return a.ID < b.ID
+	})
+	return entries, nil
+}
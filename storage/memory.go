@@ -0,0 +1,476 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Memory is a Storage that keeps everything in memory. It behaves like SQLite,
+// including the integrity checks, but nothing survives Close. It is meant for
+// tests that do not care about persistence.
+type Memory struct {
+	mu sync.Mutex
+
+	students    map[int]StudentEntry
+	classes     map[int]ClassEntry
+	enrollments map[int]int // Class ID keyed by student ID.
+	teachers    map[int]TeacherEntry
+	subjects    map[int]SubjectEntry
+	assignments map[int]AssignmentEntry // Without the names of referenced entries.
+	grades      map[int]GradeEntry
+	corrections []GradeCorrection // In the order they were made.
+	attendance  map[attendanceKey]AttendanceEntry
+	bells       map[int]BellEntry   // By lesson.
+	slots       map[int]SlotEntry   // Without the names of referenced entries.
+	lessons     map[int]LessonEntry // Without the names of referenced entries.
+	schoolYears map[int]SchoolYearEntry
+	terms       map[int]TermEntry
+	promotions  map[int]*promotion
+	graduates   map[int]graduate // By student ID.
+	finalGrades map[int]FinalGradeEntry
+	users       map[int]UserEntry
+	auditLog    []AuditEntry // Oldest first.
+	actor       string
+	touched     map[auditRef]string // Rows changed by the current mutation, as they were before.
+
+	lastStudentID    int
+	lastClassID      int
+	lastTeacherID    int
+	lastSubjectID    int
+	lastAssignmentID int
+	lastGradeID      int
+	lastCorrectionID int
+	lastSlotID       int
+	lastLessonID     int
+	lastSchoolYearID int
+	lastTermID       int
+	lastPromotionID  int
+	lastFinalGradeID int
+	lastUserID       int
+	lastAuditID      int
+}
+
+var _ Storage = (*Memory)(nil)
+
+// NewMemory returns an empty in-memory storage.
+func NewMemory() *Memory {
+	return &Memory{
+		students:    make(map[int]StudentEntry),
+		classes:     make(map[int]ClassEntry),
+		enrollments: make(map[int]int),
+		teachers:    make(map[int]TeacherEntry),
+		subjects:    make(map[int]SubjectEntry),
+		assignments: make(map[int]AssignmentEntry),
+		grades:      make(map[int]GradeEntry),
+		attendance:  make(map[attendanceKey]AttendanceEntry),
+		bells:       make(map[int]BellEntry),
+		slots:       make(map[int]SlotEntry),
+		lessons:     make(map[int]LessonEntry),
+		schoolYears: make(map[int]SchoolYearEntry),
+		terms:       make(map[int]TermEntry),
+		promotions:  make(map[int]*promotion),
+		graduates:   make(map[int]graduate),
+		finalGrades: make(map[int]FinalGradeEntry),
+		users:       make(map[int]UserEntry),
+	}
+}
+
+// Close is a no-op, it exists to satisfy the Storage interface.
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Students returns a slice of existing students.
+func (m *Memory) Students() ([]StudentEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []StudentEntry
+	for _, entry := range m.students {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// Student returns a single student by its ID.
+func (m *Memory) Student(id int) (StudentEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.students[id]
+	if !ok {
+		return StudentEntry{}, &Error{Op: fmt.Sprintf("get student %d", id), Kind: ErrNotFound}
+	}
+	return entry, nil
+}
+
+// AddStudent appends a new student entry and returns its ID.
+func (m *Memory) AddStudent(name, surname string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if err := validatePerson(name, surname); err != nil {
+		return 0, &Error{Op: "add student", Kind: ErrValidation, Err: err}
+	}
+	m.lastStudentID++
+	m.touch("students", m.lastStudentID)
+	m.students[m.lastStudentID] = StudentEntry{ID: m.lastStudentID, Name: name, Surname: surname}
+	return m.lastStudentID, nil
+}
+
+// UpdateStudent changes the name and surname of an existing student.
+func (m *Memory) UpdateStudent(id int, name, surname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("update student %d", id)
+	if err := validatePerson(name, surname); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	if _, ok := m.students[id]; !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	m.touch("students", id)
+	m.students[id] = StudentEntry{ID: id, Name: name, Surname: surname}
+	return nil
+}
+
+// DeleteStudent removes a student together with their enrollment, grades,
+// final grades, attendance and promotion history.
+func (m *Memory) DeleteStudent(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if _, ok := m.students[id]; !ok {
+		return &Error{Op: fmt.Sprintf("delete student %d", id), Kind: ErrNotFound}
+	}
+	m.deleteStudent(id)
+	return nil
+}
+
+// deleteStudent removes a student with everything that refers to them. m.mu
+// must be held.
+func (m *Memory) deleteStudent(id int) {
+	m.touch("students", id)
+	delete(m.students, id)
+	m.touch("enrollments", id)
+	delete(m.enrollments, id)
+	for _, g := range m.grades {
+		if g.StudentID == id {
+			m.deleteGrade(g.ID)
+		}
+	}
+	for finalID, g := range m.finalGrades {
+		if g.StudentID == id {
+			m.touch("final_grades", finalID)
+			delete(m.finalGrades, finalID)
+		}
+	}
+	for k := range m.attendance {
+		if k.studentID == id {
+			m.touch("attendance", k)
+			delete(m.attendance, k)
+		}
+	}
+	delete(m.graduates, id)
+	for _, p := range m.promotions {
+		moves := p.moves[:0]
+		for _, mv := range p.moves {
+			if mv.studentID != id {
+				moves = append(moves, mv)
+			}
+		}
+		p.moves = moves
+	}
+}
+
+// RestoreStudent adds a student anew with everything of the record. Nothing
+// is stored if any of it fails.
+func (m *Memory) RestoreStudent(r StudentRecord) (int, []int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	const op = "restore student"
+	if err := validatePerson(r.Student.Name, r.Student.Surname); err != nil {
+		return 0, nil, &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	if _, ok := m.classes[int(r.ClassID.Int64)]; r.ClassID.Valid && !ok {
+		return 0, nil, &Error{Op: op, Kind: ErrConstraint}
+	}
+	lastStudentID, lastGradeID, lastFinalGradeID := m.lastStudentID, m.lastGradeID, m.lastFinalGradeID
+	m.lastStudentID++
+	id := m.lastStudentID
+	m.touch("students", id)
+	m.students[id] = StudentEntry{ID: id, Name: r.Student.Name, Surname: r.Student.Surname}
+	if r.ClassID.Valid {
+		m.touch("enrollments", id)
+		m.enrollments[id] = int(r.ClassID.Int64)
+	}
+	ids, err := m.restoreRecord(op, id, r)
+	if err != nil {
+		// Everything added refers to the new student, so removing them
+		// reverts it all.
+		m.deleteStudent(id)
+		m.lastStudentID, m.lastGradeID, m.lastFinalGradeID = lastStudentID, lastGradeID, lastFinalGradeID
+		return 0, nil, err
+	}
+	return id, ids, nil
+}
+
+// restoreRecord adds the grades, attendance and final grades of the record
+// for the student and returns the IDs of the grades. m.mu must be held.
+func (m *Memory) restoreRecord(op string, studentID int, r StudentRecord) ([]int, error) {
+	ids := make([]int, 0, len(r.Grades))
+	for _, g := range r.Grades {
+		id, err := m.addGrade(studentID, g.SubjectID, g.TeacherID, g.Date, g.Mark, g.Kind)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	for _, e := range r.Attendance {
+		e.StudentID = studentID
+		if err := m.setAttendance(e); err != nil {
+			return nil, err
+		}
+	}
+	finals := make([]FinalGradeEntry, len(r.Finals))
+	for i, f := range r.Finals {
+		f.StudentID = studentID
+		finals[i] = f
+	}
+	return ids, m.saveFinalGrades(op, finals)
+}
+
+// Classes returns a slice of existing classes.
+func (m *Memory) Classes() ([]ClassEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []ClassEntry
+	for _, entry := range m.classes {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// Class returns a single class by its ID.
+func (m *Memory) Class(id int) (ClassEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.classes[id]
+	if !ok {
+		return ClassEntry{}, &Error{Op: fmt.Sprintf("get class %d", id), Kind: ErrNotFound}
+	}
+	return entry, nil
+}
+
+// AddClass appends a new class entry to the latest school year and returns
+// its ID.
+func (m *Memory) AddClass(year, modifier string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if err := validateClass(year, modifier); err != nil {
+		return 0, &Error{Op: "add class", Kind: ErrValidation, Err: err}
+	}
+	var schoolYearID sql.NullInt64
+	if y, ok := m.latestSchoolYear(); ok {
+		schoolYearID = sql.NullInt64{Int64: int64(y.ID), Valid: true}
+	}
+	if m.hasClass(0, schoolYearID, year, modifier) {
+		return 0, &Error{Op: "add class", Kind: ErrDuplicate}
+	}
+	return m.addClass(ClassEntry{Year: year, Modifier: modifier, SchoolYearID: schoolYearID}), nil
+}
+
+// addClass stores a new class and returns its ID. m.mu must be held.
+func (m *Memory) addClass(c ClassEntry) int {
+	m.lastClassID++
+	c.ID = m.lastClassID
+	m.touch("classes", c.ID)
+	m.classes[c.ID] = c
+	return c.ID
+}
+
+// UpdateClass changes the year and modifier of an existing class.
+func (m *Memory) UpdateClass(id int, year, modifier string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("update class %d", id)
+	if err := validateClass(year, modifier); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	class, ok := m.classes[id]
+	if !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	if m.hasClass(id, class.SchoolYearID, year, modifier) {
+		return &Error{Op: op, Kind: ErrDuplicate}
+	}
+	class.Year, class.Modifier = year, modifier
+	m.touch("classes", id)
+	m.classes[id] = class
+	return nil
+}
+
+// hasClass reports whether a class other than except has the given year and
+// modifier in the school year. m.mu must be held.
+func (m *Memory) hasClass(except int, schoolYearID sql.NullInt64, year, modifier string) bool {
+	for _, c := range m.classes {
+		if c.ID != except && c.SchoolYearID == schoolYearID && c.Year == year && c.Modifier == modifier {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteClass removes a class. Classes that still have enrolled students,
+// teaching assignments, lessons in the timetable or recorded lesson topics
+// cannot be deleted and ErrConstraint is returned instead.
+func (m *Memory) DeleteClass(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("delete class %d", id)
+	if _, ok := m.classes[id]; !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	if m.classReferenced(id) {
+		return &Error{Op: op, Kind: ErrConstraint}
+	}
+	m.touch("classes", id)
+	delete(m.classes, id)
+	return nil
+}
+
+// classReferenced reports whether anything refers to the class. m.mu must be
+// held.
+func (m *Memory) classReferenced(id int) bool {
+	for _, classID := range m.enrollments {
+		if classID == id {
+			return true
+		}
+	}
+	for _, a := range m.assignments {
+		if a.ClassID == id {
+			return true
+		}
+	}
+	for _, s := range m.slots {
+		if s.ClassID == id {
+			return true
+		}
+	}
+	for _, l := range m.lessons {
+		if l.ClassID == id {
+			return true
+		}
+	}
+	for _, p := range m.promotions {
+		if containsID(p.created, id) {
+			return true
+		}
+		for _, mv := range p.moves {
+			if mv.from == id || mv.to == id {
+				return true
+			}
+		}
+	}
+	for _, g := range m.graduates {
+		if g.classID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// compareYears orders class years numerically, like SQLite does.
+func compareYears(a, b string) int {
+	x, _ := strconv.Atoi(a)
+	y, _ := strconv.Atoi(b)
+	return x - y
+}
+
+// Groups returns every student together with their class.
+func (m *Memory) Groups() ([]GroupEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []GroupEntry
+	for id := range m.students {
+		entries = append(entries, m.group(id))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].StudentID < entries[j].StudentID })
+	return entries, nil
+}
+
+// Group returns the enrollment of a single student.
+func (m *Memory) Group(studentID int) (GroupEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.students[studentID]; !ok {
+		return GroupEntry{}, &Error{Op: fmt.Sprintf("get group of student %d", studentID), Kind: ErrNotFound}
+	}
+	return m.group(studentID), nil
+}
+
+// group joins a student with their class. m.mu must be held.
+func (m *Memory) group(studentID int) GroupEntry {
+	student := m.students[studentID]
+	entry := GroupEntry{StudentID: student.ID, Name: student.Name, Surname: student.Surname}
+	if classID, ok := m.enrollments[studentID]; ok {
+		class := m.classes[classID]
+		entry.ClassID = sql.NullInt64{Int64: int64(class.ID), Valid: true}
+		entry.Year = sql.NullString{String: class.Year, Valid: true}
+		entry.Modifier = sql.NullString{String: class.Modifier, Valid: true}
+	}
+	return entry
+}
+
+// AssignClassToStudent enrolls a student into a class, replacing any previous
+// enrollment. Both the student and the class must exist.
+func (m *Memory) AssignClassToStudent(studentID, classID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	_, studentOK := m.students[studentID]
+	_, classOK := m.classes[classID]
+	if !studentOK || !classOK {
+		return &Error{Op: fmt.Sprintf("assign class %d to student %d", classID, studentID), Kind: ErrConstraint}
+	}
+	m.touch("enrollments", studentID)
+	m.enrollments[studentID] = classID
+	return nil
+}
+
+// UnassignClassFromStudent removes the enrollment of a student. Returns
+// ErrNotFound if the student is not enrolled in any class.
+func (m *Memory) UnassignClassFromStudent(studentID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if _, ok := m.enrollments[studentID]; !ok {
+		return &Error{Op: fmt.Sprintf("unassign class from student %d", studentID), Kind: ErrNotFound}
+	}
+	m.touch("enrollments", studentID)
+	delete(m.enrollments, studentID)
+	return nil
+}
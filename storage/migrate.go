@@ -0,0 +1,435 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrSchemaTooNew is returned when the database was written by a newer
+// version of the application than the one currently running.
+var ErrSchemaTooNew = errors.New("database schema is newer than supported")
+
+// migration upgrades the schema from the previous version to the next one.
+type migration struct {
+	name string // Short description of the change, used in error messages.
+	stmt string // Statements to execute. May contain several statements.
+}
+
+// migrations lists every schema change in the order they must be applied.
+// The schema version stored in the database (`PRAGMA user_version`) is the
+// number of migrations applied so far. Never edit or reorder existing entries,
+// only append new ones.
+var migrations = []migration{
+	{
+		// Version 1. Databases created before versioning was introduced have
+		// these tables already but report version 0, hence IF NOT EXISTS.
+		name: "create students, classes and groups tables",
+		stmt: `
+		CREATE TABLE IF NOT EXISTS students (
+			id	INTEGER,
+			name	TEXT,
+			surname	TEXT,
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		CREATE TABLE IF NOT EXISTS classes (
+			id	INTEGER,
+			year INTEGER,
+			modifier	TEXT,
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		CREATE TABLE IF NOT EXISTS groups (
+			student_id	INTEGER,
+			name TEXT,
+			surname TEXT,
+			year INTEGER,
+			modifier	TEXT,
+			PRIMARY KEY(student_id)
+		);`,
+	},
+	{
+		// Version 2. Replaces the denormalized `groups` table with a
+		// membership relation. Rows whose year and modifier do not match any
+		// class are dropped, as they never referred to a real class.
+		name: "replace groups with enrollments",
+		stmt: `
+		CREATE TABLE enrollments (
+			student_id	INTEGER NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+			class_id	INTEGER NOT NULL REFERENCES classes(id),
+			PRIMARY KEY(student_id, class_id)
+		);
+		INSERT INTO enrollments (student_id, class_id)
+			SELECT groups.student_id, MIN(classes.id) FROM groups
+			JOIN students ON students.id = groups.student_id
+			JOIN classes ON classes.year = groups.year AND classes.modifier = groups.modifier
+			GROUP BY groups.student_id;
+		DROP TABLE groups;`,
+	},
+	{
+		// Version 3. Makes a second 5.a impossible. Existing duplicates are
+		// merged into the oldest class first.
+		name: "make classes unique",
+		stmt: `
+		UPDATE enrollments SET class_id = (
+			SELECT MIN(other.id) FROM classes AS this
+			JOIN classes AS other ON other.year = this.year AND other.modifier = this.modifier
+			WHERE this.id = enrollments.class_id
+		);
+		DELETE FROM classes WHERE id NOT IN (
+			SELECT MIN(id) FROM classes GROUP BY year, modifier
+		);
+		CREATE UNIQUE INDEX classes_year_modifier ON classes (year, modifier);`,
+	},
+	{
+		// Version 4. Adds staff and the homeroom teacher of each class.
+		name: "add teachers",
+		stmt: `
+		CREATE TABLE teachers (
+			id	INTEGER,
+			name	TEXT NOT NULL,
+			surname	TEXT NOT NULL,
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		ALTER TABLE classes ADD COLUMN teacher_id INTEGER REFERENCES teachers(id);`,
+	},
+	{
+		// Version 5. Adds subjects and who teaches them to which class.
+		name: "add subjects and teaching assignments",
+		stmt: `
+		CREATE TABLE subjects (
+			id	INTEGER,
+			name	TEXT NOT NULL UNIQUE,
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		CREATE TABLE teaching_assignments (
+			id	INTEGER,
+			teacher_id	INTEGER NOT NULL REFERENCES teachers(id),
+			subject_id	INTEGER NOT NULL REFERENCES subjects(id),
+			class_id	INTEGER NOT NULL REFERENCES classes(id),
+			school_year	TEXT NOT NULL,
+			PRIMARY KEY(id AUTOINCREMENT),
+			UNIQUE(teacher_id, subject_id, class_id, school_year)
+		);
+		CREATE INDEX teaching_assignments_class ON teaching_assignments (class_id);`,
+	},
+	{
+		// Version 6. Adds grades. Corrections keep the replaced marks.
+		name: "add grades and grade corrections",
+		stmt: `
+		CREATE TABLE grades (
+			id	INTEGER,
+			student_id	INTEGER NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+			subject_id	INTEGER NOT NULL REFERENCES subjects(id),
+			teacher_id	INTEGER NOT NULL REFERENCES teachers(id),
+			date	TEXT NOT NULL,
+			mark	TEXT NOT NULL CHECK(mark IN ('1', '2', '3', '4', '5', '6', '7', '8', '9', '10', 'i', 'ni', 'nv')),
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		CREATE INDEX grades_student ON grades (student_id, date);
+		CREATE INDEX grades_subject ON grades (subject_id, date);
+		CREATE TABLE grade_corrections (
+			id	INTEGER,
+			grade_id	INTEGER NOT NULL REFERENCES grades(id) ON DELETE CASCADE,
+			old_mark	TEXT NOT NULL,
+			new_mark	TEXT NOT NULL,
+			reason	TEXT NOT NULL,
+			corrected_at	TEXT NOT NULL,
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		CREATE INDEX grade_corrections_grade ON grade_corrections (grade_id);`,
+	},
+	{
+		// Version 7. Adds the attendance register. Students without an entry
+		// were present.
+		name: "add attendance",
+		stmt: `
+		CREATE TABLE attendance (
+			student_id	INTEGER NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+			date	TEXT NOT NULL,
+			lesson	INTEGER NOT NULL,
+			status	TEXT NOT NULL CHECK(status IN ('absent', 'late', 'excused')),
+			reason	TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY(student_id, date, lesson)
+		);
+		CREATE INDEX attendance_date ON attendance (date);`,
+	},
+	{
+		// Version 8. Adds the bell schedule and the weekly timetable. The
+		// unique indexes back up the conflict checks done before inserting.
+		name: "add bells and timetable",
+		stmt: `
+		CREATE TABLE bells (
+			lesson	INTEGER PRIMARY KEY,
+			starts	TEXT NOT NULL,
+			ends	TEXT NOT NULL
+		);
+		CREATE TABLE timetable (
+			id	INTEGER,
+			class_id	INTEGER NOT NULL REFERENCES classes(id),
+			weekday	INTEGER NOT NULL,
+			lesson	INTEGER NOT NULL,
+			subject_id	INTEGER NOT NULL REFERENCES subjects(id),
+			teacher_id	INTEGER NOT NULL REFERENCES teachers(id),
+			room	TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		CREATE UNIQUE INDEX timetable_class ON timetable (class_id, weekday, lesson);
+		CREATE UNIQUE INDEX timetable_teacher ON timetable (teacher_id, weekday, lesson);
+		CREATE UNIQUE INDEX timetable_room ON timetable (room, weekday, lesson) WHERE room <> '';`,
+	},
+	{
+		// Version 9. Adds lesson topics and homework.
+		name: "add lessons",
+		stmt: `
+		CREATE TABLE lessons (
+			id	INTEGER,
+			class_id	INTEGER NOT NULL REFERENCES classes(id),
+			subject_id	INTEGER NOT NULL REFERENCES subjects(id),
+			date	TEXT NOT NULL,
+			topic	TEXT NOT NULL,
+			homework	TEXT NOT NULL DEFAULT '',
+			due_date	TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		CREATE INDEX lessons_class ON lessons (class_id, date);
+		CREATE INDEX lessons_due ON lessons (class_id, due_date) WHERE homework <> '';`,
+	},
+	{
+		// Version 10. Adds school years with their terms and scopes classes
+		// to a school year, so that 5.a can exist in every year. Existing
+		// classes are moved into the school year of the day of the upgrade.
+		// Promotions record what they changed, so that they can be undone.
+		name: "add school years, terms and promotions",
+		stmt: `
+		CREATE TABLE school_years (
+			id	INTEGER,
+			name	TEXT NOT NULL UNIQUE,
+			starts	TEXT NOT NULL,
+			ends	TEXT NOT NULL,
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		CREATE TABLE terms (
+			id	INTEGER,
+			school_year_id	INTEGER NOT NULL REFERENCES school_years(id) ON DELETE CASCADE,
+			name	TEXT NOT NULL,
+			starts	TEXT NOT NULL,
+			ends	TEXT NOT NULL,
+			PRIMARY KEY(id AUTOINCREMENT),
+			UNIQUE(school_year_id, name)
+		);
+		ALTER TABLE classes ADD COLUMN school_year_id INTEGER REFERENCES school_years(id);
+		INSERT INTO school_years (name, starts, ends)
+			SELECT printf('%d/%d', y, y + 1), printf('%d-09-01', y), printf('%d-08-31', y + 1)
+			FROM (SELECT CAST(strftime('%Y', 'now') AS INTEGER) - (CAST(strftime('%m', 'now') AS INTEGER) < 9) AS y)
+			WHERE EXISTS (SELECT 1 FROM classes);
+		UPDATE classes SET school_year_id = (SELECT MAX(id) FROM school_years);
+		DROP INDEX classes_year_modifier;
+		CREATE UNIQUE INDEX classes_year_modifier ON classes (IFNULL(school_year_id, 0), year, modifier);
+		CREATE TABLE promotions (
+			id	INTEGER,
+			from_year_id	INTEGER NOT NULL UNIQUE REFERENCES school_years(id),
+			to_year_id	INTEGER NOT NULL REFERENCES school_years(id),
+			promoted_at	TEXT NOT NULL,
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		CREATE TABLE promotion_classes (
+			promotion_id	INTEGER NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+			class_id	INTEGER NOT NULL REFERENCES classes(id),
+			PRIMARY KEY(promotion_id, class_id)
+		);
+		CREATE TABLE promotion_moves (
+			promotion_id	INTEGER NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+			student_id	INTEGER NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+			from_class_id	INTEGER NOT NULL REFERENCES classes(id),
+			to_class_id	INTEGER REFERENCES classes(id),
+			PRIMARY KEY(promotion_id, student_id)
+		);
+		CREATE TABLE graduates (
+			student_id	INTEGER PRIMARY KEY REFERENCES students(id) ON DELETE CASCADE,
+			class_id	INTEGER NOT NULL REFERENCES classes(id),
+			promotion_id	INTEGER NOT NULL REFERENCES promotions(id) ON DELETE CASCADE
+		);`,
+	},
+	{
+		// Version 11. Adds the kind of assessment of grades, which weighs
+		// them in averages, and the final grades of terms and school years.
+		// Existing grades count as daily work.
+		name: "add assessment kinds and final grades",
+		stmt: `
+		ALTER TABLE grades ADD COLUMN kind TEXT NOT NULL DEFAULT 'daily' CHECK(kind IN ('daily', 'test', 'exam'));
+		CREATE TABLE final_grades (
+			id	INTEGER,
+			student_id	INTEGER NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+			subject_id	INTEGER NOT NULL REFERENCES subjects(id),
+			school_year_id	INTEGER NOT NULL REFERENCES school_years(id),
+			term_id	INTEGER REFERENCES terms(id),
+			average	REAL NOT NULL DEFAULT 0,
+			proposed	TEXT NOT NULL,
+			mark	TEXT NOT NULL CHECK(mark IN ('1', '2', '3', '4', '5', '6', '7', '8', '9', '10', 'i', 'ni', 'nv')),
+			reason	TEXT NOT NULL DEFAULT '',
+			finalized_at	TEXT NOT NULL,
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		CREATE UNIQUE INDEX final_grades_period ON final_grades (student_id, subject_id, school_year_id, IFNULL(term_id, 0));`,
+	},
+	{
+		// Version 12. Adds the users who can log in. Teachers are linked to
+		// their teacher entry, which limits what they may change.
+		name: "add users",
+		stmt: `
+		CREATE TABLE users (
+			id	INTEGER,
+			username	TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			password_hash	TEXT NOT NULL,
+			role	TEXT NOT NULL CHECK(role IN ('admin', 'class_teacher', 'teacher', 'secretary')),
+			teacher_id	INTEGER REFERENCES teachers(id),
+			PRIMARY KEY(id AUTOINCREMENT)
+		);`,
+	},
+	{
+		// Version 13. Adds the append-only audit log, which triggers write
+		// in the transaction of every change. Writers set the actor in
+		// audit_actor at the start of the transaction. A migration changing
+		// the columns of an audited table must recreate its triggers.
+		name: "add audit log",
+		stmt: `
+		CREATE TABLE audit_log (
+			id	INTEGER,
+			at	TEXT NOT NULL,
+			actor	TEXT NOT NULL,
+			entity	TEXT NOT NULL,
+			entity_id	INTEGER NOT NULL,
+			action	TEXT NOT NULL CHECK(action IN ('insert', 'update', 'delete')),
+			old	TEXT,
+			new	TEXT,
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		CREATE INDEX audit_log_entity ON audit_log (entity, entity_id);
+		CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log BEGIN
+			SELECT RAISE(ABORT, 'the audit log is append-only');
+		END;
+		CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log BEGIN
+			SELECT RAISE(ABORT, 'the audit log is append-only');
+		END;
+		CREATE TABLE audit_actor (
+			id	INTEGER PRIMARY KEY CHECK(id = 1),
+			actor	TEXT NOT NULL
+		);
+		INSERT INTO audit_actor VALUES(1, '');` +
+			auditTriggers("students", "id", "id", "name", "surname") +
+			auditTriggers("classes", "id", "id", "year", "modifier", "teacher_id", "school_year_id") +
+			auditTriggers("enrollments", "student_id", "student_id", "class_id") +
+			auditTriggers("teachers", "id", "id", "name", "surname") +
+			auditTriggers("subjects", "id", "id", "name") +
+			auditTriggers("teaching_assignments", "id", "id", "teacher_id", "subject_id", "class_id", "school_year") +
+			auditTriggers("grades", "id", "id", "student_id", "subject_id", "teacher_id", "date", "mark", "kind") +
+			auditTriggers("attendance", "student_id", "student_id", "date", "lesson", "status", "reason") +
+			auditTriggers("bells", "lesson", "lesson", "starts", "ends") +
+			auditTriggers("timetable", "id", "id", "class_id", "weekday", "lesson", "subject_id", "teacher_id", "room") +
+			auditTriggers("lessons", "id", "id", "class_id", "subject_id", "date", "topic", "homework", "due_date") +
+			auditTriggers("school_years", "id", "id", "name", "starts", "ends") +
+			auditTriggers("terms", "id", "id", "school_year_id", "name", "starts", "ends") +
+			auditTriggers("promotions", "id", "id", "from_year_id", "to_year_id", "promoted_at") +
+			auditTriggers("final_grades", "id", "id", "student_id", "subject_id", "school_year_id", "term_id", "average", "proposed", "mark", "reason", "finalized_at") +
+			auditTriggers("users", "id", "id", "username", "role", "teacher_id"),
+	},
+	{
+		// Version 14. Teaching assignments reference the school year instead
+		// of naming it, creating the school years named only by assignments.
+		// New assignments take the school year of their class. The table is
+		// rebuilt, as SQLite cannot drop a column in a UNIQUE constraint.
+		name: "reference school years from teaching assignments",
+		stmt: `
+		INSERT INTO school_years (name, starts, ends)
+			SELECT DISTINCT school_year, substr(school_year, 1, 4) || '-09-01', substr(school_year, 6, 4) || '-08-31'
+			FROM teaching_assignments
+			WHERE school_year NOT IN (SELECT name FROM school_years)
+			ORDER BY school_year;
+		CREATE TABLE school_year_assignments (
+			id	INTEGER,
+			teacher_id	INTEGER NOT NULL REFERENCES teachers(id),
+			subject_id	INTEGER NOT NULL REFERENCES subjects(id),
+			class_id	INTEGER NOT NULL REFERENCES classes(id),
+			school_year_id	INTEGER REFERENCES school_years(id),
+			PRIMARY KEY(id AUTOINCREMENT)
+		);
+		INSERT INTO school_year_assignments (id, teacher_id, subject_id, class_id, school_year_id)
+			SELECT teaching_assignments.id, teacher_id, subject_id, class_id, school_years.id
+			FROM teaching_assignments
+			JOIN school_years ON school_years.name = teaching_assignments.school_year;
+		-- Keeps the IDs of deleted assignments from being reused.
+		DELETE FROM sqlite_sequence WHERE name = 'school_year_assignments';
+		UPDATE sqlite_sequence SET name = 'school_year_assignments' WHERE name = 'teaching_assignments';
+		DROP TABLE teaching_assignments;
+		ALTER TABLE school_year_assignments RENAME TO teaching_assignments;
+		CREATE INDEX teaching_assignments_class ON teaching_assignments (class_id);
+		CREATE UNIQUE INDEX teaching_assignments_unique ON teaching_assignments (teacher_id, subject_id, class_id, IFNULL(school_year_id, 0));` +
+			auditTriggers("teaching_assignments", "id", "id", "teacher_id", "subject_id", "class_id", "school_year_id"),
+	},
+	{
+		// Version 15. A student is enrolled in one class at most, which the
+		// composite key of version 2 did not enforce. Only the latest of
+		// several enrollments of a student is kept.
+		name: "enroll students in one class at most",
+		stmt: `
+		DELETE FROM enrollments WHERE rowid NOT IN (
+			SELECT MAX(rowid) FROM enrollments GROUP BY student_id
+		);
+		CREATE UNIQUE INDEX enrollments_student ON enrollments (student_id);`,
+	},
+}
+
+// schemaVersion returns the latest schema version known to this binary.
+func schemaVersion() int {
+	return len(migrations)
+}
+
+// userVersion reads the schema version stored in the database.
+func userVersion(q sqlx.Queryer) (int, error) {
+	var v int
+	if err := sqlx.Get(q, &v, `PRAGMA user_version`); err != nil {
+		return 0, fmt.Errorf("reading schema version failed: %v", err)
+	}
+	return v, nil
+}
+
+// migrate brings the database schema up to date. Each migration runs in its
+// own transaction together with the version bump, so a failed migration
+// leaves the database at the previous version.
+func migrate(db *sqlx.DB) error {
+	current, err := userVersion(db)
+	if err != nil {
+		return err
+	}
+	if current > schemaVersion() {
+		return fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, current, schemaVersion())
+	}
+	for v := current; v < schemaVersion(); v++ {
+		if err := applyMigration(db, v+1, migrations[v]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration runs m and sets the schema version to version.
+func applyMigration(db *sqlx.DB, version int, m migration) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("starting migration %d failed: %v", version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.stmt); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %v", version, m.name, err)
+	}
+	// PRAGMA does not accept bind parameters.
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version)); err != nil {
+		return fmt.Errorf("updating schema version to %d failed: %v", version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing migration %d failed: %v", version, err)
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// baselineSchema is the schema written by releases that predate versioned
+// migrations. Such databases report `PRAGMA user_version` 0.
+const baselineSchema = `
+	CREATE TABLE IF NOT EXISTS students (
+		id	INTEGER,
+		name	TEXT,
+		surname	TEXT,
+		PRIMARY KEY(id AUTOINCREMENT)
+	);
+	CREATE TABLE IF NOT EXISTS classes (
+		id	INTEGER,
+		year INTEGER,
+		modifier	TEXT,
+		PRIMARY KEY(id AUTOINCREMENT)
+	);
+	CREATE TABLE IF NOT EXISTS groups (
+		student_id	INTEGER,
+		name TEXT,
+		surname TEXT,
+		year INTEGER,
+		modifier	TEXT,
+		PRIMARY KEY(student_id)
+	);`
+
+// openRaw opens the database at path without running migrations.
+func openRaw(t *testing.T, path string) *sqlx.DB {
+	t.Helper()
+	db, err := sqlx.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("opening %s: %v", path, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newBaselineDB creates a version 0 database with a few rows in it.
+func newBaselineDB(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "school.db")
+	db := openRaw(t, path)
+	if _, err := db.Exec(baselineSchema); err != nil {
+		t.Fatalf("creating baseline schema: %v", err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO students (name, surname) VALUES('Anna', 'Bērziņa')`,
+		`INSERT INTO groups (name, surname) VALUES('Anna', 'Bērziņa')`,
+		`INSERT INTO students (name, surname) VALUES('Jānis', 'Ozols')`,
+		`INSERT INTO groups (name, surname) VALUES('Jānis', 'Ozols')`,
+		`INSERT INTO classes (year, modifier) VALUES(5, 'a')`,
+		`UPDATE groups SET year = 5, modifier = 'a' WHERE student_id = 1`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seeding baseline database: %v", err)
+		}
+	}
+	return path
+}
+
+func TestNewCreatesLatestSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "school.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer s.Close()
+
+	got, err := userVersion(s.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != schemaVersion() {
+		t.Errorf("user_version = %d, want %d", got, schemaVersion())
+	}
+}
+
+func TestNewUpgradesBaselineDatabase(t *testing.T) {
+	path := newBaselineDB(t)
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer s.Close()
+
+	got, err := userVersion(s.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != schemaVersion() {
+		t.Errorf("user_version = %d, want %d", got, schemaVersion())
+	}
+	students, err := s.Students()
+	if err != nil {
+		t.Fatalf("Students() failed: %v", err)
+	}
+	if len(students) != 2 {
+		t.Errorf("got %d students after upgrade, want 2", len(students))
+	}
+	classes, err := s.Classes()
+	if err != nil {
+		t.Fatalf("Classes() failed: %v", err)
+	}
+	if len(classes) != 1 {
+		t.Errorf("got %d classes after upgrade, want 1", len(classes))
+	}
+	// Existing classes are moved into a school year of their own.
+	years, err := s.SchoolYears()
+	if err != nil {
+		t.Fatalf("SchoolYears() failed: %v", err)
+	}
+	if len(years) != 1 || len(classes) != 1 || int(classes[0].SchoolYearID.Int64) != years[0].ID {
+		t.Errorf("got school years %+v and classes %+v after upgrade, want the class in the only year", years, classes)
+	}
+	groups, err := s.Groups()
+	if err != nil {
+		t.Fatalf("Groups() failed: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups after upgrade, want 2", len(groups))
+	}
+	// Anna was in 5.a, Jānis was never assigned a class.
+	if g := groups[0]; !g.ClassID.Valid || int(g.ClassID.Int64) != classes[0].ID {
+		t.Errorf("%s %s is in class %v, want %d", g.Name, g.Surname, g.ClassID, classes[0].ID)
+	}
+	if g := groups[1]; g.ClassID.Valid {
+		t.Errorf("%s %s is in class %v, want none", g.Name, g.Surname, g.ClassID.Int64)
+	}
+}
+
+func TestNewMergesDuplicateClasses(t *testing.T) {
+	path := newBaselineDB(t)
+	db := openRaw(t, path)
+	for _, stmt := range []string{
+		`INSERT INTO classes (year, modifier) VALUES(5, 'a')`,
+		`INSERT INTO students (name, surname) VALUES('Pēteris', 'Kalns')`,
+		`INSERT INTO groups (student_id, name, surname, year, modifier) VALUES(3, 'Pēteris', 'Kalns', 5, 'a')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer s.Close()
+
+	classes, err := s.Classes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(classes) != 1 {
+		t.Fatalf("got %d classes after upgrade, want 1", len(classes))
+	}
+	g, err := s.Group(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(g.ClassID.Int64) != classes[0].ID {
+		t.Errorf("student is in class %v, want %d", g.ClassID, classes[0].ID)
+	}
+}
+
+func TestNewLinksAssignmentsToSchoolYears(t *testing.T) {
+	path := newBaselineDB(t)
+	db := openRaw(t, path)
+	for v := 0; v < 13; v++ {
+		if err := applyMigration(db, v+1, migrations[v]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, stmt := range []string{
+		`INSERT INTO teachers (name, surname) VALUES('Ilze', 'Kalniņa')`,
+		`INSERT INTO subjects (name) VALUES('Matemātika')`,
+		`INSERT INTO subjects (name) VALUES('Sports')`,
+		`INSERT INTO teaching_assignments (teacher_id, subject_id, class_id, school_year) VALUES(1, 1, 1, '2024/2025')`,
+		`INSERT INTO teaching_assignments (teacher_id, subject_id, class_id, school_year) VALUES(1, 2, 1, '2024/2025')`,
+		`DELETE FROM teaching_assignments WHERE id = 2`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer s.Close()
+
+	assignments, err := s.ClassAssignments(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assignments) != 1 || !assignments[0].SchoolYearID.Valid || assignments[0].SchoolYear != "2024/2025" {
+		t.Fatalf("ClassAssignments() after upgrade = %+v, want one assignment of 2024/2025", assignments)
+	}
+	id, err := s.AddAssignment(1, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 3 {
+		t.Errorf("AddAssignment() after upgrade = %d, want 3 as IDs are not reused", id)
+	}
+}
+
+func TestNewKeepsOneEnrollmentPerStudent(t *testing.T) {
+	path := newBaselineDB(t)
+	db := openRaw(t, path)
+	for v := 0; v < 14; v++ {
+		if err := applyMigration(db, v+1, migrations[v]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, stmt := range []string{
+		`INSERT INTO classes (year, modifier) VALUES(6, 'b')`,
+		`INSERT INTO enrollments (student_id, class_id) VALUES(1, 2)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer s.Close()
+
+	g, err := s.Group(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.ClassID.Int64 != 2 {
+		t.Errorf("student is in class %v after upgrade, want the latest enrollment into 2", g.ClassID)
+	}
+	if _, err := s.db.Exec(`INSERT INTO enrollments (student_id, class_id) VALUES(1, 1)`); err == nil {
+		t.Error("a second enrollment of a student was stored, want it rejected")
+	}
+}
+
+func TestNewIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "school.db")
+	for i := 0; i < 2; i++ {
+		s, err := New(path)
+		if err != nil {
+			t.Fatalf("New() #%d failed: %v", i, err)
+		}
+		s.Close()
+	}
+}
+
+func TestNewRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "school.db")
+	db := openRaw(t, path)
+	if _, err := db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, schemaVersion()+1)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(path)
+	if err == nil {
+		s.Close()
+		t.Fatal("New() succeeded, want an error")
+	}
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("New() error = %v, want %v", err, ErrSchemaTooNew)
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	saved := migrations
+	defer func() { migrations = saved }()
+	migrations = append(migrations[:len(migrations):len(migrations)],
+		migration{
+			name: "broken",
+			stmt: `CREATE TABLE broken (id INTEGER); INSERT INTO missing VALUES(1);`,
+		},
+	)
+
+	path := filepath.Join(t.TempDir(), "school.db")
+	if s, err := New(path); err == nil {
+		s.Close()
+		t.Fatal("New() succeeded, want an error")
+	}
+
+	db := openRaw(t, path)
+	got, err := userVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(saved); got != want {
+		t.Errorf("user_version = %d, want %d", got, want)
+	}
+	var n int
+	if err := db.Get(&n, `SELECT count(*) FROM sqlite_master WHERE name = 'broken'`); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Error("table from the failed migration was not rolled back")
+	}
+}
+
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "school.db"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.AddStudent("Anna", "Zariņa"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stmt := range []string{
+		`UPDATE audit_log SET actor = 'nobody'`,
+		`DELETE FROM audit_log`,
+	} {
+		if _, err := s.db.Exec(stmt); !errors.Is(wrap("tamper", err), ErrConstraint) {
+			t.Errorf("%s = %v, want %v", stmt, err, ErrConstraint)
+		}
+	}
+	if entries, err := s.AuditLog(AuditFilter{}); err != nil || len(entries) != 1 {
+		t.Errorf("AuditLog() = %+v, %v; want the added student", entries, err)
+	}
+}
@@ -0,0 +1,896 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// FinalYear is the last grade of school. Its students graduate when their
+// school year is promoted.
+const FinalYear = 12
+
+// SchoolYearEntry represents an academic year, e.g. 2025/2026.
+type SchoolYearEntry struct {
+	ID     int    `db:"id"`
+	Name   string `db:"name"`   // E.g. "2025/2026".
+	Starts string `db:"starts"` // First day, e.g. "2025-09-01".
+	Ends   string `db:"ends"`   // Last day, e.g. "2026-08-31".
+}
+
+// TermEntry represents a part of a school year, e.g. a semester.
+type TermEntry struct {
+	ID           int    `db:"id"`
+	SchoolYearID int    `db:"school_year_id"`
+	Name         string `db:"name"` // E.g. "1st semester".
+	Starts       string `db:"starts"`
+	Ends         string `db:"ends"`
+}
+
+// PromotionEntry represents moving the students of a school year into the
+// classes of the next one. The names of the school years and the number of
+// students are included for display.
+type PromotionEntry struct {
+	ID         int    `db:"id"`
+	FromYearID int    `db:"from_year_id"`
+	ToYearID   int    `db:"to_year_id"`
+	PromotedAt string `db:"promoted_at"` // UTC time in RFC 3339 format.
+
+	FromYear  string `db:"from_year"`
+	ToYear    string `db:"to_year"`
+	Promoted  int    `db:"promoted"`  // Students moved into a class of the next year.
+	Graduated int    `db:"graduated"` // Students who finished school.
+}
+
+// GraduateEntry represents a student who finished school together with their
+// last class.
+type GraduateEntry struct {
+	StudentID  int    `db:"student_id"`
+	Name       string `db:"name"`
+	Surname    string `db:"surname"`
+	ClassID    int    `db:"class_id"`
+	Year       string `db:"year"`
+	Modifier   string `db:"modifier"`
+	SchoolYear string `db:"school_year"`
+}
+
+// SchoolYearStore provides access to school years, their terms and the
+// promotion of students from one school year to the next.
+type SchoolYearStore interface {
+	// SchoolYears returns every school year ordered by start.
+	SchoolYears() ([]SchoolYearEntry, error)
+	// SchoolYear returns a single school year by its ID.
+	SchoolYear(id int) (SchoolYearEntry, error)
+	// AddSchoolYear appends a new school year and returns its ID.
+	AddSchoolYear(y SchoolYearEntry) (int, error)
+	// DeleteSchoolYear removes a school year without classes, promotions or
+	// final grades together with its terms.
+	DeleteSchoolYear(id int) error
+
+	// Terms returns the terms of a school year ordered by start.
+	Terms(schoolYearID int) ([]TermEntry, error)
+	// AddTerm appends a new term and returns its ID.
+	AddTerm(t TermEntry) (int, error)
+	// DeleteTerm removes a term without final grades.
+	DeleteTerm(id int) error
+
+	// Promote moves the students of every class of a school year into the
+	// class of the next grade in a later school year, e.g. from 5.a to 6.a,
+	// creating the classes that do not exist yet. Students of FinalYear
+	// classes graduate instead. Either everything is done or nothing, and
+	// the returned promotion can be undone.
+	Promote(fromYearID, toYearID int) (int, error)
+	// Promotions returns every promotion ordered by ID.
+	Promotions() ([]PromotionEntry, error)
+	// UndoPromotion restores the enrollments from before a promotion and
+	// removes the classes it created. Only the latest promotion can be
+	// undone.
+	UndoPromotion(id int) error
+	// Graduates returns the students who finished school ordered by school
+	// year, surname and name.
+	Graduates() ([]GraduateEntry, error)
+}
+
+var (
+	selectSchoolYearsStmt = `SELECT id, name, starts, ends FROM school_years ORDER BY starts, id`
+	selectSchoolYearStmt  = `SELECT id, name, starts, ends FROM school_years WHERE id = ?`
+	insertSchoolYearStmt  = `INSERT INTO school_years (name, starts, ends) VALUES(?, ?, ?)`
+	deleteSchoolYearStmt  = `DELETE FROM school_years WHERE id = ?`
+
+	selectTermsStmt = `SELECT id, school_year_id, name, starts, ends FROM terms
+	WHERE school_year_id = ? ORDER BY starts, id`
+	insertTermStmt = `INSERT INTO terms (school_year_id, name, starts, ends) VALUES(?, ?, ?, ?)`
+	deleteTermStmt = `DELETE FROM terms WHERE id = ?`
+
+	// Statements used by a promotion.
+	selectYearClassesStmt = `SELECT id, year, modifier, teacher_id, school_year_id FROM classes
+	WHERE school_year_id = ? ORDER BY id`
+	selectYearClassStmt      = `SELECT id FROM classes WHERE school_year_id = ? AND year = ? AND modifier = ?`
+	insertYearClassStmt      = `INSERT INTO classes (year, modifier, teacher_id, school_year_id) VALUES(?, ?, ?, ?)`
+	selectEnrolledStmt       = `SELECT student_id FROM enrollments WHERE class_id = ? ORDER BY student_id`
+	moveEnrollmentStmt       = `UPDATE enrollments SET class_id = ? WHERE student_id = ?`
+	insertPromotionStmt      = `INSERT INTO promotions (from_year_id, to_year_id, promoted_at) VALUES(?, ?, ?)`
+	insertPromotionClassStmt = `INSERT INTO promotion_classes (promotion_id, class_id) VALUES(?, ?)`
+	insertPromotionMoveStmt  = `INSERT INTO promotion_moves (promotion_id, student_id, from_class_id, to_class_id) VALUES(?, ?, ?, ?)`
+	insertGraduateStmt       = `INSERT INTO graduates (student_id, class_id, promotion_id) VALUES(?, ?, ?)
+	ON CONFLICT (student_id) DO UPDATE SET class_id = excluded.class_id, promotion_id = excluded.promotion_id`
+
+	// Statements used by undoing a promotion.
+	selectPromotionIDsStmt = `SELECT id FROM promotions ORDER BY id DESC`
+	restoreUnenrollStmt    = `DELETE FROM enrollments WHERE student_id IN (
+		SELECT student_id FROM promotion_moves WHERE promotion_id = ?)`
+	restoreEnrollStmt = `INSERT INTO enrollments (student_id, class_id)
+		SELECT student_id, from_class_id FROM promotion_moves WHERE promotion_id = ?`
+	selectPromotionClassesStmt = `SELECT class_id FROM promotion_classes WHERE promotion_id = ?`
+	deletePromotionStmt        = `DELETE FROM promotions WHERE id = ?`
+
+	selectPromotionsStmt = `SELECT promotions.id, promotions.from_year_id, promotions.to_year_id,
+		promotions.promoted_at, from_year.name AS from_year, to_year.name AS to_year,
+		(SELECT COUNT(*) FROM promotion_moves
+			WHERE promotion_id = promotions.id AND to_class_id IS NOT NULL) AS promoted,
+		(SELECT COUNT(*) FROM promotion_moves
+			WHERE promotion_id = promotions.id AND to_class_id IS NULL) AS graduated
+	FROM promotions
+	JOIN school_years AS from_year ON from_year.id = promotions.from_year_id
+	JOIN school_years AS to_year ON to_year.id = promotions.to_year_id
+	ORDER BY promotions.id`
+	selectGraduatesStmt = `SELECT graduates.student_id, students.name, students.surname,
+		graduates.class_id, classes.year, classes.modifier, school_years.name AS school_year
+	FROM graduates
+	JOIN students ON students.id = graduates.student_id
+	JOIN classes ON classes.id = graduates.class_id
+	JOIN school_years ON school_years.id = classes.school_year_id
+	ORDER BY school_years.starts, students.surname, students.name, students.id`
+)
+
+// validateSchoolYearEntry checks a school year against the existing ones in
+// others. School years must not overlap.
+func validateSchoolYearEntry(y SchoolYearEntry, others []SchoolYearEntry) error {
+	if err := validateSchoolYear(y.Name); err != nil {
+		return err
+	}
+	if err := validatePeriod(y.Starts, y.Ends); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(y.Starts, y.Name[:4]) {
+		return &ValidationError{Field: "start", Reason: "must be in " + y.Name[:4]}
+	}
+	for _, o := range others {
+		if y.Starts <= o.Ends && o.Starts <= y.Ends {
+			return &ValidationError{Field: "dates", Reason: "overlap school year " + o.Name}
+		}
+	}
+	return nil
+}
+
+// validateTerm checks a term against its school year and the other terms of
+// the year in others. Terms must lie within the year and must not overlap.
+func validateTerm(t TermEntry, year SchoolYearEntry, others []TermEntry) error {
+	if err := validateSubject(t.Name); err != nil {
+		return err
+	}
+	if err := validatePeriod(t.Starts, t.Ends); err != nil {
+		return err
+	}
+	if t.Starts < year.Starts || t.Ends > year.Ends {
+		return &ValidationError{Field: "dates", Reason: "must be within school year " + year.Name}
+	}
+	for _, o := range others {
+		if t.Starts <= o.Ends && o.Starts <= t.Ends {
+			return &ValidationError{Field: "dates", Reason: "overlap " + o.Name}
+		}
+	}
+	return nil
+}
+
+// validatePeriod accepts a start and an end date, the end not before the
+// start.
+func validatePeriod(starts, ends string) error {
+	const layout = "2006-01-02"
+	if _, err := time.Parse(layout, starts); err != nil {
+		return &ValidationError{Field: "start", Reason: "must look like 2025-09-01"}
+	}
+	if _, err := time.Parse(layout, ends); err != nil {
+		return &ValidationError{Field: "end", Reason: "must look like 2026-08-31"}
+	}
+	if ends < starts {
+		return &ValidationError{Field: "end", Reason: "must not be before the start"}
+	}
+	return nil
+}
+
+// validatePromotion checks that students are promoted into a later school
+// year.
+func validatePromotion(from, to SchoolYearEntry) error {
+	if to.Starts <= from.Starts {
+		return &ValidationError{Field: "school year", Reason: "must be later than " + from.Name}
+	}
+	return nil
+}
+
+// nextGrade returns the grade following year, or false if the students of
+// year graduate.
+func nextGrade(year string) (string, bool) {
+	y, _ := strconv.Atoi(year)
+	if y >= FinalYear {
+		return "", false
+	}
+	return strconv.Itoa(y + 1), true
+}
+
+// errNotLatestPromotion is returned when undoing a promotion that was
+// followed by another one.
+var errNotLatestPromotion = &ValidationError{Field: "promotion", Reason: "must be the latest one to be undone"}
+
+// SchoolYears returns every school year.
+func (s *SQLite) SchoolYears() ([]SchoolYearEntry, error) {
+	var entries []SchoolYearEntry
+	if err := s.db.Select(&entries, selectSchoolYearsStmt); err != nil {
+		return nil, wrap("list school years", err)
+	}
+	return entries, nil
+}
+
+// SchoolYear returns a single school year by its ID.
+func (s *SQLite) SchoolYear(id int) (SchoolYearEntry, error) {
+	var entry SchoolYearEntry
+	if err := s.db.Get(&entry, selectSchoolYearStmt, id); err != nil {
+		return SchoolYearEntry{}, wrap(fmt.Sprintf("get school year %d", id), err)
+	}
+	return entry, nil
+}
+
+// AddSchoolYear appends a new school year and returns its ID.
+func (s *SQLite) AddSchoolYear(y SchoolYearEntry) (int, error) {
+	const op = "add school year"
+	var id int
+	err := s.inTx(op, func(tx *sqlx.Tx) error {
+		var others []SchoolYearEntry
+		if err := tx.Select(&others, selectSchoolYearsStmt); err != nil {
+			return wrap(op, err)
+		}
+		for _, o := range others {
+			if o.Name == y.Name {
+				return &Error{Op: op, Kind: ErrDuplicate}
+			}
+		}
+		if err := validateSchoolYearEntry(y, others); err != nil {
+			return &Error{Op: op, Kind: ErrValidation, Err: err}
+		}
+		res, err := tx.Exec(insertSchoolYearStmt, y.Name, y.Starts, y.Ends)
+		if err != nil {
+			return wrap(op, err)
+		}
+		last, err := res.LastInsertId()
+		if err != nil {
+			return wrap(op, err)
+		}
+		id = int(last)
+		return nil
+	})
+	return id, err
+}
+
+// DeleteSchoolYear removes a school year together with its terms. School
+// years with classes, promotions or final grades cannot be deleted and
+// ErrConstraint is returned instead.
+func (s *SQLite) DeleteSchoolYear(id int) error {
+	op := fmt.Sprintf("delete school year %d", id)
+	res, err := s.exec(deleteSchoolYearStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// Terms returns the terms of a school year.
+func (s *SQLite) Terms(schoolYearID int) ([]TermEntry, error) {
+	var entries []TermEntry
+	if err := s.db.Select(&entries, selectTermsStmt, schoolYearID); err != nil {
+		return nil, wrap(fmt.Sprintf("list terms of school year %d", schoolYearID), err)
+	}
+	return entries, nil
+}
+
+// AddTerm appends a new term to an existing school year and returns its ID.
+func (s *SQLite) AddTerm(t TermEntry) (int, error) {
+	const op = "add term"
+	var id int
+	err := s.inTx(op, func(tx *sqlx.Tx) error {
+		var year SchoolYearEntry
+		if err := tx.Get(&year, selectSchoolYearStmt, t.SchoolYearID); errors.Is(err, sql.ErrNoRows) {
+			return &Error{Op: op, Kind: ErrConstraint}
+		} else if err != nil {
+			return wrap(op, err)
+		}
+		var others []TermEntry
+		if err := tx.Select(&others, selectTermsStmt, t.SchoolYearID); err != nil {
+			return wrap(op, err)
+		}
+		if err := validateTerm(t, year, others); err != nil {
+			return &Error{Op: op, Kind: ErrValidation, Err: err}
+		}
+		res, err := tx.Exec(insertTermStmt, t.SchoolYearID, t.Name, t.Starts, t.Ends)
+		if err != nil {
+			return wrap(op, err)
+		}
+		last, err := res.LastInsertId()
+		if err != nil {
+			return wrap(op, err)
+		}
+		id = int(last)
+		return nil
+	})
+	return id, err
+}
+
+// DeleteTerm removes a term. Terms with final grades cannot be deleted and
+// ErrConstraint is returned instead.
+func (s *SQLite) DeleteTerm(id int) error {
+	op := fmt.Sprintf("delete term %d", id)
+	res, err := s.exec(deleteTermStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// Promote moves the students of a school year into the next grade of a later
+// school year in a single transaction.
+func (s *SQLite) Promote(fromYearID, toYearID int) (int, error) {
+	op := fmt.Sprintf("promote school year %d", fromYearID)
+	var id int
+	err := s.inTx(op, func(tx *sqlx.Tx) error {
+		var from, to SchoolYearEntry
+		if err := tx.Get(&from, selectSchoolYearStmt, fromYearID); err != nil {
+			return wrap(op, err)
+		}
+		if err := tx.Get(&to, selectSchoolYearStmt, toYearID); err != nil {
+			return wrap(op, err)
+		}
+		if err := validatePromotion(from, to); err != nil {
+			return &Error{Op: op, Kind: ErrValidation, Err: err}
+		}
+		// A school year can be promoted once, see the unique index.
+		res, err := tx.Exec(insertPromotionStmt, fromYearID, toYearID, timestamp())
+		if err != nil {
+			return wrap(op, err)
+		}
+		last, err := res.LastInsertId()
+		if err != nil {
+			return wrap(op, err)
+		}
+		id = int(last)
+
+		var classes []ClassEntry
+		if err := tx.Select(&classes, selectYearClassesStmt, fromYearID); err != nil {
+			return wrap(op, err)
+		}
+		for _, c := range classes {
+			if err := promoteClass(tx, id, c, toYearID); err != nil {
+				return wrap(op, err)
+			}
+		}
+		return nil
+	})
+	return id, err
+}
+
+// promoteClass moves the students of class c into the class of the next
+// grade in the school year toYearID, or graduates them, and records the
+// changes under the promotion.
+func promoteClass(tx *sqlx.Tx, promotionID int, c ClassEntry, toYearID int) error {
+	var students []int
+	if err := tx.Select(&students, selectEnrolledStmt, c.ID); err != nil {
+		return err
+	}
+	grade, ok := nextGrade(c.Year)
+	if !ok {
+		for _, studentID := range students {
+			if _, err := tx.Exec(unenrollStudentStmt, studentID); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(insertGraduateStmt, studentID, c.ID, promotionID); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(insertPromotionMoveStmt, promotionID, studentID, c.ID, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var target int
+	err := tx.Get(&target, selectYearClassStmt, toYearID, grade, c.Modifier)
+	if errors.Is(err, sql.ErrNoRows) {
+		res, err := tx.Exec(insertYearClassStmt, grade, c.Modifier, c.TeacherID, toYearID)
+		if err != nil {
+			return err
+		}
+		last, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		target = int(last)
+		if _, err := tx.Exec(insertPromotionClassStmt, promotionID, target); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	for _, studentID := range students {
+		if _, err := tx.Exec(moveEnrollmentStmt, target, studentID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(insertPromotionMoveStmt, promotionID, studentID, c.ID, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Promotions returns every promotion.
+func (s *SQLite) Promotions() ([]PromotionEntry, error) {
+	var entries []PromotionEntry
+	if err := s.db.Select(&entries, selectPromotionsStmt); err != nil {
+		return nil, wrap("list promotions", err)
+	}
+	return entries, nil
+}
+
+// UndoPromotion restores the enrollments from before the latest promotion
+// and removes the classes it created, in a single transaction. Students moved
+// by hand since the promotion are moved back as well. If a created class is
+// in use already, e.g. it got a teaching assignment, ErrConstraint is
+// returned and nothing changes.
+func (s *SQLite) UndoPromotion(id int) error {
+	op := fmt.Sprintf("undo promotion %d", id)
+	return s.inTx(op, func(tx *sqlx.Tx) error {
+		var ids []int
+		if err := tx.Select(&ids, selectPromotionIDsStmt); err != nil {
+			return wrap(op, err)
+		}
+		switch {
+		case !containsID(ids, id):
+			return &Error{Op: op, Kind: ErrNotFound}
+		case ids[0] != id:
+			return &Error{Op: op, Kind: ErrValidation, Err: errNotLatestPromotion}
+		}
+		if _, err := tx.Exec(restoreUnenrollStmt, id); err != nil {
+			return wrap(op, err)
+		}
+		if _, err := tx.Exec(restoreEnrollStmt, id); err != nil {
+			return wrap(op, err)
+		}
+		var created []int
+		if err := tx.Select(&created, selectPromotionClassesStmt, id); err != nil {
+			return wrap(op, err)
+		}
+		// Removes the moves, the created classes list and the graduates.
+		if _, err := tx.Exec(deletePromotionStmt, id); err != nil {
+			return wrap(op, err)
+		}
+		for _, classID := range created {
+			if _, err := tx.Exec(deleteClassStmt, classID); err != nil {
+				return wrap(op, err)
+			}
+		}
+		return nil
+	})
+}
+
+// containsID reports whether id is one of ids.
+func containsID(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Graduates returns the students who finished school.
+func (s *SQLite) Graduates() ([]GraduateEntry, error) {
+	var entries []GraduateEntry
+	if err := s.db.Select(&entries, selectGraduatesStmt); err != nil {
+		return nil, wrap("list graduates", err)
+	}
+	return entries, nil
+}
+
+// promotion is a promotion kept in Memory together with what it changed.
+type promotion struct {
+	entry   PromotionEntry // Without the names and the numbers of students.
+	created []int          // Classes created in the next school year.
+	moves   []promotionMove
+}
+
+// promotionMove records the class a student was moved from and to. A zero to
+// class means the student graduated.
+type promotionMove struct {
+	studentID int
+	from, to  int
+}
+
+// graduate is an entry of the graduates archive in Memory.
+type graduate struct {
+	classID     int
+	promotionID int
+}
+
+// SchoolYears returns every school year.
+func (m *Memory) SchoolYears() ([]SchoolYearEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.schoolYearList(), nil
+}
+
+// schoolYearList returns every school year ordered by start. m.mu must be
+// held.
+func (m *Memory) schoolYearList() []SchoolYearEntry {
+	var entries []SchoolYearEntry
+	for _, y := range m.schoolYears {
+		entries = append(entries, y)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Starts != entries[j].Starts {
+			return entries[i].Starts < entries[j].Starts
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// latestSchoolYear returns the school year that starts last, if there is
+// any. m.mu must be held.
+func (m *Memory) latestSchoolYear() (SchoolYearEntry, bool) {
+	years := m.schoolYearList()
+	if len(years) == 0 {
+		return SchoolYearEntry{}, false
+	}
+	return years[len(years)-1], true
+}
+
+// SchoolYear returns a single school year by its ID.
+func (m *Memory) SchoolYear(id int) (SchoolYearEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	y, ok := m.schoolYears[id]
+	if !ok {
+		return SchoolYearEntry{}, &Error{Op: fmt.Sprintf("get school year %d", id), Kind: ErrNotFound}
+	}
+	return y, nil
+}
+
+// AddSchoolYear appends a new school year and returns its ID.
+func (m *Memory) AddSchoolYear(y SchoolYearEntry) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	const op = "add school year"
+	others := m.schoolYearList()
+	for _, o := range others {
+		if o.Name == y.Name {
+			return 0, &Error{Op: op, Kind: ErrDuplicate}
+		}
+	}
+	if err := validateSchoolYearEntry(y, others); err != nil {
+		return 0, &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	m.lastSchoolYearID++
+	y.ID = m.lastSchoolYearID
+	m.touch("school_years", y.ID)
+	m.schoolYears[y.ID] = y
+	return y.ID, nil
+}
+
+// DeleteSchoolYear removes a school year together with its terms. School
+// years with classes, promotions or final grades cannot be deleted and
+// ErrConstraint is returned instead.
+func (m *Memory) DeleteSchoolYear(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("delete school year %d", id)
+	if _, ok := m.schoolYears[id]; !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	for _, c := range m.classes {
+		if c.SchoolYearID.Valid && int(c.SchoolYearID.Int64) == id {
+			return &Error{Op: op, Kind: ErrConstraint}
+		}
+	}
+	for _, p := range m.promotions {
+		if p.entry.FromYearID == id || p.entry.ToYearID == id {
+			return &Error{Op: op, Kind: ErrConstraint}
+		}
+	}
+	if m.hasFinalGrade(func(g FinalGradeEntry) bool { return g.SchoolYearID == id }) {
+		return &Error{Op: op, Kind: ErrConstraint}
+	}
+	for termID, t := range m.terms {
+		if t.SchoolYearID == id {
+			m.touch("terms", termID)
+			delete(m.terms, termID)
+		}
+	}
+	m.touch("school_years", id)
+	delete(m.schoolYears, id)
+	return nil
+}
+
+// Terms returns the terms of a school year.
+func (m *Memory) Terms(schoolYearID int) ([]TermEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.termList(schoolYearID), nil
+}
+
+// termList returns the terms of a school year ordered by start. m.mu must be
+// held.
+func (m *Memory) termList(schoolYearID int) []TermEntry {
+	var entries []TermEntry
+	for _, t := range m.terms {
+		if t.SchoolYearID == schoolYearID {
+			entries = append(entries, t)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Starts != entries[j].Starts {
+			return entries[i].Starts < entries[j].Starts
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// AddTerm appends a new term to an existing school year and returns its ID.
+func (m *Memory) AddTerm(t TermEntry) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	const op = "add term"
+	year, ok := m.schoolYears[t.SchoolYearID]
+	if !ok {
+		return 0, &Error{Op: op, Kind: ErrConstraint}
+	}
+	others := m.termList(t.SchoolYearID)
+	if err := validateTerm(t, year, others); err != nil {
+		return 0, &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	for _, o := range others {
+		if o.Name == t.Name {
+			return 0, &Error{Op: op, Kind: ErrDuplicate}
+		}
+	}
+	m.lastTermID++
+	t.ID = m.lastTermID
+	m.touch("terms", t.ID)
+	m.terms[t.ID] = t
+	return t.ID, nil
+}
+
+// DeleteTerm removes a term. Terms with final grades cannot be deleted and
+// ErrConstraint is returned instead.
+func (m *Memory) DeleteTerm(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("delete term %d", id)
+	if _, ok := m.terms[id]; !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	if m.hasFinalGrade(func(g FinalGradeEntry) bool { return g.TermID.Valid && int(g.TermID.Int64) == id }) {
+		return &Error{Op: op, Kind: ErrConstraint}
+	}
+	m.touch("terms", id)
+	delete(m.terms, id)
+	return nil
+}
+
+// Promote moves the students of a school year into the next grade of a later
+// school year. Everything is checked before anything changes.
+func (m *Memory) Promote(fromYearID, toYearID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("promote school year %d", fromYearID)
+	from, fromOK := m.schoolYears[fromYearID]
+	to, toOK := m.schoolYears[toYearID]
+	if !fromOK || !toOK {
+		return 0, &Error{Op: op, Kind: ErrNotFound}
+	}
+	if err := validatePromotion(from, to); err != nil {
+		return 0, &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	for _, p := range m.promotions {
+		if p.entry.FromYearID == fromYearID {
+			return 0, &Error{Op: op, Kind: ErrDuplicate}
+		}
+	}
+
+	m.lastPromotionID++
+	p := &promotion{entry: PromotionEntry{
+		ID:         m.lastPromotionID,
+		FromYearID: fromYearID,
+		ToYearID:   toYearID,
+		PromotedAt: timestamp(),
+	}}
+	var classes []ClassEntry
+	for _, c := range m.classes {
+		if c.SchoolYearID.Valid && int(c.SchoolYearID.Int64) == fromYearID {
+			classes = append(classes, c)
+		}
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].ID < classes[j].ID })
+	for _, c := range classes {
+		var students []int
+		for studentID, classID := range m.enrollments {
+			if classID == c.ID {
+				students = append(students, studentID)
+			}
+		}
+		sort.Ints(students)
+
+		grade, ok := nextGrade(c.Year)
+		if !ok {
+			for _, studentID := range students {
+				m.touch("enrollments", studentID)
+				delete(m.enrollments, studentID)
+				m.graduates[studentID] = graduate{classID: c.ID, promotionID: p.entry.ID}
+				p.moves = append(p.moves, promotionMove{studentID: studentID, from: c.ID})
+			}
+			continue
+		}
+		target := 0
+		for _, o := range m.classes {
+			if o.SchoolYearID.Valid && int(o.SchoolYearID.Int64) == toYearID && o.Year == grade && o.Modifier == c.Modifier {
+				target = o.ID
+			}
+		}
+		if target == 0 {
+			target = m.addClass(ClassEntry{
+				Year:         grade,
+				Modifier:     c.Modifier,
+				TeacherID:    c.TeacherID,
+				SchoolYearID: sql.NullInt64{Int64: int64(toYearID), Valid: true},
+			})
+			p.created = append(p.created, target)
+		}
+		for _, studentID := range students {
+			m.touch("enrollments", studentID)
+			m.enrollments[studentID] = target
+			p.moves = append(p.moves, promotionMove{studentID: studentID, from: c.ID, to: target})
+		}
+	}
+	m.touch("promotions", p.entry.ID)
+	m.promotions[p.entry.ID] = p
+	return p.entry.ID, nil
+}
+
+// Promotions returns every promotion.
+func (m *Memory) Promotions() ([]PromotionEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []PromotionEntry
+	for _, p := range m.promotions {
+		e := p.entry
+		e.FromYear, e.ToYear = m.schoolYears[e.FromYearID].Name, m.schoolYears[e.ToYearID].Name
+		for _, mv := range p.moves {
+			if mv.to == 0 {
+				e.Graduated++
+			} else {
+				e.Promoted++
+			}
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// UndoPromotion restores the enrollments from before the latest promotion
+// and removes the classes it created. If a created class is in use already,
+// ErrConstraint is returned and nothing changes.
+func (m *Memory) UndoPromotion(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("undo promotion %d", id)
+	p, ok := m.promotions[id]
+	if !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	for other := range m.promotions {
+		if other > id {
+			return &Error{Op: op, Kind: ErrValidation, Err: errNotLatestPromotion}
+		}
+	}
+
+	// Apply the undo and put things back if a created class turns out to
+	// be referenced elsewhere.
+	enrollments := make(map[int]int, len(m.enrollments))
+	for studentID, classID := range m.enrollments {
+		enrollments[studentID] = classID
+	}
+	graduates := make(map[int]graduate, len(m.graduates))
+	for studentID, g := range m.graduates {
+		graduates[studentID] = g
+	}
+	m.touch("promotions", id)
+	delete(m.promotions, id)
+	for _, mv := range p.moves {
+		m.touch("enrollments", mv.studentID)
+		m.enrollments[mv.studentID] = mv.from
+	}
+	for studentID, g := range m.graduates {
+		if g.promotionID == id {
+			delete(m.graduates, studentID)
+		}
+	}
+	for _, classID := range p.created {
+		if m.classReferenced(classID) {
+			m.enrollments, m.graduates = enrollments, graduates
+			m.touch("promotions", id)
+			m.promotions[id] = p
+			return &Error{Op: op, Kind: ErrConstraint}
+		}
+	}
+	for _, classID := range p.created {
+		m.touch("classes", classID)
+		delete(m.classes, classID)
+	}
+	return nil
+}
+
+// Graduates returns the students who finished school.
+func (m *Memory) Graduates() ([]GraduateEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []GraduateEntry
+	starts := make(map[int]string) // By student ID.
+	for studentID, g := range m.graduates {
+		student, class := m.students[studentID], m.classes[g.classID]
+		year := m.schoolYears[int(class.SchoolYearID.Int64)]
+		starts[studentID] = year.Starts
+		entries = append(entries, GraduateEntry{
+			StudentID:  studentID,
+			Name:       student.Name,
+			Surname:    student.Surname,
+			ClassID:    class.ID,
+			Year:       class.Year,
+			Modifier:   class.Modifier,
+			SchoolYear: year.Name,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case starts[a.StudentID] != starts[b.StudentID]:
+			return starts[a.StudentID] < starts[b.StudentID]
+		case a.Surname != b.Surname:
+			return a.Surname < b.Surname
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		}
+		return a.StudentID < b.StudentID
+	})
+	return entries, nil
+}
@@ -0,0 +1,358 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"modernc.org/sqlite"
+)
+
+// SortKey names what a list is sorted by.
+type SortKey string
+
+const (
+	SortID         SortKey = "id"
+	SortName       SortKey = "name"        // Name, then surname.
+	SortSurname    SortKey = "surname"     // Surname, then name.
+	SortClass      SortKey = "class"       // Year, then modifier.
+	SortSchoolYear SortKey = "school_year" // Start of the school year.
+)
+
+// Query selects and orders the entries of a list. The zero Query lists every
+// entry by ID. Names are compared without case and diacritics, see Fold.
+type Query struct {
+	// Search holds words that must each occur in the names of an entry,
+	// e.g. "berzins" finds "Bērziņš".
+	Search string
+	Sort   SortKey // SortID if empty.
+	Desc   bool    // Whether the order is reversed. Ties stay by ID.
+
+	// Filters of the lists that support them. Zero values match everything.
+	ClassID      int  // Groups of a class only.
+	Unenrolled   bool // Groups of students enrolled nowhere only.
+	SchoolYearID int  // Classes of a school year only.
+}
+
+// words returns the folded words of the search.
+func (q Query) words() []string {
+	return strings.Fields(Fold(q.Search))
+}
+
+// Fold returns s in lower case without diacritics, e.g. "berzins" for
+// "Bērziņš". Searches match folded names, and SQL queries can call it as
+// fold().
+func Fold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("fold", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return args[0], nil
+		}
+		return Fold(s), nil
+	})
+}
+
+// listing describes how a list is searched and sorted in SQL.
+type listing struct {
+	op     string               // E.g. "search students".
+	text   string               // Expression of the names matched by searches.
+	orders map[SortKey][]string // Expressions sorted by, besides the ID.
+	id     string               // Expression of the ID.
+}
+
+// check rejects sort keys the list does not support.
+func (l listing) check(q Query) error {
+	if _, ok := l.orders[q.Sort]; ok || q.Sort == "" || q.Sort == SortID {
+		return nil
+	}
+	return &Error{Op: l.op, Kind: ErrValidation, Err: &ValidationError{Field: "sort", Reason: fmt.Sprintf("cannot be %q", q.Sort)}}
+}
+
+// clauses appends the conditions of the search in q to where and returns the
+// WHERE and ORDER BY clauses with their arguments.
+func (l listing) clauses(q Query, where []string, args []interface{}) (string, []interface{}, error) {
+	if err := l.check(q); err != nil {
+		return "", nil, err
+	}
+	for _, w := range q.words() {
+		where = append(where, "instr(fold("+l.text+"), ?) > 0")
+		args = append(args, w)
+	}
+	var clause string
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+	direction := ""
+	if q.Desc {
+		direction = " DESC"
+	}
+	var orders []string
+	for _, o := range l.orders[q.Sort] {
+		orders = append(orders, o+direction)
+	}
+	if len(orders) == 0 {
+		orders = append(orders, l.id+direction)
+	} else {
+		// Ties stay in the order of the IDs.
+		orders = append(orders, l.id)
+	}
+	return clause + " ORDER BY " + strings.Join(orders, ", "), args, nil
+}
+
+var (
+	searchStudents = listing{
+		op:   "search students",
+		text: "students.name || ' ' || students.surname",
+		orders: map[SortKey][]string{
+			SortName:    {"fold(students.name)", "fold(students.surname)"},
+			SortSurname: {"fold(students.surname)", "fold(students.name)"},
+		},
+		id: "students.id",
+	}
+	searchClasses = listing{
+		op:   "search classes",
+		text: "classes.year || '.' || classes.modifier || ' ' || IFNULL(school_years.name, '')",
+		orders: map[SortKey][]string{
+			SortClass:      {"CAST(classes.year AS INTEGER)", "fold(classes.modifier)"},
+			SortSchoolYear: {"IFNULL(school_years.starts, '')"},
+		},
+		id: "classes.id",
+	}
+	searchGroups = listing{
+		op:   "search groups",
+		text: "students.name || ' ' || students.surname || ' ' || IFNULL(classes.year || '.' || classes.modifier, '')",
+		orders: map[SortKey][]string{
+			SortName:    {"fold(students.name)", "fold(students.surname)"},
+			SortSurname: {"fold(students.surname)", "fold(students.name)"},
+			// Students enrolled nowhere come last.
+			SortClass: {"classes.id IS NULL", "CAST(classes.year AS INTEGER)", "fold(classes.modifier)", "fold(students.surname)", "fold(students.name)"},
+		},
+		id: "students.id",
+	}
+)
+
+var selectSearchClassesStmt = `SELECT classes.id, classes.year, classes.modifier, classes.teacher_id, classes.school_year_id
+	FROM classes
+	LEFT JOIN school_years ON school_years.id = classes.school_year_id`
+
+// SearchStudents returns the students matching q.
+func (s *SQLite) SearchStudents(q Query) ([]StudentEntry, error) {
+	clauses, args, err := searchStudents.clauses(q, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var entries []StudentEntry
+	if err := s.db.Select(&entries, `SELECT id, name, surname FROM students`+clauses, args...); err != nil {
+		return nil, wrap(searchStudents.op, err)
+	}
+	return entries, nil
+}
+
+// SearchClasses returns the classes matching q.
+func (s *SQLite) SearchClasses(q Query) ([]ClassEntry, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if q.SchoolYearID != 0 {
+		where = append(where, "classes.school_year_id = ?")
+		args = append(args, q.SchoolYearID)
+	}
+	clauses, args, err := searchClasses.clauses(q, where, args)
+	if err != nil {
+		return nil, err
+	}
+	var entries []ClassEntry
+	if err := s.db.Select(&entries, selectSearchClassesStmt+clauses, args...); err != nil {
+		return nil, wrap(searchClasses.op, err)
+	}
+	return entries, nil
+}
+
+// SearchGroups returns the students with their classes matching q.
+func (s *SQLite) SearchGroups(q Query) ([]GroupEntry, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if q.ClassID != 0 {
+		where = append(where, "classes.id = ?")
+		args = append(args, q.ClassID)
+	}
+	if q.Unenrolled {
+		where = append(where, "classes.id IS NULL")
+	}
+	clauses, args, err := searchGroups.clauses(q, where, args)
+	if err != nil {
+		return nil, err
+	}
+	var entries []GroupEntry
+	if err := s.db.Select(&entries, selectGroupsStmt+clauses, args...); err != nil {
+		return nil, wrap(searchGroups.op, err)
+	}
+	return entries, nil
+}
+
+// matches reports whether every word of the search occurs in text, like the
+// SQL queries of the listings.
+func (q Query) matches(text string) bool {
+	folded := Fold(text)
+	for _, w := range q.words() {
+		if !strings.Contains(folded, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortRows orders n rows like the SQL queries of the listings: by the values
+// returned by keys, which are compared as strings, then by ID.
+func sortRows(q Query, n int, swap func(i, j int), keys func(i int) []string, id func(i int) int) {
+	sort.Sort(rows{n: n, swap: swap, less: func(i, j int) bool {
+		a, b := keys(i), keys(j)
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k] != q.Desc
+			}
+		}
+		if len(a) == 0 {
+			return id(i) < id(j) != q.Desc
+		}
+		return id(i) < id(j)
+	}})
+}
+
+// rows adapts the functions of sortRows to sort.Interface.
+type rows struct {
+	n    int
+	swap func(i, j int)
+	less func(i, j int) bool
+}
+
+func (r rows) Len() int           { return r.n }
+func (r rows) Swap(i, j int)      { r.swap(i, j) }
+func (r rows) Less(i, j int) bool { return r.less(i, j) }
+
+// yearKey returns the year of a class padded to sort like a number.
+func yearKey(year string) string {
+	y, _ := strconv.Atoi(year)
+	return fmt.Sprintf("%02d", y)
+}
+
+// SearchStudents returns the students matching q.
+func (m *Memory) SearchStudents(q Query) ([]StudentEntry, error) {
+	if err := searchStudents.check(q); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []StudentEntry
+	for _, e := range m.students {
+		if q.matches(e.Name + " " + e.Surname) {
+			entries = append(entries, e)
+		}
+	}
+	sortRows(q, len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] }, func(i int) []string {
+		e := entries[i]
+		switch q.Sort {
+		case SortName:
+			return []string{Fold(e.Name), Fold(e.Surname)}
+		case SortSurname:
+			return []string{Fold(e.Surname), Fold(e.Name)}
+		}
+		return nil
+	}, func(i int) int { return entries[i].ID })
+	return entries, nil
+}
+
+// SearchClasses returns the classes matching q.
+func (m *Memory) SearchClasses(q Query) ([]ClassEntry, error) {
+	if err := searchClasses.check(q); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schoolYear := func(c ClassEntry) SchoolYearEntry {
+		return m.schoolYears[int(c.SchoolYearID.Int64)]
+	}
+	var entries []ClassEntry
+	for _, e := range m.classes {
+		if q.SchoolYearID != 0 && int(e.SchoolYearID.Int64) != q.SchoolYearID {
+			continue
+		}
+		if q.matches(e.Year + "." + e.Modifier + " " + schoolYear(e).Name) {
+			entries = append(entries, e)
+		}
+	}
+	sortRows(q, len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] }, func(i int) []string {
+		e := entries[i]
+		switch q.Sort {
+		case SortClass:
+			return []string{yearKey(e.Year), Fold(e.Modifier)}
+		case SortSchoolYear:
+			return []string{schoolYear(e).Starts}
+		}
+		return nil
+	}, func(i int) int { return entries[i].ID })
+	return entries, nil
+}
+
+// SearchGroups returns the students with their classes matching q.
+func (m *Memory) SearchGroups(q Query) ([]GroupEntry, error) {
+	if err := searchGroups.check(q); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []GroupEntry
+	for id := range m.students {
+		e := m.group(id)
+		switch {
+		case q.ClassID != 0 && int(e.ClassID.Int64) != q.ClassID,
+			q.Unenrolled && e.ClassID.Valid:
+			continue
+		}
+		class := ""
+		if e.ClassID.Valid {
+			class = e.Year.String + "." + e.Modifier.String
+		}
+		if q.matches(e.Name + " " + e.Surname + " " + class) {
+			entries = append(entries, e)
+		}
+	}
+	sortRows(q, len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] }, func(i int) []string {
+		e := entries[i]
+		switch q.Sort {
+		case SortName:
+			return []string{Fold(e.Name), Fold(e.Surname)}
+		case SortSurname:
+			return []string{Fold(e.Surname), Fold(e.Name)}
+		case SortClass:
+			enrolled := "0"
+			if !e.ClassID.Valid {
+				enrolled = "1"
+			}
+			return []string{enrolled, yearKey(e.Year.String), Fold(e.Modifier.String), Fold(e.Surname), Fold(e.Name)}
+		}
+		return nil
+	}, func(i int) int { return entries[i].StudentID })
+	return entries, nil
+}
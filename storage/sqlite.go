@@ -0,0 +1,330 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+
+	_ "modernc.org/sqlite" // Registers the "sqlite" driver.
+)
+
+var (
+	// Statement for adding a new entry into `students` table.
+	insertStudentsStmt = `INSERT INTO students (name, surname) VALUES(?, ?)`
+	// Statement for getting all entries from `students` table.
+	selectStudentsStmt = `SELECT id, name, surname FROM students ORDER BY id`
+	selectStudentStmt  = `SELECT id, name, surname FROM students WHERE id = ?`
+	updateStudentStmt  = `UPDATE students SET name = ?, surname = ? WHERE id = ?`
+	deleteStudentStmt  = `DELETE FROM students WHERE id = ?`
+	// New classes belong to the latest school year, if there is one.
+	insertClassesStmt = `INSERT INTO classes (year, modifier, school_year_id) VALUES(?, ?,
+		(SELECT id FROM school_years ORDER BY starts DESC, id DESC LIMIT 1))`
+	selectClassesStmt = `SELECT id, year, modifier, teacher_id, school_year_id FROM classes ORDER BY id`
+	selectClassStmt   = `SELECT id, year, modifier, teacher_id, school_year_id FROM classes WHERE id = ?`
+	updateClassStmt   = `UPDATE classes SET year = ?, modifier = ? WHERE id = ?`
+	deleteClassStmt   = `DELETE FROM classes WHERE id = ?`
+	// Statement for getting every student together with their class. Students
+	// that are not enrolled anywhere have NULL class columns.
+	selectGroupsStmt = `SELECT students.id AS student_id, students.name, students.surname,
+		classes.id AS class_id, classes.year, classes.modifier
+	FROM students
+	LEFT JOIN enrollments ON enrollments.student_id = students.id
+	LEFT JOIN classes ON classes.id = enrollments.class_id`
+	selectAllGroupsStmt = selectGroupsStmt + ` ORDER BY students.id`
+	selectGroupStmt     = selectGroupsStmt + ` WHERE students.id = ?`
+	// Statements for moving a student into a class. A student is enrolled in
+	// at most one class, so the previous enrollment is removed first.
+	unenrollStudentStmt = `DELETE FROM enrollments WHERE student_id = ?`
+	enrollStudentStmt   = `INSERT INTO enrollments (student_id, class_id) VALUES(?, ?)`
+)
+
+// SQLite is a Storage backed by an SQLite database file.
+type SQLite struct {
+	db *sqlx.DB
+
+	mu    sync.Mutex
+	actor string // Who changes are recorded for in the audit log.
+}
+
+var _ Storage = (*SQLite)(nil)
+
+// New initializes a new DB given its path, or opens an existing DB, and
+// initializes the handler. Returns an error if any of the steps fails.
+func New(path string) (*SQLite, error) {
+	// Open a DB by the path. Foreign keys are enforced per connection.
+	// Writers wait for each other for a while before giving up with
+	// ErrDatabaseLocked.
+	db, err := sqlx.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, wrap("open database", err)
+	}
+
+	// Create or upgrade the tables. Note that the tables may exist already.
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLite{db: db}, nil
+}
+
+// Must is a helper that wraps a call to New and exits if it fails.
+func Must(s *SQLite, err error) *SQLite {
+	if err != nil {
+		log.Fatalf("unable to create storage: %v", err)
+	}
+	return s
+}
+
+// Close closes the database after it is no longer required.
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// inTx runs f in a transaction that is committed if f succeeds and rolled
+// back otherwise.
+func (s *SQLite) inTx(op string, f func(tx *sqlx.Tx) error) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return wrap(op, err)
+	}
+	defer tx.Rollback()
+
+	if err := s.setActor(tx); err != nil {
+		return wrap(op, err)
+	}
+	if err := f(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return wrap(op, err)
+	}
+	return nil
+}
+
+// Students returns a slice of existing students.
+func (s *SQLite) Students() ([]StudentEntry, error) {
+	var entries []StudentEntry
+	// Read rows from the `students` table and populate students field in the
+	// handler.
+	if err := s.db.Select(&entries, selectStudentsStmt); err != nil {
+		return nil, wrap("list students", err)
+	}
+	return entries, nil
+}
+
+// Classes returns a slice of existing classes.
+func (s *SQLite) Classes() ([]ClassEntry, error) {
+	var entries []ClassEntry
+	if err := s.db.Select(&entries, selectClassesStmt); err != nil {
+		return nil, wrap("list classes", err)
+	}
+	return entries, nil
+}
+
+// Groups returns every student together with their class.
+func (s *SQLite) Groups() ([]GroupEntry, error) {
+	var entries []GroupEntry
+	if err := s.db.Select(&entries, selectAllGroupsStmt); err != nil {
+		return nil, wrap("list groups", err)
+	}
+	return entries, nil
+}
+
+// AddStudent appends a new student entry to the database and returns its ID.
+func (s *SQLite) AddStudent(name, surname string) (int, error) {
+	if err := validatePerson(name, surname); err != nil {
+		return 0, &Error{Op: "add student", Kind: ErrValidation, Err: err}
+	}
+	// Attempt to add an entry to the database first.
+	// If it fails, the student field will not be modified.
+	res, err := s.exec(insertStudentsStmt, name, surname)
+	if err != nil {
+		return 0, wrap("add student", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, wrap("add student", err)
+	}
+	return int(id), nil
+}
+
+// Student returns a single student by its ID.
+func (s *SQLite) Student(id int) (StudentEntry, error) {
+	var entry StudentEntry
+	if err := s.db.Get(&entry, selectStudentStmt, id); err != nil {
+		return StudentEntry{}, wrap(fmt.Sprintf("get student %d", id), err)
+	}
+	return entry, nil
+}
+
+// UpdateStudent changes the name and surname of an existing student.
+func (s *SQLite) UpdateStudent(id int, name, surname string) error {
+	op := fmt.Sprintf("update student %d", id)
+	if err := validatePerson(name, surname); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	res, err := s.exec(updateStudentStmt, name, surname, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// DeleteStudent removes a student together with their enrollment, grades,
+// attendance and promotion history.
+func (s *SQLite) DeleteStudent(id int) error {
+	op := fmt.Sprintf("delete student %d", id)
+	res, err := s.exec(deleteStudentStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// RestoreStudent adds a student anew with everything of the record in a
+// single transaction.
+func (s *SQLite) RestoreStudent(r StudentRecord) (int, []int, error) {
+	const op = "restore student"
+	if err := validatePerson(r.Student.Name, r.Student.Surname); err != nil {
+		return 0, nil, &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	var (
+		id  int
+		ids = make([]int, 0, len(r.Grades))
+	)
+	err := s.inTx(op, func(tx *sqlx.Tx) error {
+		res, err := tx.Exec(insertStudentsStmt, r.Student.Name, r.Student.Surname)
+		if err != nil {
+			return wrap(op, err)
+		}
+		studentID, err := res.LastInsertId()
+		if err != nil {
+			return wrap(op, err)
+		}
+		id = int(studentID)
+		if r.ClassID.Valid {
+			if _, err := tx.Exec(enrollStudentStmt, id, r.ClassID.Int64); err != nil {
+				return wrap(op, err)
+			}
+		}
+		for _, g := range r.Grades {
+			gradeID, err := addGrade(tx, id, g.SubjectID, g.TeacherID, g.Date, g.Mark, g.Kind)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, gradeID)
+		}
+		for _, e := range r.Attendance {
+			if err := validateAttendance(e); err != nil {
+				return &Error{Op: op, Kind: ErrValidation, Err: err}
+			}
+			// Present students have no entry.
+			if e.Status == Present {
+				continue
+			}
+			if _, err := tx.Exec(upsertAttendanceStmt, id, e.Date, e.Lesson, e.Status, e.Reason); err != nil {
+				return wrap(op, err)
+			}
+		}
+		finals := make([]FinalGradeEntry, len(r.Finals))
+		for i, f := range r.Finals {
+			f.StudentID = id
+			finals[i] = f
+		}
+		return saveFinalGrades(tx, op, finals)
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return id, ids, nil
+}
+
+// AddClass appends a new class entry to the database and returns its ID. The
+// class belongs to the latest school year.
+func (s *SQLite) AddClass(year, modifier string) (int, error) {
+	if err := validateClass(year, modifier); err != nil {
+		return 0, &Error{Op: "add class", Kind: ErrValidation, Err: err}
+	}
+	res, err := s.exec(insertClassesStmt, year, modifier)
+	if err != nil {
+		return 0, wrap("add class", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, wrap("add class", err)
+	}
+	return int(id), nil
+}
+
+// Class returns a single class by its ID.
+func (s *SQLite) Class(id int) (ClassEntry, error) {
+	var entry ClassEntry
+	if err := s.db.Get(&entry, selectClassStmt, id); err != nil {
+		return ClassEntry{}, wrap(fmt.Sprintf("get class %d", id), err)
+	}
+	return entry, nil
+}
+
+// UpdateClass changes the year and modifier of an existing class.
+func (s *SQLite) UpdateClass(id int, year, modifier string) error {
+	op := fmt.Sprintf("update class %d", id)
+	if err := validateClass(year, modifier); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	res, err := s.exec(updateClassStmt, year, modifier, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// DeleteClass removes a class. Classes that still have enrolled students,
+// teaching assignments, lessons in the timetable or recorded lesson topics
+// cannot be deleted and ErrConstraint is returned instead.
+func (s *SQLite) DeleteClass(id int) error {
+	op := fmt.Sprintf("delete class %d", id)
+	res, err := s.exec(deleteClassStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// Group returns the enrollment of a single student. Class fields are not
+// valid if the student is not enrolled anywhere.
+func (s *SQLite) Group(studentID int) (GroupEntry, error) {
+	var entry GroupEntry
+	if err := s.db.Get(&entry, selectGroupStmt, studentID); err != nil {
+		return GroupEntry{}, wrap(fmt.Sprintf("get group of student %d", studentID), err)
+	}
+	return entry, nil
+}
+
+// AssignClassToStudent enrolls a student into a class, replacing any previous
+// enrollment. Both the student and the class must exist.
+func (s *SQLite) AssignClassToStudent(studentID, classID int) error {
+	op := fmt.Sprintf("assign class %d to student %d", classID, studentID)
+	return s.inTx(op, func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(unenrollStudentStmt, studentID); err != nil {
+			return wrap(op, err)
+		}
+		if _, err := tx.Exec(enrollStudentStmt, studentID, classID); err != nil {
+			return wrap(op, err)
+		}
+		return nil
+	})
+}
+
+// UnassignClassFromStudent removes the enrollment of a student. Returns
+// ErrNotFound if the student is not enrolled in any class.
+func (s *SQLite) UnassignClassFromStudent(studentID int) error {
+	op := fmt.Sprintf("unassign class from student %d", studentID)
+	res, err := s.exec(unenrollStudentStmt, studentID)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
@@ -3,47 +3,6 @@ package storage
 
 import (
 	"database/sql"
-	"fmt"
-	"log"
-
-	"github.com/jmoiron/sqlx"
-)
-
-var (
-	// Statement for creating tables. Currently creates `students` table only.
-	// In the future can be expanded with creation of other tables.
-	createTableStmt = `
-
-	CREATE TABLE IF NOT EXISTS students (
-		id	INTEGER,
-		name	TEXT,
-		surname	TEXT,
-		PRIMARY KEY(id AUTOINCREMENT)
-	);
-	CREATE TABLE IF NOT EXISTS classes (
-		id	INTEGER,
-		year INTEGER,
-		modifier	TEXT,
-		PRIMARY KEY(id AUTOINCREMENT)
-	);
-	CREATE TABLE IF NOT EXISTS groups (
-		student_id	INTEGER,
-		name TEXT,
-		surname TEXT,
-		year INTEGER,
-		modifier	TEXT,
-		PRIMARY KEY(student_id)
-	);`
-	// Statement for adding a new entry into `students` table.
-	insertStudentsStmt = `INSERT INTO students (name, surname) VALUES(?, ?);
-	INSERT INTO groups (name, surname) VALUES(?, ?);`
-	// Statement for getting all entries from `students` table.
-	selectStudentsStmt = `SELECT id, name, surname FROM students`
-	insertClassesStmt  = `INSERT INTO classes (year, modifier) VALUES(?, ?)`
-	selectClassesStmt  = `SELECT id, year, modifier FROM classes`
-	selectGroupsStmt   = `SELECT students.name, students.surname, year, modifier FROM groups
-	JOIN students ON groups.student_id = students.id`
-	assignClassToStudentStmt = `UPDATE groups SET year = ?, modifier = ? WHERE student_id = ?`
 )
 
 // StudentEntry represents a row for a single student in the DB.
@@ -53,117 +12,118 @@ type StudentEntry struct {
 	Surname string `db:"surname"`
 }
 
+// ClassEntry represents a row for a single class in the DB.
 type ClassEntry struct {
-	ID       int    `db:"id"`
-	Year     string `db:"year"`
-	Modifier string `db:"modifier"`
+	ID        int           `db:"id"`
+	Year      string        `db:"year"`
+	Modifier  string        `db:"modifier"`
+	TeacherID sql.NullInt64 `db:"teacher_id"` // Homeroom teacher, if any.
+	// School year the class is taught in. Not valid only if the class was
+	// created before any school year existed.
+	SchoolYearID sql.NullInt64 `db:"school_year_id"`
 }
 
+// GroupEntry represents a student together with the class they are enrolled
+// in. Class fields are not valid if the student is not enrolled anywhere.
 type GroupEntry struct {
 	StudentID int            `db:"student_id"`
-	Name      sql.NullString `db:"name"`
-	Surname   sql.NullString `db:"surname"`
+	Name      string         `db:"name"`
+	Surname   string         `db:"surname"`
+	ClassID   sql.NullInt64  `db:"class_id"`
 	Year      sql.NullString `db:"year"`
 	Modifier  sql.NullString `db:"modifier"`
 }
 
-// Storage is an interface for interacting with persistent storage.
-type Storage struct {
-	db *sqlx.DB
-}
-
-// New initializes a new DB given its path, or opens an existing DB, and
-// initializes the handler. Returns an error if any of the steps fails.
-func New(path string) (*Storage, error) {
-	// Open a DB by the path.
-	db, err := sqlx.Open("sqlite", path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open SQLite DB: %v", db)
-	}
-
-	// Create new tables. Note that the tables may exist already.
-	res, err := db.Exec(createTableStmt)
-	if err != nil {
-		return nil, fmt.Errorf("table creation failed. Query: %v\nError: %v", createTableStmt, err)
-	}
-	if cnt, err := res.RowsAffected(); err != nil {
-		log.Printf("%d rows affected.", cnt)
-	}
-
-	return &Storage{db: db}, nil
-}
-
-func Must(s *Storage, err error) *Storage {
-	if err != nil {
-		log.Fatalf("unable to create storage: %v", err)
-	}
-	return s
-}
-
-// Close closes the database after it is no longer required.
-func (s *Storage) Close() error {
-	return s.db.Close()
-}
-
-// Students returns a slice of existing students.
-func (s Storage) Students() ([]StudentEntry, error) {
-	var entries []StudentEntry
-	// Read rows from the `students` table and populate students field in the
-	// handler.
-	if err := s.db.Select(&entries, selectStudentsStmt); err != nil {
-		return nil, fmt.Errorf("querying 'students' table failed. Query: %v\nError: %v", selectStudentsStmt, err)
-	}
-	return entries, nil
-}
-
-func (s Storage) Classes() ([]ClassEntry, error) {
-	var entries []ClassEntry
-	if err := s.db.Select(&entries, selectClassesStmt); err != nil {
-		return nil, fmt.Errorf("querying 'classes' table failed. Query: %v\nError: %v", selectClassesStmt, err)
-	}
-	return entries, nil
+// Storage is an interface for interacting with persistent storage. It is
+// implemented by SQLite for the application and by Memory for tests.
+type Storage interface {
+	StudentStore
+	ClassStore
+	GroupStore
+	TeacherStore
+	SubjectStore
+	GradeStore
+	AttendanceStore
+	TimetableStore
+	LessonStore
+	SchoolYearStore
+	FinalGradeStore
+	ImportStore
+	UserStore
+	AuditStore
+
+	// Close releases the storage after it is no longer required.
+	Close() error
 }
 
-func (s Storage) Groups() ([]GroupEntry, error) {
-	var entries []GroupEntry
-	if err := s.db.Select(&entries, selectGroupsStmt); err != nil {
-		return nil, fmt.Errorf("querying 'groups' table failed. Query: %v\nError: %v", selectGroupsStmt, err)
-	}
-	return entries, nil
+// StudentStore provides access to students.
+type StudentStore interface {
+	// Students returns a slice of existing students ordered by ID.
+	Students() ([]StudentEntry, error)
+	// SearchStudents returns the students whose names match q, sorted by
+	// ID, name or surname.
+	SearchStudents(q Query) ([]StudentEntry, error)
+	// Student returns a single student by its ID.
+	Student(id int) (StudentEntry, error)
+	// AddStudent appends a new student and returns its ID.
+	AddStudent(name, surname string) (int, error)
+	// UpdateStudent changes the name and surname of an existing student.
+	UpdateStudent(id int, name, surname string) error
+	// DeleteStudent removes a student together with their enrollment,
+	// grades, attendance and promotion history.
+	DeleteStudent(id int) error
+	// RestoreStudent adds a student anew together with the class, grades,
+	// attendance and final grades of the record, either all of them or none.
+	// It returns the ID of the student and those of the grades in order.
+	RestoreStudent(r StudentRecord) (int, []int, error)
 }
 
-// AddStudent appends a new student entry to the database.
-func (s *Storage) AddStudent(name, surname string) error {
-	// Attempt to add an entry to the database first.
-	// If it fails, the student field will not be modified.
-	res, err := s.db.Exec(insertStudentsStmt, name, surname)
-	if err != nil {
-		return fmt.Errorf("table creation failed. Query: %v\nError: %v", createTableStmt, err)
-	}
-	if cnt, err := res.RowsAffected(); err != nil {
-		log.Printf("%d rows affected.", cnt)
-	}
-	return nil
+// StudentRecord is what DeleteStudent removes of a student, except for the
+// corrections of the grades and the promotions. The student IDs of its
+// entries are ignored.
+type StudentRecord struct {
+	Student    StudentEntry
+	ClassID    sql.NullInt64 // Not valid if the student is not enrolled.
+	Grades     []GradeEntry
+	Attendance []AttendanceEntry
+	Finals     []FinalGradeEntry
 }
 
-func (s *Storage) AddClass(year, modifier string) error {
-	res, err := s.db.Exec(insertClassesStmt, year, modifier)
-	if err != nil {
-		return fmt.Errorf("table creation failed. Query: %v\nError: %v", createTableStmt, err)
-	}
-	if cnt, err := res.RowsAffected(); err != nil {
-		log.Printf("%d rows affected.", cnt)
-	}
-	return nil
+// ClassStore provides access to classes.
+type ClassStore interface {
+	// Classes returns a slice of existing classes ordered by ID.
+	Classes() ([]ClassEntry, error)
+	// SearchClasses returns the classes whose names or school years match q,
+	// sorted by ID, class or school year. It filters by school year.
+	SearchClasses(q Query) ([]ClassEntry, error)
+	// Class returns a single class by its ID.
+	Class(id int) (ClassEntry, error)
+	// AddClass appends a new class to the latest school year and returns its
+	// ID.
+	AddClass(year, modifier string) (int, error)
+	// UpdateClass changes the year and modifier of an existing class.
+	UpdateClass(id int, year, modifier string) error
+	// DeleteClass removes a class that is not referenced elsewhere.
+	DeleteClass(id int) error
+	// SetClassTeacher sets the homeroom teacher of a class. A NULL teacher
+	// clears it.
+	SetClassTeacher(classID int, teacherID sql.NullInt64) error
 }
 
-func (s *Storage) AssignClassToStudent(year, modifier string, student_id int) error {
-	res, err := s.db.Exec(assignClassToStudentStmt, year, modifier, student_id)
-	if err != nil {
-		return fmt.Errorf("table creation failed. Query: %v\nError: %v", createTableStmt, err)
-	}
-	if cnt, err := res.RowsAffected(); err != nil {
-		log.Printf("%d rows affected.", cnt)
-	}
-	return nil
+// GroupStore provides access to the class membership of students.
+type GroupStore interface {
+	// Groups returns every student together with their class, ordered by
+	// student ID.
+	Groups() ([]GroupEntry, error)
+	// SearchGroups returns the students whose names or classes match q,
+	// sorted by student ID, name, surname or class. It filters by class and
+	// by enrollment.
+	SearchGroups(q Query) ([]GroupEntry, error)
+	// Group returns the enrollment of a single student.
+	Group(studentID int) (GroupEntry, error)
+	// AssignClassToStudent enrolls a student into a class, replacing any
+	// previous enrollment.
+	AssignClassToStudent(studentID, classID int) error
+	// UnassignClassFromStudent removes the enrollment of a student.
+	UnassignClassFromStudent(studentID int) error
 }
@@ -0,0 +1,394 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// SubjectEntry represents a row for a single subject, e.g. Mathematics.
+type SubjectEntry struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+// AssignmentEntry represents a teacher teaching a subject to a class during
+// a school year, which is the school year of the class when the assignment
+// was added. Names of the referenced entries are included for display.
+type AssignmentEntry struct {
+	ID           int           `db:"id"`
+	TeacherID    int           `db:"teacher_id"`
+	SubjectID    int           `db:"subject_id"`
+	ClassID      int           `db:"class_id"`
+	SchoolYearID sql.NullInt64 `db:"school_year_id"` // Not valid before school years exist.
+
+	TeacherName    string `db:"teacher_name"`
+	TeacherSurname string `db:"teacher_surname"`
+	Subject        string `db:"subject"`
+	Year           string `db:"year"`
+	Modifier       string `db:"modifier"`
+	SchoolYear     string `db:"school_year"` // E.g. "2025/2026", or empty.
+}
+
+// SubjectStore provides access to subjects and teaching assignments.
+type SubjectStore interface {
+	// Subjects returns a slice of existing subjects ordered by name.
+	Subjects() ([]SubjectEntry, error)
+	// Subject returns a single subject by its ID.
+	Subject(id int) (SubjectEntry, error)
+	// AddSubject appends a new subject and returns its ID.
+	AddSubject(name string) (int, error)
+	// UpdateSubject renames an existing subject.
+	UpdateSubject(id int, name string) error
+	// DeleteSubject removes a subject nobody teaches or grades.
+	DeleteSubject(id int) error
+
+	// Assignments returns every teaching assignment ordered by school year,
+	// class and subject.
+	Assignments() ([]AssignmentEntry, error)
+	// ClassAssignments returns the teaching assignments of a single class.
+	ClassAssignments(classID int) ([]AssignmentEntry, error)
+	// AddAssignment records that a teacher teaches a subject to a class
+	// during its school year and returns the ID of the assignment.
+	AddAssignment(teacherID, subjectID, classID int) (int, error)
+	// DeleteAssignment removes a teaching assignment.
+	DeleteAssignment(id int) error
+}
+
+var (
+	insertSubjectStmt  = `INSERT INTO subjects (name) VALUES(?)`
+	selectSubjectsStmt = `SELECT id, name FROM subjects ORDER BY name, id`
+	selectSubjectStmt  = `SELECT id, name FROM subjects WHERE id = ?`
+	updateSubjectStmt  = `UPDATE subjects SET name = ? WHERE id = ?`
+	deleteSubjectStmt  = `DELETE FROM subjects WHERE id = ?`
+
+	selectAssignmentsStmt = `SELECT teaching_assignments.id, teaching_assignments.teacher_id,
+		teaching_assignments.subject_id, teaching_assignments.class_id, teaching_assignments.school_year_id,
+		teachers.name AS teacher_name, teachers.surname AS teacher_surname,
+		subjects.name AS subject, classes.year, classes.modifier, IFNULL(school_years.name, '') AS school_year
+	FROM teaching_assignments
+	JOIN teachers ON teachers.id = teaching_assignments.teacher_id
+	JOIN subjects ON subjects.id = teaching_assignments.subject_id
+	JOIN classes ON classes.id = teaching_assignments.class_id
+	LEFT JOIN school_years ON school_years.id = teaching_assignments.school_year_id`
+	assignmentsOrder          = ` ORDER BY IFNULL(school_years.starts, ''), classes.year, classes.modifier, subjects.name, teaching_assignments.id`
+	selectAllAssignmentsStmt  = selectAssignmentsStmt + assignmentsOrder
+	selectClassAssignmentStmt = selectAssignmentsStmt + ` WHERE teaching_assignments.class_id = ?` + assignmentsOrder
+	insertAssignmentStmt      = `INSERT INTO teaching_assignments (teacher_id, subject_id, class_id, school_year_id)
+	VALUES(?, ?, ?, (SELECT school_year_id FROM classes WHERE id = ?))`
+	deleteAssignmentStmt = `DELETE FROM teaching_assignments WHERE id = ?`
+)
+
+// Subjects returns a slice of existing subjects.
+func (s *SQLite) Subjects() ([]SubjectEntry, error) {
+	var entries []SubjectEntry
+	if err := s.db.Select(&entries, selectSubjectsStmt); err != nil {
+		return nil, wrap("list subjects", err)
+	}
+	return entries, nil
+}
+
+// Subject returns a single subject by its ID.
+func (s *SQLite) Subject(id int) (SubjectEntry, error) {
+	var entry SubjectEntry
+	if err := s.db.Get(&entry, selectSubjectStmt, id); err != nil {
+		return SubjectEntry{}, wrap(fmt.Sprintf("get subject %d", id), err)
+	}
+	return entry, nil
+}
+
+// AddSubject appends a new subject and returns its ID.
+func (s *SQLite) AddSubject(name string) (int, error) {
+	if err := validateSubject(name); err != nil {
+		return 0, &Error{Op: "add subject", Kind: ErrValidation, Err: err}
+	}
+	res, err := s.exec(insertSubjectStmt, name)
+	if err != nil {
+		return 0, wrap("add subject", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, wrap("add subject", err)
+	}
+	return int(id), nil
+}
+
+// UpdateSubject renames an existing subject.
+func (s *SQLite) UpdateSubject(id int, name string) error {
+	op := fmt.Sprintf("update subject %d", id)
+	if err := validateSubject(name); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	res, err := s.exec(updateSubjectStmt, name, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// DeleteSubject removes a subject. Subjects that are taught, graded, in the
+// timetable or have recorded lesson topics or final grades cannot be deleted
+// and ErrConstraint is returned instead.
+func (s *SQLite) DeleteSubject(id int) error {
+	op := fmt.Sprintf("delete subject %d", id)
+	res, err := s.exec(deleteSubjectStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// Assignments returns every teaching assignment.
+func (s *SQLite) Assignments() ([]AssignmentEntry, error) {
+	var entries []AssignmentEntry
+	if err := s.db.Select(&entries, selectAllAssignmentsStmt); err != nil {
+		return nil, wrap("list assignments", err)
+	}
+	return entries, nil
+}
+
+// ClassAssignments returns the teaching assignments of a single class.
+func (s *SQLite) ClassAssignments(classID int) ([]AssignmentEntry, error) {
+	var entries []AssignmentEntry
+	if err := s.db.Select(&entries, selectClassAssignmentStmt, classID); err != nil {
+		return nil, wrap(fmt.Sprintf("list assignments of class %d", classID), err)
+	}
+	return entries, nil
+}
+
+// AddAssignment records that a teacher teaches a subject to a class. The
+// teacher, subject and class must exist.
+func (s *SQLite) AddAssignment(teacherID, subjectID, classID int) (int, error) {
+	res, err := s.exec(insertAssignmentStmt, teacherID, subjectID, classID, classID)
+	if err != nil {
+		return 0, wrap("add assignment", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, wrap("add assignment", err)
+	}
+	return int(id), nil
+}
+
+// DeleteAssignment removes a teaching assignment.
+func (s *SQLite) DeleteAssignment(id int) error {
+	op := fmt.Sprintf("delete assignment %d", id)
+	res, err := s.exec(deleteAssignmentStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// Subjects returns a slice of existing subjects.
+func (m *Memory) Subjects() ([]SubjectEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []SubjectEntry
+	for _, entry := range m.subjects {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// Subject returns a single subject by its ID.
+func (m *Memory) Subject(id int) (SubjectEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.subjects[id]
+	if !ok {
+		return SubjectEntry{}, &Error{Op: fmt.Sprintf("get subject %d", id), Kind: ErrNotFound}
+	}
+	return entry, nil
+}
+
+// AddSubject appends a new subject and returns its ID.
+func (m *Memory) AddSubject(name string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if err := validateSubject(name); err != nil {
+		return 0, &Error{Op: "add subject", Kind: ErrValidation, Err: err}
+	}
+	if m.hasSubject(0, name) {
+		return 0, &Error{Op: "add subject", Kind: ErrDuplicate}
+	}
+	m.lastSubjectID++
+	m.touch("subjects", m.lastSubjectID)
+	m.subjects[m.lastSubjectID] = SubjectEntry{ID: m.lastSubjectID, Name: name}
+	return m.lastSubjectID, nil
+}
+
+// UpdateSubject renames an existing subject.
+func (m *Memory) UpdateSubject(id int, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("update subject %d", id)
+	if err := validateSubject(name); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	if _, ok := m.subjects[id]; !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	if m.hasSubject(id, name) {
+		return &Error{Op: op, Kind: ErrDuplicate}
+	}
+	m.touch("subjects", id)
+	m.subjects[id] = SubjectEntry{ID: id, Name: name}
+	return nil
+}
+
+// hasSubject reports whether a subject other than except has the given name.
+// m.mu must be held.
+func (m *Memory) hasSubject(except int, name string) bool {
+	for _, s := range m.subjects {
+		if s.ID != except && s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteSubject removes a subject. Subjects that are taught, graded, in the
+// timetable or have recorded lesson topics or final grades cannot be deleted
+// and ErrConstraint is returned instead.
+func (m *Memory) DeleteSubject(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("delete subject %d", id)
+	if _, ok := m.subjects[id]; !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	for _, a := range m.assignments {
+		if a.SubjectID == id {
+			return &Error{Op: op, Kind: ErrConstraint}
+		}
+	}
+	for _, g := range m.grades {
+		if g.SubjectID == id {
+			return &Error{Op: op, Kind: ErrConstraint}
+		}
+	}
+	for _, s := range m.slots {
+		if s.SubjectID == id {
+			return &Error{Op: op, Kind: ErrConstraint}
+		}
+	}
+	for _, l := range m.lessons {
+		if l.SubjectID == id {
+			return &Error{Op: op, Kind: ErrConstraint}
+		}
+	}
+	if m.hasFinalGrade(func(g FinalGradeEntry) bool { return g.SubjectID == id }) {
+		return &Error{Op: op, Kind: ErrConstraint}
+	}
+	m.touch("subjects", id)
+	delete(m.subjects, id)
+	return nil
+}
+
+// Assignments returns every teaching assignment.
+func (m *Memory) Assignments() ([]AssignmentEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.listAssignments(func(AssignmentEntry) bool { return true }), nil
+}
+
+// ClassAssignments returns the teaching assignments of a single class.
+func (m *Memory) ClassAssignments(classID int) ([]AssignmentEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.listAssignments(func(a AssignmentEntry) bool { return a.ClassID == classID }), nil
+}
+
+// listAssignments returns the assignments matching keep with the names of
+// the referenced entries filled in. m.mu must be held.
+func (m *Memory) listAssignments(keep func(AssignmentEntry) bool) []AssignmentEntry {
+	var entries []AssignmentEntry
+	for _, a := range m.assignments {
+		if !keep(a) {
+			continue
+		}
+		teacher, subject, class := m.teachers[a.TeacherID], m.subjects[a.SubjectID], m.classes[a.ClassID]
+		a.TeacherName, a.TeacherSurname = teacher.Name, teacher.Surname
+		a.Subject = subject.Name
+		a.Year, a.Modifier = class.Year, class.Modifier
+		a.SchoolYear = m.schoolYears[int(a.SchoolYearID.Int64)].Name
+		entries = append(entries, a)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case a.SchoolYearID != b.SchoolYearID:
+			return m.schoolYears[int(a.SchoolYearID.Int64)].Starts < m.schoolYears[int(b.SchoolYearID.Int64)].Starts
+		case a.Year != b.Year:
+			return compareYears(a.Year, b.Year) < 0
+		case a.Modifier != b.Modifier:
+			return a.Modifier < b.Modifier
+		case a.Subject != b.Subject:
+			return a.Subject < b.Subject
+		}
+		return a.ID < b.ID
+	})
+	return entries
+}
+
+// AddAssignment records that a teacher teaches a subject to a class. The
+// teacher, subject and class must exist.
+func (m *Memory) AddAssignment(teacherID, subjectID, classID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	const op = "add assignment"
+	_, teacherOK := m.teachers[teacherID]
+	_, subjectOK := m.subjects[subjectID]
+	class, classOK := m.classes[classID]
+	if !teacherOK || !subjectOK || !classOK {
+		return 0, &Error{Op: op, Kind: ErrConstraint}
+	}
+	for _, a := range m.assignments {
+		if a.TeacherID == teacherID && a.SubjectID == subjectID && a.ClassID == classID && a.SchoolYearID == class.SchoolYearID {
+			return 0, &Error{Op: op, Kind: ErrDuplicate}
+		}
+	}
+	m.lastAssignmentID++
+	m.touch("teaching_assignments", m.lastAssignmentID)
+	m.assignments[m.lastAssignmentID] = AssignmentEntry{
+		ID:           m.lastAssignmentID,
+		TeacherID:    teacherID,
+		SubjectID:    subjectID,
+		ClassID:      classID,
+		SchoolYearID: class.SchoolYearID,
+	}
+	return m.lastAssignmentID, nil
+}
+
+// DeleteAssignment removes a teaching assignment.
+func (m *Memory) DeleteAssignment(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if _, ok := m.assignments[id]; !ok {
+		return &Error{Op: fmt.Sprintf("delete assignment %d", id), Kind: ErrNotFound}
+	}
+	m.touch("teaching_assignments", id)
+	delete(m.assignments, id)
+	return nil
+}
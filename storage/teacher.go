@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// TeacherEntry represents a row for a single teacher in the DB.
+type TeacherEntry struct {
+	ID      int    `db:"id"`
+	Name    string `db:"name"`
+	Surname string `db:"surname"`
+}
+
+// TeacherStore provides access to teachers.
+type TeacherStore interface {
+	// Teachers returns a slice of existing teachers ordered by ID.
+	Teachers() ([]TeacherEntry, error)
+	// Teacher returns a single teacher by its ID.
+	Teacher(id int) (TeacherEntry, error)
+	// AddTeacher appends a new teacher and returns its ID.
+	AddTeacher(name, surname string) (int, error)
+	// UpdateTeacher changes the name and surname of an existing teacher.
+	UpdateTeacher(id int, name, surname string) error
+	// DeleteTeacher removes a teacher that is not referenced elsewhere.
+	DeleteTeacher(id int) error
+}
+
+var (
+	insertTeacherStmt   = `INSERT INTO teachers (name, surname) VALUES(?, ?)`
+	selectTeachersStmt  = `SELECT id, name, surname FROM teachers ORDER BY id`
+	selectTeacherStmt   = `SELECT id, name, surname FROM teachers WHERE id = ?`
+	updateTeacherStmt   = `UPDATE teachers SET name = ?, surname = ? WHERE id = ?`
+	deleteTeacherStmt   = `DELETE FROM teachers WHERE id = ?`
+	setClassTeacherStmt = `UPDATE classes SET teacher_id = ? WHERE id = ?`
+)
+
+// Teachers returns a slice of existing teachers.
+func (s *SQLite) Teachers() ([]TeacherEntry, error) {
+	var entries []TeacherEntry
+	if err := s.db.Select(&entries, selectTeachersStmt); err != nil {
+		return nil, wrap("list teachers", err)
+	}
+	return entries, nil
+}
+
+// Teacher returns a single teacher by its ID.
+func (s *SQLite) Teacher(id int) (TeacherEntry, error) {
+	var entry TeacherEntry
+	if err := s.db.Get(&entry, selectTeacherStmt, id); err != nil {
+		return TeacherEntry{}, wrap(fmt.Sprintf("get teacher %d", id), err)
+	}
+	return entry, nil
+}
+
+// AddTeacher appends a new teacher entry to the database and returns its ID.
+func (s *SQLite) AddTeacher(name, surname string) (int, error) {
+	if err := validatePerson(name, surname); err != nil {
+		return 0, &Error{Op: "add teacher", Kind: ErrValidation, Err: err}
+	}
+	res, err := s.exec(insertTeacherStmt, name, surname)
+	if err != nil {
+		return 0, wrap("add teacher", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, wrap("add teacher", err)
+	}
+	return int(id), nil
+}
+
+// UpdateTeacher changes the name and surname of an existing teacher.
+func (s *SQLite) UpdateTeacher(id int, name, surname string) error {
+	op := fmt.Sprintf("update teacher %d", id)
+	if err := validatePerson(name, surname); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	res, err := s.exec(updateTeacherStmt, name, surname, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// DeleteTeacher removes a teacher. Homeroom teachers and teachers with
+// teaching assignments, grades, lessons in the timetable or a user account
+// cannot be deleted and ErrConstraint is returned instead.
+func (s *SQLite) DeleteTeacher(id int) error {
+	op := fmt.Sprintf("delete teacher %d", id)
+	res, err := s.exec(deleteTeacherStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// SetClassTeacher sets or clears the homeroom teacher of a class.
+func (s *SQLite) SetClassTeacher(classID int, teacherID sql.NullInt64) error {
+	op := fmt.Sprintf("set teacher of class %d", classID)
+	res, err := s.exec(setClassTeacherStmt, teacherID, classID)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// Teachers returns a slice of existing teachers.
+func (m *Memory) Teachers() ([]TeacherEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []TeacherEntry
+	for _, entry := range m.teachers {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// Teacher returns a single teacher by its ID.
+func (m *Memory) Teacher(id int) (TeacherEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.teachers[id]
+	if !ok {
+		return TeacherEntry{}, &Error{Op: fmt.Sprintf("get teacher %d", id), Kind: ErrNotFound}
+	}
+	return entry, nil
+}
+
+// AddTeacher appends a new teacher entry and returns its ID.
+func (m *Memory) AddTeacher(name, surname string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if err := validatePerson(name, surname); err != nil {
+		return 0, &Error{Op: "add teacher", Kind: ErrValidation, Err: err}
+	}
+	m.lastTeacherID++
+	m.touch("teachers", m.lastTeacherID)
+	m.teachers[m.lastTeacherID] = TeacherEntry{ID: m.lastTeacherID, Name: name, Surname: surname}
+	return m.lastTeacherID, nil
+}
+
+// UpdateTeacher changes the name and surname of an existing teacher.
+func (m *Memory) UpdateTeacher(id int, name, surname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("update teacher %d", id)
+	if err := validatePerson(name, surname); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	if _, ok := m.teachers[id]; !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	m.touch("teachers", id)
+	m.teachers[id] = TeacherEntry{ID: id, Name: name, Surname: surname}
+	return nil
+}
+
+// DeleteTeacher removes a teacher. Homeroom teachers and teachers with
+// teaching assignments, grades, lessons in the timetable or a user account
+// cannot be deleted and ErrConstraint is returned instead.
+func (m *Memory) DeleteTeacher(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("delete teacher %d", id)
+	if _, ok := m.teachers[id]; !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	if m.teacherReferenced(id) {
+		return &Error{Op: op, Kind: ErrConstraint}
+	}
+	m.touch("teachers", id)
+	delete(m.teachers, id)
+	return nil
+}
+
+// teacherReferenced reports whether anything refers to the teacher. m.mu must
+// be held.
+func (m *Memory) teacherReferenced(id int) bool {
+	for _, c := range m.classes {
+		if c.TeacherID.Valid && int(c.TeacherID.Int64) == id {
+			return true
+		}
+	}
+	for _, a := range m.assignments {
+		if a.TeacherID == id {
+			return true
+		}
+	}
+	for _, g := range m.grades {
+		if g.TeacherID == id {
+			return true
+		}
+	}
+	for _, s := range m.slots {
+		if s.TeacherID == id {
+			return true
+		}
+	}
+	for _, u := range m.users {
+		if u.TeacherID.Valid && int(u.TeacherID.Int64) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SetClassTeacher sets or clears the homeroom teacher of a class.
+func (m *Memory) SetClassTeacher(classID int, teacherID sql.NullInt64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("set teacher of class %d", classID)
+	class, ok := m.classes[classID]
+	if !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	if _, ok := m.teachers[int(teacherID.Int64)]; teacherID.Valid && !ok {
+		return &Error{Op: op, Kind: ErrConstraint}
+	}
+	class.TeacherID = teacherID
+	m.touch("classes", classID)
+	m.classes[classID] = class
+	return nil
+}
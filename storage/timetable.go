@@ -0,0 +1,365 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SchoolDays is the number of days with lessons in a week, Monday to Friday.
+const SchoolDays = 5
+
+// BellEntry represents the start and end of a lesson in the bell schedule.
+type BellEntry struct {
+	Lesson int    `db:"lesson"`
+	Starts string `db:"starts"` // E.g. "08:30".
+	Ends   string `db:"ends"`
+}
+
+// SlotEntry represents a weekly lesson of a class in the timetable. Names of
+// the referenced entries are included for display.
+type SlotEntry struct {
+	ID        int    `db:"id"`
+	ClassID   int    `db:"class_id"`
+	Weekday   int    `db:"weekday"` // 1 is Monday, like time.Monday.
+	Lesson    int    `db:"lesson"`
+	SubjectID int    `db:"subject_id"`
+	TeacherID int    `db:"teacher_id"`
+	Room      string `db:"room"` // Empty if not known.
+
+	Year           string `db:"year"`
+	Modifier       string `db:"modifier"`
+	Subject        string `db:"subject"`
+	TeacherName    string `db:"teacher_name"`
+	TeacherSurname string `db:"teacher_surname"`
+}
+
+// SlotFilter selects timetable slots. Zero fields match every slot.
+type SlotFilter struct {
+	ClassID   int
+	TeacherID int
+}
+
+// TimetableStore provides access to the bell schedule and the timetable.
+type TimetableStore interface {
+	// Bells returns the bell schedule ordered by lesson.
+	Bells() ([]BellEntry, error)
+	// SetBell sets the times of a lesson in the bell schedule.
+	SetBell(b BellEntry) error
+	// DeleteBell removes a lesson from the bell schedule.
+	DeleteBell(lesson int) error
+
+	// Slots returns the timetable slots matching f ordered by day and
+	// lesson.
+	Slots(f SlotFilter) ([]SlotEntry, error)
+	// AddSlot adds a weekly lesson and returns its ID. A *ConflictError is
+	// returned if the class, the teacher or the room is busy already.
+	AddSlot(s SlotEntry) (int, error)
+	// DeleteSlot removes a weekly lesson.
+	DeleteSlot(id int) error
+}
+
+var (
+	selectBellsStmt = `SELECT lesson, starts, ends FROM bells ORDER BY lesson`
+	upsertBellStmt  = `INSERT INTO bells (lesson, starts, ends) VALUES(?, ?, ?)
+	ON CONFLICT (lesson) DO UPDATE SET starts = excluded.starts, ends = excluded.ends`
+	deleteBellStmt = `DELETE FROM bells WHERE lesson = ?`
+
+	selectSlotsStmt = `SELECT timetable.id, timetable.class_id, timetable.weekday, timetable.lesson,
+		timetable.subject_id, timetable.teacher_id, timetable.room,
+		classes.year, classes.modifier, subjects.name AS subject,
+		teachers.name AS teacher_name, teachers.surname AS teacher_surname
+	FROM timetable
+	JOIN classes ON classes.id = timetable.class_id
+	JOIN subjects ON subjects.id = timetable.subject_id
+	JOIN teachers ON teachers.id = timetable.teacher_id
+	WHERE (? = 0 OR timetable.class_id = ?) AND (? = 0 OR timetable.teacher_id = ?)
+	ORDER BY timetable.weekday, timetable.lesson, classes.year, classes.modifier, timetable.id`
+	// Statement for finding the slots a new slot would clash with.
+	selectClashingSlotsStmt = `SELECT timetable.id, timetable.class_id, timetable.weekday, timetable.lesson,
+		timetable.subject_id, timetable.teacher_id, timetable.room,
+		classes.year, classes.modifier, subjects.name AS subject,
+		teachers.name AS teacher_name, teachers.surname AS teacher_surname
+	FROM timetable
+	JOIN classes ON classes.id = timetable.class_id
+	JOIN subjects ON subjects.id = timetable.subject_id
+	JOIN teachers ON teachers.id = timetable.teacher_id
+	WHERE timetable.weekday = ? AND timetable.lesson = ?
+		AND (timetable.class_id = ? OR timetable.teacher_id = ? OR (timetable.room <> '' AND timetable.room = ?))
+	ORDER BY timetable.id`
+	insertSlotStmt = `INSERT INTO timetable (class_id, weekday, lesson, subject_id, teacher_id, room) VALUES(?, ?, ?, ?, ?, ?)`
+	deleteSlotStmt = `DELETE FROM timetable WHERE id = ?`
+)
+
+// validateBell checks a lesson of the bell schedule against the other
+// lessons in bells.
+func validateBell(b BellEntry, bells []BellEntry) error {
+	if b.Lesson < 1 || b.Lesson > MaxLessons {
+		return &ValidationError{Field: "lesson", Reason: fmt.Sprintf("must be a number from 1 to %d", MaxLessons)}
+	}
+	starts, err1 := time.Parse("15:04", b.Starts)
+	ends, err2 := time.Parse("15:04", b.Ends)
+	if err1 != nil || err2 != nil {
+		return &ValidationError{Field: "time", Reason: "must look like 08:30"}
+	}
+	if !starts.Before(ends) {
+		return &ValidationError{Field: "end", Reason: "must be after the start"}
+	}
+	// Times are zero padded, so they compare as strings. Earlier lessons
+	// must end before later ones start.
+	for _, o := range bells {
+		if o.Lesson < b.Lesson && o.Ends > b.Starts || o.Lesson > b.Lesson && o.Starts < b.Ends {
+			return &ValidationError{Field: "time", Reason: fmt.Sprintf("overlaps lesson %d", o.Lesson)}
+		}
+	}
+	return nil
+}
+
+// validateSlot checks the fields of a timetable slot before it is stored.
+func validateSlot(s SlotEntry) error {
+	if s.Weekday < int(time.Monday) || s.Weekday > SchoolDays {
+		return &ValidationError{Field: "day", Reason: "must be a day from Monday to Friday"}
+	}
+	if s.Lesson < 1 || s.Lesson > MaxLessons {
+		return &ValidationError{Field: "lesson", Reason: fmt.Sprintf("must be a number from 1 to %d", MaxLessons)}
+	}
+	if len([]rune(s.Room)) > maxNameLength {
+		return &ValidationError{Field: "room", Reason: "is too long"}
+	}
+	return nil
+}
+
+// conflict returns the error describing the clash of s with existing, which
+// must be one of the slots found by selectClashingSlotsStmt.
+func conflict(s SlotEntry, existing SlotEntry) *ConflictError {
+	resource := "room"
+	switch {
+	case existing.ClassID == s.ClassID:
+		resource = "class"
+	case existing.TeacherID == s.TeacherID:
+		resource = "teacher"
+	}
+	return &ConflictError{Resource: resource, Slot: existing}
+}
+
+// Bells returns the bell schedule.
+func (s *SQLite) Bells() ([]BellEntry, error) {
+	var entries []BellEntry
+	if err := s.db.Select(&entries, selectBellsStmt); err != nil {
+		return nil, wrap("list bells", err)
+	}
+	return entries, nil
+}
+
+// SetBell sets the times of a lesson. Lessons must not overlap.
+func (s *SQLite) SetBell(b BellEntry) error {
+	op := fmt.Sprintf("set bell of lesson %d", b.Lesson)
+	return s.inTx(op, func(tx *sqlx.Tx) error {
+		var bells []BellEntry
+		if err := tx.Select(&bells, selectBellsStmt); err != nil {
+			return wrap(op, err)
+		}
+		if err := validateBell(b, bells); err != nil {
+			return &Error{Op: op, Kind: ErrValidation, Err: err}
+		}
+		if _, err := tx.Exec(upsertBellStmt, b.Lesson, b.Starts, b.Ends); err != nil {
+			return wrap(op, err)
+		}
+		return nil
+	})
+}
+
+// DeleteBell removes a lesson from the bell schedule.
+func (s *SQLite) DeleteBell(lesson int) error {
+	op := fmt.Sprintf("delete bell of lesson %d", lesson)
+	res, err := s.exec(deleteBellStmt, lesson)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// Slots returns the timetable slots matching f.
+func (s *SQLite) Slots(f SlotFilter) ([]SlotEntry, error) {
+	var entries []SlotEntry
+	if err := s.db.Select(&entries, selectSlotsStmt, f.ClassID, f.ClassID, f.TeacherID, f.TeacherID); err != nil {
+		return nil, wrap("list timetable", err)
+	}
+	return entries, nil
+}
+
+// AddSlot adds a weekly lesson unless it clashes with an existing one. The
+// class, subject and teacher must exist.
+func (s *SQLite) AddSlot(slot SlotEntry) (int, error) {
+	const op = "add timetable slot"
+	if err := validateSlot(slot); err != nil {
+		return 0, &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	var id int
+	err := s.inTx(op, func(tx *sqlx.Tx) error {
+		var clashing []SlotEntry
+		if err := tx.Select(&clashing, selectClashingSlotsStmt, slot.Weekday, slot.Lesson, slot.ClassID, slot.TeacherID, slot.Room); err != nil {
+			return wrap(op, err)
+		}
+		if len(clashing) > 0 {
+			return &Error{Op: op, Kind: ErrConflict, Err: conflict(slot, clashing[0])}
+		}
+		res, err := tx.Exec(insertSlotStmt, slot.ClassID, slot.Weekday, slot.Lesson, slot.SubjectID, slot.TeacherID, slot.Room)
+		if err != nil {
+			return wrap(op, err)
+		}
+		last, err := res.LastInsertId()
+		if err != nil {
+			return wrap(op, err)
+		}
+		id = int(last)
+		return nil
+	})
+	return id, err
+}
+
+// DeleteSlot removes a weekly lesson.
+func (s *SQLite) DeleteSlot(id int) error {
+	op := fmt.Sprintf("delete timetable slot %d", id)
+	res, err := s.exec(deleteSlotStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// Bells returns the bell schedule.
+func (m *Memory) Bells() ([]BellEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.bellList(), nil
+}
+
+// bellList returns the bell schedule ordered by lesson. m.mu must be held.
+func (m *Memory) bellList() []BellEntry {
+	var entries []BellEntry
+	for _, b := range m.bells {
+		entries = append(entries, b)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Lesson < entries[j].Lesson })
+	return entries
+}
+
+// SetBell sets the times of a lesson. Lessons must not overlap.
+func (m *Memory) SetBell(b BellEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if err := validateBell(b, m.bellList()); err != nil {
+		return &Error{Op: fmt.Sprintf("set bell of lesson %d", b.Lesson), Kind: ErrValidation, Err: err}
+	}
+	m.touch("bells", b.Lesson)
+	m.bells[b.Lesson] = b
+	return nil
+}
+
+// DeleteBell removes a lesson from the bell schedule.
+func (m *Memory) DeleteBell(lesson int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if _, ok := m.bells[lesson]; !ok {
+		return &Error{Op: fmt.Sprintf("delete bell of lesson %d", lesson), Kind: ErrNotFound}
+	}
+	m.touch("bells", lesson)
+	delete(m.bells, lesson)
+	return nil
+}
+
+// Slots returns the timetable slots matching f.
+func (m *Memory) Slots(f SlotFilter) ([]SlotEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.listSlots(func(s SlotEntry) bool {
+		return (f.ClassID == 0 || s.ClassID == f.ClassID) && (f.TeacherID == 0 || s.TeacherID == f.TeacherID)
+	}), nil
+}
+
+// listSlots returns the slots matching keep with the names of the referenced
+// entries filled in. m.mu must be held.
+func (m *Memory) listSlots(keep func(SlotEntry) bool) []SlotEntry {
+	var entries []SlotEntry
+	for _, s := range m.slots {
+		if !keep(s) {
+			continue
+		}
+		class, subject, teacher := m.classes[s.ClassID], m.subjects[s.SubjectID], m.teachers[s.TeacherID]
+		s.Year, s.Modifier = class.Year, class.Modifier
+		s.Subject = subject.Name
+		s.TeacherName, s.TeacherSurname = teacher.Name, teacher.Surname
+		entries = append(entries, s)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case a.Weekday != b.Weekday:
+			return a.Weekday < b.Weekday
+		case a.Lesson != b.Lesson:
+			return a.Lesson < b.Lesson
+		case a.Year != b.Year:
+			return compareYears(a.Year, b.Year) < 0
+		case a.Modifier != b.Modifier:
+			return a.Modifier < b.Modifier
+		}
+		return a.ID < b.ID
+	})
+	return entries
+}
+
+// AddSlot adds a weekly lesson unless it clashes with an existing one. The
+// class, subject and teacher must exist.
+func (m *Memory) AddSlot(slot SlotEntry) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	const op = "add timetable slot"
+	if err := validateSlot(slot); err != nil {
+		return 0, &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	_, classOK := m.classes[slot.ClassID]
+	_, subjectOK := m.subjects[slot.SubjectID]
+	_, teacherOK := m.teachers[slot.TeacherID]
+	if !classOK || !subjectOK || !teacherOK {
+		return 0, &Error{Op: op, Kind: ErrConstraint}
+	}
+	clashing := m.listSlots(func(s SlotEntry) bool {
+		return s.Weekday == slot.Weekday && s.Lesson == slot.Lesson &&
+			(s.ClassID == slot.ClassID || s.TeacherID == slot.TeacherID || s.Room != "" && s.Room == slot.Room)
+	})
+	if len(clashing) > 0 {
+		sort.Slice(clashing, func(i, j int) bool { return clashing[i].ID < clashing[j].ID })
+		return 0, &Error{Op: op, Kind: ErrConflict, Err: conflict(slot, clashing[0])}
+	}
+	m.lastSlotID++
+	slot.ID = m.lastSlotID
+	slot.Year, slot.Modifier, slot.Subject, slot.TeacherName, slot.TeacherSurname = "", "", "", "", ""
+	m.touch("timetable", slot.ID)
+	m.slots[slot.ID] = slot
+	return slot.ID, nil
+}
+
+// DeleteSlot removes a weekly lesson.
+func (m *Memory) DeleteSlot(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if _, ok := m.slots[id]; !ok {
+		return &Error{Op: fmt.Sprintf("delete timetable slot %d", id), Kind: ErrNotFound}
+	}
+	m.touch("timetable", id)
+	delete(m.slots, id)
+	return nil
+}
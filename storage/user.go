@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxUsernameLength is the longest accepted username, in characters.
+const maxUsernameLength = 32
+
+// Role is what a user may do in the application.
+type Role string
+
+const (
+	// RoleAdmin may change everything, including the users.
+	RoleAdmin Role = "admin"
+	// RoleTeacher may record grades, lessons and attendance of the subjects
+	// and classes they teach.
+	RoleTeacher Role = "teacher"
+	// RoleClassTeacher may additionally record the attendance of their
+	// homeroom class.
+	RoleClassTeacher Role = "class_teacher"
+	// RoleSecretary may read everything but change nothing.
+	RoleSecretary Role = "secretary"
+)
+
+// Roles lists the roles from the most to the least powerful.
+var Roles = []Role{RoleAdmin, RoleClassTeacher, RoleTeacher, RoleSecretary}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	for _, known := range Roles {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Teaches reports whether users of the role are teachers, who must be linked
+// to a teacher entry.
+func (r Role) Teaches() bool {
+	return r == RoleTeacher || r == RoleClassTeacher
+}
+
+// UserEntry represents a row for a single user who can log in.
+type UserEntry struct {
+	ID           int           `db:"id"`
+	Username     string        `db:"username"`      // Unique regardless of case.
+	PasswordHash string        `db:"password_hash"` // Never the password itself.
+	Role         Role          `db:"role"`
+	TeacherID    sql.NullInt64 `db:"teacher_id"` // Valid for teachers only.
+}
+
+// UserStore provides access to the users of the application.
+type UserStore interface {
+	// Users returns a slice of existing users ordered by username.
+	Users() ([]UserEntry, error)
+	// User returns a single user by its ID.
+	User(id int) (UserEntry, error)
+	// UserByName returns a single user by their username, ignoring case.
+	UserByName(username string) (UserEntry, error)
+	// AddUser appends a new user and returns its ID. The ID of u is ignored.
+	AddUser(u UserEntry) (int, error)
+	// UpdateUser changes the username, password hash, role and teacher of an
+	// existing user.
+	UpdateUser(u UserEntry) error
+	// DeleteUser removes a user.
+	DeleteUser(id int) error
+}
+
+var (
+	selectUsersStmt      = `SELECT id, username, password_hash, role, teacher_id FROM users ORDER BY username, id`
+	selectUserStmt       = `SELECT id, username, password_hash, role, teacher_id FROM users WHERE id = ?`
+	selectUserByNameStmt = `SELECT id, username, password_hash, role, teacher_id FROM users WHERE username = ?`
+	insertUserStmt       = `INSERT INTO users (username, password_hash, role, teacher_id) VALUES(?, ?, ?, ?)`
+	updateUserStmt       = `UPDATE users SET username = ?, password_hash = ?, role = ?, teacher_id = ? WHERE id = ?`
+	deleteUserStmt       = `DELETE FROM users WHERE id = ?`
+)
+
+// validateUser checks the fields of a user before they are stored. Usernames
+// are limited to ASCII, so that SQLite compares them without case like Go.
+func validateUser(u UserEntry) error {
+	switch {
+	case u.Username == "":
+		return &ValidationError{Field: "username", Reason: "must not be empty"}
+	case len(u.Username) > maxUsernameLength:
+		return &ValidationError{Field: "username", Reason: "is too long"}
+	}
+	for _, r := range u.Username {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("._-", r)) {
+			return &ValidationError{Field: "username", Reason: "may contain Latin letters, digits, dots, dashes and underscores only"}
+		}
+	}
+	switch {
+	case u.PasswordHash == "":
+		return &ValidationError{Field: "password", Reason: "must be set"}
+	case !u.Role.Valid():
+		return &ValidationError{Field: "role", Reason: "must be admin, class_teacher, teacher or secretary"}
+	case u.Role.Teaches() && !u.TeacherID.Valid:
+		return &ValidationError{Field: "teacher", Reason: "must be chosen for teachers"}
+	case !u.Role.Teaches() && u.TeacherID.Valid:
+		return &ValidationError{Field: "teacher", Reason: "may be chosen for teachers only"}
+	}
+	return nil
+}
+
+// Users returns a slice of existing users.
+func (s *SQLite) Users() ([]UserEntry, error) {
+	var entries []UserEntry
+	if err := s.db.Select(&entries, selectUsersStmt); err != nil {
+		return nil, wrap("list users", err)
+	}
+	return entries, nil
+}
+
+// User returns a single user by its ID.
+func (s *SQLite) User(id int) (UserEntry, error) {
+	var entry UserEntry
+	if err := s.db.Get(&entry, selectUserStmt, id); err != nil {
+		return UserEntry{}, wrap(fmt.Sprintf("get user %d", id), err)
+	}
+	return entry, nil
+}
+
+// UserByName returns a single user by their username, ignoring case.
+func (s *SQLite) UserByName(username string) (UserEntry, error) {
+	var entry UserEntry
+	if err := s.db.Get(&entry, selectUserByNameStmt, username); err != nil {
+		return UserEntry{}, wrap(fmt.Sprintf("get user %q", username), err)
+	}
+	return entry, nil
+}
+
+// AddUser appends a new user entry to the database and returns its ID.
+func (s *SQLite) AddUser(u UserEntry) (int, error) {
+	if err := validateUser(u); err != nil {
+		return 0, &Error{Op: "add user", Kind: ErrValidation, Err: err}
+	}
+	res, err := s.exec(insertUserStmt, u.Username, u.PasswordHash, u.Role, u.TeacherID)
+	if err != nil {
+		return 0, wrap("add user", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, wrap("add user", err)
+	}
+	return int(id), nil
+}
+
+// UpdateUser changes the fields of an existing user.
+func (s *SQLite) UpdateUser(u UserEntry) error {
+	op := fmt.Sprintf("update user %d", u.ID)
+	if err := validateUser(u); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	res, err := s.exec(updateUserStmt, u.Username, u.PasswordHash, u.Role, u.TeacherID, u.ID)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// DeleteUser removes a user.
+func (s *SQLite) DeleteUser(id int) error {
+	op := fmt.Sprintf("delete user %d", id)
+	res, err := s.exec(deleteUserStmt, id)
+	if err != nil {
+		return wrap(op, err)
+	}
+	return checkAffected(op, res)
+}
+
+// Users returns a slice of existing users.
+func (m *Memory) Users() ([]UserEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []UserEntry
+	for _, entry := range m.users {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Username != entries[j].Username {
+			return entries[i].Username < entries[j].Username
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// User returns a single user by its ID.
+func (m *Memory) User(id int) (UserEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.users[id]
+	if !ok {
+		return UserEntry{}, &Error{Op: fmt.Sprintf("get user %d", id), Kind: ErrNotFound}
+	}
+	return entry, nil
+}
+
+// UserByName returns a single user by their username, ignoring case.
+func (m *Memory) UserByName(username string) (UserEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, entry := range m.users {
+		if strings.EqualFold(entry.Username, username) {
+			return entry, nil
+		}
+	}
+	return UserEntry{}, &Error{Op: fmt.Sprintf("get user %q", username), Kind: ErrNotFound}
+}
+
+// AddUser appends a new user entry and returns its ID.
+func (m *Memory) AddUser(u UserEntry) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if err := m.checkUser("add user", u); err != nil {
+		return 0, err
+	}
+	m.lastUserID++
+	u.ID = m.lastUserID
+	m.touch("users", u.ID)
+	m.users[u.ID] = u
+	return u.ID, nil
+}
+
+// UpdateUser changes the fields of an existing user.
+func (m *Memory) UpdateUser(u UserEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	op := fmt.Sprintf("update user %d", u.ID)
+	if err := m.checkUser(op, u); err != nil {
+		return err
+	}
+	if _, ok := m.users[u.ID]; !ok {
+		return &Error{Op: op, Kind: ErrNotFound}
+	}
+	m.touch("users", u.ID)
+	m.users[u.ID] = u
+	return nil
+}
+
+// checkUser validates u and its references like SQLite would. m.mu must be
+// held.
+func (m *Memory) checkUser(op string, u UserEntry) error {
+	if err := validateUser(u); err != nil {
+		return &Error{Op: op, Kind: ErrValidation, Err: err}
+	}
+	for _, other := range m.users {
+		if other.ID != u.ID && strings.EqualFold(other.Username, u.Username) {
+			return &Error{Op: op, Kind: ErrDuplicate}
+		}
+	}
+	if _, ok := m.teachers[int(u.TeacherID.Int64)]; u.TeacherID.Valid && !ok {
+		return &Error{Op: op, Kind: ErrConstraint}
+	}
+	return nil
+}
+
+// DeleteUser removes a user.
+func (m *Memory) DeleteUser(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.audit()()
+
+	if _, ok := m.users[id]; !ok {
+		return &Error{Op: fmt.Sprintf("delete user %d", id), Kind: ErrNotFound}
+	}
+	m.touch("users", id)
+	delete(m.users, id)
+	return nil
+}
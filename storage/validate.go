@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxNameLength is the longest accepted name or surname, in characters.
+const maxNameLength = 64
+
+// validatePerson checks the name and surname of a student or a teacher before
+// they are stored.
+func validatePerson(name, surname string) error {
+	if err := validateName("name", name); err != nil {
+		return err
+	}
+	return validateName("surname", surname)
+}
+
+// validateName accepts non-empty names made of letters, optionally joined by
+// hyphens, apostrophes or spaces (e.g. "Anna-Marija").
+func validateName(field, value string) error {
+	if strings.TrimSpace(value) != value {
+		return &ValidationError{Field: field, Reason: "must not start or end with a space"}
+	}
+	if value == "" {
+		return &ValidationError{Field: field, Reason: "must not be empty"}
+	}
+	if len([]rune(value)) > maxNameLength {
+		return &ValidationError{Field: field, Reason: "is too long"}
+	}
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !strings.ContainsRune("-' ", r) {
+			return &ValidationError{Field: field, Reason: "may contain letters only"}
+		}
+	}
+	return nil
+}
+
+// validateClass checks the fields of a class before they are stored.
+func validateClass(year, modifier string) error {
+	if y, err := strconv.Atoi(year); err != nil || y < 1 || y > 12 {
+		return &ValidationError{Field: "year", Reason: "must be a number from 1 to 12"}
+	}
+	if modifier == "" {
+		return &ValidationError{Field: "modifier", Reason: "must not be empty"}
+	}
+	for _, r := range modifier {
+		if !unicode.IsLetter(r) {
+			return &ValidationError{Field: "modifier", Reason: "may contain letters only"}
+		}
+	}
+	return nil
+}
+
+// validateSubject accepts non-empty subject names, e.g. "Mathematics" or
+// "English (B2)".
+func validateSubject(name string) error {
+	if strings.TrimSpace(name) != name {
+		return &ValidationError{Field: "name", Reason: "must not start or end with a space"}
+	}
+	if name == "" {
+		return &ValidationError{Field: "name", Reason: "must not be empty"}
+	}
+	if len([]rune(name)) > maxNameLength {
+		return &ValidationError{Field: "name", Reason: "is too long"}
+	}
+	for _, r := range name {
+		if !unicode.IsPrint(r) {
+			return &ValidationError{Field: "name", Reason: "must not contain control characters"}
+		}
+	}
+	return nil
+}
+
+// validateSchoolYear accepts school years written as "2025/2026".
+func validateSchoolYear(year string) error {
+	const reason = "must look like 2025/2026"
+	parts := strings.Split(year, "/")
+	if len(parts) != 2 || len(parts[0]) != 4 || len(parts[1]) != 4 {
+		return &ValidationError{Field: "school year", Reason: reason}
+	}
+	start, err1 := strconv.Atoi(parts[0])
+	end, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || end != start+1 {
+		return &ValidationError{Field: "school year", Reason: reason}
+	}
+	return nil
+}